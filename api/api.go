@@ -1,7 +1,14 @@
 package api
 
 import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/multistate"
 )
 
@@ -15,11 +22,67 @@ const (
 	PathSubmitTransaction   = "/submit_tx"
 	PathGetSyncInfo         = "/sync_info"
 	PathGetNodeInfo         = "/node_info"
+	PathSimulateTransaction = "/simulate_tx"
+	PathGetLedgerIdentity   = "/get_ledger_identity"
+	PathGetTokenOutputs     = "/get_token_outputs"
+	PathGetLedgerLibrary    = "/get_ledger_library"
+	PathGetAccountHistory   = "/get_account_history"
+	PathGetChainHistory     = "/get_chain_history"
+	PathGetFinalizedBranch  = "/get_finalized_branch"
+	PathGetStateDiff        = "/get_state_diff"
+)
+
+// Versioned API. Same response structures as the unversioned paths, but errors are returned
+// with proper HTTP status codes and typed error codes. The schema is described in openapi.yaml
+const (
+	PrefixV1                  = "/v1"
+	PathV1GetLedgerID         = PrefixV1 + "/ledger_id"
+	PathV1GetAccountOutputs   = PrefixV1 + "/account_outputs"
+	PathV1GetChainOutput      = PrefixV1 + "/chain_output"
+	PathV1GetOutput           = PrefixV1 + "/output"
+	PathV1QueryTxStatus       = PrefixV1 + "/tx_status"
+	PathV1QueryInclusionScore = PrefixV1 + "/inclusion_score"
+	PathV1SubmitTransaction   = PrefixV1 + "/submit_tx"
+	PathV1GetSyncInfo         = PrefixV1 + "/sync_info"
+	PathV1GetNodeInfo         = PrefixV1 + "/node_info"
+	PathV1SimulateTransaction = PrefixV1 + "/simulate_tx"
+	PathV1GetLedgerIdentity   = PrefixV1 + "/ledger_identity"
+	PathV1GetTokenOutputs     = PrefixV1 + "/token_outputs"
+	PathV1GetLedgerLibrary    = PrefixV1 + "/ledger_library"
+	PathV1GetAccountHistory   = PrefixV1 + "/account_history"
+	PathV1GetChainHistory     = PrefixV1 + "/chain_history"
+	PathV1GetFinalizedBranch  = PrefixV1 + "/finalized_branch"
+	PathV1GetStateDiff        = PrefixV1 + "/state_diff"
+	PathV1OpenAPI             = PrefixV1 + "/openapi.yaml"
+)
+
+// OpenAPISpecV1 is the schema of the versioned API in OpenAPI 3 YAML format
+//
+//go:embed openapi.yaml
+var OpenAPISpecV1 []byte
+
+// error codes returned by the versioned API in the field 'code'
+const (
+	ErrCodeBadRequest       = "bad_request"
+	ErrCodeNotFound         = "not_found"
+	ErrCodeMethodNotAllowed = "method_not_allowed"
+	ErrCodeRejected         = "rejected"
+	ErrCodeNotImplemented   = "not_implemented"
+	ErrCodeInternal         = "internal"
 )
 
 type Error struct {
 	// empty string when no error
 	Error string `json:"error,omitempty"`
+	// typed error code. Only returned by the versioned API
+	Code string `json:"code,omitempty"`
+}
+
+// StatusError is an error with HTTP status and error code
+type StatusError struct {
+	Status  int
+	Code    string
+	Message string
 }
 
 type LedgerID struct {
@@ -28,6 +91,36 @@ type LedgerID struct {
 	LedgerIDBytes string `json:"ledger_id_bytes,omitempty"`
 }
 
+// LedgerLibrary is returned by 'get_ledger_library'
+type LedgerLibrary struct {
+	Error
+	// hex-encoded library hash
+	LibraryHash string `json:"library_hash,omitempty"`
+	// hex-encoded ledger library bytes
+	LibraryBytes string `json:"library_bytes,omitempty"`
+	// hex-encoded library upgrade constraints applied by the node, in the order of function codes
+	Upgrades []string `json:"upgrades,omitempty"`
+}
+
+// LedgerIdentity is returned by 'get_ledger_identity'. Decoded ledger identity data with values derived from it
+// for the current slot and the supply in the latest heaviest branch
+type LedgerIdentity struct {
+	Error
+	ledger.IdentityDataYAMLAble
+	SlotDurationNanosec int64  `json:"slot_duration_nanosec"`
+	TicksPerSlot        int    `json:"ticks_per_slot"`
+	GenesisOutputID     string `json:"genesis_output_id"`
+	GenesisStemOutputID string `json:"genesis_stem_output_id"`
+	// current slot by the clock of the node
+	CurrentSlot  uint32 `json:"current_slot"`
+	HalvingEpoch uint64 `json:"halving_epoch"`
+	// inflation per tick of the chain in the current slot is amount/ChainInflationPerTickFraction
+	ChainInflationPerTickFraction uint64 `json:"chain_inflation_per_tick_fraction"`
+	// total supply in the latest heaviest branch
+	Supply     uint64 `json:"supply"`
+	SupplySlot uint32 `json:"supply_slot"`
+}
+
 // OutputList is returned by 'get_account_outputs'
 type OutputList struct {
 	Error
@@ -45,6 +138,68 @@ type ChainOutput struct {
 	OutputData string `json:"output_data,omitempty"`
 }
 
+// AccountHistory is returned by 'get_account_history'. Transactions are ordered the latest first
+type AccountHistory struct {
+	Error
+	Transactions []AccountHistoryItem `json:"transactions,omitempty"`
+}
+
+type AccountHistoryItem struct {
+	// hex-encoded transaction ID
+	TxID string `json:"txid"`
+	// total amount of outputs produced by the transaction to the account
+	In uint64 `json:"in"`
+	// total amount of outputs of the account consumed by the transaction
+	Out uint64 `json:"out"`
+}
+
+// ChainHistory is returned by 'get_chain_history'. Outputs are ordered the latest first
+type ChainHistory struct {
+	Error
+	Outputs []ChainHistoryItem `json:"outputs,omitempty"`
+}
+
+type ChainHistoryItem struct {
+	// hex-encoded output ID
+	OutputID string `json:"output_id"`
+	Amount   uint64 `json:"amount"`
+}
+
+// AccountTx is the record of the account history kept by the indexer on the node.
+// It is the transaction which produced outputs to the account and/or consumed outputs of the account
+type AccountTx struct {
+	TxID ledger.TransactionID
+	// In is total amount of outputs produced by the transaction and locked in the account
+	In uint64
+	// Out is total amount of outputs of the account consumed by the transaction
+	Out uint64
+}
+
+// ChainHistoryOutput is the record of the chain history kept by the indexer on the node
+type ChainHistoryOutput struct {
+	ID     ledger.OutputID
+	Amount uint64
+}
+
+// ErrIndexerNotEnabled is returned by account and chain history queries if indexer is not running on the node
+var ErrIndexerNotEnabled = errors.New("indexer is not enabled on the node")
+
+// FinalizedBranch is returned by 'get_finalized_branch'. It is the latest branch recorded as final by the finality tracker
+// of the node. Outputs in the state of the finalized branch are final
+type FinalizedBranch struct {
+	Error
+	// hex-encoded transaction ID of the branch
+	BranchID   string                         `json:"branch_id,omitempty"`
+	Slot       uint32                         `json:"slot"`
+	RootRecord *multistate.RootRecordJSONAble `json:"root_record,omitempty"`
+}
+
+// StateDiff is returned by 'get_state_diff'. It is the difference from the state of branch1 to the state of branch2
+type StateDiff struct {
+	Error
+	Diff *multistate.StateDiffJSONAble `json:"diff,omitempty"`
+}
+
 // OutputData is returned by 'get_output'
 type OutputData struct {
 	Error
@@ -73,6 +228,55 @@ type QueryTxInclusionScore struct {
 	TxInclusionScore
 }
 
+// submit_tx final statuses in the wait mode
+const (
+	SubmitStatusIncluded = "included"
+	SubmitStatusRejected = "rejected"
+	SubmitStatusTimeout  = "timeout"
+)
+
+// DefaultSubmitWaitTimeout is used by 'submit_tx' in the wait mode when parameter 'timeout' is absent
+const DefaultSubmitWaitTimeout = 10 * time.Second
+
+// SubmitTxResult is returned by 'submit_tx' in the wait mode
+type SubmitTxResult struct {
+	Error
+	// hex-encoded transaction ID
+	TxID string `json:"txid,omitempty"`
+	// one of 'included', 'rejected' or 'timeout'
+	Status string `json:"status,omitempty"`
+	// error reported by the attacher when status is 'rejected'
+	RejectReason string `json:"reject_reason,omitempty"`
+	// the latest inclusion score
+	Score *TxInclusionScore `json:"score,omitempty"`
+}
+
+// SimulateTxResult is returned by 'simulate_tx'
+type SimulateTxResult struct {
+	Error
+	// hex-encoded transaction ID. Empty if transaction bytes cannot be parsed
+	TxID string `json:"txid,omitempty"`
+	// true if transaction is valid in the context of the latest heaviest state
+	Valid bool `json:"valid"`
+	// validation error if transaction is not valid
+	ValidationError string `json:"validation_error,omitempty"`
+	// constraint which failed and its path in the transaction context tree, if failure was in the constraint
+	FailedConstraint     string `json:"failed_constraint,omitempty"`
+	FailedConstraintPath string `json:"failed_constraint_path,omitempty"`
+	// indices of consumed outputs which failed validation
+	FailedConsumedOutputs []int `json:"failed_consumed_outputs,omitempty"`
+	// state mutations the transaction would make, if valid
+	Mutations *StateMutations `json:"mutations,omitempty"`
+}
+
+// StateMutations UTXO changes made by the transaction
+type StateMutations struct {
+	// hex-encoded IDs of consumed outputs
+	Deleted []string `json:"deleted"`
+	// key is hex-encoded output ID, value is hex-encoded output data
+	Added map[string]string `json:"added"`
+}
+
 type (
 	SyncInfo struct {
 		Error
@@ -89,6 +293,31 @@ type (
 
 const ErrGetOutputNotFound = "output not found"
 
+func NewStatusError(status int, code string, format string, args ...any) *StatusError {
+	return &StatusError{
+		Status:  status,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// StatusErrorFromResponse makes error from the error response of the versioned API
+func StatusErrorFromResponse(status int, resp *Error) *StatusError {
+	code := resp.Code
+	if code == "" {
+		code = ErrCodeInternal
+	}
+	msg := resp.Error
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	return &StatusError{Status: status, Code: code, Message: msg}
+}
+
 func CalcTxInclusionScore(inclusion *multistate.TxInclusion, thresholdNumerator, thresholdDenominator int) TxInclusionScore {
 	ret := TxInclusionScore{
 		ThresholdNumerator:   thresholdNumerator,
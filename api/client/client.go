@@ -3,6 +3,7 @@ package client
 import (
 	"bytes"
 	"crypto/ed25519"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -27,8 +28,9 @@ import (
 const apiDefaultClientTimeout = 7 * time.Second
 
 type APIClient struct {
-	c      http.Client
-	prefix string
+	c           http.Client
+	prefix      string
+	bearerToken string
 }
 
 func New(serverURL string, timeout ...time.Duration) *APIClient {
@@ -44,6 +46,33 @@ func New(serverURL string, timeout ...time.Duration) *APIClient {
 	}
 }
 
+// WithBearerToken makes client to send 'Authorization: Bearer <token>' header with each request
+func (c *APIClient) WithBearerToken(token string) *APIClient {
+	c.bearerToken = token
+	return c
+}
+
+// WithTLSConfig sets TLS configuration for 'https' endpoints, for example the client certificate for mTLS
+func (c *APIClient) WithTLSConfig(cfg *tls.Config) *APIClient {
+	c.c.Transport = &http.Transport{TLSClientConfig: cfg}
+	return c
+}
+
+func (c *APIClient) do(req *http.Request) (*http.Response, error) {
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusTooManyRequests {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("from server: %s", resp.Status)
+	}
+	return resp, nil
+}
+
 // GetLedgerID retrieves ledger ID from server
 func (c *APIClient) GetLedgerID() (*ledger.IdentityData, error) {
 	body, err := c.getBody(api.PathGetLedgerID)
@@ -75,6 +104,57 @@ func (c *APIClient) GetLedgerID() (*ledger.IdentityData, error) {
 	return ret, nil
 }
 
+// GetLedgerLibrary retrieves ledger library data bytes from server. The hash of it is checked against the library hash.
+// Also returns bytes of library upgrade constraints applied by the node
+func (c *APIClient) GetLedgerLibrary() ([]byte, [][]byte, error) {
+	body, err := c.getBody(api.PathGetLedgerLibrary)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res api.LedgerLibrary
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.Error.Error != "" {
+		return nil, nil, fmt.Errorf("GetLedgerLibrary: from server: %s", res.Error.Error)
+	}
+	libBin, err := hex.DecodeString(res.LibraryBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GetLedgerLibrary: error while decoding data: %w", err)
+	}
+	h := blake2b.Sum256(libBin)
+	if hex.EncodeToString(h[:]) != res.LibraryHash {
+		return nil, nil, fmt.Errorf("GetLedgerLibrary: library hash does not match library data")
+	}
+	upgrades := make([][]byte, len(res.Upgrades))
+	for i, u := range res.Upgrades {
+		if upgrades[i], err = hex.DecodeString(u); err != nil {
+			return nil, nil, fmt.Errorf("GetLedgerLibrary: error while decoding library upgrade: %w", err)
+		}
+	}
+	return libBin, upgrades, nil
+}
+
+// GetLedgerIdentity retrieves decoded ledger identity with derived values and the current supply
+func (c *APIClient) GetLedgerIdentity() (*api.LedgerIdentity, error) {
+	body, err := c.getBody(api.PathGetLedgerIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	var res api.LedgerIdentity
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error.Error != "" {
+		return nil, fmt.Errorf("GetLedgerIdentity: from server: %s", res.Error.Error)
+	}
+	return &res, nil
+}
+
 // getAccountOutputs fetches all outputs of the account
 func (c *APIClient) getAccountOutputs(accountable ledger.Accountable) ([]*ledger.OutputDataWithID, error) {
 	path := fmt.Sprintf(api.PathGetAccountOutputs+"?accountable=%s", accountable.String())
@@ -82,9 +162,22 @@ func (c *APIClient) getAccountOutputs(accountable ledger.Accountable) ([]*ledger
 	if err != nil {
 		return nil, err
 	}
+	return parseOutputList(body)
+}
 
+// getTokenOutputs fetches all outputs which carry the token
+func (c *APIClient) getTokenOutputs(tokenID ledger.TokenID) ([]*ledger.OutputDataWithID, error) {
+	path := fmt.Sprintf(api.PathGetTokenOutputs+"?tokenid=%s", tokenID.StringHex())
+	body, err := c.getBody(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseOutputList(body)
+}
+
+func parseOutputList(body []byte) ([]*ledger.OutputDataWithID, error) {
 	var res api.OutputList
-	err = json.Unmarshal(body, &res)
+	err := json.Unmarshal(body, &res)
 	if err != nil {
 		return nil, err
 	}
@@ -228,7 +321,7 @@ func (c *APIClient) SubmitTransaction(txBytes []byte, trace ...bool) error {
 	if err != nil {
 		return err
 	}
-	resp, err := c.c.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -250,6 +343,104 @@ func (c *APIClient) SubmitTransaction(txBytes []byte, trace ...bool) error {
 	return nil
 }
 
+type SubmitTransactionWaitParams struct {
+	ThresholdNumerator   int
+	ThresholdDenominator int
+	SlotSpan             int
+	// required inclusion score in percents. 0 means 100
+	Score int
+	// if true, weak score is checked, otherwise strong score
+	Weak bool
+	// 0 means the default timeout of the node
+	Timeout time.Duration
+	Trace   bool
+}
+
+// query returns query of the 'submit_tx' request in the wait mode. Optional parameters
+// with zero values are omitted, so that defaults of the node are used
+func (par *SubmitTransactionWaitParams) query() string {
+	ret := fmt.Sprintf("wait&threshold=%d-%d", par.ThresholdNumerator, par.ThresholdDenominator)
+	if par.SlotSpan > 0 {
+		ret += fmt.Sprintf("&slots=%d", par.SlotSpan)
+	}
+	if par.Score > 0 {
+		ret += fmt.Sprintf("&score=%d", par.Score)
+	}
+	if par.Weak {
+		ret += "&weak"
+	}
+	if par.Timeout > 0 {
+		ret += fmt.Sprintf("&timeout=%d", int(par.Timeout/time.Second))
+	}
+	if par.Trace {
+		ret += "&trace=true"
+	}
+	return ret
+}
+
+// SubmitTransactionWait submits transaction and blocks until it is included with required score,
+// is rejected by the node, or timeout expires. Returns final status reported by the node
+func (c *APIClient) SubmitTransactionWait(txBytes []byte, par SubmitTransactionWaitParams) (*api.SubmitTxResult, error) {
+	url := c.prefix + api.PathSubmitTransaction + "?" + par.query()
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(txBytes))
+	if err != nil {
+		return nil, err
+	}
+	// the request blocks on the server up to the timeout, so the client timeout must be longer
+	waitTimeout := par.Timeout
+	if waitTimeout == 0 {
+		waitTimeout = api.DefaultSubmitWaitTimeout
+	}
+	cWait := *c
+	if cWait.c.Timeout != 0 && cWait.c.Timeout < waitTimeout+apiDefaultClientTimeout {
+		cWait.c.Timeout = waitTimeout + apiDefaultClientTimeout
+	}
+	resp, err := cWait.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var res api.SubmitTxResult
+	if err = json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if res.Error.Error != "" {
+		return nil, fmt.Errorf("from server: %s", res.Error.Error)
+	}
+	return &res, nil
+}
+
+// SimulateTransaction validates transaction in the latest heaviest state of the node without submitting it
+func (c *APIClient) SimulateTransaction(txBytes []byte) (*api.SimulateTxResult, error) {
+	req, err := http.NewRequest(http.MethodPost, c.prefix+api.PathSimulateTransaction, bytes.NewBuffer(txBytes))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var res api.SimulateTxResult
+	if err = json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if res.Error.Error != "" {
+		return nil, fmt.Errorf("from server: %s", res.Error.Error)
+	}
+	return &res, nil
+}
+
 func (c *APIClient) GetAccountOutputs(account ledger.Accountable, filter ...func(o *ledger.Output) bool) ([]*ledger.OutputWithID, error) {
 	filterFun := func(o *ledger.Output) bool { return true }
 	if len(filter) > 0 {
@@ -267,6 +458,120 @@ func (c *APIClient) GetAccountOutputs(account ledger.Accountable, filter ...func
 	return outs, nil
 }
 
+// GetTokenOutputs returns all outputs which carry the token, sorted descending by token amount
+func (c *APIClient) GetTokenOutputs(tokenID ledger.TokenID) ([]*ledger.OutputWithID, error) {
+	oData, err := c.getTokenOutputs(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	outs, err := txutils.ParseAndSortOutputData(oData, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(outs, func(i, j int) bool {
+		ti, _ := outs[i].Output.Token()
+		tj, _ := outs[j].Output.Token()
+		return ti.Amount > tj.Amount
+	})
+	return outs, nil
+}
+
+// GetAccountHistory returns transactions of the account recorded by the indexer on the node, the latest first
+func (c *APIClient) GetAccountHistory(account ledger.Accountable, maxItems int) ([]api.AccountTx, error) {
+	path := fmt.Sprintf(api.PathGetAccountHistory+"?accountable=%s&max=%d", account.String(), maxItems)
+	body, err := c.getBody(path)
+	if err != nil {
+		return nil, err
+	}
+	var res api.AccountHistory
+	if err = json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if res.Error.Error != "" {
+		return nil, fmt.Errorf("from server: %s", res.Error.Error)
+	}
+	ret := make([]api.AccountTx, len(res.Transactions))
+	for i, item := range res.Transactions {
+		if ret[i].TxID, err = ledger.TransactionIDFromHexString(item.TxID); err != nil {
+			return nil, fmt.Errorf("wrong transaction ID data from server: %s", item.TxID)
+		}
+		ret[i].In, ret[i].Out = item.In, item.Out
+	}
+	return ret, nil
+}
+
+// GetChainHistory returns outputs of the chain recorded by the indexer on the node, the latest first
+func (c *APIClient) GetChainHistory(chainID ledger.ChainID, maxItems int) ([]api.ChainHistoryOutput, error) {
+	path := fmt.Sprintf(api.PathGetChainHistory+"?chainid=%s&max=%d", chainID.StringHex(), maxItems)
+	body, err := c.getBody(path)
+	if err != nil {
+		return nil, err
+	}
+	var res api.ChainHistory
+	if err = json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if res.Error.Error != "" {
+		return nil, fmt.Errorf("from server: %s", res.Error.Error)
+	}
+	ret := make([]api.ChainHistoryOutput, len(res.Outputs))
+	for i, item := range res.Outputs {
+		if ret[i].ID, err = ledger.OutputIDFromHexString(item.OutputID); err != nil {
+			return nil, fmt.Errorf("wrong output ID data from server: %s", item.OutputID)
+		}
+		ret[i].Amount = item.Amount
+	}
+	return ret, nil
+}
+
+// GetFinalizedBranch returns the latest branch recorded as final by the node and its root record
+func (c *APIClient) GetFinalizedBranch() (ledger.TransactionID, *multistate.RootRecord, error) {
+	body, err := c.getBody(api.PathGetFinalizedBranch)
+	if err != nil {
+		return ledger.TransactionID{}, nil, err
+	}
+	var res api.FinalizedBranch
+	if err = json.Unmarshal(body, &res); err != nil {
+		return ledger.TransactionID{}, nil, err
+	}
+	if res.Error.Error != "" {
+		return ledger.TransactionID{}, nil, fmt.Errorf("from server: %s", res.Error.Error)
+	}
+	branchID, err := ledger.TransactionIDFromHexString(res.BranchID)
+	if err != nil {
+		return ledger.TransactionID{}, nil, fmt.Errorf("wrong branch ID data from server: %s", res.BranchID)
+	}
+	if res.RootRecord == nil {
+		return ledger.TransactionID{}, nil, fmt.Errorf("root record is missing in the response from server")
+	}
+	rr, err := res.RootRecord.Parse()
+	if err != nil {
+		return ledger.TransactionID{}, nil, err
+	}
+	return branchID, rr, nil
+}
+
+// GetStateDiff returns difference from the state of branch1 to the state of branch2.
+// The endpoint is served by the admin listener of the node only
+func (c *APIClient) GetStateDiff(branch1, branch2 ledger.TransactionID) (*multistate.StateDiff, error) {
+	path := fmt.Sprintf(api.PathGetStateDiff+"?branch1=%s&branch2=%s", branch1.StringHex(), branch2.StringHex())
+	body, err := c.getBody(path)
+	if err != nil {
+		return nil, err
+	}
+	var res api.StateDiff
+	if err = json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if res.Error.Error != "" {
+		return nil, fmt.Errorf("from server: %s", res.Error.Error)
+	}
+	if res.Diff == nil {
+		return nil, fmt.Errorf("state diff is missing in the response from server")
+	}
+	return res.Diff.Parse()
+}
+
 func (c *APIClient) QueryTxIDStatus(txid *ledger.TransactionID, slotSpan int) (*vertex.TxIDStatus, *multistate.TxInclusion, error) {
 	var path string
 	if txid != nil {
@@ -335,6 +640,10 @@ func (c *APIClient) GetTransferableOutputs(account ledger.Accountable, ts ledger
 		if idx != 0xff {
 			return false
 		}
+		// filter out outputs with native tokens, they are transferred by token transfers only
+		if token, _ := o.Token(); token != nil {
+			return false
+		}
 		if !o.Lock().UnlockableWith(account.AccountID(), ts) {
 			return false
 		}
@@ -363,81 +672,122 @@ func (c *APIClient) GetTransferableOutputs(account ledger.Accountable, ts ledger
 // MakeCompactTransaction requests server and creates a compact transaction for ED25519 outputs in the form of transaction context. Does not submit it
 func (c *APIClient) MakeCompactTransaction(walletPrivateKey ed25519.PrivateKey, tagAlongSeqID *ledger.ChainID, tagAlongFee uint64, maxInputs ...int) (*transaction.TxContext, error) {
 	walletAccount := ledger.AddressED25519FromPrivateKey(walletPrivateKey)
+	txBytes, walletOutputs, err := c.makeCompactTransaction(walletAccount, walletPrivateKey, tagAlongSeqID, tagAlongFee, maxInputs...)
+	if err != nil || txBytes == nil {
+		return nil, err
+	}
+	txCtx, err := transaction.TxContextFromTransferableBytes(txBytes, transaction.PickOutputFromListFunc(walletOutputs))
+	if err != nil {
+		return nil, err
+	}
+	return txCtx, err
+}
+
+// MakeCompactTransactionUnsigned creates unsigned compact transaction for ED25519 outputs of the account, to be signed offline.
+// Returns nil if there is nothing to compact
+func (c *APIClient) MakeCompactTransactionUnsigned(walletAccount ledger.AddressED25519, tagAlongSeqID *ledger.ChainID, tagAlongFee uint64, maxInputs ...int) ([]byte, []*ledger.OutputWithID, error) {
+	return c.makeCompactTransaction(walletAccount, nil, tagAlongSeqID, tagAlongFee, maxInputs...)
+}
 
+func (c *APIClient) makeCompactTransaction(walletAccount ledger.AddressED25519, walletPrivateKey ed25519.PrivateKey, tagAlongSeqID *ledger.ChainID, tagAlongFee uint64, maxInputs ...int) ([]byte, []*ledger.OutputWithID, error) {
 	nowisTs := ledger.TimeNow()
-	inTotal := uint64(0)
 
 	walletOutputs, inTotal, err := c.GetTransferableOutputs(walletAccount, nowisTs, maxInputs...)
+	if err != nil {
+		return nil, nil, err
+	}
 	if len(walletOutputs) <= 1 {
-		return nil, nil
+		return nil, nil, nil
 	}
 	if inTotal < tagAlongFee {
-		return nil, fmt.Errorf("non enough balance for fees")
+		return nil, nil, fmt.Errorf("non enough balance for fees")
 	}
 	txBytes, err := MakeTransferTransaction(MakeTransferTransactionParams{
 		Inputs:        walletOutputs,
 		Target:        walletAccount,
 		Amount:        inTotal - tagAlongFee,
+		Remainder:     walletAccount,
 		PrivateKey:    walletPrivateKey,
 		TagAlongSeqID: tagAlongSeqID,
 		TagAlongFee:   tagAlongFee,
 		Timestamp:     nowisTs,
 	})
 	if err != nil {
-		return nil, err
-	}
-
-	txCtx, err := transaction.TxContextFromTransferableBytes(txBytes, transaction.PickOutputFromListFunc(walletOutputs))
-	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return txCtx, err
+	return txBytes, walletOutputs, nil
 }
 
 type TransferFromED25519WalletParams struct {
 	WalletPrivateKey ed25519.PrivateKey
-	TagAlongSeqID    *ledger.ChainID
-	TagAlongFee      uint64 // 0 means no fee output will be produced
-	Amount           uint64
-	Target           ledger.Lock
-	MaxOutputs       int
-	TraceTx          bool
+	// source account if WalletPrivateKey is nil. Then transaction is built unsigned
+	WalletAccount ledger.AddressED25519
+	TagAlongSeqID *ledger.ChainID
+	TagAlongFee   uint64 // 0 means no fee output will be produced
+	Amount        uint64
+	Target        ledger.Lock
+	MaxOutputs    int
+	TraceTx       bool
+}
+
+func (par *TransferFromED25519WalletParams) walletAccount() ledger.AddressED25519 {
+	if par.WalletPrivateKey != nil {
+		return ledger.AddressED25519FromPrivateKey(par.WalletPrivateKey)
+	}
+	return par.WalletAccount
 }
 
 const minimumTransferAmount = uint64(1000)
 
 func (c *APIClient) TransferFromED25519Wallet(par TransferFromED25519WalletParams) (*transaction.TxContext, error) {
+	txBytes, walletOutputs, err := c.MakeTransferFromED25519Wallet(par)
+	if err != nil {
+		return nil, err
+	}
+	txCtx, err := transaction.TxContextFromTransferableBytes(txBytes, transaction.PickOutputFromListFunc(walletOutputs))
+	if err != nil {
+		return nil, err
+	}
+	err = c.SubmitTransaction(txBytes, par.TraceTx)
+	return txCtx, err
+}
+
+// MakeTransferFromED25519Wallet builds transfer transaction from the wallet account without submitting it.
+// Returns transaction bytes and consumed outputs. Transaction is unsigned if WalletPrivateKey is nil
+func (c *APIClient) MakeTransferFromED25519Wallet(par TransferFromED25519WalletParams) ([]byte, []*ledger.OutputWithID, error) {
 	if par.Amount < minimumTransferAmount {
-		return nil, fmt.Errorf("minimum transfer amount is %d", minimumTransferAmount)
+		return nil, nil, fmt.Errorf("minimum transfer amount is %d", minimumTransferAmount)
 	}
-	walletAccount := ledger.AddressED25519FromPrivateKey(par.WalletPrivateKey)
+	walletAccount := par.walletAccount()
 	nowisTs := ledger.TimeNow()
 
 	walletOutputs, _, err := c.GetTransferableOutputs(walletAccount, nowisTs, par.MaxOutputs)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	txBytes, err := MakeTransferTransaction(MakeTransferTransactionParams{
 		Inputs:        walletOutputs,
 		Target:        par.Target,
 		Amount:        par.Amount,
+		Remainder:     walletAccount,
 		PrivateKey:    par.WalletPrivateKey,
 		TagAlongSeqID: par.TagAlongSeqID,
 		TagAlongFee:   par.TagAlongFee,
 		Timestamp:     nowisTs,
 	})
 	if err != nil {
-		return nil, err
-	}
-	txCtx, err := transaction.TxContextFromTransferableBytes(txBytes, transaction.PickOutputFromListFunc(walletOutputs))
-	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	err = c.SubmitTransaction(txBytes, par.TraceTx)
-	return txCtx, err
+	return txBytes, walletOutputs, nil
 }
 
 func (c *APIClient) getBody(path string) ([]byte, error) {
-	url := c.prefix + path
-	resp, err := c.c.Get(url)
+	req, err := http.NewRequest(http.MethodGet, c.prefix+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("GET returned: %v", err)
 	}
@@ -451,22 +801,47 @@ func (c *APIClient) getBody(path string) ([]byte, error) {
 }
 
 func (c *APIClient) MakeChainOrigin(par TransferFromED25519WalletParams) (*transaction.TxContext, ledger.ChainID, error) {
+	txBytes, inps, err := c.MakeChainOriginTransaction(par)
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+
+	txCtx, err := transaction.TxContextFromTransferableBytes(txBytes, transaction.PickOutputFromListFunc(inps))
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+	if err = c.SubmitTransaction(txBytes); err != nil {
+		return nil, [32]byte{}, err
+	}
+
+	oChain, err := transaction.OutputWithIDFromTransactionBytes(txBytes, 0)
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+
+	chainID := blake2b.Sum256(oChain.ID[:])
+	return txCtx, chainID, err
+}
+
+// MakeChainOriginTransaction builds chain origin transaction without submitting it. Chain origin is the output #0.
+// Returns transaction bytes and consumed outputs. Transaction is unsigned if WalletPrivateKey is nil
+func (c *APIClient) MakeChainOriginTransaction(par TransferFromED25519WalletParams) ([]byte, []*ledger.OutputWithID, error) {
 	if par.Amount < minimumTransferAmount {
-		return nil, ledger.NilChainID, fmt.Errorf("minimum transfer amount is %d", minimumTransferAmount)
+		return nil, nil, fmt.Errorf("minimum transfer amount is %d", minimumTransferAmount)
 	}
 	if par.Amount > 0 && par.TagAlongSeqID == nil {
-		return nil, [32]byte{}, fmt.Errorf("tag-along sequencer not specified")
+		return nil, nil, fmt.Errorf("tag-along sequencer not specified")
 	}
 
-	walletAccount := ledger.AddressED25519FromPrivateKey(par.WalletPrivateKey)
+	walletAccount := par.walletAccount()
 
 	ts := ledger.TimeNow()
 	inps, totalInputs, err := c.GetTransferableOutputs(walletAccount, ts)
 	if err != nil {
-		return nil, [32]byte{}, err
+		return nil, nil, err
 	}
 	if totalInputs < par.Amount+par.TagAlongFee {
-		return nil, [32]byte{}, fmt.Errorf("not enough source balance %s", util.GoTh(totalInputs))
+		return nil, nil, fmt.Errorf("not enough source balance %s", util.GoTh(totalInputs))
 	}
 
 	totalInputs = 0
@@ -481,7 +856,7 @@ func (c *APIClient) MakeChainOrigin(par TransferFromED25519WalletParams) (*trans
 	txb := txbuilder.NewTransactionBuilder()
 	_, ts1, err := txb.ConsumeOutputs(inps...)
 	if err != nil {
-		return nil, [32]byte{}, err
+		return nil, nil, err
 	}
 	ts = ledger.MaxTime(ts1.AddTicks(ledger.TransactionPace()), ts)
 
@@ -502,7 +877,7 @@ func (c *APIClient) MakeChainOrigin(par TransferFromED25519WalletParams) (*trans
 				WithLock(ledger.ChainLockFromChainID(*par.TagAlongSeqID))
 		})
 		if _, err = txb.ProduceOutput(tagAlongFeeOut); err != nil {
-			return nil, [32]byte{}, err
+			return nil, nil, err
 		}
 	}
 
@@ -512,37 +887,23 @@ func (c *APIClient) MakeChainOrigin(par TransferFromED25519WalletParams) (*trans
 				WithLock(walletAccount)
 		})
 		if _, err = txb.ProduceOutput(remainder); err != nil {
-			return nil, [32]byte{}, err
+			return nil, nil, err
 		}
 	}
 	txb.TransactionData.Timestamp = ts
 	txb.TransactionData.InputCommitment = txb.InputCommitment()
-	txb.SignED25519(par.WalletPrivateKey)
-
-	txBytes := txb.TransactionData.Bytes()
-
-	txCtx, err := transaction.TxContextFromTransferableBytes(txBytes, transaction.PickOutputFromListFunc(inps))
-	if err != nil {
-		return nil, [32]byte{}, err
-	}
-	if err = c.SubmitTransaction(txBytes); err != nil {
-		return nil, [32]byte{}, err
-	}
-
-	oChain, err := transaction.OutputWithIDFromTransactionBytes(txBytes, 0)
-	if err != nil {
-		return nil, [32]byte{}, err
+	if par.WalletPrivateKey != nil {
+		txb.SignED25519(par.WalletPrivateKey)
 	}
-
-	chainID := blake2b.Sum256(oChain.ID[:])
-	return txCtx, chainID, err
+	return txb.TransactionData.Bytes(), inps, nil
 }
 
 type MakeTransferTransactionParams struct {
-	Inputs        []*ledger.OutputWithID
-	Target        ledger.Lock
-	Amount        uint64
-	Remainder     ledger.Lock
+	Inputs    []*ledger.OutputWithID
+	Target    ledger.Lock
+	Amount    uint64
+	Remainder ledger.Lock
+	// if nil, transaction is not signed. Then Remainder must be specified
 	PrivateKey    ed25519.PrivateKey
 	TagAlongSeqID *ledger.ChainID
 	TagAlongFee   uint64
@@ -597,6 +958,9 @@ func MakeTransferTransaction(par MakeTransferTransactionParams) ([]byte, error)
 	if inTotal > par.Amount+par.TagAlongFee {
 		remainderLock := par.Remainder
 		if remainderLock == nil {
+			if par.PrivateKey == nil {
+				return nil, fmt.Errorf("remainder lock not specified")
+			}
 			remainderLock = ledger.AddressED25519FromPrivateKey(par.PrivateKey)
 		}
 		remainderOut := ledger.NewOutput(func(o *ledger.Output) {
@@ -610,7 +974,8 @@ func MakeTransferTransaction(par MakeTransferTransactionParams) ([]byte, error)
 
 	txb.TransactionData.Timestamp = par.Timestamp
 	txb.TransactionData.InputCommitment = txb.InputCommitment()
-	txb.SignED25519(par.PrivateKey)
-
+	if par.PrivateKey != nil {
+		txb.SignED25519(par.PrivateKey)
+	}
 	return txb.TransactionData.Bytes(), nil
 }
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitTransactionWait(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.EqualValues(t, api.PathSubmitTransaction, r.URL.Path)
+		query = r.URL.Query()
+		_ = json.NewEncoder(w).Encode(&api.SubmitTxResult{TxID: "01", Status: api.SubmitStatusIncluded})
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+
+	t.Run("node default timeout", func(t *testing.T) {
+		res, err := c.SubmitTransactionWait([]byte{1}, SubmitTransactionWaitParams{ThresholdNumerator: 2, ThresholdDenominator: 3})
+		require.NoError(t, err)
+		require.EqualValues(t, api.SubmitStatusIncluded, res.Status)
+		require.True(t, query.Has("wait"))
+		require.EqualValues(t, "2-3", query.Get("threshold"))
+		require.False(t, query.Has("timeout"))
+		require.False(t, query.Has("slots"))
+		require.False(t, query.Has("score"))
+		require.False(t, query.Has("weak"))
+	})
+	t.Run("all parameters", func(t *testing.T) {
+		_, err := c.SubmitTransactionWait([]byte{1}, SubmitTransactionWaitParams{
+			ThresholdNumerator:   1,
+			ThresholdDenominator: 2,
+			SlotSpan:             3,
+			Score:                90,
+			Weak:                 true,
+			Timeout:              5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, "1-2", query.Get("threshold"))
+		require.EqualValues(t, "5", query.Get("timeout"))
+		require.EqualValues(t, "3", query.Get("slots"))
+		require.EqualValues(t, "90", query.Get("score"))
+		require.True(t, query.Has("weak"))
+	})
+}
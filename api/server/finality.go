@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/unitrie/common"
+)
+
+// getFinalizedBranch returns the latest branch recorded as final by the finality tracker of the node
+func (srv *Server) getFinalizedBranch(_ *http.Request) (any, error) {
+	srv.Tracef(TraceTag, "getFinalizedBranch invoked")
+
+	var ret *api.FinalizedBranch
+	err := util.CatchPanicOrError(func() error {
+		ret = finalizedBranch(srv.StateStore())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, errNotFound("finalized branch is not known yet")
+	}
+	return ret, nil
+}
+
+// finalizedBranch returns nil if no finalized branch is recorded
+func finalizedBranch(store common.KVReader) *api.FinalizedBranch {
+	branchID, found := multistate.FetchFinalizedBranch(store)
+	if !found {
+		return nil
+	}
+	rr, found := multistate.FetchRootRecord(store, branchID)
+	if !found {
+		return nil
+	}
+	return &api.FinalizedBranch{
+		BranchID:   branchID.StringHex(),
+		Slot:       uint32(branchID.Slot()),
+		RootRecord: rr.JSONAble(),
+	}
+}
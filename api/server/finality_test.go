@@ -0,0 +1,24 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinalizedBranch(t *testing.T) {
+	store := common.NewInMemoryKVStore()
+	multistate.InitStateStore(*ledger.L().ID, store)
+	require.Nil(t, finalizedBranch(store))
+
+	genesisBranchID := *ledger.GenesisTransactionID()
+	require.NoError(t, multistate.WriteFinalizedBranch(store, genesisBranchID))
+	res := finalizedBranch(store)
+	require.NotNil(t, res)
+	require.EqualValues(t, genesisBranchID.StringHex(), res.BranchID)
+	require.EqualValues(t, 0, res.Slot)
+	require.EqualValues(t, ledger.L().ID.InitialSupply, res.RootRecord.Supply)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/util"
+)
+
+type (
+	// handlerFunc returns response structure to be JSON-encoded or error.
+	// Errors of type *api.StatusError carry HTTP status and error code, other errors are treated as internal
+	handlerFunc func(r *http.Request) (any, error)
+
+	endpoint struct {
+		// unversioned path. Errors are returned in the body with status 200
+		path string
+		// versioned path. Errors are returned with HTTP status codes
+		pathV1 string
+		// defaults to GET
+		method      string
+		handler     handlerFunc
+		rateLimited bool
+	}
+)
+
+func registerEndpoints(mux *http.ServeMux, endpoints []endpoint, limiter *rateLimiter) {
+	for i := range endpoints {
+		ep := &endpoints[i]
+		h, hV1 := ep.legacyHandler(), ep.v1Handler()
+		if ep.rateLimited {
+			h, hV1 = limiter.wrap(h), limiter.wrap(hV1)
+		}
+		if ep.path != "" {
+			mux.Handle(ep.path, h)
+		}
+		if ep.pathV1 != "" {
+			mux.Handle(ep.pathV1, hV1)
+		}
+	}
+}
+
+func (ep *endpoint) expectedMethod() string {
+	if ep.method == "" {
+		return http.MethodGet
+	}
+	return ep.method
+}
+
+// legacyHandler keeps behavior of the unversioned API: any error is returned as api.Error with status 200
+func (ep *endpoint) legacyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ep.expectedMethod() == http.MethodPost {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxTxUploadSize)
+		}
+		resp, err := ep.handler(r)
+		if err != nil {
+			writeErr(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+// v1Handler checks HTTP method and returns errors with HTTP status codes and error codes
+func (ep *endpoint) v1Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != ep.expectedMethod() {
+			w.Header().Set("Allow", ep.expectedMethod())
+			writeStatusErr(w, api.NewStatusError(http.StatusMethodNotAllowed, api.ErrCodeMethodNotAllowed, "method not allowed"))
+			return
+		}
+		if r.Method == http.MethodPost {
+			r.Body = http.MaxBytesReader(w, r.Body, maxTxUploadSize)
+		}
+		resp, err := ep.handler(r)
+		if err != nil {
+			var statusErr *api.StatusError
+			if !errors.As(err, &statusErr) {
+				statusErr = api.NewStatusError(http.StatusInternalServerError, api.ErrCodeInternal, "%v", err)
+			}
+			writeStatusErr(w, statusErr)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+func errBadRequest(format string, args ...any) error {
+	return api.NewStatusError(http.StatusBadRequest, api.ErrCodeBadRequest, format, args...)
+}
+
+func errNotFound(format string, args ...any) error {
+	return api.NewStatusError(http.StatusNotFound, api.ErrCodeNotFound, format, args...)
+}
+
+func writeJSON(w http.ResponseWriter, status int, resp any) {
+	respBin, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(respBin)
+	util.AssertNoError(err)
+}
+
+func writeErr(w http.ResponseWriter, errStr string) {
+	writeJSON(w, http.StatusOK, &api.Error{Error: errStr})
+}
+
+func writeStatusErr(w http.ResponseWriter, err *api.StatusError) {
+	writeJSON(w, err.Status, &api.Error{Error: err.Message, Code: err.Code})
+}
+
+func serveOpenAPISpec(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, err := w.Write(api.OpenAPISpecV1)
+	util.AssertNoError(err)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointHandlers(t *testing.T) {
+	ep := endpoint{
+		path:   "/get_something",
+		pathV1: "/v1/something",
+		handler: func(r *http.Request) (any, error) {
+			switch r.URL.Query().Get("case") {
+			case "bad":
+				return nil, errBadRequest("wrong parameter")
+			case "missing":
+				return nil, errNotFound("not here")
+			case "internal":
+				return nil, errors.New("boom")
+			}
+			return &api.OutputData{OutputData: "0102"}, nil
+		},
+	}
+	mux := http.NewServeMux()
+	registerEndpoints(mux, []endpoint{ep}, nil)
+
+	run := func(method, url string) (int, api.OutputData) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+		var resp api.OutputData
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec.Code, resp
+	}
+	t.Run("legacy", func(t *testing.T) {
+		status, resp := run(http.MethodGet, "/get_something")
+		require.EqualValues(t, http.StatusOK, status)
+		require.EqualValues(t, "0102", resp.OutputData)
+
+		status, resp = run(http.MethodGet, "/get_something?case=missing")
+		require.EqualValues(t, http.StatusOK, status)
+		require.EqualValues(t, "not here", resp.Error.Error)
+		require.EqualValues(t, "", resp.Code)
+	})
+	t.Run("v1", func(t *testing.T) {
+		status, resp := run(http.MethodGet, "/v1/something")
+		require.EqualValues(t, http.StatusOK, status)
+		require.EqualValues(t, "0102", resp.OutputData)
+
+		status, resp = run(http.MethodGet, "/v1/something?case=bad")
+		require.EqualValues(t, http.StatusBadRequest, status)
+		require.EqualValues(t, api.ErrCodeBadRequest, resp.Code)
+
+		status, resp = run(http.MethodGet, "/v1/something?case=missing")
+		require.EqualValues(t, http.StatusNotFound, status)
+		require.EqualValues(t, api.ErrCodeNotFound, resp.Code)
+
+		status, resp = run(http.MethodGet, "/v1/something?case=internal")
+		require.EqualValues(t, http.StatusInternalServerError, status)
+		require.EqualValues(t, api.ErrCodeInternal, resp.Code)
+		require.EqualValues(t, "boom", resp.Error.Error)
+
+		status, resp = run(http.MethodPost, "/v1/something")
+		require.EqualValues(t, http.StatusMethodNotAllowed, status)
+		require.EqualValues(t, api.ErrCodeMethodNotAllowed, resp.Code)
+	})
+	t.Run("several endpoints", func(t *testing.T) {
+		endpointReturning := func(path, data string) endpoint {
+			return endpoint{
+				path:   path,
+				pathV1: "/v1" + path,
+				handler: func(_ *http.Request) (any, error) {
+					return &api.OutputData{OutputData: data}, nil
+				},
+			}
+		}
+		mux := http.NewServeMux()
+		registerEndpoints(mux, []endpoint{endpointReturning("/first", "01"), endpointReturning("/second", "02")}, nil)
+
+		for path, expected := range map[string]string{"/first": "01", "/v1/first": "01", "/second": "02", "/v1/second": "02"} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			var resp api.OutputData
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.EqualValues(t, expected, resp.OutputData, path)
+		}
+	})
+}
+
+func TestExpensiveEndpointsProtected(t *testing.T) {
+	srv := New(nil)
+	rateLimited := make(map[string]bool)
+	for _, ep := range srv.publicEndpoints() {
+		rateLimited[ep.pathV1] = ep.rateLimited
+		require.NotEqualValues(t, api.PathV1GetStateDiff, ep.pathV1)
+	}
+	require.True(t, rateLimited[api.PathV1SubmitTransaction])
+	require.True(t, rateLimited[api.PathV1SimulateTransaction])
+
+	admin := make(map[string]bool)
+	for _, ep := range srv.adminEndpoints() {
+		admin[ep.pathV1] = true
+	}
+	require.True(t, admin[api.PathV1GetStateDiff])
+}
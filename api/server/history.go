@@ -0,0 +1,96 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/ledger"
+)
+
+const (
+	defaultHistoryItems = 100
+	maxHistoryItems     = 1000
+)
+
+// maxItemsParameter parses 'max' parameter. Defaults to defaultHistoryItems
+func maxItemsParameter(r *http.Request) (int, error) {
+	lst, ok := r.URL.Query()["max"]
+	if !ok || len(lst) != 1 {
+		return defaultHistoryItems, nil
+	}
+	maxItems, err := strconv.Atoi(lst[0])
+	if err != nil || maxItems < 1 || maxItems > maxHistoryItems {
+		return 0, errBadRequest("parameter 'max' must be between 1 and %d", maxHistoryItems)
+	}
+	return maxItems, nil
+}
+
+func historyError(err error) error {
+	if errors.Is(err, api.ErrIndexerNotEnabled) {
+		return api.NewStatusError(http.StatusNotImplemented, api.ErrCodeNotImplemented, "%v", err)
+	}
+	return err
+}
+
+// getAccountHistory returns transactions of the account from the indexer, the latest first
+func (srv *Server) getAccountHistory(r *http.Request) (any, error) {
+	srv.Tracef(TraceTag, "getAccountHistory invoked")
+
+	lst, ok := r.URL.Query()["accountable"]
+	if !ok || len(lst) != 1 {
+		return nil, errBadRequest("wrong parameters in request 'get_account_history'")
+	}
+	accountable, err := ledger.AccountableFromSource(lst[0])
+	if err != nil {
+		return nil, errBadRequest("%v", err)
+	}
+	maxItems, err := maxItemsParameter(r)
+	if err != nil {
+		return nil, err
+	}
+	hist, err := srv.AccountHistory(accountable.AccountID(), maxItems)
+	if err != nil {
+		return nil, historyError(err)
+	}
+	resp := &api.AccountHistory{}
+	for _, a := range hist {
+		resp.Transactions = append(resp.Transactions, api.AccountHistoryItem{
+			TxID: a.TxID.StringHex(),
+			In:   a.In,
+			Out:  a.Out,
+		})
+	}
+	return resp, nil
+}
+
+// getChainHistory returns outputs of the chain from the indexer, the latest first
+func (srv *Server) getChainHistory(r *http.Request) (any, error) {
+	srv.Tracef(TraceTag, "getChainHistory invoked")
+
+	lst, ok := r.URL.Query()["chainid"]
+	if !ok || len(lst) != 1 {
+		return nil, errBadRequest("wrong parameters in request 'get_chain_history'")
+	}
+	chainID, err := ledger.ChainIDFromHexString(lst[0])
+	if err != nil {
+		return nil, errBadRequest("%v", err)
+	}
+	maxItems, err := maxItemsParameter(r)
+	if err != nil {
+		return nil, err
+	}
+	hist, err := srv.ChainHistory(chainID, maxItems)
+	if err != nil {
+		return nil, historyError(err)
+	}
+	resp := &api.ChainHistory{}
+	for _, o := range hist {
+		resp.Outputs = append(resp.Outputs, api.ChainHistoryItem{
+			OutputID: o.ID.StringHex(),
+			Amount:   o.Amount,
+		})
+	}
+	return resp, nil
+}
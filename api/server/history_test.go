@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/stretchr/testify/require"
+)
+
+type historyTestEnvironment struct {
+	waitTestEnvironment
+	maxItems int
+}
+
+func (e *historyTestEnvironment) AccountHistory(_ ledger.AccountID, maxItems int) ([]api.AccountTx, error) {
+	e.maxItems = maxItems
+	return []api.AccountTx{{TxID: ledger.RandomTransactionID(false), In: 100, Out: 50}}, nil
+}
+
+func (e *historyTestEnvironment) ChainHistory(_ ledger.ChainID, maxItems int) ([]api.ChainHistoryOutput, error) {
+	e.maxItems = maxItems
+	txid := ledger.RandomTransactionID(true)
+	return []api.ChainHistoryOutput{{ID: ledger.NewOutputID(&txid, 1), Amount: 1000}}, nil
+}
+
+func TestHistory(t *testing.T) {
+	addr := ledger.AddressED25519Null()
+	var chainID ledger.ChainID
+
+	get := func(srv *Server, path string) (int, []byte) {
+		mux := http.NewServeMux()
+		srv.registerPublicHandlers(mux, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code, rec.Body.Bytes()
+	}
+	t.Run("account", func(t *testing.T) {
+		env := &historyTestEnvironment{waitTestEnvironment: waitTestEnvironment{Global: global.NewDefault()}}
+		status, body := get(New(env), api.PathV1GetAccountHistory+"?accountable="+url.QueryEscape(addr.String())+"&max=10")
+		require.EqualValues(t, http.StatusOK, status)
+		require.EqualValues(t, 10, env.maxItems)
+		var resp api.AccountHistory
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.EqualValues(t, 1, len(resp.Transactions))
+		require.EqualValues(t, 100, resp.Transactions[0].In)
+		require.EqualValues(t, 50, resp.Transactions[0].Out)
+
+		status, _ = get(New(env), api.PathV1GetAccountHistory+"?accountable="+url.QueryEscape(addr.String())+"&max=0")
+		require.EqualValues(t, http.StatusBadRequest, status)
+	})
+	t.Run("chain", func(t *testing.T) {
+		env := &historyTestEnvironment{waitTestEnvironment: waitTestEnvironment{Global: global.NewDefault()}}
+		status, body := get(New(env), api.PathV1GetChainHistory+"?chainid="+chainID.StringHex())
+		require.EqualValues(t, http.StatusOK, status)
+		require.EqualValues(t, defaultHistoryItems, env.maxItems)
+		var resp api.ChainHistory
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.EqualValues(t, 1, len(resp.Outputs))
+		require.EqualValues(t, 1000, resp.Outputs[0].Amount)
+	})
+	t.Run("not enabled", func(t *testing.T) {
+		srv := New(&waitTestEnvironment{Global: global.NewDefault()})
+		status, body := get(srv, api.PathV1GetChainHistory+"?chainid="+chainID.StringHex())
+		require.EqualValues(t, http.StatusNotImplemented, status)
+		var resp api.ChainHistory
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.EqualValues(t, api.ErrCodeNotImplemented, resp.Code)
+	})
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util"
+)
+
+// getLedgerIdentity returns decoded ledger identity with derived values. Supply is taken
+// from the heaviest root record of the latest slot
+func (srv *Server) getLedgerIdentity(_ *http.Request) (any, error) {
+	srv.Tracef(TraceTag, "getLedgerIdentity invoked")
+
+	var rr *multistate.RootRecord
+	var latestSlot ledger.Slot
+	err := util.CatchPanicOrError(func() error {
+		latestSlot = multistate.FetchLatestSlot(srv.StateStore())
+		if roots := multistate.FetchLatestRootRecords(srv.StateStore()); len(roots) > 0 {
+			rr = &roots[0]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ledgerIdentity(ledger.L().ID, ledger.TimeNow().Slot(), rr, latestSlot), nil
+}
+
+func ledgerIdentity(id *ledger.IdentityData, currentSlot ledger.Slot, rr *multistate.RootRecord, rrSlot ledger.Slot) *api.LedgerIdentity {
+	genesisOid := ledger.GenesisOutputID()
+	genesisStemOid := ledger.GenesisStemOutputID()
+	ret := &api.LedgerIdentity{
+		IdentityDataYAMLAble:          *id.YAMLAble(),
+		SlotDurationNanosec:           int64(id.SlotDuration()),
+		TicksPerSlot:                  id.TicksPerSlot(),
+		GenesisOutputID:               genesisOid.StringHex(),
+		GenesisStemOutputID:           genesisStemOid.StringHex(),
+		CurrentSlot:                   uint32(currentSlot),
+		HalvingEpoch:                  id.HalvingEpoch(currentSlot),
+		ChainInflationPerTickFraction: id.InflationFractionBySlot(currentSlot),
+	}
+	if rr != nil {
+		ret.Supply = rr.Supply
+		ret.SupplySlot = uint32(rrSlot)
+	}
+	return ret
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerIdentity(t *testing.T) {
+	id := ledger.L().ID
+	bootstrapChainID := id.OriginChainID()
+
+	t.Run("genesis", func(t *testing.T) {
+		res := ledgerIdentity(id, 0, nil, 0)
+		require.EqualValues(t, id.InitialSupply, res.InitialSupply)
+		require.EqualValues(t, id.SlotDuration(), res.SlotDurationNanosec)
+		require.EqualValues(t, 0, res.HalvingEpoch)
+		require.EqualValues(t, id.ChainInflationPerTickFractionBase, res.ChainInflationPerTickFraction)
+		require.EqualValues(t, bootstrapChainID.StringHex(), res.BootstrapChainID)
+		require.EqualValues(t, 0, res.Supply)
+	})
+	t.Run("after halving", func(t *testing.T) {
+		slot := ledger.Slot(id.SlotsPerHalvingEpoch + 1)
+		rr := &multistate.RootRecord{Supply: id.InitialSupply + 1000}
+		res := ledgerIdentity(id, slot, rr, slot-1)
+		require.EqualValues(t, 1, res.HalvingEpoch)
+		require.EqualValues(t, 2*id.ChainInflationPerTickFractionBase, res.ChainInflationPerTickFraction)
+		require.EqualValues(t, id.InitialSupply+1000, res.Supply)
+		require.EqualValues(t, slot-1, res.SupplySlot)
+	})
+	t.Run("json", func(t *testing.T) {
+		data, err := json.Marshal(ledgerIdentity(id, 0, nil, 0))
+		require.NoError(t, err)
+		var back api.LedgerIdentity
+		require.NoError(t, json.Unmarshal(data, &back))
+		require.EqualValues(t, id.BranchBonusBase, back.BranchBonusBase)
+		require.EqualValues(t, id.GenesisControlledAddress().String(), back.GenesisControllerAddress)
+		require.Contains(t, string(data), `"branch_bonus_base"`)
+	})
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+type (
+	// ListenerConfig is configuration of one API listener (public or admin)
+	ListenerConfig struct {
+		// address in the form '<host>:<port>'
+		Addr string
+		// TLS is enabled if both certificate and key files are specified
+		CertFile string
+		KeyFile  string
+		// if specified, TLS client certificate signed by one of CAs in the file is required (mTLS)
+		ClientCAFile string
+		// if not empty, each request must contain header 'Authorization: Bearer <token>'
+		BearerToken string
+		// maximum number of 'submit_tx' requests per minute from one IP address. 0 means no limit
+		SubmitTxPerMinute int
+	}
+
+	// Listener is a running API listener
+	Listener struct {
+		name string
+		cfg  ListenerConfig
+		srv  *http.Server
+	}
+)
+
+const shutdownTimeout = 5 * time.Second
+
+func (cfg *ListenerConfig) TLSEnabled() bool {
+	return cfg.CertFile != "" && cfg.KeyFile != ""
+}
+
+func (cfg *ListenerConfig) tlsConfig() (*tls.Config, error) {
+	if !cfg.TLSEnabled() {
+		if cfg.ClientCAFile != "" {
+			return nil, fmt.Errorf("client CA file is specified but TLS is not enabled")
+		}
+		return nil, nil
+	}
+	ret := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCAFile == "" {
+		return ret, nil
+	}
+	caBytes, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("can't parse client CA certificates from '%s'", cfg.ClientCAFile)
+	}
+	ret.ClientCAs = pool
+	ret.ClientAuth = tls.RequireAndVerifyClientCert
+	return ret, nil
+}
+
+// ListenPublic starts listener with read-only endpoints and rate-limited transaction submission endpoint
+func (srv *Server) ListenPublic(cfg ListenerConfig) (*Listener, error) {
+	mux := http.NewServeMux()
+	srv.registerPublicHandlers(mux, newRateLimiter(cfg.SubmitTxPerMinute, time.Minute))
+	return srv.listen("public", cfg, mux)
+}
+
+// ListenAdmin starts listener with operator endpoints
+func (srv *Server) ListenAdmin(cfg ListenerConfig) (*Listener, error) {
+	mux := http.NewServeMux()
+	srv.registerAdminHandlers(mux)
+	return srv.listen("admin", cfg, mux)
+}
+
+func (srv *Server) listen(name string, cfg ListenerConfig, mux *http.ServeMux) (*Listener, error) {
+	tlsCfg, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("%s API listener: %w", name, err)
+	}
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s API listener: %w", name, err)
+	}
+	ret := &Listener{
+		name: name,
+		cfg:  cfg,
+		srv: &http.Server{
+			Handler:           withBearerToken(cfg.BearerToken, mux),
+			TLSConfig:         tlsCfg,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+	go func() {
+		var err error
+		if cfg.TLSEnabled() {
+			err = ret.srv.ServeTLS(ln, cfg.CertFile, cfg.KeyFile)
+		} else {
+			err = ret.srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			srv.Log().Errorf("%s API listener on %s: %v", name, cfg.Addr, err)
+		}
+	}()
+	srv.Log().Infof("%s API listener started on %s, TLS: %v, mTLS: %v, bearer token: %v, submit_tx rate limit: %d/min",
+		name, ln.Addr().String(), cfg.TLSEnabled(), cfg.ClientCAFile != "", cfg.BearerToken != "", cfg.SubmitTxPerMinute)
+	return ret, nil
+}
+
+// Stop gracefully shuts down the listener
+func (l *Listener) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return l.srv.Shutdown(ctx)
+}
+
+func (l *Listener) Name() string {
+	return l.name
+}
+
+// withBearerToken wraps handler with the check of the 'Authorization' header. No check if token is empty
+func withBearerToken(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBearerToken(t *testing.T) {
+	h := withBearerToken("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	run := func(header string) int {
+		req := httptest.NewRequest(http.MethodGet, "/get_ledger_id", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	require.EqualValues(t, http.StatusUnauthorized, run(""))
+	require.EqualValues(t, http.StatusUnauthorized, run("Bearer wrong"))
+	require.EqualValues(t, http.StatusUnauthorized, run("secret"))
+	require.EqualValues(t, http.StatusOK, run("Bearer secret"))
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		rl := newRateLimiter(0, time.Minute)
+		require.True(t, rl == nil)
+		for i := 0; i < 100; i++ {
+			require.True(t, rl.allow("1.1.1.1"))
+		}
+	})
+	t.Run("window", func(t *testing.T) {
+		nowis := time.Now()
+		rl := newRateLimiter(3, time.Minute)
+		rl.nowFun = func() time.Time { return nowis }
+		for i := 0; i < 3; i++ {
+			require.True(t, rl.allow("1.1.1.1"))
+		}
+		require.False(t, rl.allow("1.1.1.1"))
+		require.True(t, rl.allow("2.2.2.2"))
+
+		nowis = nowis.Add(time.Minute)
+		require.True(t, rl.allow("1.1.1.1"))
+	})
+	t.Run("handler", func(t *testing.T) {
+		h := newRateLimiter(1, time.Minute).wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest(http.MethodPost, "/submit_tx", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.EqualValues(t, http.StatusOK, rec.Code)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.EqualValues(t, http.StatusTooManyRequests, rec.Code)
+	})
+}
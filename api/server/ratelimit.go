@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a fixed window per-IP request counter.
+// nil rateLimiter does not limit anything
+type rateLimiter struct {
+	mutex       sync.Mutex
+	maxRequests int
+	window      time.Duration
+	windowStart time.Time
+	counters    map[string]int
+	nowFun      func() time.Time
+}
+
+func newRateLimiter(maxRequests int, window time.Duration) *rateLimiter {
+	if maxRequests <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		maxRequests: maxRequests,
+		window:      window,
+		counters:    make(map[string]int),
+		nowFun:      time.Now,
+	}
+}
+
+// allow counts the request from the IP and returns false if limit in the current window is exceeded
+func (rl *rateLimiter) allow(ip string) bool {
+	if rl == nil {
+		return true
+	}
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if nowis := rl.nowFun(); nowis.Sub(rl.windowStart) >= rl.window {
+		// new window starts. All counters are reset
+		rl.windowStart = nowis
+		rl.counters = make(map[string]int)
+	}
+	if rl.counters[ip] >= rl.maxRequests {
+		return false
+	}
+	rl.counters[ip]++
+	return true
+}
+
+func (rl *rateLimiter) wrap(h http.Handler) http.Handler {
+	if rl == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !rl.allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -2,7 +2,6 @@ package server
 
 import (
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -28,6 +27,11 @@ type (
 		SubmitTxBytesFromAPI(txBytes []byte, trace ...bool) (*ledger.TransactionID, error)
 		QueryTxIDStatusJSONAble(txid *ledger.TransactionID) vertex.TxIDStatusJSONAble
 		GetTxInclusion(txid *ledger.TransactionID, slotsBack int) *multistate.TxInclusion
+		MaxDurationInTheFuture() time.Duration
+		StateStore() global.StateStore
+		// AccountHistory and ChainHistory return api.ErrIndexerNotEnabled if indexer is not running on the node
+		AccountHistory(accountID ledger.AccountID, maxItems int) ([]api.AccountTx, error)
+		ChainHistory(chainID ledger.ChainID, maxItems int) ([]api.ChainHistoryOutput, error)
 	}
 
 	Server struct {
@@ -47,52 +51,94 @@ func New(env Environment) *Server {
 	return &Server{Environment: env}
 }
 
-func (srv *Server) registerHandlers() {
-	// GET request format: 'get_account_outputs?accountable=<EasyFL source form of the accountable lock constraint>'
-	http.HandleFunc(api.PathGetLedgerID, getLedgerID)
-	// GET request format: 'get_account_outputs?accountable=<EasyFL source form of the accountable lock constraint>'
-	http.HandleFunc(api.PathGetAccountOutputs, srv.getAccountOutputs)
-	// GET request format: 'get_chain_output?chainid=<hex-encoded chain ID>'
-	http.HandleFunc(api.PathGetChainOutput, srv.getChainOutput)
-	// GET request format: 'get_output?id=<hex-encoded output ID>'
-	http.HandleFunc(api.PathGetOutput, srv.getOutput)
-	// GET request format: 'query_txid_status?txid=<hex-encoded transaction ID>[&slots=<slot span>]'
-	http.HandleFunc(api.PathQueryTxStatus, srv.queryTxStatus)
-	// GET request format: 'query_inclusion_score?txid=<hex-encoded transaction ID>&threshold=N-D[&slots=<slot span>]'
-	http.HandleFunc(api.PathQueryInclusionScore, srv.queryTxInclusionScore)
-	// POST request format 'submit_nowait'. Feedback only on parsing error, otherwise async posting
-	http.HandleFunc(api.PathSubmitTransaction, srv.submitTx)
-	// GET sync info from the node
-	http.HandleFunc(api.PathGetSyncInfo, srv.getSyncInfo)
-	// GET sync info from the node
-	http.HandleFunc(api.PathGetNodeInfo, srv.getNodeInfo)
+// publicEndpoints read-only endpoints and the transaction submission endpoint
+func (srv *Server) publicEndpoints() []endpoint {
+	return []endpoint{
+		// GET request format: 'get_ledger_id'
+		{path: api.PathGetLedgerID, pathV1: api.PathV1GetLedgerID, handler: getLedgerID},
+		// GET request format: 'get_ledger_library'. Ledger library data committed into the state
+		{path: api.PathGetLedgerLibrary, pathV1: api.PathV1GetLedgerLibrary, handler: getLedgerLibrary},
+		// GET request format: 'get_ledger_identity'. Decoded ledger identity with derived values
+		{path: api.PathGetLedgerIdentity, pathV1: api.PathV1GetLedgerIdentity, handler: srv.getLedgerIdentity},
+		// GET request format: 'get_account_outputs?accountable=<EasyFL source form of the accountable lock constraint>'
+		{path: api.PathGetAccountOutputs, pathV1: api.PathV1GetAccountOutputs, handler: srv.getAccountOutputs},
+		// GET request format: 'get_chain_output?chainid=<hex-encoded chain ID>'
+		{path: api.PathGetChainOutput, pathV1: api.PathV1GetChainOutput, handler: srv.getChainOutput},
+		// GET request format: 'get_account_history?accountable=<EasyFL source form of the accountable lock constraint>[&max=<max items>]'
+		{path: api.PathGetAccountHistory, pathV1: api.PathV1GetAccountHistory, handler: srv.getAccountHistory},
+		// GET request format: 'get_chain_history?chainid=<hex-encoded chain ID>[&max=<max items>]'
+		{path: api.PathGetChainHistory, pathV1: api.PathV1GetChainHistory, handler: srv.getChainHistory},
+		// GET request format: 'get_finalized_branch'. The latest branch recorded as final by the finality tracker
+		{path: api.PathGetFinalizedBranch, pathV1: api.PathV1GetFinalizedBranch, handler: srv.getFinalizedBranch},
+		// GET request format: 'get_token_outputs?tokenid=<hex-encoded token ID>'
+		{path: api.PathGetTokenOutputs, pathV1: api.PathV1GetTokenOutputs, handler: srv.getTokenOutputs},
+		// GET request format: 'get_output?id=<hex-encoded output ID>'
+		{path: api.PathGetOutput, pathV1: api.PathV1GetOutput, handler: srv.getOutput},
+		// GET request format: 'query_txid_status?txid=<hex-encoded transaction ID>[&slots=<slot span>]'
+		{path: api.PathQueryTxStatus, pathV1: api.PathV1QueryTxStatus, handler: srv.queryTxStatus},
+		// GET request format: 'query_inclusion_score?txid=<hex-encoded transaction ID>&threshold=N-D[&slots=<slot span>]'
+		{path: api.PathQueryInclusionScore, pathV1: api.PathV1QueryInclusionScore, handler: srv.queryTxInclusionScore},
+		// POST request format 'submit_tx[?wait&threshold=N-D[&slots=&score=&weak&timeout=]]'. Without 'wait' feedback only on parsing error, otherwise async posting
+		{path: api.PathSubmitTransaction, pathV1: api.PathV1SubmitTransaction, method: http.MethodPost, handler: srv.submitTx, rateLimited: true},
+		// POST request format 'simulate_tx'. Validates transaction bytes in the latest heaviest state without submitting
+		{path: api.PathSimulateTransaction, pathV1: api.PathV1SimulateTransaction, method: http.MethodPost, handler: srv.simulateTx, rateLimited: true},
+		// GET sync info from the node
+		{path: api.PathGetSyncInfo, pathV1: api.PathV1GetSyncInfo, handler: srv.getSyncInfo},
+		// GET node info from the node
+		{path: api.PathGetNodeInfo, pathV1: api.PathV1GetNodeInfo, handler: srv.getNodeInfo},
+	}
+}
+
+// adminEndpoints operator endpoints, served by the admin listener only. Expensive queries go here
+func (srv *Server) adminEndpoints() []endpoint {
+	return []endpoint{
+		// GET request format: 'get_state_diff?branch1=<hex-encoded branch transaction ID>&branch2=<hex-encoded branch transaction ID>'
+		{path: api.PathGetStateDiff, pathV1: api.PathV1GetStateDiff, handler: srv.getStateDiff},
+	}
+}
+
+// registerPublicHandlers registers public endpoints. Rate-limited endpoints are limited per client IP if limiter is not nil.
+// All rate-limited endpoints share the same limit
+func (srv *Server) registerPublicHandlers(mux *http.ServeMux, limiter *rateLimiter) {
+	registerEndpoints(mux, srv.publicEndpoints(), limiter)
+	mux.HandleFunc(api.PathV1OpenAPI, serveOpenAPISpec)
 }
 
-func getLedgerID(w http.ResponseWriter, r *http.Request) {
-	resp := &api.LedgerID{
+// registerAdminHandlers registers operator endpoints. Admin listener also serves all public endpoints without rate limits
+func (srv *Server) registerAdminHandlers(mux *http.ServeMux) {
+	srv.registerPublicHandlers(mux, nil)
+	registerEndpoints(mux, srv.adminEndpoints(), nil)
+}
+
+func getLedgerID(_ *http.Request) (any, error) {
+	return &api.LedgerID{
 		LedgerIDBytes: hex.EncodeToString(ledger.L().ID.Bytes()),
+	}, nil
+}
+
+func getLedgerLibrary(_ *http.Request) (any, error) {
+	libData := ledger.L().LibraryData()
+	h := libData.Hash()
+	ret := &api.LedgerLibrary{
+		LibraryHash:  hex.EncodeToString(h[:]),
+		LibraryBytes: hex.EncodeToString(libData.Bytes()),
 	}
-	respBin, err := json.MarshalIndent(resp, "", "  ")
-	if err != nil {
-		writeErr(w, err.Error())
-		return
+	for _, u := range ledger.L().Upgrades() {
+		ret.Upgrades = append(ret.Upgrades, hex.EncodeToString(u.Bytes()))
 	}
-	_, err = w.Write(respBin)
-	util.AssertNoError(err)
+	return ret, nil
 }
 
-func (srv *Server) getAccountOutputs(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) getAccountOutputs(r *http.Request) (any, error) {
 	srv.Tracef(TraceTag, "getAccountOutputs invoked")
 
 	lst, ok := r.URL.Query()["accountable"]
 	if !ok || len(lst) != 1 {
-		writeErr(w, "wrong parameters in request 'get_account_outputs'")
-		return
+		return nil, errBadRequest("wrong parameters in request 'get_account_outputs'")
 	}
 	accountable, err := ledger.AccountableFromSource(lst[0])
 	if err != nil {
-		writeErr(w, err.Error())
-		return
+		return nil, errBadRequest("%v", err)
 	}
 
 	var oData []*ledger.OutputDataWithID
@@ -102,8 +148,7 @@ func (srv *Server) getAccountOutputs(w http.ResponseWriter, r *http.Request) {
 		return err1
 	})
 	if err != nil {
-		writeErr(w, err.Error())
-		return
+		return nil, err
 	}
 	resp := &api.OutputList{}
 	if len(oData) > 0 {
@@ -112,28 +157,19 @@ func (srv *Server) getAccountOutputs(w http.ResponseWriter, r *http.Request) {
 			resp.Outputs[o.ID.StringHex()] = hex.EncodeToString(o.OutputData)
 		}
 	}
-
-	respBin, err := json.MarshalIndent(resp, "", "  ")
-	if err != nil {
-		writeErr(w, err.Error())
-		return
-	}
-	_, err = w.Write(respBin)
-	util.AssertNoError(err)
+	return resp, nil
 }
 
-func (srv *Server) getChainOutput(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) getChainOutput(r *http.Request) (any, error) {
 	srv.Tracef(TraceTag, "getChainOutput invoked")
 
 	lst, ok := r.URL.Query()["chainid"]
 	if !ok || len(lst) != 1 {
-		writeErr(w, "wrong parameters in request 'get_chain_output'")
-		return
+		return nil, errBadRequest("wrong parameters in request 'get_chain_output'")
 	}
 	chainID, err := ledger.ChainIDFromHexString(lst[0])
 	if err != nil {
-		writeErr(w, err.Error())
-		return
+		return nil, errBadRequest("%v", err)
 	}
 	var out *ledger.OutputWithID
 	err = util.CatchPanicOrError(func() error {
@@ -141,36 +177,59 @@ func (srv *Server) getChainOutput(w http.ResponseWriter, r *http.Request) {
 		out, err1 = srv.HeaviestStateForLatestTimeSlot().GetChainOutput(&chainID)
 		return err1
 	})
+	if errors.Is(err, multistate.ErrNotFound) {
+		return nil, errNotFound("%v", err)
+	}
 	if err != nil {
-		writeErr(w, err.Error())
-		return
+		return nil, err
 	}
-	resp := &api.ChainOutput{
+	return &api.ChainOutput{
 		OutputID:   out.ID.StringHex(),
 		OutputData: hex.EncodeToString(out.Output.Bytes()),
+	}, nil
+}
+
+func (srv *Server) getTokenOutputs(r *http.Request) (any, error) {
+	srv.Tracef(TraceTag, "getTokenOutputs invoked")
+
+	lst, ok := r.URL.Query()["tokenid"]
+	if !ok || len(lst) != 1 {
+		return nil, errBadRequest("wrong parameters in request 'get_token_outputs'")
+	}
+	tokenID, err := ledger.TokenIDFromHexString(lst[0])
+	if err != nil {
+		return nil, errBadRequest("%v", err)
 	}
 
-	respBin, err := json.MarshalIndent(resp, "", "  ")
+	var oData []*ledger.OutputDataWithID
+	err = util.CatchPanicOrError(func() error {
+		var err1 error
+		oData, err1 = srv.HeaviestStateForLatestTimeSlot().GetUTXOsWithToken(&tokenID)
+		return err1
+	})
 	if err != nil {
-		writeErr(w, err.Error())
-		return
+		return nil, err
+	}
+	resp := &api.OutputList{}
+	if len(oData) > 0 {
+		resp.Outputs = make(map[string]string)
+		for _, o := range oData {
+			resp.Outputs[o.ID.StringHex()] = hex.EncodeToString(o.OutputData)
+		}
 	}
-	_, err = w.Write(respBin)
-	util.AssertNoError(err)
+	return resp, nil
 }
 
-func (srv *Server) getOutput(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) getOutput(r *http.Request) (any, error) {
 	srv.Tracef(TraceTag, "getOutput invoked")
 
 	lst, ok := r.URL.Query()["id"]
 	if !ok || len(lst) != 1 {
-		writeErr(w, "wrong parameter in request 'get_output'")
-		return
+		return nil, errBadRequest("wrong parameter in request 'get_output'")
 	}
 	oid, err := ledger.OutputIDFromHexString(lst[0])
 	if err != nil {
-		writeErr(w, err.Error())
-		return
+		return nil, errBadRequest("%v", err)
 	}
 	var oData []byte
 	err = util.CatchPanicOrError(func() error {
@@ -182,36 +241,25 @@ func (srv *Server) getOutput(w http.ResponseWriter, r *http.Request) {
 		return nil
 	})
 	if err != nil {
-		writeErr(w, api.ErrGetOutputNotFound)
-		return
+		return nil, errNotFound(api.ErrGetOutputNotFound)
 	}
-	resp := &api.OutputData{
+	return &api.OutputData{
 		OutputData: hex.EncodeToString(oData),
-	}
-
-	respBin, err := json.MarshalIndent(resp, "", "  ")
-	if err != nil {
-		writeErr(w, err.Error())
-		return
-	}
-	_, err = w.Write(respBin)
-	util.AssertNoError(err)
+	}, nil
 }
 
 const (
-	maxTxUploadSize            = 64 * (1 << 10)
-	defaultTxAppendWaitTimeout = 10 * time.Second
-	maxTxAppendWaitTimeout     = 2 * time.Minute
+	maxTxUploadSize        = 64 * (1 << 10)
+	maxTxAppendWaitTimeout = 2 * time.Minute
 )
 
-func (srv *Server) submitTx(w http.ResponseWriter, r *http.Request) {
+// submitTx submits transaction bytes. By default, returns as soon as transaction is parsed.
+// In the wait mode (parameter 'wait' is present) it blocks until transaction reaches required inclusion score
+// (parameters 'threshold', 'slots', 'score' and 'weak'), is rejected by the attacher, or timeout expires
+func (srv *Server) submitTx(r *http.Request) (any, error) {
 	srv.Tracef(TraceTag, "submitTx invoked")
 
-	if r.Method != "POST" {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-	timeout := defaultTxAppendWaitTimeout
+	timeout := api.DefaultSubmitWaitTimeout
 	lst, ok := r.URL.Query()["timeout"]
 	if ok {
 		wrong := len(lst) != 1
@@ -222,19 +270,23 @@ func (srv *Server) submitTx(w http.ResponseWriter, r *http.Request) {
 			wrong = err != nil || timeoutSec < 0
 		}
 		if wrong {
-			writeErr(w, "wrong 'timeout' parameter in request 'submit_wait'")
-			return
+			return nil, errBadRequest("wrong 'timeout' parameter in request 'submit_wait'")
 		}
 		timeout = time.Duration(timeoutSec) * time.Second
 		if timeout > maxTxAppendWaitTimeout {
 			timeout = maxTxAppendWaitTimeout
 		}
 	}
-	r.Body = http.MaxBytesReader(w, r.Body, maxTxUploadSize)
+	var waitPar *waitParams
+	if _, wait := r.URL.Query()["wait"]; wait {
+		var err error
+		if waitPar, err = waitParamsFromRequest(r); err != nil {
+			return nil, err
+		}
+	}
 	txBytes, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, errBadRequest("%v", err)
 	}
 	// tx tracing on server parameter
 	_, trace := r.URL.Query()["trace"]
@@ -245,59 +297,64 @@ func (srv *Server) submitTx(w http.ResponseWriter, r *http.Request) {
 		return err1
 	})
 	if err != nil {
-		writeErr(w, fmt.Sprintf("submit_tx: %v", err))
 		srv.Tracef(TraceTag, "submit transaction: '%v'", err)
-		return
+		return nil, api.NewStatusError(http.StatusUnprocessableEntity, api.ErrCodeRejected, "submit_tx: %v", err)
 	}
 	srv.lastSubmittedTxID = *txid
 	srv.Tracef(TraceTag, "submitted transaction %s, trace = %v", txid.StringShort, trace)
 
-	writeOk(w)
+	if waitPar == nil {
+		return &api.Error{}, nil
+	}
+	return srv.waitTxFinalStatus(r.Context(), txid, waitPar, timeout)
 }
 
-func (srv *Server) getSyncInfo(w http.ResponseWriter, r *http.Request) {
-	writeErr(w, "getSyncInfo: not implemented")
+func (srv *Server) getSyncInfo(_ *http.Request) (any, error) {
+	return nil, api.NewStatusError(http.StatusNotImplemented, api.ErrCodeNotImplemented, "getSyncInfo: not implemented")
 }
 
-func (srv *Server) getNodeInfo(w http.ResponseWriter, r *http.Request) {
-	nodeInfo := srv.GetNodeInfo()
-	respBin, err := json.MarshalIndent(nodeInfo, "", "  ")
-	if err != nil {
-		writeErr(w, err.Error())
-		return
-	}
-	_, err = w.Write(respBin)
-	util.AssertNoError(err)
+func (srv *Server) getNodeInfo(_ *http.Request) (any, error) {
+	return srv.GetNodeInfo(), nil
 }
 
 const maxSlotsSpan = 10
 
-func (srv *Server) queryTxStatus(w http.ResponseWriter, r *http.Request) {
-	srv.Tracef(TraceTag, "queryTxStatus invoked")
-
-	var txid ledger.TransactionID
-	var err error
-
+// txidParameter parses 'txid' parameter. Defaults to the last submitted transaction ID
+func (srv *Server) txidParameter(r *http.Request) (ledger.TransactionID, error) {
 	lst, ok := r.URL.Query()["txid"]
 	if !ok || len(lst) != 1 {
-		txid = srv.lastSubmittedTxID
-	} else {
-		txid, err = ledger.TransactionIDFromHexString(lst[0])
-		if err != nil {
-			writeErr(w, err.Error())
-			return
-		}
+		return srv.lastSubmittedTxID, nil
+	}
+	txid, err := ledger.TransactionIDFromHexString(lst[0])
+	if err != nil {
+		return ledger.TransactionID{}, errBadRequest("%v", err)
 	}
+	return txid, nil
+}
 
-	slotSpan := 1
-	lst, ok = r.URL.Query()["slots"]
-	if ok && len(lst) == 1 {
-		slotSpan, err = strconv.Atoi(lst[0])
+// slotSpanParameter parses 'slots' parameter. Defaults to 1
+func slotSpanParameter(r *http.Request) (int, error) {
+	lst, ok := r.URL.Query()["slots"]
+	if !ok || len(lst) != 1 {
+		return 1, nil
+	}
+	slotSpan, err := strconv.Atoi(lst[0])
+	if err != nil || slotSpan < 1 || slotSpan > maxSlotsSpan {
+		return 0, errBadRequest("parameter 'slots' must be between 1 and %d", maxSlotsSpan)
+	}
+	return slotSpan, nil
+}
 
-		if slotSpan < 1 || slotSpan > maxSlotsSpan {
-			writeErr(w, fmt.Sprintf("parameter 'slots' must be between 1 and %d", maxSlotsSpan))
-			return
-		}
+func (srv *Server) queryTxStatus(r *http.Request) (any, error) {
+	srv.Tracef(TraceTag, "queryTxStatus invoked")
+
+	txid, err := srv.txidParameter(r)
+	if err != nil {
+		return nil, err
+	}
+	slotSpan, err := slotSpanParameter(r)
+	if err != nil {
+		return nil, err
 	}
 
 	// query tx ID status
@@ -310,16 +367,9 @@ func (srv *Server) queryTxStatus(w http.ResponseWriter, r *http.Request) {
 		return nil
 	})
 	if err != nil {
-		writeErr(w, err.Error())
-		return
+		return nil, err
 	}
-	respBin, err := json.MarshalIndent(resp, "", "  ")
-	if err != nil {
-		writeErr(w, err.Error())
-		return
-	}
-	_, err = w.Write(respBin)
-	util.AssertNoError(err)
+	return &resp, nil
 }
 
 func decodeThreshold(par string) (int, int, error) {
@@ -343,45 +393,27 @@ func decodeThreshold(par string) (int, int, error) {
 
 const TraceTagQueryInclusion = "inclusion"
 
-func (srv *Server) queryTxInclusionScore(w http.ResponseWriter, r *http.Request) {
+func (srv *Server) queryTxInclusionScore(r *http.Request) (any, error) {
 	srv.Tracef(TraceTagQueryInclusion, "queryTxInclusionScore invoked")
 
-	var txid ledger.TransactionID
-	var err error
-
-	lst, ok := r.URL.Query()["txid"]
-	if !ok || len(lst) != 1 {
-		txid = srv.lastSubmittedTxID
-	} else {
-		txid, err = ledger.TransactionIDFromHexString(lst[0])
-		if err != nil {
-			writeErr(w, err.Error())
-			return
-		}
+	txid, err := srv.txidParameter(r)
+	if err != nil {
+		return nil, err
 	}
-
-	slotSpan := 1
-	lst, ok = r.URL.Query()["slots"]
-	if ok && len(lst) == 1 {
-		slotSpan, err = strconv.Atoi(lst[0])
-
-		if slotSpan < 1 || slotSpan > maxSlotsSpan {
-			writeErr(w, fmt.Sprintf("parameter 'slots' must be between 1 and %d", maxSlotsSpan))
-			return
-		}
+	slotSpan, err := slotSpanParameter(r)
+	if err != nil {
+		return nil, err
 	}
 
 	var thresholdNumerator, thresholdDenominator int
-	lst, ok = r.URL.Query()["threshold"]
+	lst, ok := r.URL.Query()["threshold"]
 	if ok && len(lst) == 1 {
 		thresholdNumerator, thresholdDenominator, err = decodeThreshold(lst[0])
 		if err != nil {
-			writeErr(w, err.Error())
-			return
+			return nil, errBadRequest("%v", err)
 		}
 	} else {
-		writeErr(w, fmt.Sprintf("wrong or missing parameter 'threshold': %+v", lst))
-		return
+		return nil, errBadRequest("wrong or missing parameter 'threshold': %+v", lst)
 	}
 	var inclusion *multistate.TxInclusion
 	err = util.CatchPanicOrError(func() error {
@@ -389,20 +421,11 @@ func (srv *Server) queryTxInclusionScore(w http.ResponseWriter, r *http.Request)
 		return nil
 	})
 	if err != nil {
-		writeErr(w, err.Error())
-		return
+		return nil, err
 	}
-	resp := api.QueryTxInclusionScore{
+	return &api.QueryTxInclusionScore{
 		TxInclusionScore: srv.calcTxInclusionScore(inclusion, thresholdNumerator, thresholdDenominator),
-	}
-
-	respBin, err := json.MarshalIndent(resp, "", "  ")
-	if err != nil {
-		writeErr(w, err.Error())
-		return
-	}
-	_, err = w.Write(respBin)
-	util.AssertNoError(err)
+	}, nil
 }
 
 // calcTxInclusionScore calculates inclusion score response from inclusion data
@@ -411,30 +434,3 @@ func (srv *Server) calcTxInclusionScore(inclusion *multistate.TxInclusion, thres
 
 	return api.CalcTxInclusionScore(inclusion, thresholdNumerator, thresholdDenominator)
 }
-
-func writeErr(w http.ResponseWriter, errStr string) {
-	respBytes, err := json.Marshal(&api.Error{Error: errStr})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	_, err = w.Write(respBytes)
-	util.AssertNoError(err)
-}
-
-func writeOk(w http.ResponseWriter) {
-	respBytes, err := json.Marshal(&api.Error{})
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	_, err = w.Write(respBytes)
-	util.AssertNoError(err)
-}
-
-func RunOn(addr string, env Environment) {
-	srv := New(env)
-	srv.registerHandlers()
-	err := http.ListenAndServe(addr, nil)
-	util.AssertNoError(err)
-}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util"
+)
+
+// simulateTx validates transaction in the context of the latest heaviest state without submitting it.
+// Invalid transaction is not an error of the request: result contains validation error and, if available,
+// the failed constraint with its path
+func (srv *Server) simulateTx(r *http.Request) (any, error) {
+	srv.Tracef(TraceTag, "simulateTx invoked")
+
+	txBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errBadRequest("%v", err)
+	}
+	var ret *api.SimulateTxResult
+	err = util.CatchPanicOrError(func() error {
+		ret = simulateTransaction(txBytes, srv.HeaviestStateForLatestTimeSlot(), time.Now().Add(srv.MaxDurationInTheFuture()))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// simulateTransaction validates the transaction against the state, with the library of the state
+func simulateTransaction(txBytes []byte, rdr global.IndexedStateReader, timeUpperBound time.Time) *api.SimulateTxResult {
+	ret := &api.SimulateTxResult{}
+
+	opts := []transaction.TxValidationOption{transaction.CheckTimestampUpperBound(timeUpperBound)}
+	opts = append(opts, transaction.MainTxValidationOptions...)
+	tx, err := transaction.FromBytes(txBytes, opts...)
+	if err != nil {
+		ret.ValidationError = err.Error()
+		return ret
+	}
+	ret.TxID = tx.ID().StringHex()
+
+	lib, err := multistate.LibraryOfState(rdr)
+	if err != nil {
+		ret.ValidationError = err.Error()
+		return ret
+	}
+	ctx, err := transaction.TxContextFromTransactionWithLibrary(tx, lib, tx.InputLoaderByIndex(rdr.GetUTXO))
+	if err != nil {
+		ret.ValidationError = err.Error()
+		return ret
+	}
+	failedConsumed, err := ctx.ValidateWithReportOnConsumedOutputs()
+	if err != nil {
+		ret.ValidationError = err.Error()
+		var constraintErr *transaction.ConstraintError
+		if errors.As(err, &constraintErr) {
+			ret.FailedConstraint = constraintErr.Constraint
+			ret.FailedConstraintPath = constraintErr.Path
+		}
+		for _, idx := range failedConsumed {
+			ret.FailedConsumedOutputs = append(ret.FailedConsumedOutputs, int(idx))
+		}
+		return ret
+	}
+	ret.Valid = true
+	ret.Mutations = &api.StateMutations{
+		Deleted: make([]string, 0, tx.NumInputs()),
+		Added:   make(map[string]string),
+	}
+	tx.ForEachInput(func(_ byte, oid *ledger.OutputID) bool {
+		ret.Mutations.Deleted = append(ret.Mutations.Deleted, oid.StringHex())
+		return true
+	})
+	tx.ForEachProducedOutput(func(_ byte, o *ledger.Output, oid *ledger.OutputID) bool {
+		ret.Mutations.Added[oid.StringHex()] = hex.EncodeToString(o.Bytes())
+		return true
+	})
+	return ret
+}
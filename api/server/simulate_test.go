@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/util/utxodb"
+	"github.com/stretchr/testify/require"
+)
+
+// initializes ledger.Library singleton for all tests and creates testing genesis private key
+
+var genesisPrivateKey ed25519.PrivateKey
+
+func init() {
+	genesisPrivateKey = ledger.InitWithTestingLedgerIDData()
+}
+
+func TestSimulateTransaction(t *testing.T) {
+	u := utxodb.NewUTXODB(genesisPrivateKey)
+	privKeys, _, addrs := u.GenerateAddressesWithFaucetAmount(0, 2, 10_000)
+	upperBound := time.Now().Add(time.Minute)
+
+	t.Run("valid", func(t *testing.T) {
+		par, err := u.MakeTransferInputData(privKeys[0], nil, ledger.NilLedgerTime)
+		require.NoError(t, err)
+		txBytes, err := txbuilder.MakeTransferTransaction(par.WithAmount(5_000).WithTargetLock(addrs[1]))
+		require.NoError(t, err)
+
+		res := simulateTransaction(txBytes, u.StateReader(), upperBound)
+		require.True(t, res.Valid, res.ValidationError)
+		require.True(t, res.TxID != "")
+		require.EqualValues(t, len(par.Inputs), len(res.Mutations.Deleted))
+		require.EqualValues(t, 2, len(res.Mutations.Added))
+		// nothing has been changed in the state
+		require.EqualValues(t, 10_000, u.Balance(addrs[0]))
+
+		require.NoError(t, u.AddTransaction(txBytes))
+		res = simulateTransaction(txBytes, u.StateReader(), upperBound)
+		require.False(t, res.Valid)
+		require.Contains(t, res.ValidationError, "can't load input")
+	})
+	t.Run("constraint fails", func(t *testing.T) {
+		// outputs of addrs[1] signed with the key of addrs[0]
+		par, err := u.MakeTransferInputData(privKeys[0], addrs[1], ledger.NilLedgerTime)
+		require.NoError(t, err)
+		txBytes, err := txbuilder.MakeTransferTransaction(par.WithAmount(5_000).WithTargetLock(addrs[0]))
+		require.NoError(t, err)
+
+		res := simulateTransaction(txBytes, u.StateReader(), upperBound)
+		t.Logf("validation error: %s", res.ValidationError)
+		require.False(t, res.Valid)
+		require.True(t, res.Mutations == nil)
+		require.True(t, res.FailedConstraint != "")
+		require.True(t, res.FailedConstraintPath != "")
+		require.True(t, len(res.FailedConsumedOutputs) > 0)
+	})
+	t.Run("garbage", func(t *testing.T) {
+		res := simulateTransaction([]byte("abc"), u.StateReader(), upperBound)
+		require.False(t, res.Valid)
+		require.True(t, res.TxID == "")
+		require.True(t, res.ValidationError != "")
+	})
+}
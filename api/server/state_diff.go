@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+)
+
+// getStateDiff returns outputs added and removed, balance changes of accounts and chains moved
+// from the state of branch1 to the state of branch2
+func (srv *Server) getStateDiff(r *http.Request) (any, error) {
+	srv.Tracef(TraceTag, "getStateDiff invoked")
+
+	branch1, err := branchIDParameter(r, "branch1")
+	if err != nil {
+		return nil, err
+	}
+	branch2, err := branchIDParameter(r, "branch2")
+	if err != nil {
+		return nil, err
+	}
+	for _, branchID := range []ledger.TransactionID{branch1, branch2} {
+		if _, found := multistate.FetchRootRecord(srv.StateStore(), branchID); !found {
+			return nil, errNotFound("branch %s not found", branchID.StringHex())
+		}
+	}
+	diff, err := multistate.DiffBranches(srv.StateStore(), branch1, branch2)
+	if err != nil {
+		return nil, err
+	}
+	return &api.StateDiff{Diff: diff.JSONAble()}, nil
+}
+
+func branchIDParameter(r *http.Request, name string) (ledger.TransactionID, error) {
+	lst, ok := r.URL.Query()[name]
+	if !ok || len(lst) != 1 {
+		return ledger.TransactionID{}, errBadRequest("parameter '%s' is required", name)
+	}
+	ret, err := ledger.TransactionIDFromHexString(lst[0])
+	if err != nil {
+		return ledger.TransactionID{}, errBadRequest("wrong parameter '%s': %v", name, err)
+	}
+	if !ret.IsBranchTransaction() {
+		return ledger.TransactionID{}, errBadRequest("parameter '%s' must be a branch transaction ID", name)
+	}
+	return ret, nil
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/stretchr/testify/require"
+)
+
+type stateDiffTestEnvironment struct {
+	waitTestEnvironment
+	stateStore global.StateStore
+}
+
+func (e *stateDiffTestEnvironment) StateStore() global.StateStore {
+	return e.stateStore
+}
+
+func TestStateDiff(t *testing.T) {
+	addr := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(100))
+	stateStore := common.NewInMemoryKVStore()
+	multistate.InitStateStore(*ledger.L().ID, stateStore)
+	_, distribTxID, err := txbuilder.DistributeInitialSupplyExt(stateStore, genesisPrivateKey, []ledger.LockBalance{
+		{Lock: addr, Balance: 1_000_000},
+	})
+	require.NoError(t, err)
+	genesisBranchID := *ledger.GenesisTransactionID()
+
+	srv := New(&stateDiffTestEnvironment{
+		waitTestEnvironment: waitTestEnvironment{Global: global.NewDefault()},
+		stateStore:          stateStore,
+	})
+	get := func(path string) (int, *api.StateDiff) {
+		mux := http.NewServeMux()
+		srv.registerAdminHandlers(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp api.StateDiff
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec.Code, &resp
+	}
+
+	status, resp := get(api.PathV1GetStateDiff + "?branch1=" + genesisBranchID.StringHex() + "&branch2=" + distribTxID.StringHex())
+	require.EqualValues(t, http.StatusOK, status)
+	diff, err := resp.Diff.Parse()
+	require.NoError(t, err)
+	require.EqualValues(t, 2, len(diff.Removed))
+	require.True(t, len(diff.Added) > 0)
+
+	status, _ = get(api.PathV1GetStateDiff + "?branch1=" + genesisBranchID.StringHex())
+	require.EqualValues(t, http.StatusBadRequest, status)
+
+	unknown := ledger.NewTransactionID(ledger.MustNewLedgerTime(5, 0), ledger.TransactionIDShort{}, true)
+	status, _ = get(api.PathV1GetStateDiff + "?branch1=" + genesisBranchID.StringHex() + "&branch2=" + unknown.StringHex())
+	require.EqualValues(t, http.StatusNotFound, status)
+
+	// not served by the public listener
+	mux := http.NewServeMux()
+	srv.registerPublicHandlers(mux, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.PathV1GetStateDiff, nil))
+	require.EqualValues(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/util"
+)
+
+// waitParams parameters of the 'submit_tx' wait mode
+type waitParams struct {
+	thresholdNumerator   int
+	thresholdDenominator int
+	slotSpan             int
+	// required score in percents
+	score int
+	// if true, weak score is checked, otherwise strong score
+	weak bool
+}
+
+const waitTxFinalStatusPollPeriod = 200 * time.Millisecond
+
+func waitParamsFromRequest(r *http.Request) (*waitParams, error) {
+	ret := &waitParams{score: 100}
+	var err error
+
+	lst, ok := r.URL.Query()["threshold"]
+	if !ok || len(lst) != 1 {
+		return nil, errBadRequest("wrong or missing parameter 'threshold' in the wait mode: %+v", lst)
+	}
+	if ret.thresholdNumerator, ret.thresholdDenominator, err = decodeThreshold(lst[0]); err != nil {
+		return nil, errBadRequest("%v", err)
+	}
+	if ret.slotSpan, err = slotSpanParameter(r); err != nil {
+		return nil, err
+	}
+	if lst, ok = r.URL.Query()["score"]; ok {
+		if len(lst) == 1 {
+			ret.score, err = strconv.Atoi(lst[0])
+		}
+		if len(lst) != 1 || err != nil || ret.score < 1 || ret.score > 100 {
+			return nil, errBadRequest("parameter 'score' must be between 1 and 100")
+		}
+	}
+	_, ret.weak = r.URL.Query()["weak"]
+	return ret, nil
+}
+
+func (p *waitParams) reached(score *api.TxInclusionScore) bool {
+	if p.weak {
+		return score.WeakScore >= p.score
+	}
+	return score.StrongScore >= p.score
+}
+
+// waitTxFinalStatus polls transaction status and inclusion until one of final statuses is reached.
+// Timeout and cancelled request are not errors, the latest known status is returned
+func (srv *Server) waitTxFinalStatus(ctx context.Context, txid *ledger.TransactionID, par *waitParams, timeout time.Duration) (*api.SubmitTxResult, error) {
+	ret := &api.SubmitTxResult{
+		TxID:   txid.StringHex(),
+		Status: api.SubmitStatusTimeout,
+	}
+	deadline := time.After(timeout)
+	for {
+		var status vertex.TxIDStatusJSONAble
+		var score api.TxInclusionScore
+		err := util.CatchPanicOrError(func() error {
+			status = srv.QueryTxIDStatusJSONAble(txid)
+			score = srv.calcTxInclusionScore(srv.GetTxInclusion(txid, par.slotSpan), par.thresholdNumerator, par.thresholdDenominator)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		ret.Score = &score
+
+		if vertex.StatusFromString(status.Status) == vertex.Bad {
+			ret.Status = api.SubmitStatusRejected
+			if status.Err != nil {
+				ret.RejectReason = status.Err.Error()
+			}
+			return ret, nil
+		}
+		if par.reached(&score) {
+			ret.Status = api.SubmitStatusIncluded
+			return ret, nil
+		}
+		select {
+		case <-ctx.Done():
+			return ret, nil
+		case <-deadline:
+			return ret, nil
+		case <-time.After(waitTxFinalStatusPollPeriod):
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/stretchr/testify/require"
+)
+
+type waitTestEnvironment struct {
+	*global.Global
+	numCalls atomic.Int32
+	// status and inclusion change after includedAfter calls
+	includedAfter int32
+	rejected      bool
+}
+
+func (e *waitTestEnvironment) GetNodeInfo() *global.NodeInfo { return nil }
+
+func (e *waitTestEnvironment) MaxDurationInTheFuture() time.Duration { return time.Minute }
+
+func (e *waitTestEnvironment) StateStore() global.StateStore {
+	panic("not implemented")
+}
+
+func (e *waitTestEnvironment) AccountHistory(_ ledger.AccountID, _ int) ([]api.AccountTx, error) {
+	return nil, api.ErrIndexerNotEnabled
+}
+
+func (e *waitTestEnvironment) ChainHistory(_ ledger.ChainID, _ int) ([]api.ChainHistoryOutput, error) {
+	return nil, api.ErrIndexerNotEnabled
+}
+
+func (e *waitTestEnvironment) HeaviestStateForLatestTimeSlot() multistate.SugaredStateReader {
+	panic("not implemented")
+}
+
+func (e *waitTestEnvironment) SubmitTxBytesFromAPI(_ []byte, _ ...bool) (*ledger.TransactionID, error) {
+	panic("not implemented")
+}
+
+func (e *waitTestEnvironment) QueryTxIDStatusJSONAble(txid *ledger.TransactionID) vertex.TxIDStatusJSONAble {
+	ret := vertex.TxIDStatus{ID: *txid, OnDAG: true}
+	if e.rejected && e.numCalls.Load() >= e.includedAfter {
+		ret.Status = vertex.Bad
+		ret.Err = errors.New("conflict")
+	}
+	return ret.JSONAble()
+}
+
+func (e *waitTestEnvironment) GetTxInclusion(txid *ledger.TransactionID, _ int) *multistate.TxInclusion {
+	n := e.numCalls.Add(1)
+	ret := &multistate.TxInclusion{
+		TxID:      *txid,
+		Inclusion: []multistate.RootInclusion{{RootRecord: multistate.RootRecord{LedgerCoverage: 2000, Supply: 1000}}},
+	}
+	ret.Inclusion[0].Included = !e.rejected && n > e.includedAfter
+	return ret
+}
+
+func TestWaitTxFinalStatus(t *testing.T) {
+	par := &waitParams{thresholdNumerator: 2, thresholdDenominator: 3, slotSpan: 1, score: 100}
+	var txid ledger.TransactionID
+
+	t.Run("included", func(t *testing.T) {
+		srv := New(&waitTestEnvironment{Global: global.NewDefault(), includedAfter: 2})
+		res, err := srv.waitTxFinalStatus(context.Background(), &txid, par, 10*time.Second)
+		require.NoError(t, err)
+		require.EqualValues(t, api.SubmitStatusIncluded, res.Status)
+		require.EqualValues(t, 100, res.Score.StrongScore)
+	})
+	t.Run("rejected", func(t *testing.T) {
+		srv := New(&waitTestEnvironment{Global: global.NewDefault(), includedAfter: 1, rejected: true})
+		res, err := srv.waitTxFinalStatus(context.Background(), &txid, par, 10*time.Second)
+		require.NoError(t, err)
+		require.EqualValues(t, api.SubmitStatusRejected, res.Status)
+		require.EqualValues(t, "conflict", res.RejectReason)
+	})
+	t.Run("timeout", func(t *testing.T) {
+		srv := New(&waitTestEnvironment{Global: global.NewDefault(), includedAfter: 1000})
+		res, err := srv.waitTxFinalStatus(context.Background(), &txid, par, 500*time.Millisecond)
+		require.NoError(t, err)
+		require.EqualValues(t, api.SubmitStatusTimeout, res.Status)
+		require.EqualValues(t, 0, res.Score.StrongScore)
+	})
+}
@@ -90,6 +90,7 @@ func InvalidateTxID(txid ledger.TransactionID, env Environment, reason error) *v
 
 	vid := AttachTxID(txid, env, OptionDoNotLoadBranch, OptionInvokedBy("InvalidateTxID"))
 	vid.SetTxStatusBad(reason)
+	env.PostEventBadTx(vid)
 	return vid
 }
 
@@ -141,11 +142,12 @@ func AttachTransaction(tx *transaction.Transaction, env Environment, opts ...Opt
 					callback = func(_ *vertex.WrappedTx, _ error) {}
 				}
 				metadata := options.metadata
+				onMetadataMismatch := options.metadataMismatch
 
 				go func() {
 					env.MarkWorkProcessStarted(vid.IDShortString())
 					env.TraceTx(&vid.ID, "runMilestoneAttacher: start")
-					runMilestoneAttacher(vid, metadata, callback, env)
+					runMilestoneAttacher(vid, metadata, callback, onMetadataMismatch, env)
 					env.TraceTx(&vid.ID, "runMilestoneAttacher: exit")
 					env.MarkWorkProcessStopped(vid.IDShortString())
 				}()
@@ -40,6 +40,13 @@ func (a *attacher) baselineStateReader() multistate.SugaredStateReader {
 	return multistate.MakeSugared(a.GetStateReaderForTheBranch(&a.baseline.ID))
 }
 
+// baselineLibrary returns the ledger library with upgrades committed into the baseline state.
+// Transactions are validated with it, so validity does not depend on the upgrades applied to the global library
+func (a *attacher) baselineLibrary() (*ledger.Library, error) {
+	a.Assertf(a.baseline != nil, "a.baseline != nil")
+	return multistate.LibraryOfState(a.GetStateReaderForTheBranch(&a.baseline.ID))
+}
+
 func (a *attacher) setError(err error) {
 	a.Tracef(TraceTagAttach, "set err: '%v'", err)
 	a.err = err
@@ -331,7 +338,11 @@ func (a *attacher) finalTouchNonSequencer(v *vertex.Vertex, vid *vertex.WrappedT
 	glbFlags := vid.FlagsNoLock()
 	if !glbFlags.FlagsUp(vertex.FlagVertexConstraintsValid) {
 		// constraints are not validated yet
-		if err := v.ValidateConstraints(); err != nil {
+		lib, err := a.baselineLibrary()
+		if err == nil {
+			err = v.ValidateConstraints(lib)
+		}
+		if err != nil {
 			v.UnReferenceDependencies()
 			a.setError(err)
 			a.Tracef(TraceTagAttachVertex, "constraint validation failed in %s: '%v'", vid.IDShortString(), err)
@@ -368,7 +379,11 @@ func (a *attacher) validateSequencerTx(v *vertex.Vertex, vid *vertex.WrappedTx)
 	glbFlags := vid.FlagsNoLock()
 	a.Assertf(!glbFlags.FlagsUp(vertex.FlagVertexConstraintsValid), "%s: !glbFlags.FlagsUp(vertex.FlagConstraintsValid) in %s", a.name, vid.IDShortString)
 
-	if err := v.ValidateConstraints(); err != nil {
+	lib, err := a.baselineLibrary()
+	if err == nil {
+		err = v.ValidateConstraints(lib)
+	}
+	if err != nil {
 		a.setError(err)
 		a.Tracef(TraceTagAttachVertex, "constraint validation failed in %s: '%v'", vid.IDShortString, err)
 		return false, false
@@ -16,8 +16,15 @@ const (
 	periodicCheckEach       = 100 * time.Millisecond
 )
 
-func runMilestoneAttacher(vid *vertex.WrappedTx, metadata *txmetadata.TransactionMetadata, callback func(vid *vertex.WrappedTx, err error), env Environment) {
+func runMilestoneAttacher(
+	vid *vertex.WrappedTx,
+	metadata *txmetadata.TransactionMetadata,
+	callback func(vid *vertex.WrappedTx, err error),
+	onMetadataMismatch func(err error),
+	env Environment,
+) {
 	a := newMilestoneAttacher(vid, env, metadata)
+	a.onMetadataMismatch = onMetadataMismatch
 	defer func() {
 		go a.close()
 	}()
@@ -26,6 +33,7 @@ func runMilestoneAttacher(vid *vertex.WrappedTx, metadata *txmetadata.Transactio
 
 	if err != nil {
 		vid.SetTxStatusBad(err)
+		env.PostEventBadTx(vid)
 		env.Log().Warnf(a.logErrorStatusString(err))
 	} else {
 		msData := env.ParseMilestoneData(vid)
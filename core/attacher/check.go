@@ -3,6 +3,7 @@ package attacher
 import (
 	"fmt"
 
+	"github.com/lunfardo314/proxima/core/txmetadata"
 	"github.com/lunfardo314/proxima/core/vertex"
 	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/util"
@@ -110,15 +111,11 @@ func (a *milestoneAttacher) _checkConsistencyBeforeFinalization() (err error) {
 	return
 }
 
-// enforceConsistencyWithTxMetadata :
-// if true, node is crashed immediately upon inconsistency with provided transaction metadata
-// if false, an error is reported
 // The transaction metadata is optionally provided together with the sequencer transaction bytes by other nodes
-// or by the tx store, therefore is not trust-less.
-// A malicious node could crash other peers by sending inconsistent metadata,
-// therefore in the production environment enforceConsistencyWithTxMetadata should be false
-// and the connection with the malicious peer should be immediately severed
-const enforceConsistencyWithTxMetadata = true
+// or by the tx store. Metadata received from peers is not trust-less, it is advisory only: inconsistency with it
+// is reported to the log and to the metadata mismatch callback (e.g. to penalize the peer), but it never invalidates
+// the milestone. Metadata from the local tx store was computed by the node itself, so inconsistency with it
+// means corruption of the local database or non-determinism of the node, and the node is stopped
 
 // checkConsistencyWithMetadata does not check root
 func (a *milestoneAttacher) checkConsistencyWithMetadata() {
@@ -138,13 +135,8 @@ func (a *milestoneAttacher) checkConsistencyWithMetadata() {
 		err = fmt.Errorf("checkConsistencyWithMetadata %s: major inconsistency: computed supply (%s) not equal to the supply provided in the metadata (%s)",
 			a.vid.IDShortString(), util.GoTh(a.baselineSupply+a.slotInflation), util.GoTh(*a.metadata.Supply))
 	}
-	if err == nil {
-		return
-	}
-	if enforceConsistencyWithTxMetadata {
-		a.Log().Fatal(err)
-	} else {
-		a.Log().Error(err)
+	if err != nil {
+		a.reportMetadataMismatch(err)
 	}
 }
 
@@ -153,11 +145,16 @@ func (a *milestoneAttacher) checkStateRootConsistentWithMetadata() {
 		return
 	}
 	if !ledger.CommitmentModel.EqualCommitments(a.finals.root, a.metadata.StateRoot) {
-		err := fmt.Errorf("commitBranch %s: major inconsistency: state root not equal to the state root provided in metadata", a.vid.IDShortString())
-		if enforceConsistencyWithTxMetadata {
-			a.Log().Fatal(err)
-		} else {
-			a.Log().Error(err)
-		}
+		a.reportMetadataMismatch(fmt.Errorf("commitBranch %s: major inconsistency: state root not equal to the state root provided in metadata", a.vid.IDShortString()))
+	}
+}
+
+func (a *milestoneAttacher) reportMetadataMismatch(err error) {
+	if a.metadata.SourceTypeNonPersistent == txmetadata.SourceTypeTxStore {
+		a.Log().Fatal(err)
+	}
+	a.Log().Error(err)
+	if a.onMetadataMismatch != nil {
+		a.onMetadataMismatch(err)
 	}
 }
@@ -35,6 +35,7 @@ type (
 	PostEventEnvironment interface {
 		PostEventNewGood(vid *vertex.WrappedTx)
 		PostEventNewTransaction(vid *vertex.WrappedTx)
+		PostEventBadTx(vid *vertex.WrappedTx)
 	}
 
 	EvidenceEnvironment interface {
@@ -91,6 +92,8 @@ type (
 		pokeClosingMutex sync.RWMutex
 		finals           attachFinals
 		closed           bool
+		// called when the milestone is inconsistent with the provided metadata
+		onMetadataMismatch func(err error)
 	}
 
 	attachStats struct {
@@ -99,6 +102,7 @@ type (
 	_attacherOptions struct {
 		metadata           *txmetadata.TransactionMetadata
 		attachmentCallback func(vid *vertex.WrappedTx, err error)
+		metadataMismatch   func(err error)
 		pullNonBranch      bool
 		doNotLoadBranch    bool
 		calledBy           string
@@ -172,6 +176,14 @@ func OptionWithAttachmentCallback(fun func(vid *vertex.WrappedTx, err error)) Op
 	}
 }
 
+// OptionOnMetadataMismatch sets function which is called when the milestone is inconsistent with the
+// transaction metadata provided with it
+func OptionOnMetadataMismatch(fun func(err error)) Option {
+	return func(options *_attacherOptions) {
+		options.metadataMismatch = fun
+	}
+}
+
 func OptionPullNonBranch(options *_attacherOptions) {
 	options.pullNonBranch = true
 }
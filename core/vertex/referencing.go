@@ -22,7 +22,7 @@ func (vid *WrappedTx) Reference() bool {
 	}
 	if vid.references == 1 {
 		// lifetime of the vertex starts (or re-starts, if completely unreferenced before)
-		vid.dontPruneUntil = time.Now().Add(referencedVertexTTLSlots * ledger.L().ID.SlotDuration())
+		vid.dontPruneUntil = ledger.Clock().Now().Add(referencedVertexTTLSlots * ledger.L().ID.SlotDuration())
 	}
 	vid.references++
 	return true
@@ -36,7 +36,7 @@ func (vid *WrappedTx) UnReference() {
 	vid.references--
 	util.Assertf(vid.references >= 1, "UnReference: reference count can't go below 1: %s", vid.ID.StringShort)
 	if vid.references == 1 {
-		vid.dontPruneUntil = time.Now().Add(notReferencedVertexTTLSlots * ledger.L().ID.SlotDuration())
+		vid.dontPruneUntil = ledger.Clock().Now().Add(notReferencedVertexTTLSlots * ledger.L().ID.SlotDuration())
 	}
 }
 
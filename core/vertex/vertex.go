@@ -92,13 +92,13 @@ func (v *Vertex) GetConsumedOutput(i byte) (ret *ledger.Output) {
 }
 
 // ValidateConstraints creates full transaction context from the (solid) vertex data
-// and runs validation of all constraints in the context
-func (v *Vertex) ValidateConstraints(traceOption ...int) error {
+// and runs validation of all constraints in the context with the library of the baseline state
+func (v *Vertex) ValidateConstraints(lib *ledger.Library, traceOption ...int) error {
 	traceOpt := transaction.TraceOptionFailedConstraints
 	if len(traceOption) > 0 {
 		traceOpt = traceOption[0]
 	}
-	ctx, err := transaction.TxContextFromTransaction(v.Tx, v.InputLoaderByIndex, traceOpt)
+	ctx, err := transaction.TxContextFromTransactionWithLibrary(v.Tx, lib, v.InputLoaderByIndex, traceOpt)
 	if err != nil {
 		return err
 	}
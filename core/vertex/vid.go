@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"runtime/debug"
-	"time"
 
 	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/util"
@@ -44,7 +43,7 @@ func _newVID(g _genericVertex, txid ledger.TransactionID) *WrappedTx {
 		ID:             txid,
 		_genericVertex: g,
 		references:     1, // we always start with 1 reference, which is reference by the MemDAG itself. 0 references means it is deleted
-		dontPruneUntil: time.Now().Add(notReferencedVertexTTLSlots * ledger.SlotDuration()),
+		dontPruneUntil: ledger.Clock().Now().Add(notReferencedVertexTTLSlots * ledger.SlotDuration()),
 	}
 	ret.onPoke.Store(func() {})
 	return ret
@@ -121,7 +120,7 @@ func (vid *WrappedTx) SetSequencerAttachmentFinished() {
 	defer vid.mutex.Unlock()
 
 	vid.flags.SetFlagsUp(FlagVertexTxAttachmentFinished)
-	vid.dontPruneUntil = time.Now().Add(referencedVertexTTLSlots * ledger.L().ID.SlotDuration())
+	vid.dontPruneUntil = ledger.Clock().Now().Add(referencedVertexTTLSlots * ledger.L().ID.SlotDuration())
 }
 
 func (vid *WrappedTx) SetTxStatusBad(reason error) {
@@ -0,0 +1,216 @@
+package finality
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util/queue"
+	"github.com/lunfardo314/unitrie/common"
+)
+
+type (
+	Environment interface {
+		global.NodeGlobal
+		StateStore() global.StateStore
+		PostEventBranchFinalized(branchID ledger.TransactionID)
+	}
+
+	// Params of the finality rule. Same as the parameters of the strong inclusion score, see api.CalcTxInclusionScore
+	Params struct {
+		ThresholdNumerator   int
+		ThresholdDenominator int
+		// SlotsBack number of latest slots with branches, which are finality candidates
+		SlotsBack int
+	}
+
+	// Input is the branch committed to the multi-state DB. It triggers finality check
+	Input struct {
+		BranchID ledger.TransactionID
+	}
+
+	// Tracker maintains the latest finalized branch. The branch is final if it is included in every branch
+	// with coverage above the threshold in the latest SlotsBack slots (i.e. every transaction in the finalized branch
+	// has strong inclusion score 100%). The finalized branch is recorded in the multi-state DB, it only moves forward
+	// along the chain of branches. Event is posted for each branch which becomes final, in the order of slots
+	Tracker struct {
+		*queue.Queue[Input]
+		Environment
+		params    Params
+		mutex     sync.RWMutex
+		finalized *ledger.TransactionID
+	}
+)
+
+const (
+	Name           = "finality"
+	TraceTag       = Name
+	chanBufferSize = 10
+
+	DefaultThresholdNumerator   = 2
+	DefaultThresholdDenominator = 3
+	DefaultSlotsBack            = 2
+
+	// maxBranchesBack limits how far back from the heaviest branch the finalized branch is searched for.
+	// It does not limit number of newly finalized branches: all branches between the previous and the new
+	// finalized branch are reported, even if finality jumps over many slots at once
+	maxBranchesBack = 100
+)
+
+func DefaultParams() Params {
+	return Params{
+		ThresholdNumerator:   DefaultThresholdNumerator,
+		ThresholdDenominator: DefaultThresholdDenominator,
+		SlotsBack:            DefaultSlotsBack,
+	}
+}
+
+func New(env Environment, params Params) *Tracker {
+	return &Tracker{
+		Queue:       queue.NewQueueWithBufferSize[Input](Name, chanBufferSize, env.Log().Level(), nil),
+		Environment: env,
+		params:      params,
+	}
+}
+
+func (t *Tracker) Start() {
+	if txid, ok := multistate.FetchFinalizedBranch(t.StateStore()); ok {
+		t.finalized = &txid
+		t.Environment.Log().Infof("finality: latest finalized branch is %s", txid.StringShort())
+	}
+	t.MarkWorkProcessStarted(Name)
+	t.AddOnClosed(func() {
+		t.MarkWorkProcessStopped(Name)
+	})
+	t.Queue.Start(t, t.Environment.Ctx())
+}
+
+func (t *Tracker) Consume(inp Input) {
+	t.Tracef(TraceTag, "finality check triggered by branch %s", inp.BranchID.StringShort)
+
+	if err := t.update(); err != nil {
+		t.Environment.Log().Errorf("finality: %v", err)
+	}
+}
+
+// FinalizedBranch returns the latest finalized branch, if any
+func (t *Tracker) FinalizedBranch() (ledger.TransactionID, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	if t.finalized == nil {
+		return ledger.TransactionID{}, false
+	}
+	return *t.finalized, true
+}
+
+func (t *Tracker) update() error {
+	current, hasCurrent := t.FinalizedBranch()
+	var after *ledger.TransactionID
+	if hasCurrent {
+		after = &current
+	}
+	newlyFinalized := FindNewlyFinalizedBranches(t.StateStore(), t.params, after)
+	if len(newlyFinalized) == 0 {
+		return nil
+	}
+	latest := newlyFinalized[len(newlyFinalized)-1]
+	if hasCurrent && !multistate.BranchIsDescendantOf(&latest, &current, func() common.KVReader { return t.StateStore() }) {
+		return fmt.Errorf("branch %s with coverage above the threshold does not descend from the finalized branch %s",
+			latest.StringShort(), current.StringShort())
+	}
+	if err := multistate.WriteFinalizedBranch(t.StateStore(), latest); err != nil {
+		return fmt.Errorf("failed to record finalized branch %s: '%v'", latest.StringShort(), err)
+	}
+	t.mutex.Lock()
+	t.finalized = &latest
+	t.mutex.Unlock()
+
+	for _, branchID := range newlyFinalized {
+		t.Tracef(TraceTag, "branch %s is final", branchID.StringShort)
+		t.PostEventBranchFinalized(branchID)
+	}
+	return nil
+}
+
+// FindNewlyFinalizedBranches returns branches which are final according to params and are later than
+// the branch 'after'. Branches are returned in the ascending order of slots, the last one is the latest finalized
+func FindNewlyFinalizedBranches(store global.StateStoreReader, params Params, after *ledger.TransactionID) []ledger.TransactionID {
+	candidates := make([]*multistate.BranchData, 0)
+	var heaviest *multistate.BranchData
+	for _, rr := range multistate.FetchRootRecordsNSlotsBack(store, params.SlotsBack) {
+		if !rr.IsCoverageAboveThreshold(params.ThresholdNumerator, params.ThresholdDenominator) {
+			continue
+		}
+		bd := multistate.FetchBranchDataByRoot(store, rr)
+		candidates = append(candidates, &bd)
+		if heaviest == nil || bd.LedgerCoverage > heaviest.LedgerCoverage {
+			heaviest = &bd
+		}
+	}
+	if heaviest == nil {
+		return nil
+	}
+	getStore := func() common.KVReader { return store }
+	includedInAllCandidates := func(branchID *ledger.TransactionID) bool {
+		for _, c := range candidates {
+			if !multistate.BranchIsDescendantOf(c.TxID(), branchID, getStore) {
+				return false
+			}
+		}
+		return true
+	}
+	// the finalized branch is the latest common ancestor of all candidates on the chain of the heaviest one
+	depth := 0
+	for bd := heaviest; bd != nil && depth < maxBranchesBack; bd = predecessorBranch(store, bd) {
+		if after != nil && bd.TxID().Slot() <= after.Slot() {
+			return nil
+		}
+		if includedInAllCandidates(bd.TxID()) {
+			return reverse(collectChainBack(store, bd, after))
+		}
+		depth++
+	}
+	return nil
+}
+
+// collectChainBack returns the branch and all its predecessors later than 'after'. If 'after' is nil, returns only the branch.
+// The chain is not limited by maxBranchesBack, so that no finalized branch is skipped
+func collectChainBack(store global.StateStoreReader, bd *multistate.BranchData, after *ledger.TransactionID) []ledger.TransactionID {
+	ret := []ledger.TransactionID{*bd.TxID()}
+	if after == nil {
+		return ret
+	}
+	for bd = predecessorBranch(store, bd); bd != nil; bd = predecessorBranch(store, bd) {
+		if bd.TxID().Slot() <= after.Slot() {
+			break
+		}
+		ret = append(ret, *bd.TxID())
+	}
+	return ret
+}
+
+// predecessorBranch returns the branch of the predecessor stem or nil if not available
+func predecessorBranch(store common.KVReader, bd *multistate.BranchData) *multistate.BranchData {
+	if bd.Stem.ID == ledger.GenesisStemOutputID() {
+		return nil
+	}
+	stemLock, ok := bd.Stem.Output.StemLock()
+	if !ok {
+		return nil
+	}
+	pred, found := multistate.FetchBranchData(store, stemLock.PredecessorOutputID.TransactionID())
+	if !found {
+		return nil
+	}
+	return &pred
+}
+
+func reverse(lst []ledger.TransactionID) []ledger.TransactionID {
+	for i, j := 0, len(lst)-1; i < j; i, j = i+1, j-1 {
+		lst[i], lst[j] = lst[j], lst[i]
+	}
+	return lst
+}
@@ -0,0 +1,94 @@
+package finality
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnvironment struct {
+	*global.Global
+	stateStore global.StateStore
+	mutex      sync.Mutex
+	finalized  []ledger.TransactionID
+}
+
+func (e *testEnvironment) StateStore() global.StateStore {
+	return e.stateStore
+}
+
+func (e *testEnvironment) PostEventBranchFinalized(branchID ledger.TransactionID) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.finalized = append(e.finalized, branchID)
+}
+
+func (e *testEnvironment) finalizedBranches() []ledger.TransactionID {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]ledger.TransactionID{}, e.finalized...)
+}
+
+func TestFinality(t *testing.T) {
+	genesisPrivateKey := ledger.InitWithTestingLedgerIDData()
+	addr := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(100))
+	stateStore := common.NewInMemoryKVStore()
+	multistate.InitStateStore(*ledger.L().ID, stateStore)
+	// distribution branch has coverage 1.5 of the supply, genesis branch has 1.0
+	_, distribTxID, err := txbuilder.DistributeInitialSupplyExt(stateStore, genesisPrivateKey, []ledger.LockBalance{
+		{Lock: addr, Balance: 1_000_000},
+	})
+	require.NoError(t, err)
+
+	t.Run("find", func(t *testing.T) {
+		res := FindNewlyFinalizedBranches(stateStore, DefaultParams(), nil)
+		require.EqualValues(t, []ledger.TransactionID{distribTxID}, res)
+
+		res = FindNewlyFinalizedBranches(stateStore, DefaultParams(), ledger.GenesisTransactionID())
+		require.EqualValues(t, []ledger.TransactionID{distribTxID}, res)
+
+		res = FindNewlyFinalizedBranches(stateStore, DefaultParams(), &distribTxID)
+		require.EqualValues(t, 0, len(res))
+
+		// no branches above threshold 4/5 of the double supply
+		res = FindNewlyFinalizedBranches(stateStore, Params{ThresholdNumerator: 4, ThresholdDenominator: 5, SlotsBack: 2}, nil)
+		require.EqualValues(t, 0, len(res))
+	})
+	t.Run("tracker", func(t *testing.T) {
+		glb := global.NewDefault()
+		defer glb.Stop()
+		env := &testEnvironment{Global: glb, stateStore: stateStore}
+		tracker := New(env, DefaultParams())
+		tracker.Start()
+
+		_, found := tracker.FinalizedBranch()
+		require.False(t, found)
+
+		tracker.Push(Input{BranchID: distribTxID})
+		require.Eventually(t, func() bool {
+			_, found = tracker.FinalizedBranch()
+			return found
+		}, 5*time.Second, 10*time.Millisecond)
+
+		branchID, _ := tracker.FinalizedBranch()
+		require.EqualValues(t, distribTxID, branchID)
+		branchID, found = multistate.FetchFinalizedBranch(stateStore)
+		require.True(t, found)
+		require.EqualValues(t, distribTxID, branchID)
+
+		// same branch is not reported again
+		tracker.Push(Input{BranchID: distribTxID})
+		require.Never(t, func() bool {
+			return len(env.finalizedBranches()) != 1
+		}, 200*time.Millisecond, 10*time.Millisecond)
+		require.EqualValues(t, []ledger.TransactionID{distribTxID}, env.finalizedBranches())
+	})
+}
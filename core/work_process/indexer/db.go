@@ -0,0 +1,104 @@
+package indexer
+
+import (
+	"encoding/binary"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/unitrie/common"
+)
+
+// Keys are prefixed with the timestamp, so that records of the account or the chain are ordered chronologically.
+// Transaction ID cannot be used for ordering because of the sequencer flag in its first byte
+const (
+	// partitionAccount: partitionAccount | len(accountID) | accountID | timestamp | txid -> in | out
+	partitionAccount = byte(iota)
+	// partitionChain: partitionChain | chainID | timestamp | outputID -> amount
+	partitionChain
+	// partitionLastIndexedSlot: partitionLastIndexedSlot -> slot
+	partitionLastIndexedSlot
+)
+
+func accountPrefix(accountID ledger.AccountID) []byte {
+	return common.ConcatBytes([]byte{partitionAccount, byte(len(accountID))}, accountID)
+}
+
+func chainPrefix(chainID ledger.ChainID) []byte {
+	return common.ConcatBytes([]byte{partitionChain}, chainID[:])
+}
+
+func writeAccountTx(w common.KVWriter, accountID ledger.AccountID, a *api.AccountTx) {
+	var value [16]byte
+	binary.BigEndian.PutUint64(value[:8], a.In)
+	binary.BigEndian.PutUint64(value[8:], a.Out)
+	w.Set(common.ConcatBytes(accountPrefix(accountID), a.TxID.Timestamp().Bytes(), a.TxID[:]), value[:])
+}
+
+func writeChainOutput(w common.KVWriter, chainID ledger.ChainID, o api.ChainHistoryOutput) {
+	var value [8]byte
+	binary.BigEndian.PutUint64(value[:], o.Amount)
+	w.Set(common.ConcatBytes(chainPrefix(chainID), o.ID.Timestamp().Bytes(), o.ID[:]), value[:])
+}
+
+func writeLastIndexedSlot(w common.KVWriter, slot ledger.Slot) {
+	w.Set([]byte{partitionLastIndexedSlot}, slot.Bytes())
+}
+
+func fetchLastIndexedSlot(store common.KVReader) (ledger.Slot, bool) {
+	bin := store.Get([]byte{partitionLastIndexedSlot})
+	if len(bin) == 0 {
+		return 0, false
+	}
+	ret, err := ledger.SlotFromBytes(bin)
+	if err != nil {
+		return 0, false
+	}
+	return ret, true
+}
+
+// FetchAccountHistory returns at most maxItems transactions of the account, the latest first. maxItems <= 0 means all
+func FetchAccountHistory(store common.Traversable, accountID ledger.AccountID, maxItems int) []api.AccountTx {
+	prefix := accountPrefix(accountID)
+	ret := make([]api.AccountTx, 0)
+	store.Iterator(prefix).Iterate(func(k, v []byte) bool {
+		txid, err := ledger.TransactionIDFromBytes(k[len(prefix)+ledger.TimeByteLength:])
+		if err != nil || len(v) != 16 {
+			return true
+		}
+		ret = append(ret, api.AccountTx{
+			TxID: txid,
+			In:   binary.BigEndian.Uint64(v[:8]),
+			Out:  binary.BigEndian.Uint64(v[8:]),
+		})
+		return true
+	})
+	return latestFirst(ret, maxItems)
+}
+
+// FetchChainHistory returns at most maxItems outputs of the chain, the latest first. maxItems <= 0 means all
+func FetchChainHistory(store common.Traversable, chainID ledger.ChainID, maxItems int) []api.ChainHistoryOutput {
+	prefix := chainPrefix(chainID)
+	ret := make([]api.ChainHistoryOutput, 0)
+	store.Iterator(prefix).Iterate(func(k, v []byte) bool {
+		oid, err := ledger.OutputIDFromBytes(k[len(prefix)+ledger.TimeByteLength:])
+		if err != nil || len(v) != 8 {
+			return true
+		}
+		ret = append(ret, api.ChainHistoryOutput{
+			ID:     oid,
+			Amount: binary.BigEndian.Uint64(v),
+		})
+		return true
+	})
+	return latestFirst(ret, maxItems)
+}
+
+func latestFirst[T any](lst []T, maxItems int) []T {
+	for i, j := 0, len(lst)-1; i < j; i, j = i+1, j-1 {
+		lst[i], lst[j] = lst[j], lst[i]
+	}
+	if maxItems > 0 && len(lst) > maxItems {
+		lst = lst[:maxItems]
+	}
+	return lst
+}
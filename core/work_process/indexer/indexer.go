@@ -0,0 +1,288 @@
+package indexer
+
+import (
+	"fmt"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/core/txmetadata"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util/queue"
+	"github.com/lunfardo314/unitrie/common"
+)
+
+type (
+	Environment interface {
+		global.NodeGlobal
+		StateStore() global.StateStore
+		TxBytesStore() global.TxBytesStore
+	}
+
+	// Store is the separate database of the indexer
+	Store interface {
+		common.KVReader
+		common.Traversable
+		common.BatchedUpdatable
+	}
+
+	// Input is the branch which became final
+	Input struct {
+		BranchID ledger.TransactionID
+	}
+
+	// Indexer maintains history of accounts and chains in the separate database. Only branches of the finalized chain
+	// are indexed, so records never have to be rolled back. Each finalized branch is indexed
+	// with transactions of its past cone, which were not committed in the predecessor branch.
+	// Transactions are loaded from the transaction store, so indexer does not work with the 'dummy' transaction store
+	Indexer struct {
+		*queue.Queue[Input]
+		Environment
+		store Store
+	}
+)
+
+const (
+	Name           = "indexer"
+	TraceTag       = Name
+	chanBufferSize = 10
+)
+
+func New(env Environment, store Store) *Indexer {
+	return &Indexer{
+		Queue:       queue.NewQueueWithBufferSize[Input](Name, chanBufferSize, env.Log().Level(), nil),
+		Environment: env,
+		store:       store,
+	}
+}
+
+func (ix *Indexer) Start() {
+	ix.MarkWorkProcessStarted(Name)
+	ix.AddOnClosed(func() {
+		ix.MarkWorkProcessStopped(Name)
+	})
+	ix.Queue.Start(ix, ix.Environment.Ctx())
+	ix.catchUp()
+}
+
+func (ix *Indexer) Consume(inp Input) {
+	if err := ix.indexBranch(&inp.BranchID); err != nil {
+		ix.Environment.Log().Errorf("indexer: failed to index branch %s: '%v'", inp.BranchID.StringShort(), err)
+		return
+	}
+	ix.Tracef(TraceTag, "indexed branch %s", inp.BranchID.StringShort)
+}
+
+// catchUp indexes branches of the finalized chain which became final while indexer was not running
+func (ix *Indexer) catchUp() {
+	branches := finalizedBranchesNotIndexed(ix.StateStore(), ix.store)
+	if len(branches) == 0 {
+		return
+	}
+	ix.Environment.Log().Infof("indexer: indexing %d branches of the finalized chain", len(branches))
+	for _, branchID := range branches {
+		ix.Push(Input{BranchID: branchID})
+	}
+}
+
+// finalizedBranchesNotIndexed returns branches of the finalized chain later than the last indexed slot,
+// in the ascending order of slots. Returns nil if there's no finalized branch recorded in the state store
+func finalizedBranchesNotIndexed(stateStore common.KVReader, store common.KVReader) []ledger.TransactionID {
+	finalized, ok := multistate.FetchFinalizedBranch(stateStore)
+	if !ok {
+		return nil
+	}
+	lastSlot, indexed := fetchLastIndexedSlot(store)
+	ret := make([]ledger.TransactionID, 0)
+	for branchID, ok := finalized, true; ok; branchID, ok = predecessorBranchID(stateStore, branchID) {
+		if indexed && branchID.Slot() <= lastSlot {
+			break
+		}
+		ret = append(ret, branchID)
+	}
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return ret
+}
+
+// predecessorBranchID returns the branch of the predecessor stem, if it is in the state store
+func predecessorBranchID(stateStore common.KVReader, branchID ledger.TransactionID) (ledger.TransactionID, bool) {
+	br, found := multistate.FetchBranchData(stateStore, branchID)
+	if !found || br.Stem.ID == ledger.GenesisStemOutputID() {
+		return ledger.TransactionID{}, false
+	}
+	stemLock, ok := br.Stem.Output.StemLock()
+	if !ok {
+		return ledger.TransactionID{}, false
+	}
+	predID := stemLock.PredecessorOutputID.TransactionID()
+	if _, found = multistate.FetchRootRecord(stateStore, predID); !found {
+		return ledger.TransactionID{}, false
+	}
+	return predID, true
+}
+
+func (ix *Indexer) AccountHistory(accountID ledger.AccountID, maxItems int) []api.AccountTx {
+	return FetchAccountHistory(ix.store, accountID, maxItems)
+}
+
+func (ix *Indexer) ChainHistory(chainID ledger.ChainID, maxItems int) []api.ChainHistoryOutput {
+	return FetchChainHistory(ix.store, chainID, maxItems)
+}
+
+// indexBranch collects transactions committed in the branch and unknown in the predecessor branch, by traversing
+// past cone of the branch transaction, and writes history records of all accounts and chains touched by them
+func (ix *Indexer) indexBranch(branchID *ledger.TransactionID) error {
+	br, found := multistate.FetchBranchData(ix.StateStore(), *branchID)
+	if !found {
+		return fmt.Errorf("branch data not found")
+	}
+	rdr, err := multistate.NewReadable(ix.StateStore(), br.Root)
+	if err != nil {
+		return err
+	}
+	var prev *multistate.Readable
+	if stemLock, ok := br.Stem.Output.StemLock(); ok && br.Stem.ID != ledger.GenesisStemOutputID() {
+		if predBr, found := multistate.FetchBranchData(ix.StateStore(), stemLock.PredecessorOutputID.TransactionID()); found {
+			if prev, err = multistate.NewReadable(ix.StateStore(), predBr.Root); err != nil {
+				return err
+			}
+		}
+	}
+	txs, err := ix.newTransactionsInBranch(branchID, rdr, prev)
+	if err != nil {
+		return err
+	}
+	w := ix.store.BatchedWriter()
+	for _, tx := range txs {
+		ix.writeTxRecords(w, tx, consumedOutputLoader(prev, txs, ix.TxBytesStore()))
+	}
+	if lastSlot, ok := fetchLastIndexedSlot(ix.store); !ok || lastSlot < branchID.Slot() {
+		writeLastIndexedSlot(w, branchID.Slot())
+	}
+	return w.Commit()
+}
+
+// newTransactionsInBranch traverses past cone of the branch transaction until transactions known in the predecessor state
+func (ix *Indexer) newTransactionsInBranch(branchID *ledger.TransactionID, rdr, prev *multistate.Readable) (map[ledger.TransactionID]*transaction.Transaction, error) {
+	ret := make(map[ledger.TransactionID]*transaction.Transaction)
+	visited := map[ledger.TransactionID]struct{}{*branchID: {}}
+	stack := []ledger.TransactionID{*branchID}
+	for len(stack) > 0 {
+		txid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if prev != nil && prev.KnowsCommittedTransaction(&txid) || !rdr.KnowsCommittedTransaction(&txid) {
+			continue
+		}
+		tx, err := loadTransaction(ix.TxBytesStore(), &txid)
+		if err != nil {
+			return nil, err
+		}
+		if tx == nil {
+			ix.Tracef(TraceTag, "transaction %s not found in the transaction store", txid.StringShort)
+			continue
+		}
+		ret[txid] = tx
+		push := func(id ledger.TransactionID) {
+			if _, already := visited[id]; !already {
+				visited[id] = struct{}{}
+				stack = append(stack, id)
+			}
+		}
+		tx.ForEachInput(func(_ byte, oid *ledger.OutputID) bool {
+			push(oid.TransactionID())
+			return true
+		})
+		tx.ForEachEndorsement(func(_ byte, txid *ledger.TransactionID) bool {
+			push(*txid)
+			return true
+		})
+	}
+	return ret, nil
+}
+
+// writeTxRecords sums amounts produced to and consumed from each account by the transaction
+// and records chain outputs produced by it
+func (ix *Indexer) writeTxRecords(w common.KVWriter, tx *transaction.Transaction, getConsumed func(oid *ledger.OutputID) *ledger.Output) {
+	amounts := make(map[string]*api.AccountTx)
+	account := func(acc ledger.Accountable) *api.AccountTx {
+		id := string(acc.AccountID())
+		ret, ok := amounts[id]
+		if !ok {
+			ret = &api.AccountTx{TxID: *tx.ID()}
+			amounts[id] = ret
+		}
+		return ret
+	}
+	tx.ForEachProducedOutput(func(_ byte, o *ledger.Output, oid *ledger.OutputID) bool {
+		for _, acc := range o.Lock().Accounts() {
+			account(acc).In += o.Amount()
+		}
+		if cc, idx := o.ChainConstraint(); idx != 0xff {
+			chainID := cc.ID
+			if cc.IsOrigin() {
+				chainID = ledger.MakeOriginChainID(oid)
+			}
+			writeChainOutput(w, chainID, api.ChainHistoryOutput{ID: *oid, Amount: o.Amount()})
+		}
+		return true
+	})
+	tx.ForEachInput(func(_ byte, oid *ledger.OutputID) bool {
+		o := getConsumed(oid)
+		if o == nil {
+			ix.Tracef(TraceTag, "consumed output %s of %s not found", oid.StringShort, tx.IDShortString)
+			return true
+		}
+		for _, acc := range o.Lock().Accounts() {
+			account(acc).Out += o.Amount()
+		}
+		return true
+	})
+	for id, a := range amounts {
+		writeAccountTx(w, ledger.AccountID(id), a)
+	}
+}
+
+// consumedOutputLoader looks for the consumed output in the predecessor state, then among transactions of the branch,
+// then in the transaction store
+func consumedOutputLoader(prev *multistate.Readable, txs map[ledger.TransactionID]*transaction.Transaction, txStore global.TxBytesGet) func(oid *ledger.OutputID) *ledger.Output {
+	return func(oid *ledger.OutputID) *ledger.Output {
+		if prev != nil {
+			if oData, found := prev.GetUTXO(oid); found {
+				o, err := ledger.OutputFromBytesReadOnly(oData)
+				if err == nil {
+					return o
+				}
+			}
+		}
+		txid := oid.TransactionID()
+		tx, ok := txs[txid]
+		if !ok {
+			var err error
+			if tx, err = loadTransaction(txStore, &txid); err != nil || tx == nil {
+				return nil
+			}
+		}
+		o, err := tx.ProducedOutputAt(oid.Index())
+		if err != nil {
+			return nil
+		}
+		return o
+	}
+}
+
+// loadTransaction returns nil, nil if transaction is not in the store
+func loadTransaction(txStore global.TxBytesGet, txid *ledger.TransactionID) (*transaction.Transaction, error) {
+	txBytesWithMetadata := txStore.GetTxBytesWithMetadata(txid)
+	if len(txBytesWithMetadata) == 0 {
+		return nil, nil
+	}
+	_, txBytes, err := txmetadata.SplitTxBytesWithMetadata(txBytesWithMetadata)
+	if err != nil {
+		return nil, err
+	}
+	return transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+}
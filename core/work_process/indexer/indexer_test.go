@@ -0,0 +1,111 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/core/txmetadata"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/txstore"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnvironment struct {
+	*global.Global
+	stateStore global.StateStore
+	txStore    global.TxBytesStore
+}
+
+func (e *testEnvironment) StateStore() global.StateStore {
+	return e.stateStore
+}
+
+func (e *testEnvironment) TxBytesStore() global.TxBytesStore {
+	return e.txStore
+}
+
+func TestIndexer(t *testing.T) {
+	genesisPrivateKey := ledger.InitWithTestingLedgerIDData()
+	genesisAddr := ledger.AddressED25519FromPrivateKey(genesisPrivateKey)
+	addr := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(100))
+	stateStore := common.NewInMemoryKVStore()
+	multistate.InitStateStore(*ledger.L().ID, stateStore)
+	txBytes, distribTxID, err := txbuilder.DistributeInitialSupplyExt(stateStore, genesisPrivateKey, []ledger.LockBalance{
+		{Lock: addr, Balance: 1_000_000},
+		{Lock: addr, Balance: 2_000_000, ChainOrigin: true},
+	})
+	require.NoError(t, err)
+
+	txStore := txstore.NewSimpleTxBytesStore(common.NewInMemoryKVStore())
+	_, err = txStore.PersistTxBytesWithMetadata(txBytes, &txmetadata.TransactionMetadata{})
+	require.NoError(t, err)
+
+	tx, err := transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+	require.NoError(t, err)
+	var chainID ledger.ChainID
+	var chainOutputID ledger.OutputID
+	tx.ForEachProducedOutput(func(_ byte, o *ledger.Output, oid *ledger.OutputID) bool {
+		if cc, idx := o.ChainConstraint(); idx != 0xff && cc.IsOrigin() {
+			chainID, chainOutputID = ledger.MakeOriginChainID(oid), *oid
+			return false
+		}
+		return true
+	})
+
+	// only the finalized chain is indexed
+	indexStore := common.NewInMemoryKVStore()
+	require.EqualValues(t, 0, len(finalizedBranchesNotIndexed(stateStore, indexStore)))
+	require.NoError(t, multistate.WriteFinalizedBranch(stateStore, distribTxID))
+	// genesis branch and the distribution branch
+	branches := finalizedBranchesNotIndexed(stateStore, indexStore)
+	require.EqualValues(t, 2, len(branches))
+	require.EqualValues(t, *ledger.GenesisTransactionID(), branches[0])
+	require.EqualValues(t, distribTxID, branches[1])
+
+	glb := global.NewDefault()
+	defer glb.Stop()
+	ix := New(&testEnvironment{Global: glb, stateStore: stateStore, txStore: txStore}, indexStore)
+	// catches up with the finalized chain upon start
+	ix.Start()
+
+	require.Eventually(t, func() bool {
+		return len(ix.AccountHistory(addr.AccountID(), 0)) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	hist := ix.AccountHistory(addr.AccountID(), 0)
+	require.EqualValues(t, 1, len(hist))
+	require.EqualValues(t, distribTxID, hist[0].TxID)
+	require.EqualValues(t, 3_000_000, hist[0].In)
+	require.EqualValues(t, 0, hist[0].Out)
+
+	// genesis controller account spends genesis output and receives the remainder
+	hist = ix.AccountHistory(genesisAddr.AccountID(), 0)
+	require.EqualValues(t, 1, len(hist))
+	require.EqualValues(t, 3_000_000, hist[0].Out-hist[0].In)
+
+	chainHist := ix.ChainHistory(chainID, 0)
+	require.EqualValues(t, 1, len(chainHist))
+	require.EqualValues(t, chainOutputID, chainHist[0].ID)
+	require.EqualValues(t, 2_000_000, chainHist[0].Amount)
+
+	// indexing is idempotent
+	require.NoError(t, ix.indexBranch(&distribTxID))
+	require.EqualValues(t, 1, len(ix.AccountHistory(addr.AccountID(), 0)))
+
+	lastSlot, ok := fetchLastIndexedSlot(ix.store)
+	require.True(t, ok)
+	require.EqualValues(t, distribTxID.Slot(), lastSlot)
+	require.EqualValues(t, 0, len(finalizedBranchesNotIndexed(stateStore, indexStore)))
+}
+
+func TestLatestFirst(t *testing.T) {
+	require.EqualValues(t, []int{3, 2, 1}, latestFirst([]int{1, 2, 3}, 0))
+	require.EqualValues(t, []int{3, 2}, latestFirst([]int{1, 2, 3}, 2))
+	require.EqualValues(t, []int{}, latestFirst([]int{}, 2))
+}
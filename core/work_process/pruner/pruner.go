@@ -3,7 +3,6 @@ package pruner
 import (
 	"fmt"
 	"runtime"
-	"time"
 
 	"github.com/lunfardo314/proxima/core/vertex"
 	"github.com/lunfardo314/proxima/global"
@@ -39,7 +38,7 @@ func New(env Environment) *Pruner {
 // pruneVertices returns how many marked for deletion and how many past cones unreferenced
 func (p *Pruner) pruneVertices() (int, int) {
 	toDelete := make([]*vertex.WrappedTx, 0)
-	nowis := time.Now()
+	nowis := p.Clock().Now()
 	var markedForDeletionCount, unreferencedPastConeCount int
 	for _, vid := range p.Vertices() {
 		markedForDeletion, unreferencedPastCone := vid.DoPruningIfRelevant(nowis)
@@ -95,7 +94,7 @@ func (p *Pruner) mainLoop() {
 		select {
 		case <-p.Ctx().Done():
 			return
-		case <-time.After(prunerLoopPeriod):
+		case <-p.Clock().After(prunerLoopPeriod):
 		}
 		p.doPrune()
 	}
@@ -0,0 +1,63 @@
+package pull_client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/core/txmetadata"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/txstore"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func init() {
+	ledger.InitWithTestingLedgerIDData()
+}
+
+type pullClientDummyEnvironment struct {
+	*global.Global
+	txBytesStore global.TxBytesStore
+	numQueried   atomic.Int32
+}
+
+func (d *pullClientDummyEnvironment) TxBytesStore() global.TxBytesStore {
+	return d.txBytesStore
+}
+
+func (d *pullClientDummyEnvironment) QueryTransactionsFromRandomPeer(lst ...ledger.TransactionID) bool {
+	d.numQueried.Add(int32(len(lst)))
+	return true
+}
+
+func (d *pullClientDummyEnvironment) TxBytesWithMetadataIn(_ []byte, _ *txmetadata.TransactionMetadata) (*ledger.TransactionID, error) {
+	return nil, nil
+}
+
+func TestRepull(t *testing.T) {
+	env := &pullClientDummyEnvironment{
+		Global:       global.NewDefault(),
+		txBytesStore: txstore.NewSimpleTxBytesStore(common.NewInMemoryKVStore()),
+	}
+	p := New(env)
+	p.Start()
+
+	txid := ledger.RandomTransactionID(true)
+	p.Pull(&txid)
+
+	// the initial query goes out immediately, the transaction must be queried again after each pull period
+	require.Eventually(t, func() bool {
+		return env.numQueried.Load() >= 3
+	}, 4*pullPeriod, pullLoopPeriod)
+
+	p.StopPulling(&txid)
+	time.Sleep(pullLoopPeriod)
+	n := env.numQueried.Load()
+	time.Sleep(2 * pullPeriod)
+	require.EqualValues(t, n, env.numQueried.Load())
+
+	env.Stop()
+	env.MustWaitAllWorkProcessesStop()
+}
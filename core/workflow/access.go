@@ -80,6 +80,7 @@ func (w *Workflow) EvidenceIncomingBranch(txid *ledger.TransactionID, seqID ledg
 
 func (w *Workflow) EvidenceBookedBranch(txid *ledger.TransactionID, seqID ledger.ChainID) {
 	w.syncData.EvidenceBookedBranch(txid, seqID)
+	w.events.PostEvent(EventBranchBooked, *txid)
 }
 
 func (w *Workflow) SyncData() *SyncData {
@@ -133,6 +134,11 @@ func (w *Workflow) GetTxInclusion(txid *ledger.TransactionID, slotsBack int) *mu
 	return multistate.GetTxInclusion(w.StateStore(), txid, slotsBack)
 }
 
+// FinalizedBranch returns the latest finalized branch, if any
+func (w *Workflow) FinalizedBranch() (ledger.TransactionID, bool) {
+	return w.finality.FinalizedBranch()
+}
+
 func (w *Workflow) WaitTxIDDefined(txid *ledger.TransactionID, pollPeriod, timeout time.Duration) (vertex.Status, error) {
 	deadline := time.Now().Add(timeout)
 	for {
@@ -1,17 +1,45 @@
 package workflow
 
+import (
+	"github.com/lunfardo314/proxima/core/work_process/finality"
+	"github.com/lunfardo314/proxima/multistate"
+)
+
 type (
 	ConfigParams struct {
 		doNotStartPruner bool
+		finality         finality.Params
 	}
 
 	ConfigOption func(c *ConfigParams)
 )
 
 func defaultConfigParams() ConfigParams {
-	return ConfigParams{}
+	return ConfigParams{
+		finality: finality.DefaultParams(),
+	}
 }
 
 func OptionDoNotStartPruner(c *ConfigParams) {
 	c.doNotStartPruner = true
 }
+
+// OptionFinalityThreshold sets coverage threshold of branches which are candidates for the finality.
+// Wrong fraction is ignored
+func OptionFinalityThreshold(numerator, denominator int) ConfigOption {
+	return func(c *ConfigParams) {
+		if multistate.ValidInclusionThresholdFraction(numerator, denominator) {
+			c.finality.ThresholdNumerator = numerator
+			c.finality.ThresholdDenominator = denominator
+		}
+	}
+}
+
+// OptionFinalitySlotsBack sets number of latest slots with branches, which are candidates for the finality
+func OptionFinalitySlotsBack(slotsBack int) ConfigOption {
+	return func(c *ConfigParams) {
+		if slotsBack > 0 {
+			c.finality.SlotsBack = slotsBack
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package workflow
 
-import "github.com/lunfardo314/proxima/core/vertex"
+import (
+	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/ledger"
+)
 
 func (w *Workflow) PostEventNewGood(vid *vertex.WrappedTx) {
 	w.Tracef("events", "PostEventNewGood: %s", vid.IDShortString())
@@ -11,3 +14,27 @@ func (w *Workflow) PostEventNewTransaction(vid *vertex.WrappedTx) {
 	w.Tracef("events", "PostEventNewTransaction: %s", vid.IDShortString())
 	w.events.PostEvent(EventNewTx, vid)
 }
+
+func (w *Workflow) PostEventBadTx(vid *vertex.WrappedTx) {
+	w.Tracef("events", "PostEventBadTx: %s", vid.IDShortString())
+	w.events.PostEvent(EventBadTx, vid)
+}
+
+func (w *Workflow) PostEventBranchFinalized(branchID ledger.TransactionID) {
+	w.Tracef("events", "PostEventBranchFinalized: %s", branchID.StringShort)
+	w.events.PostEvent(EventBranchFinalized, branchID)
+}
+
+// AddVertexNoLock adds vertex to the MemDAG and posts the event
+func (w *Workflow) AddVertexNoLock(vid *vertex.WrappedTx) {
+	w.MemDAG.AddVertexNoLock(vid)
+	w.events.PostEvent(EventNewVertex, vid)
+}
+
+// PurgeDeletedVertices removes vertices from the MemDAG and posts event for each of them
+func (w *Workflow) PurgeDeletedVertices(deleted []*vertex.WrappedTx) {
+	w.MemDAG.PurgeDeletedVertices(deleted)
+	for _, vid := range deleted {
+		w.events.PostEvent(EventVertexDeleted, vid.ID)
+	}
+}
@@ -40,6 +40,31 @@ func (w *Workflow) ListenToSequencers(fun func(vid *vertex.WrappedTx)) {
 	})
 }
 
+// ListenToNewVertices calls fun on each vertex added to the MemDAG
+func (w *Workflow) ListenToNewVertices(fun func(vid *vertex.WrappedTx)) {
+	w.events.OnEvent(EventNewVertex, fun)
+}
+
+// ListenToBadTransactions calls fun on each transaction which becomes 'bad'
+func (w *Workflow) ListenToBadTransactions(fun func(vid *vertex.WrappedTx)) {
+	w.events.OnEvent(EventBadTx, fun)
+}
+
+// ListenToBookedBranches calls fun on each branch committed to the multi-state DB
+func (w *Workflow) ListenToBookedBranches(fun func(txid ledger.TransactionID)) {
+	w.events.OnEvent(EventBranchBooked, fun)
+}
+
+// ListenToDeletedVertices calls fun on each vertex removed from the MemDAG by the pruner
+func (w *Workflow) ListenToDeletedVertices(fun func(txid ledger.TransactionID)) {
+	w.events.OnEvent(EventVertexDeleted, fun)
+}
+
+// ListenToFinalizedBranches calls fun on each branch which becomes final, in the order of slots
+func (w *Workflow) ListenToFinalizedBranches(fun func(branchID ledger.TransactionID)) {
+	w.events.OnEvent(EventBranchFinalized, fun)
+}
+
 const fetchLastNTimeSlotsUponStartup = 5
 
 // LoadSequencerTips pulls tip transactions relevant to the sequencer startup from fixed amount of lates slots
@@ -6,6 +6,7 @@ import (
 
 	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/clock"
 	"github.com/lunfardo314/proxima/util/set"
 )
 
@@ -22,6 +23,7 @@ type (
 		lastCutFinal time.Time
 		StartTime    time.Time
 		PerSequencer map[ledger.ChainID]SequencerSyncStatus
+		clock        clock.Clock
 	}
 
 	SyncInfo struct {
@@ -42,11 +44,12 @@ type (
 	}
 )
 
-func newSyncData() *SyncData {
+func newSyncData(clk clock.Clock) *SyncData {
 	return &SyncData{
 		mutex:        sync.RWMutex{},
-		StartTime:    time.Now(),
+		StartTime:    clk.Now(),
 		PerSequencer: make(map[ledger.ChainID]SequencerSyncStatus),
+		clock:        clk,
 	}
 }
 
@@ -78,7 +81,7 @@ func (s *SyncData) SinceLastPrunedOrphaned() time.Duration {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	return time.Since(s.lastPrunedOrphaned)
+	return s.clock.Now().Sub(s.lastPrunedOrphaned)
 }
 
 func (s *SyncData) SetLastPrunedOrphaned(t time.Time) {
@@ -92,7 +95,7 @@ func (s *SyncData) SinceLastCutFinal() time.Duration {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	return time.Since(s.lastCutFinal)
+	return s.clock.Now().Sub(s.lastCutFinal)
 }
 
 func (s *SyncData) SetLastCutFinal(t time.Time) {
@@ -186,7 +189,7 @@ func syncWindowDuration() time.Duration {
 
 // IsInSyncWindow returns true if latest added transaction (by timestamp) is no more than 1/2 time slot back from now
 func (s *SyncData) isInSyncWindow() bool {
-	return s.latestTransactionTSTime.Add(syncWindowDuration()).After(time.Now())
+	return s.latestTransactionTSTime.Add(syncWindowDuration()).After(s.clock.Now())
 }
 
 func (s *SyncData) IsSynced() bool {
@@ -62,7 +62,7 @@ func (w *Workflow) TxBytesIn(txBytes []byte, opts ...TxBytesInOption) (*ledger.T
 
 	enforceTimeBounds := options.txMetadata.SourceTypeNonPersistent == txmetadata.SourceTypeAPI || options.txMetadata.SourceTypeNonPersistent == txmetadata.SourceTypePeer
 	// transaction is rejected if it is too far in the future wrt the local clock
-	nowis := time.Now()
+	nowis := w.Clock().Now()
 
 	timeUpperBound := nowis.Add(w.MaxDurationInTheFuture())
 	err = tx.Validate(transaction.CheckTimestampUpperBound(timeUpperBound))
@@ -109,6 +109,13 @@ func (w *Workflow) TxBytesIn(txBytes []byte, opts ...TxBytesInOption) (*ledger.T
 	if options.callback != nil {
 		attachOpts = append(attachOpts, attacher.OptionWithAttachmentCallback(options.callback))
 	}
+	if options.receivedFromPeer != nil {
+		// metadata is advisory. The peer which sent inconsistent metadata is penalized
+		from := *options.receivedFromPeer
+		attachOpts = append(attachOpts, attacher.OptionOnMetadataMismatch(func(_ error) {
+			w.peers.BlockCommsWithPeer(from)
+		}))
+	}
 
 	if txTime.Before(nowis) {
 		// timestamp is in the past -> attach immediately
@@ -122,7 +129,7 @@ func (w *Workflow) TxBytesIn(txBytes []byte, opts ...TxBytesInOption) (*ledger.T
 	w.TraceTx(txid, "TxBytesIn: delay for %v", delayFor)
 
 	go func() {
-		time.Sleep(delayFor)
+		w.Clock().Sleep(delayFor)
 		w.Tracef(TraceTagTxInput, "%s -> release", txid.StringShort)
 		w.TraceTx(txid, "TxBytesIn: -> release")
 
@@ -150,7 +157,8 @@ func (w *Workflow) SequencerMilestoneAttachWait(txBytes []byte, meta *txmetadata
 
 	closed := false
 	var closedMutex sync.Mutex
-	resCh := make(chan result)
+	// buffered, so that the callback never blocks when the waiting side has already returned
+	resCh := make(chan result, 1)
 	defer func() {
 		closedMutex.Lock()
 		defer closedMutex.Unlock()
@@ -165,7 +173,10 @@ func (w *Workflow) SequencerMilestoneAttachWait(txBytes []byte, meta *txmetadata
 			if closed {
 				return
 			}
-			resCh <- res
+			select {
+			case resCh <- res:
+			default:
+			}
 		}
 
 		_, errParse := w.TxBytesIn(txBytes,
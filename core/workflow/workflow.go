@@ -8,6 +8,7 @@ import (
 	"github.com/lunfardo314/proxima/core/txmetadata"
 	"github.com/lunfardo314/proxima/core/vertex"
 	"github.com/lunfardo314/proxima/core/work_process/events"
+	"github.com/lunfardo314/proxima/core/work_process/finality"
 	"github.com/lunfardo314/proxima/core/work_process/gossip"
 	"github.com/lunfardo314/proxima/core/work_process/persist_txbytes"
 	"github.com/lunfardo314/proxima/core/work_process/poker"
@@ -17,6 +18,7 @@ import (
 	"github.com/lunfardo314/proxima/core/work_process/tippool"
 	"github.com/lunfardo314/proxima/global"
 	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
 	"github.com/lunfardo314/proxima/peering"
 	"github.com/lunfardo314/proxima/util/eventtype"
 	"github.com/lunfardo314/proxima/util/set"
@@ -41,6 +43,7 @@ type (
 		poker            *poker.Poker
 		events           *events.Events
 		tippool          *tippool.SequencerTips
+		finality         *finality.Tracker
 		syncData         *SyncData
 		doNotStartPruner bool
 		//
@@ -53,6 +56,13 @@ type (
 var (
 	EventNewGoodTx = eventtype.RegisterNew[*vertex.WrappedTx]("new good seq")
 	EventNewTx     = eventtype.RegisterNew[*vertex.WrappedTx]("new tx") // event may be posted more than once for the transaction
+	// events of MemDAG changes. Used by the visualizer
+	EventNewVertex     = eventtype.RegisterNew[*vertex.WrappedTx]("new vertex")
+	EventBadTx         = eventtype.RegisterNew[*vertex.WrappedTx]("bad tx")
+	EventBranchBooked  = eventtype.RegisterNew[ledger.TransactionID]("branch booked")
+	EventVertexDeleted = eventtype.RegisterNew[ledger.TransactionID]("vertex deleted")
+	// EventBranchFinalized is posted for each branch which becomes final, in the order of slots
+	EventBranchFinalized = eventtype.RegisterNew[ledger.TransactionID]("branch finalized")
 )
 
 func New(env Environment, peers *peering.Peers, opts ...ConfigOption) *Workflow {
@@ -65,7 +75,7 @@ func New(env Environment, peers *peering.Peers, opts ...ConfigOption) *Workflow
 		Environment:      env,
 		MemDAG:           memdag.New(env),
 		peers:            peers,
-		syncData:         newSyncData(),
+		syncData:         newSyncData(env.Clock()),
 		traceTags:        set.New[string](),
 		doNotStartPruner: cfg.doNotStartPruner,
 	}
@@ -76,6 +86,7 @@ func New(env Environment, peers *peering.Peers, opts ...ConfigOption) *Workflow
 	ret.gossip = gossip.New(ret)
 	ret.persistTxBytes = persist_txbytes.New(ret)
 	ret.tippool = tippool.New(ret)
+	ret.finality = finality.New(ret, cfg.finality)
 
 	return ret
 }
@@ -90,6 +101,16 @@ func (w *Workflow) Start() {
 	w.gossip.Start()
 	w.persistTxBytes.Start()
 	w.tippool.Start()
+	w.finality.Start()
+	w.ListenToBookedBranches(func(branchID ledger.TransactionID) {
+		w.finality.Push(finality.Input{BranchID: branchID})
+	})
+	// library upgrades are applied only from final states. Upgrades of branches which may be orphaned are ignored
+	w.ListenToFinalizedBranches(func(branchID ledger.TransactionID) {
+		if err := multistate.ApplyLibraryUpgradesOfBranch(w.StateStore(), branchID); err != nil {
+			w.Log().Errorf("failed to apply library upgrades of the finalized branch %s: %v", branchID.StringShort(), err)
+		}
+	})
 	if !w.doNotStartPruner {
 		prune := pruner.New(w) // refactor
 		prune.Start()
@@ -2,6 +2,7 @@ package workflow
 
 import (
 	"testing"
+	"time"
 
 	"github.com/lunfardo314/proxima/global"
 	"github.com/lunfardo314/proxima/ledger"
@@ -53,3 +54,30 @@ func TestBasic(t *testing.T) {
 	env.Stop()
 	env.MustWaitAllWorkProcessesStop()
 }
+
+func TestSequencerMilestoneAttachWaitCancelled(t *testing.T) {
+	env := newWorkflowDummyEnvironment()
+	peers := peering.NewPeersDummy()
+
+	w := New(env, peers, OptionDoNotStartPruner)
+	w.Start()
+	env.Stop()
+	env.MustWaitAllWorkProcessesStop()
+
+	// the attachment result and the cancellation may both be ready. The waiting side must return anyway
+	const howMany = 100_000
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < howMany; i++ {
+			if _, err := w.SequencerMilestoneAttachWait([]byte("dummy data"), nil, time.Minute, false); err == nil {
+				t.Errorf("error expected")
+			}
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("SequencerMilestoneAttachWait is blocked")
+	}
+}
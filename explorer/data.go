@@ -0,0 +1,253 @@
+package explorer
+
+import (
+	"fmt"
+
+	"github.com/lunfardo314/proxima/core/txmetadata"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util"
+)
+
+type (
+	// outputStatus is the fate of the output along the heaviest branch chain
+	outputStatus struct {
+		// Unspent output is in the state of the latest heaviest branch
+		Unspent bool
+		// ProducedIn is the branch in the heaviest chain which first contains the output. Nil if the output was
+		// produced before the explored part of the chain
+		ProducedIn *ledger.TransactionID
+		// ConsumedIn is the earliest branch in the heaviest chain which does not contain the output anymore
+		ConsumedIn *ledger.TransactionID
+		// ConsumedBy is the transaction, which consumes the output. May be nil when the output is consumed but
+		// the consuming transaction is not in the transaction store
+		ConsumedBy *ledger.TransactionID
+	}
+
+	chainHistoryItem struct {
+		ID     ledger.OutputID
+		Amount uint64
+	}
+)
+
+// heaviestBranchChain returns branches of the heaviest chain nBack slots back from the latest slot, descending by slot.
+// nBack < 0 means all branches
+func (e *Explorer) heaviestBranchChain(nBack int) []*multistate.BranchData {
+	if nBack < 0 || ledger.Slot(nBack) >= multistate.FetchLatestSlot(e.stateStore) {
+		nBack = -1
+	}
+	return multistate.FetchHeaviestBranchChainNSlotsBack(e.stateStore, nBack)
+}
+
+// heaviestState returns state of the latest branch with the biggest ledger coverage
+func (e *Explorer) heaviestState() (multistate.SugaredStateReader, *multistate.BranchData, error) {
+	branches := multistate.FetchLatestBranches(e.stateStore)
+	if len(branches) == 0 {
+		return multistate.SugaredStateReader{}, nil, fmt.Errorf("no branches found in the state DB")
+	}
+	br := util.Maximum(branches, func(br1, br2 *multistate.BranchData) bool {
+		return br1.LedgerCoverage < br2.LedgerCoverage
+	})
+	rdr, err := multistate.NewSugaredReadableState(e.stateStore, br.Root)
+	if err != nil {
+		return multistate.SugaredStateReader{}, nil, err
+	}
+	return rdr, br, nil
+}
+
+// getTransaction loads transaction and its metadata from the transaction store. Returns nil, nil, nil if not found
+func (e *Explorer) getTransaction(txid *ledger.TransactionID) (*transaction.Transaction, *txmetadata.TransactionMetadata, error) {
+	txBytesWithMetadata := e.txStore.GetTxBytesWithMetadata(txid)
+	if len(txBytesWithMetadata) == 0 {
+		return nil, nil, nil
+	}
+	metadataBytes, txBytes, err := txmetadata.SplitTxBytesWithMetadata(txBytesWithMetadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata, err := txmetadata.TransactionMetadataFromBytes(metadataBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if *tx.ID() != *txid {
+		return nil, nil, fmt.Errorf("inconsistency: transaction store returned %s instead of %s", tx.IDShortString(), txid.StringShort())
+	}
+	return tx, metadata, nil
+}
+
+// getOutput looks for the output in the heaviest state, then in the producing transaction in the transaction store,
+// then among the genesis outputs, which are not produced by any stored transaction. Returns nil, nil if not found
+func (e *Explorer) getOutput(oid *ledger.OutputID) (*ledger.Output, error) {
+	if rdr, _, err := e.heaviestState(); err == nil {
+		if oData, found := rdr.GetUTXO(oid); found {
+			return ledger.OutputFromBytesReadOnly(oData)
+		}
+	}
+	txid := oid.TransactionID()
+	tx, _, err := e.getTransaction(&txid)
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		if int(oid.Index()) >= tx.NumProducedOutputs() {
+			return nil, fmt.Errorf("wrong output index %d: transaction %s has %d outputs", oid.Index(), txid.StringShort(), tx.NumProducedOutputs())
+		}
+		return tx.ProducedOutputAt(oid.Index())
+	}
+	switch *oid {
+	case ledger.GenesisOutputID():
+		id := ledger.L().ID
+		return ledger.GenesisOutput(id.InitialSupply, ledger.AddressED25519FromPublicKey(id.GenesisControllerPublicKey)).Output, nil
+	case ledger.GenesisStemOutputID():
+		return ledger.GenesisStemOutput().Output, nil
+	}
+	return nil, nil
+}
+
+// inputLoader is used to display transaction with its consumed outputs
+func (e *Explorer) inputLoader(tx *transaction.Transaction) func(idx byte) (*ledger.Output, error) {
+	return func(idx byte) (*ledger.Output, error) {
+		oid, err := tx.InputAt(idx)
+		if err != nil {
+			return nil, err
+		}
+		o, err := e.getOutput(&oid)
+		if err != nil {
+			return nil, err
+		}
+		if o == nil {
+			return nil, fmt.Errorf("consumed output %s not found", oid.StringShort())
+		}
+		return o, nil
+	}
+}
+
+// getOutputStatus walks the heaviest branch chain from the past to the latest branch and finds the first branch
+// which contains the output and the first branch, which does not contain the output anymore.
+// The consuming transaction is one of transactions committed in the latter branch and unknown in the branch before
+func (e *Explorer) getOutputStatus(oid *ledger.OutputID) (ret outputStatus, err error) {
+	txid := oid.TransactionID()
+	chain := e.heaviestBranchChain(e.slotsBack)
+
+	var prev *multistate.Readable
+	for i := len(chain) - 1; i >= 0; i-- {
+		br := chain[i]
+		if br.Stem.ID.Slot() < oid.Slot() {
+			continue
+		}
+		rdr, err := multistate.NewReadable(e.stateStore, br.Root)
+		if err != nil {
+			return ret, err
+		}
+		switch {
+		case rdr.HasUTXO(oid):
+			if ret.ProducedIn == nil && (prev == nil || !prev.KnowsCommittedTransaction(&txid)) {
+				ret.ProducedIn = br.TxID()
+			}
+			ret.Unspent = i == 0
+		case rdr.KnowsCommittedTransaction(&txid):
+			ret.ConsumedIn = br.TxID()
+			ret.ConsumedBy, err = e.findConsumer(rdr, prev, oid)
+			return ret, err
+		}
+		prev = rdr
+	}
+	return ret, nil
+}
+
+// findConsumer looks for the transaction, which consumes the output, among transactions committed in the state
+// and unknown in the previous state. Previous state may be nil
+func (e *Explorer) findConsumer(rdr, prev *multistate.Readable, oid *ledger.OutputID) (*ledger.TransactionID, error) {
+	candidates := make([]ledger.TransactionID, 0)
+	rdr.IterateKnownCommittedTransactions(func(txid *ledger.TransactionID, slot ledger.Slot) bool {
+		if slot >= oid.Slot() && (prev == nil || !prev.KnowsCommittedTransaction(txid)) {
+			candidates = append(candidates, *txid)
+		}
+		return true
+	})
+	for i := range candidates {
+		tx, _, err := e.getTransaction(&candidates[i])
+		if err != nil {
+			return nil, err
+		}
+		if tx == nil {
+			continue
+		}
+		found := false
+		tx.ForEachInput(func(_ byte, inp *ledger.OutputID) bool {
+			found = *inp == *oid
+			return !found
+		})
+		if found {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// newTransactionsInBranch returns transactions committed in the branch and unknown in its predecessor branch
+func (e *Explorer) newTransactionsInBranch(br *multistate.BranchData) ([]ledger.TransactionID, error) {
+	rdr, err := multistate.NewReadable(e.stateStore, br.Root)
+	if err != nil {
+		return nil, err
+	}
+	var prev *multistate.Readable
+	if predBranch, ok := predecessorBranch(br); ok {
+		if predData, found := multistate.FetchRootRecord(e.stateStore, predBranch); found {
+			if prev, err = multistate.NewReadable(e.stateStore, predData.Root); err != nil {
+				return nil, err
+			}
+		}
+	}
+	ret := make([]ledger.TransactionID, 0)
+	rdr.IterateKnownCommittedTransactions(func(txid *ledger.TransactionID, _ ledger.Slot) bool {
+		if prev == nil || !prev.KnowsCommittedTransaction(txid) {
+			ret = append(ret, *txid)
+		}
+		return true
+	})
+	return ret, nil
+}
+
+// predecessorBranch is the branch which produced stem output consumed by the branch
+func predecessorBranch(br *multistate.BranchData) (ledger.TransactionID, bool) {
+	stemLock, ok := br.Stem.Output.StemLock()
+	if !ok || br.Stem.ID == ledger.GenesisStemOutputID() {
+		return ledger.TransactionID{}, false
+	}
+	return stemLock.PredecessorOutputID.TransactionID(), true
+}
+
+// chainHistory follows chain predecessors from the output back to the origin or to the first transaction
+// not found in the transaction store. Returns at most maxItems items, the latest first
+func (e *Explorer) chainHistory(oid ledger.OutputID, maxItems int) ([]chainHistoryItem, error) {
+	ret := make([]chainHistoryItem, 0)
+	for len(ret) < maxItems {
+		txid := oid.TransactionID()
+		tx, _, err := e.getTransaction(&txid)
+		if err != nil {
+			return nil, err
+		}
+		if tx == nil {
+			return ret, nil
+		}
+		o, err := tx.ProducedOutputAt(oid.Index())
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, chainHistoryItem{ID: oid, Amount: o.Amount()})
+		cc, idx := o.ChainConstraint()
+		if idx == 0xff || cc.IsOrigin() {
+			return ret, nil
+		}
+		if oid, err = tx.InputAt(cc.PredecessorInputIndex); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
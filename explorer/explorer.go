@@ -0,0 +1,376 @@
+package explorer
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lunfardo314/proxima/core/txmetadata"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util"
+)
+
+// Explorer is a web-based ledger explorer. It serves HTML pages of transactions, outputs, accounts, chains and branches.
+// Transactions are loaded from the transaction store, ledger states are read from the multi-state DB.
+// It can be served by the node or by the standalone 'proxi db explorer' over the databases of the stopped node
+type Explorer struct {
+	stateStore global.StateStoreReader
+	txStore    global.TxBytesGet
+	slotsBack  int
+	mux        *http.ServeMux
+}
+
+const (
+	// DefaultSlotsBack is how many slots back the heaviest branch chain is explored by default
+	DefaultSlotsBack = 100
+	// maxChainHistory is the maximum number of chain outputs displayed on the chain page
+	maxChainHistory = 50
+)
+
+var errNotFound = errors.New("not found")
+
+// New creates explorer over the state store and the transaction store.
+// Optional slotsBack limits how deep the heaviest branch chain is explored. Negative value means all branches
+func New(stateStore global.StateStoreReader, txStore global.TxBytesGet, slotsBack ...int) *Explorer {
+	ret := &Explorer{
+		stateStore: stateStore,
+		txStore:    txStore,
+		slotsBack:  DefaultSlotsBack,
+		mux:        http.NewServeMux(),
+	}
+	if len(slotsBack) > 0 {
+		ret.slotsBack = slotsBack[0]
+	}
+	ret.mux.HandleFunc("/", ret.handleHome)
+	ret.mux.HandleFunc("/search", ret.handleSearch)
+	ret.mux.HandleFunc("/tx/", ret.handleTransaction)
+	ret.mux.HandleFunc("/output/", ret.handleOutput)
+	ret.mux.HandleFunc("/account", ret.handleAccount)
+	ret.mux.HandleFunc("/chain/", ret.handleChain)
+	ret.mux.HandleFunc("/branch/", ret.handleBranch)
+	return ret
+}
+
+func (e *Explorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+func (e *Explorer) handleHome(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		e.renderError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+	slotsBack := 10
+	if s := r.URL.Query().Get("slots"); s != "" {
+		var err error
+		if slotsBack, err = strconv.Atoi(s); err != nil {
+			e.renderError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	e.render(w, "home", struct {
+		LatestSlot ledger.Slot
+		SlotsBack  int
+		Branches   []*multistate.BranchData
+	}{
+		LatestSlot: multistate.FetchLatestSlot(e.stateStore),
+		SlotsBack:  slotsBack,
+		Branches:   e.heaviestBranchChain(slotsBack),
+	})
+}
+
+// handleSearch recognizes the search string by its length and redirects to the corresponding page.
+// 32 bytes are interpreted as transaction ID if the transaction is known, otherwise as chain ID
+func (e *Explorer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if strings.Contains(q, "(") {
+		http.Redirect(w, r, "/account?lock="+url.QueryEscape(q), http.StatusFound)
+		return
+	}
+	q = strings.TrimPrefix(q, "0x")
+	data, err := hex.DecodeString(q)
+	if err != nil {
+		e.renderError(w, http.StatusBadRequest, fmt.Errorf("can't interpret '%s': %v", q, err))
+		return
+	}
+	switch len(data) {
+	case ledger.OutputIDLength:
+		http.Redirect(w, r, "/output/"+q, http.StatusFound)
+		return
+	case ledger.TransactionIDLength:
+		txid, _ := ledger.TransactionIDFromBytes(data)
+		if e.txStore.HasTxBytes(&txid) || txid == *ledger.GenesisTransactionID() {
+			http.Redirect(w, r, "/tx/"+q, http.StatusFound)
+		} else {
+			http.Redirect(w, r, "/chain/"+q, http.StatusFound)
+		}
+		return
+	}
+	if _, err = ledger.AccountableFromBytes(data); err == nil {
+		http.Redirect(w, r, "/account?lock="+url.QueryEscape(q), http.StatusFound)
+		return
+	}
+	e.renderError(w, http.StatusBadRequest, fmt.Errorf("can't interpret '%s' as transaction ID, output ID, chain ID or account", q))
+}
+
+func (e *Explorer) handleTransaction(w http.ResponseWriter, r *http.Request) {
+	txid, err := ledger.TransactionIDFromHexString(strings.TrimPrefix(r.URL.Path, "/tx/"))
+	if err != nil {
+		e.renderError(w, http.StatusBadRequest, err)
+		return
+	}
+	tx, metadata, err := e.getTransaction(&txid)
+	if err != nil {
+		e.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data := struct {
+		ID        ledger.TransactionID
+		Found     bool
+		Metadata  *txmetadata.TransactionMetadata
+		Lines     string
+		Inputs    []ledger.OutputID
+		Endorse   []ledger.TransactionID
+		Outputs   []*ledger.OutputWithID
+		Inclusion *multistate.TxInclusion
+	}{
+		ID:        txid,
+		Found:     tx != nil,
+		Metadata:  metadata,
+		Inclusion: multistate.GetTxInclusion(e.stateStore, &txid, e.slotsBack),
+	}
+	if tx != nil {
+		data.Lines = tx.Lines(e.inputLoader(tx)).String()
+		data.Inputs = tx.Inputs()
+		tx.ForEachEndorsement(func(_ byte, txid *ledger.TransactionID) bool {
+			data.Endorse = append(data.Endorse, *txid)
+			return true
+		})
+		data.Outputs = tx.ProducedOutputs()
+	}
+	e.render(w, "tx", &data)
+}
+
+type constraintView struct {
+	Index  byte
+	Name   string
+	Source string
+	Hex    string
+}
+
+func (e *Explorer) handleOutput(w http.ResponseWriter, r *http.Request) {
+	oid, err := ledger.OutputIDFromHexString(strings.TrimPrefix(r.URL.Path, "/output/"))
+	if err != nil {
+		e.renderError(w, http.StatusBadRequest, err)
+		return
+	}
+	o, err := e.getOutput(&oid)
+	if err != nil {
+		e.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	status, err := e.getOutputStatus(&oid)
+	if err != nil {
+		e.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data := struct {
+		ID          ledger.OutputID
+		Found       bool
+		Amount      uint64
+		Lock        string
+		Accounts    []ledger.Accountable
+		ChainID     *ledger.ChainID
+		Constraints []constraintView
+		Status      outputStatus
+	}{
+		ID:     oid,
+		Found:  o != nil,
+		Status: status,
+	}
+	if o != nil {
+		data.Amount = o.Amount()
+		data.Lock = o.Lock().String()
+		data.Accounts = o.Lock().Accounts()
+		if cc, idx := o.ChainConstraint(); idx != 0xff {
+			chainID := cc.ID
+			if cc.IsOrigin() {
+				chainID = ledger.MakeOriginChainID(&oid)
+			}
+			data.ChainID = &chainID
+		}
+		data.Constraints = constraintViews(o)
+	}
+	e.render(w, "output", &data)
+}
+
+// constraintViews decompiles each constraint of the output to EasyFL source
+func constraintViews(o *ledger.Output) []constraintView {
+	ret := make([]constraintView, 0, o.NumConstraints())
+	o.ForEachConstraint(func(idx byte, constr []byte) bool {
+		cv := constraintView{Index: idx, Hex: hex.EncodeToString(constr)}
+		if c, err := ledger.ConstraintFromBytes(constr); err == nil {
+			cv.Name = c.Name()
+		} else {
+			cv.Name = fmt.Sprintf("error: %v", err)
+		}
+		if src, err := ledger.L().DecompileBytecode(constr); err == nil {
+			cv.Source = src
+		} else {
+			cv.Source = fmt.Sprintf("can't decompile: %v", err)
+		}
+		ret = append(ret, cv)
+		return true
+	})
+	return ret
+}
+
+func (e *Explorer) handleAccount(w http.ResponseWriter, r *http.Request) {
+	lockStr := strings.TrimSpace(r.URL.Query().Get("lock"))
+	accountable, err := ledger.AccountableFromSource(lockStr)
+	if err != nil {
+		if accountable, err = ledger.AccountableFromHexString(strings.TrimPrefix(lockStr, "0x")); err != nil {
+			e.renderError(w, http.StatusBadRequest, fmt.Errorf("can't parse account '%s': %v", lockStr, err))
+			return
+		}
+	}
+	rdr, br, err := e.heaviestState()
+	if err != nil {
+		e.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	outs, err := rdr.GetOutputsForAccount(accountable.AccountID())
+	if err != nil {
+		e.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	total := uint64(0)
+	for _, o := range outs {
+		total += o.Output.Amount()
+	}
+	e.render(w, "account", struct {
+		Account ledger.Accountable
+		Branch  *ledger.TransactionID
+		Outputs []*ledger.OutputWithID
+		Total   uint64
+	}{
+		Account: accountable,
+		Branch:  br.TxID(),
+		Outputs: outs,
+		Total:   total,
+	})
+}
+
+func (e *Explorer) handleChain(w http.ResponseWriter, r *http.Request) {
+	chainID, err := ledger.ChainIDFromHexString(strings.TrimPrefix(r.URL.Path, "/chain/"))
+	if err != nil {
+		e.renderError(w, http.StatusBadRequest, err)
+		return
+	}
+	rdr, br, err := e.heaviestState()
+	if err != nil {
+		e.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data := struct {
+		ID      ledger.ChainID
+		Branch  *ledger.TransactionID
+		Found   bool
+		Output  *ledger.OutputWithID
+		History []chainHistoryItem
+	}{
+		ID:     chainID,
+		Branch: br.TxID(),
+	}
+	if data.Output, err = rdr.GetChainOutput(&chainID); err == nil {
+		data.Found = true
+		if data.History, err = e.chainHistory(data.Output.ID, maxChainHistory); err != nil {
+			e.renderError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	e.render(w, "chain", &data)
+}
+
+func (e *Explorer) handleBranch(w http.ResponseWriter, r *http.Request) {
+	branchID, err := ledger.TransactionIDFromHexString(strings.TrimPrefix(r.URL.Path, "/branch/"))
+	if err != nil {
+		e.renderError(w, http.StatusBadRequest, err)
+		return
+	}
+	br, found := multistate.FetchBranchData(e.stateStore, branchID)
+	if !found {
+		e.renderError(w, http.StatusNotFound, fmt.Errorf("branch %s %w", branchID.StringShort(), errNotFound))
+		return
+	}
+	txids, err := e.newTransactionsInBranch(&br)
+	if err != nil {
+		e.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	data := struct {
+		Branch       *multistate.BranchData
+		Predecessor  *ledger.TransactionID
+		Transactions []ledger.TransactionID
+	}{
+		Branch:       &br,
+		Transactions: txids,
+	}
+	if pred, ok := predecessorBranch(&br); ok {
+		data.Predecessor = &pred
+	}
+	e.render(w, "branch", &data)
+}
+
+func (e *Explorer) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pageTemplates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (e *Explorer) renderError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = pageTemplates.ExecuteTemplate(w, "error", err.Error())
+}
+
+var pageTemplates = template.Must(template.New("explorer").Funcs(template.FuncMap{
+	"goth":        func(v uint64) string { return util.GoTh(v) },
+	"txLink":      txLink,
+	"outputLink":  outputLink,
+	"chainLink":   chainLink,
+	"branchLink":  branchLink,
+	"accountLink": accountLink,
+}).Parse(templates))
+
+func txLink(txid ledger.TransactionID) template.HTML {
+	return link("/tx/"+txid.StringHex(), txid.String())
+}
+
+func outputLink(oid ledger.OutputID) template.HTML {
+	return link("/output/"+oid.StringHex(), oid.String())
+}
+
+func chainLink(chainID ledger.ChainID) template.HTML {
+	return link("/chain/"+chainID.StringHex(), chainID.String())
+}
+
+func branchLink(txid ledger.TransactionID) template.HTML {
+	return link("/branch/"+txid.StringHex(), txid.String())
+}
+
+func accountLink(acc ledger.Accountable) template.HTML {
+	return link("/account?lock="+url.QueryEscape(hex.EncodeToString(acc.Bytes())), acc.String())
+}
+
+func link(href, text string) template.HTML {
+	return template.HTML(fmt.Sprintf(`<a href="%s">%s</a>`, template.HTMLEscapeString(href), template.HTMLEscapeString(text)))
+}
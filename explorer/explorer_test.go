@@ -0,0 +1,131 @@
+package explorer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/lunfardo314/proxima/core/txmetadata"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/txstore"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, e *Explorer, path string) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	e.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+	return resp
+}
+
+func TestExplorer(t *testing.T) {
+	genesisPrivateKey := ledger.InitWithTestingLedgerIDData()
+	addr := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(100))
+	distrib := []ledger.LockBalance{
+		{Lock: addr, Balance: 1_000_000},
+		{Lock: addr, Balance: 2_000_000, ChainOrigin: true},
+	}
+	stateStore := common.NewInMemoryKVStore()
+	multistate.InitStateStore(*ledger.L().ID, stateStore)
+	txBytes, distribTxID, err := txbuilder.DistributeInitialSupplyExt(stateStore, genesisPrivateKey, distrib)
+	require.NoError(t, err)
+
+	rr, found := multistate.FetchRootRecord(stateStore, distribTxID)
+	require.True(t, found)
+	txStore := txstore.NewSimpleTxBytesStore(common.NewInMemoryKVStore())
+	_, err = txStore.PersistTxBytesWithMetadata(txBytes, &txmetadata.TransactionMetadata{
+		StateRoot:      rr.Root,
+		LedgerCoverage: util.Ref(rr.LedgerCoverage),
+	})
+	require.NoError(t, err)
+
+	tx, err := transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+	require.NoError(t, err)
+	var chainOut *ledger.OutputWithID
+	var chainID ledger.ChainID
+	tx.ForEachProducedOutput(func(_ byte, o *ledger.Output, oid *ledger.OutputID) bool {
+		if cc, idx := o.ChainConstraint(); idx != 0xff && cc.IsOrigin() {
+			chainID = ledger.MakeOriginChainID(oid)
+			chainOut = &ledger.OutputWithID{ID: *oid, Output: o}
+			return false
+		}
+		return true
+	})
+	require.True(t, chainOut != nil)
+
+	e := New(stateStore, txStore)
+
+	t.Run("home", func(t *testing.T) {
+		resp := get(t, e, "/")
+		require.EqualValues(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), "/branch/"+distribTxID.StringHex())
+	})
+	t.Run("search", func(t *testing.T) {
+		resp := get(t, e, "/search?q="+distribTxID.StringHex())
+		require.EqualValues(t, http.StatusFound, resp.Code)
+		require.EqualValues(t, "/tx/"+distribTxID.StringHex(), resp.Header().Get("Location"))
+
+		resp = get(t, e, "/search?q="+chainID.StringHex())
+		require.EqualValues(t, http.StatusFound, resp.Code)
+		require.EqualValues(t, "/chain/"+chainID.StringHex(), resp.Header().Get("Location"))
+
+		resp = get(t, e, "/search?q="+chainOut.ID.StringHex())
+		require.EqualValues(t, http.StatusFound, resp.Code)
+		require.EqualValues(t, "/output/"+chainOut.ID.StringHex(), resp.Header().Get("Location"))
+
+		resp = get(t, e, "/search?q="+url.QueryEscape(addr.String()))
+		require.EqualValues(t, http.StatusFound, resp.Code)
+
+		resp = get(t, e, "/search?q=garbage")
+		require.EqualValues(t, http.StatusBadRequest, resp.Code)
+	})
+	t.Run("transaction", func(t *testing.T) {
+		resp := get(t, e, "/tx/"+distribTxID.StringHex())
+		require.EqualValues(t, http.StatusOK, resp.Code)
+		body := resp.Body.String()
+		// consumed genesis output and produced outputs are linked
+		genesisOut := ledger.GenesisOutputID()
+		require.Contains(t, body, "/output/"+genesisOut.StringHex())
+		require.Contains(t, body, "/output/"+chainOut.ID.StringHex())
+		require.NotContains(t, body, "can&#39;t create context")
+	})
+	t.Run("output", func(t *testing.T) {
+		genesisOut := ledger.GenesisOutputID()
+		resp := get(t, e, "/output/"+genesisOut.StringHex())
+		require.EqualValues(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), "consumed in branch")
+		require.Contains(t, resp.Body.String(), "/tx/"+distribTxID.StringHex())
+
+		resp = get(t, e, "/output/"+chainOut.ID.StringHex())
+		require.EqualValues(t, http.StatusOK, resp.Code)
+		body := resp.Body.String()
+		require.Contains(t, body, "unspent in the heaviest state")
+		require.Contains(t, body, "/chain/"+chainID.StringHex())
+		// constraints are decompiled
+		require.Contains(t, body, "amount(")
+	})
+	t.Run("account", func(t *testing.T) {
+		resp := get(t, e, "/account?lock="+url.QueryEscape(addr.String()))
+		require.EqualValues(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), util.GoTh(3_000_000))
+	})
+	t.Run("chain", func(t *testing.T) {
+		resp := get(t, e, "/chain/"+chainID.StringHex())
+		require.EqualValues(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), "/output/"+chainOut.ID.StringHex())
+	})
+	t.Run("branch", func(t *testing.T) {
+		resp := get(t, e, "/branch/"+distribTxID.StringHex())
+		require.EqualValues(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), "/branch/"+ledger.GenesisTransactionID().StringHex())
+
+		resp = get(t, e, "/branch/"+chainID.StringHex())
+		require.EqualValues(t, http.StatusNotFound, resp.Code)
+	})
+}
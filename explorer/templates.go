@@ -0,0 +1,151 @@
+package explorer
+
+const templates = `
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Proxima ledger explorer</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 3px 8px; text-align: left; vertical-align: top; }
+pre { background: #f4f4f4; padding: 8px; overflow-x: auto; }
+.mono { font-family: monospace; }
+.bad { color: #b00; }
+.good { color: #080; }
+</style>
+</head>
+<body>
+<p><a href="/">Proxima ledger explorer</a></p>
+<form action="/search" method="get">
+<input type="text" name="q" size="100" placeholder="transaction ID, output ID, chain ID (hex) or account lock, e.g. a(0x...)">
+<input type="submit" value="Search">
+</form>
+<hr>
+{{end}}
+
+{{define "footer"}}
+</body>
+</html>
+{{end}}
+
+{{define "error"}}{{template "header"}}
+<h3 class="bad">Error</h3>
+<p>{{.}}</p>
+{{template "footer"}}{{end}}
+
+{{define "home"}}{{template "header"}}
+<h3>Heaviest branch chain</h3>
+<p>Latest slot: {{.LatestSlot}}. Branches of last {{.SlotsBack}} slots, latest first</p>
+<table>
+<tr><th>Branch</th><th>Sequencer</th><th>Coverage</th><th>Slot inflation</th><th>Supply</th><th>Transactions</th></tr>
+{{range .Branches}}<tr>
+<td class="mono">{{branchLink .Stem.ID.TransactionID}}</td>
+<td class="mono">{{chainLink .SequencerID}}</td>
+<td>{{goth .LedgerCoverage}}</td>
+<td>{{goth .SlotInflation}}</td>
+<td>{{goth .Supply}}</td>
+<td>{{.NumTransactions}}</td>
+</tr>{{end}}
+</table>
+{{template "footer"}}{{end}}
+
+{{define "tx"}}{{template "header"}}
+<h3>Transaction <span class="mono">{{.ID.String}}</span></h3>
+<p>Hex: <span class="mono">{{.ID.StringHex}}</span></p>
+{{if .ID.IsBranchTransaction}}<p>Branch: {{branchLink .ID}}</p>{{end}}
+{{if .Found}}
+<p>Metadata: {{.Metadata.String}}</p>
+<h4>Inputs</h4>
+<table>
+<tr><th>#</th><th>Consumed output</th><th>Produced by</th></tr>
+{{range $i, $inp := .Inputs}}<tr><td>{{$i}}</td><td class="mono">{{outputLink $inp}}</td><td class="mono">{{txLink $inp.TransactionID}}</td></tr>{{end}}
+</table>
+<h4>Endorsements</h4>
+<table>
+{{range $i, $e := .Endorse}}<tr><td>{{$i}}</td><td class="mono">{{txLink $e}}</td></tr>{{end}}
+</table>
+<h4>Outputs</h4>
+<table>
+<tr><th>Output</th><th>Amount</th><th>Lock</th></tr>
+{{range .Outputs}}<tr><td class="mono">{{outputLink .ID}}</td><td>{{goth .Output.Amount}}</td><td class="mono">{{.Output.Lock}}</td></tr>{{end}}
+</table>
+<h4>Details</h4>
+<pre>{{.Lines}}</pre>
+{{else}}
+<p class="bad">transaction not found in the transaction store</p>
+{{end}}
+<h4>Inclusion into branches, slots {{.Inclusion.EarliestSlot}} - {{.Inclusion.LatestSlot}}</h4>
+<table>
+<tr><th>Branch</th><th>Coverage</th><th>Included</th></tr>
+{{range .Inclusion.Inclusion}}<tr><td class="mono">{{branchLink .BranchID}}</td><td>{{goth .RootRecord.LedgerCoverage}}</td>
+<td>{{if .Included}}<span class="good">yes</span>{{else}}no{{end}}</td></tr>{{end}}
+</table>
+{{template "footer"}}{{end}}
+
+{{define "output"}}{{template "header"}}
+<h3>Output <span class="mono">{{.ID.String}}</span></h3>
+<p>Hex: <span class="mono">{{.ID.StringHex}}</span></p>
+<p>Produced by: {{txLink .ID.TransactionID}}</p>
+<p>Status:
+{{if .Status.Unspent}}<span class="good">unspent in the heaviest state</span>
+{{else if .Status.ConsumedIn}}consumed in branch {{branchLink .Status.ConsumedIn}}
+{{if .Status.ConsumedBy}} by {{txLink .Status.ConsumedBy}}{{else}}, consuming transaction not found{{end}}
+{{else}}not found in the explored part of the heaviest branch chain{{end}}
+</p>
+{{if .Status.ProducedIn}}<p>First seen in branch: {{branchLink .Status.ProducedIn}}</p>{{end}}
+{{if .Found}}
+<p>Amount: {{goth .Amount}}</p>
+<p>Lock: <span class="mono">{{.Lock}}</span></p>
+<p>Accounts: {{range .Accounts}}<span class="mono">{{accountLink .}}</span> {{end}}</p>
+{{if .ChainID}}<p>Chain: {{chainLink .ChainID}}</p>{{end}}
+<h4>Constraints</h4>
+<table>
+<tr><th>#</th><th>Name</th><th>EasyFL source</th><th>Bytecode</th></tr>
+{{range .Constraints}}<tr><td>{{.Index}}</td><td>{{.Name}}</td><td class="mono">{{.Source}}</td><td class="mono">{{.Hex}}</td></tr>{{end}}
+</table>
+{{else}}
+<p class="bad">output not found</p>
+{{end}}
+{{template "footer"}}{{end}}
+
+{{define "account"}}{{template "header"}}
+<h3>Account <span class="mono">{{.Account.String}}</span></h3>
+<p>Heaviest state at branch {{branchLink .Branch}}</p>
+<p>Balance: {{goth .Total}} in {{len .Outputs}} outputs</p>
+<table>
+<tr><th>Output</th><th>Amount</th><th>Lock</th></tr>
+{{range .Outputs}}<tr><td class="mono">{{outputLink .ID}}</td><td>{{goth .Output.Amount}}</td><td class="mono">{{.Output.Lock}}</td></tr>{{end}}
+</table>
+{{template "footer"}}{{end}}
+
+{{define "chain"}}{{template "header"}}
+<h3>Chain <span class="mono">{{.ID.String}}</span></h3>
+<p>Heaviest state at branch {{branchLink .Branch}}</p>
+{{if .Found}}
+<p>Latest output: {{outputLink .Output.ID}}, amount: {{goth .Output.Output.Amount}}</p>
+<h4>History, latest first</h4>
+<table>
+<tr><th>Chain output</th><th>Amount</th></tr>
+{{range .History}}<tr><td class="mono">{{outputLink .ID}}</td><td>{{goth .Amount}}</td></tr>{{end}}
+</table>
+{{else}}
+<p class="bad">chain not found in the heaviest state</p>
+{{end}}
+{{template "footer"}}{{end}}
+
+{{define "branch"}}{{template "header"}}
+<h3>Branch <span class="mono">{{.Branch.TxID.String}}</span></h3>
+<p>Transaction: {{txLink .Branch.Stem.ID.TransactionID}}</p>
+<p>Root: <span class="mono">{{.Branch.Root}}</span></p>
+<p>Sequencer: {{chainLink .Branch.SequencerID}}, sequencer output: {{outputLink .Branch.SequencerOutput.ID}}</p>
+<p>Stem output: {{outputLink .Branch.Stem.ID}}</p>
+<p>Coverage: {{goth .Branch.LedgerCoverage}}, slot inflation: {{goth .Branch.SlotInflation}}, supply: {{goth .Branch.Supply}}</p>
+{{if .Predecessor}}<p>Predecessor branch: {{branchLink .Predecessor}}</p>{{end}}
+<h4>New transactions ({{len .Transactions}})</h4>
+<table>
+{{range .Transactions}}<tr><td class="mono">{{txLink .}}</td></tr>{{end}}
+</table>
+{{template "footer"}}{{end}}
+`
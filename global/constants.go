@@ -3,5 +3,6 @@ package global
 const (
 	MultiStateDBName     = "proximadb"
 	TxStoreDBName        = "proximadb.txstore"
+	IndexerDBName        = "proximadb.indexer"
 	ConfigKeyTxStoreType = "txstore.type"
 )
@@ -12,6 +12,7 @@ import (
 
 	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/clock"
 	"github.com/lunfardo314/proxima/util/lines"
 	"github.com/lunfardo314/proxima/util/set"
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,6 +39,7 @@ type Global struct {
 	txTraceMutex     sync.RWMutex
 	txTraceIDs       map[ledger.TransactionID]time.Time
 	logAttacherStats bool
+	clock            clock.Clock
 }
 
 const TraceTag = "global"
@@ -91,6 +93,11 @@ func NewDefault() *Global {
 	return _new(zapcore.DebugLevel, []string{"stderr"})
 }
 
+// NewDefaultWithLogLevel same as NewDefault, only with the specified logging level
+func NewDefaultWithLogLevel(logLevel zapcore.Level) *Global {
+	return _new(logLevel, []string{"stderr"})
+}
+
 func _new(logLevel zapcore.Level, outputs []string) *Global {
 	ctx, cancelFun := context.WithCancel(context.Background())
 	ret := &Global{
@@ -103,6 +110,7 @@ func _new(logLevel zapcore.Level, outputs []string) *Global {
 		logStopOnce:   &sync.Once{},
 		components:    set.New[string](),
 		txTraceIDs:    make(map[ledger.TransactionID]time.Time),
+		clock:         clock.Real(),
 	}
 	go ret.purgeLoop()
 
@@ -113,6 +121,16 @@ func (l *Global) MetricsRegistry() *prometheus.Registry {
 	return l.metrics
 }
 
+func (l *Global) Clock() clock.Clock {
+	return l.clock
+}
+
+// SetClock sets clock of the node. Must be called before starting work processes.
+// Ledger time is process-wide and its source is set separately with ledger.SetClock
+func (l *Global) SetClock(c clock.Clock) {
+	l.clock = c
+}
+
 func (l *Global) MarkWorkProcessStarted(name string) {
 	l.Tracef(TraceTag, "MarkWorkProcessStarted: %s", name)
 	l.mutex.Lock()
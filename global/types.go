@@ -6,6 +6,7 @@ import (
 
 	"github.com/lunfardo314/proxima/core/txmetadata"
 	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/util/clock"
 	"github.com/lunfardo314/unitrie/common"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -22,8 +23,10 @@ type (
 		GetIDsLockedInAccount(addr ledger.AccountID) ([]ledger.OutputID, error)
 		GetUTXOsLockedInAccount(accountID ledger.AccountID) ([]*ledger.OutputDataWithID, error)
 		GetUTXOForChainID(id *ledger.ChainID) (*ledger.OutputDataWithID, error)
+		GetUTXOsWithToken(tokenID *ledger.TokenID) ([]*ledger.OutputDataWithID, error)
 		Root() common.VCommitment
 		MustLedgerIdentityBytes() []byte // either state identity consistent or panic
+		GetLibraryUpgrades() ([]*ledger.LibraryUpgrade, error)
 	}
 
 	// IndexedStateReader state and indexer readers packing together
@@ -88,10 +91,16 @@ type (
 		MetricsRegistry() *prometheus.Registry
 	}
 
+	// Clock is the source of time for time-dependent work processes of the node
+	Clock interface {
+		Clock() clock.Clock
+	}
+
 	NodeGlobal interface {
 		Logging
 		TraceTx
 		StartStop
 		Metrics
+		Clock
 	}
 )
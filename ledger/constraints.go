@@ -43,6 +43,8 @@ type (
 	LockBalance struct {
 		Lock    Lock
 		Balance uint64
+		// if true, the output is the origin of a new chain
+		ChainOrigin bool
 	}
 )
 
@@ -71,7 +73,11 @@ func (lib *Library) runInlineTests() {
 }
 
 func NameByPrefix(prefix []byte) (string, bool) {
-	if ret, found := L().constraintByPrefix[string(prefix)]; found {
+	return L().NameByPrefix(prefix)
+}
+
+func (lib *Library) NameByPrefix(prefix []byte) (string, bool) {
+	if ret, found := lib.constraintByPrefix[string(prefix)]; found {
 		return ret.name, true
 	}
 	return "", false
@@ -130,17 +136,21 @@ var AllLockNames = []string{
 	ChainLockName,
 	StemLockName,
 	DeadlineLockName,
+	MultisigED25519Name,
+	HTLCLockName,
 }
 
 func LockFromBytes(data []byte) (Lock, error) {
-	prefix, err := L().ParseBytecodePrefix(data)
+	return L().LockFromBytes(data)
+}
+
+// LockFromBytes parses lock with the library. Locks of library upgrades are only known to the library with the upgrade
+func (lib *Library) LockFromBytes(data []byte) (Lock, error) {
+	prefix, err := lib.ParseBytecodePrefix(data)
 	if err != nil {
 		return nil, err
 	}
-	name, ok := NameByPrefix(prefix)
-	if !ok {
-		return nil, fmt.Errorf("unknown constraint with prefix '%s'", easyfl.Fmt(prefix))
-	}
+	name, _ := lib.NameByPrefix(prefix)
 	switch name {
 	case AddressED25519Name:
 		return AddressED25519FromBytes(data)
@@ -150,8 +160,13 @@ func LockFromBytes(data []byte) (Lock, error) {
 		return ChainLockFromBytes(data)
 	case StemLockName:
 		return StemLockFromBytes(data)
+	case MultisigED25519Name:
+		return MultisigED25519FromBytes(data)
+	case HTLCLockName:
+		return HTLCLockFromBytes(data)
 	}
-	return nil, fmt.Errorf("not a lock constraint '%s'", name)
+	// any other constraint at the lock index must be a lock defined by the library upgrade
+	return lib.scriptLockFromBytes(data)
 }
 
 func LockFromSource(src string) (Lock, error) {
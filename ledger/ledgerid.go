@@ -50,28 +50,31 @@ type (
 		TransactionPaceSequencer byte
 		// this limits number of sequencers in the network. Reasonable amount would be few hundreds of sequencers
 		MinimumAmountOnSequencer uint64
+		// LibraryHash hash of the ledger library data, committed into the state at genesis. All-0 means not set
+		LibraryHash [32]byte
 	}
 
-	// IdentityDataYAMLAble structure for canonical YAMLAble marshaling
+	// IdentityDataYAMLAble structure for canonical YAMLAble marshaling. Also used for JSON marshaling in the API
 	IdentityDataYAMLAble struct {
-		GenesisTimeUnix                   uint32 `yaml:"genesis_time_unix"`
-		InitialSupply                     uint64 `yaml:"initial_supply"`
-		GenesisControllerPublicKey        string `yaml:"genesis_controller_public_key"`
-		TimeTickDurationNanosec           int64  `yaml:"time_tick_duration_nanosec"`
-		MaxTimeTickValueInTimeSlot        uint8  `yaml:"max_time_tick_value_in_time_slot"`
-		BranchBonusBase                   uint64 `yaml:"branch_bonus_base"`
-		SlotsPerHalvingEpoch              uint32 `yaml:"slots_per_halving_epoch"`
-		VBCost                            uint64 `yaml:"vb_cost"`
-		TransactionPace                   byte   `yaml:"transaction_pace"`
-		TransactionPaceSequencer          byte   `yaml:"transaction_pace_sequencer"`
-		NumHalvingEpochs                  byte   `yaml:"num_halving_epochs"`
-		ChainInflationPerTickFractionBase uint64 `yaml:"chain_inflation_per_tick_fraction_base"`
-		ChainInflationOpportunitySlots    uint64 `yaml:"chain_inflation_opportunity_slots"`
-		MinimumAmountOnSequencer          uint64 `yaml:"minimum_amount_on_sequencer"`
-		Description                       string `yaml:"description"`
+		GenesisTimeUnix                   uint32 `yaml:"genesis_time_unix" json:"genesis_time_unix"`
+		InitialSupply                     uint64 `yaml:"initial_supply" json:"initial_supply"`
+		GenesisControllerPublicKey        string `yaml:"genesis_controller_public_key" json:"genesis_controller_public_key"`
+		TimeTickDurationNanosec           int64  `yaml:"time_tick_duration_nanosec" json:"time_tick_duration_nanosec"`
+		MaxTimeTickValueInTimeSlot        uint8  `yaml:"max_time_tick_value_in_time_slot" json:"max_time_tick_value_in_time_slot"`
+		BranchBonusBase                   uint64 `yaml:"branch_bonus_base" json:"branch_bonus_base"`
+		SlotsPerHalvingEpoch              uint32 `yaml:"slots_per_halving_epoch" json:"slots_per_halving_epoch"`
+		VBCost                            uint64 `yaml:"vb_cost" json:"vb_cost"`
+		TransactionPace                   byte   `yaml:"transaction_pace" json:"transaction_pace"`
+		TransactionPaceSequencer          byte   `yaml:"transaction_pace_sequencer" json:"transaction_pace_sequencer"`
+		NumHalvingEpochs                  byte   `yaml:"num_halving_epochs" json:"num_halving_epochs"`
+		ChainInflationPerTickFractionBase uint64 `yaml:"chain_inflation_per_tick_fraction_base" json:"chain_inflation_per_tick_fraction_base"`
+		ChainInflationOpportunitySlots    uint64 `yaml:"chain_inflation_opportunity_slots" json:"chain_inflation_opportunity_slots"`
+		MinimumAmountOnSequencer          uint64 `yaml:"minimum_amount_on_sequencer" json:"minimum_amount_on_sequencer"`
+		Description                       string `yaml:"description" json:"description"`
+		LibraryHash                       string `yaml:"library_hash,omitempty" json:"library_hash,omitempty"`
 		// non-persistent, for control
-		GenesisControllerAddress string `yaml:"genesis_controller_address"`
-		BootstrapChainID         string `yaml:"bootstrap_chain_id"`
+		GenesisControllerAddress string `yaml:"genesis_controller_address" json:"genesis_controller_address"`
+		BootstrapChainID         string `yaml:"bootstrap_chain_id" json:"bootstrap_chain_id"`
 	}
 )
 
@@ -99,7 +102,10 @@ func (id *IdentityData) Bytes() []byte {
 	_ = binary.Write(&buf, binary.BigEndian, id.MinimumAmountOnSequencer)
 	_ = binary.Write(&buf, binary.BigEndian, uint16(len(id.Description)))
 	buf.Write([]byte(id.Description))
-
+	// library hash is optional for backward compatibility
+	if id.LibraryHash != ([32]byte{}) {
+		buf.Write(id.LibraryHash[:])
+	}
 	return buf.Bytes()
 }
 
@@ -165,6 +171,11 @@ func MustLedgerIdentityDataFromBytes(data []byte) *IdentityData {
 	util.Assertf(n == int(size16), "wrong data size")
 	ret.Description = string(buf)
 
+	if rdr.Len() > 0 {
+		n, err = rdr.Read(ret.LibraryHash[:])
+		util.AssertNoError(err)
+		util.Assertf(n == len(ret.LibraryHash), "wrong data size")
+	}
 	util.Assertf(rdr.Len() == 0, "not all bytes has been read")
 	return ret
 }
@@ -223,7 +234,8 @@ func (id *IdentityData) Lines(prefix ...string) *lines.Lines {
 		Add("Description: '%s'", id.Description).
 		Add("Initial supply: %s", util.GoTh(id.InitialSupply)).
 		Add("Genesis controller address: %s", id.GenesisControlledAddress().String()).
-		Add("Origin chain ID: %s", originChainID.String())
+		Add("Origin chain ID: %s", originChainID.String()).
+		Add("Library hash: %s", id.LibraryHashString())
 }
 
 func (id *IdentityData) YAMLAble() *IdentityDataYAMLAble {
@@ -246,9 +258,18 @@ func (id *IdentityData) YAMLAble() *IdentityDataYAMLAble {
 		MinimumAmountOnSequencer:          id.MinimumAmountOnSequencer,
 		BootstrapChainID:                  chainID.StringHex(),
 		Description:                       id.Description,
+		LibraryHash:                       id.LibraryHashString(),
 	}
 }
 
+// LibraryHashString hex-encoded library hash or empty string if not set
+func (id *IdentityData) LibraryHashString() string {
+	if id.LibraryHash == ([32]byte{}) {
+		return ""
+	}
+	return hex.EncodeToString(id.LibraryHash[:])
+}
+
 func (id *IdentityData) TimeHorizonYears() int {
 	return math.MaxUint32 / int(id.SlotsPerHalvingEpoch)
 }
@@ -297,6 +318,7 @@ const stateIDFileComment = `# This is Proxima ledger identity file.
 # Public key of the controller identifies originator of the ledger for its lifetime.
 # 'genesis_controller_address' is computed from the public key of the controller
 # 'bootstrap_chain_id' is a constant, i.e. same for all ledgers
+# 'library_hash' is the hash of the ledger library. If omitted, it is set from the library of the node binary at genesis
 `
 
 func (id *IdentityDataYAMLAble) YAML() []byte {
@@ -332,6 +354,13 @@ func (id *IdentityDataYAMLAble) stateIdentityData() (*IdentityData, error) {
 	ret.NumHalvingEpochs = id.NumHalvingEpochs
 	ret.MinimumAmountOnSequencer = id.MinimumAmountOnSequencer
 	ret.Description = id.Description
+	if id.LibraryHash != "" {
+		h, err := hex.DecodeString(id.LibraryHash)
+		if err != nil || len(h) != len(ret.LibraryHash) {
+			return nil, fmt.Errorf("wrong library hash")
+		}
+		copy(ret.LibraryHash[:], h)
+	}
 
 	// control
 	if AddressED25519FromPublicKey(ret.GenesisControllerPublicKey).String() != id.GenesisControllerAddress {
@@ -0,0 +1,131 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+)
+
+// Execution cost of constraints. Each EasyFL call evaluated while validating the transaction is charged
+// with the cost of the function being called:
+//  - embedded functions, literals and parameters cost 1
+//  - extended function of the ledger library costs 1 + sum of the costs of the calls in its body.
+//    It is calculated statically when the function is added to the library
+//  - call of the function of the transaction-local library is charged with its static cost, see LocalLibraryCosts.
+//    Parsing of the local library is charged once per transaction, by the size of the library
+// The cost is deterministic: it depends only on the data of the transaction and the consumed outputs.
+// Total cost of all constraints of the transaction is limited by the execution budget, which
+// is proportional to the transaction size and the VBCost of the ledger
+
+const (
+	// ExecutionBudgetPerByte number of cost units per byte of the transaction with VBCost = 1
+	ExecutionBudgetPerByte = 100
+	// maxFunctionCost saturation limit of the static cost of the library function
+	maxFunctionCost = math.MaxInt32
+)
+
+var ErrExecutionBudgetExceeded = errors.New("execution budget exceeded")
+
+// ExecutionBudget returns maximum total execution cost of constraints of the transaction of the given size
+func (id *IdentityData) ExecutionBudget(txSize int) uint64 {
+	return uint64(txSize) * id.VBCost * ExecutionBudgetPerByte
+}
+
+// FunctionCost returns static cost of the call of the library function
+func (lib *Library) FunctionCost(sym string) int {
+	if c, found := lib.costs[sym]; found {
+		return c
+	}
+	return 1
+}
+
+// registerCost calculates and stores static cost of the extended function from its bytecode
+func (lib *Library) registerCost(sym string, bytecode []byte) error {
+	expr, err := lib.ExpressionFromBytecode(bytecode)
+	if err != nil {
+		return fmt.Errorf("cost of '%s': %w", sym, err)
+	}
+	lib.costs[sym] = min(1+lib.expressionCost(expr), maxFunctionCost)
+	return nil
+}
+
+func (lib *Library) expressionCost(expr *easyfl.Expression) int {
+	ret := lib.FunctionCost(expr.FunctionName)
+	for _, arg := range expr.Args {
+		ret = min(ret+lib.expressionCost(arg), maxFunctionCost)
+	}
+	return ret
+}
+
+// chargeCost wraps evaluation functions of the expression tree with charging of the execution cost to the data context
+func (lib *Library) chargeCost(expr *easyfl.Expression, ctx *DataContext) {
+	f := expr.EvalFunc
+	cost := uint64(lib.FunctionCost(expr.FunctionName))
+	expr.EvalFunc = func(par *easyfl.CallParams) []byte {
+		ctx.charge(cost)
+		return f(par)
+	}
+	for _, arg := range expr.Args {
+		lib.chargeCost(arg, ctx)
+	}
+}
+
+// chargeFromCallParams charges cost to the data context of the call. Does nothing if evaluation
+// is not in the context of the transaction, for example in inline tests of the library
+func chargeFromCallParams(par *easyfl.CallParams, cost uint64) {
+	if ctx := dataContextFromCallParams(par); ctx != nil {
+		ctx.charge(cost)
+	}
+}
+
+// dataContextFromCallParams returns data context of the call or nil if evaluation is not in the context of the transaction
+func dataContextFromCallParams(par *easyfl.CallParams) *DataContext {
+	var ret *DataContext
+	_ = util.CatchPanicOrError(func() error {
+		// DataContext panics when call has no global data
+		ret, _ = par.DataContext().(*DataContext)
+		return nil
+	})
+	return ret
+}
+
+// EvalFromBinaryWithCost evaluates constraint the same way as EvalFromBinary, only each call is charged
+// to the execution budget of the data context. Returns the cost of the evaluation
+func (lib *Library) EvalFromBinaryWithCost(glb easyfl.GlobalData, code []byte, args ...[]byte) ([]byte, uint64, error) {
+	var ret []byte
+	ctx, ok := glb.Data().(*DataContext)
+	if !ok || ctx == nil {
+		return nil, 0, fmt.Errorf("EvalFromBinaryWithCost: data context expected")
+	}
+	before := ctx.cost
+	err := util.CatchPanicOrError(func() error {
+		expr, err := lib.ExpressionFromBytecode(code)
+		if err != nil {
+			return err
+		}
+		lib.chargeCost(expr, ctx)
+		ret = easyfl.EvalExpression(glb, expr, args...)
+		return nil
+	})
+	return ret, ctx.cost - before, err
+}
+
+// SetExecutionBudget sets the limit of the total execution cost in the data context. 0 means no limit
+func (c *DataContext) SetExecutionBudget(budget uint64) {
+	c.budget = budget
+}
+
+// ExecutionCost total cost of evaluations in the data context so far
+func (c *DataContext) ExecutionCost() uint64 {
+	return c.cost
+}
+
+func (c *DataContext) charge(cost uint64) {
+	c.cost += cost
+	if c.budget > 0 && c.cost > c.budget {
+		panic(fmt.Errorf("%w: cost %d, budget %d", ErrExecutionBudgetExceeded, c.cost, c.budget))
+	}
+}
@@ -0,0 +1,390 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/lazybytes"
+	"github.com/lunfardo314/proxima/util/lines"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Ledger library data is the full EasyFL library of the ledger in the serializable form: the sequence
+// of all functions in the order they were added to the library, with function codes, sources and compiled bytecode.
+// It is committed into the ledger state at genesis and the hash of it is part of the ledger identity.
+// Functions embedded in Go are represented by names only, the node binary must provide them.
+// Identity constants are represented by names only too: their values are derived from the identity data itself,
+// so the library hash does not depend on the identity data
+
+type (
+	LibraryFunctionKind byte
+
+	LibraryFunction struct {
+		Kind      LibraryFunctionKind
+		Sym       string
+		NumParams int
+		FunCode   uint16
+		// source and bytecode only for extended functions
+		Source   string
+		Bytecode []byte
+	}
+
+	LibraryData struct {
+		// hash of the base EasyFL library
+		BaseHash  [32]byte
+		Functions []*LibraryFunction
+	}
+)
+
+const (
+	LibraryFunctionEmbeddedShort = LibraryFunctionKind(iota)
+	LibraryFunctionEmbeddedLong
+	LibraryFunctionIdentityConstant
+	LibraryFunctionExtended
+)
+
+const maxLibraryFunctions = easyfl.MaxGlobalFunCode + 1
+
+func (k LibraryFunctionKind) String() string {
+	switch k {
+	case LibraryFunctionEmbeddedShort:
+		return "embedded short"
+	case LibraryFunctionEmbeddedLong:
+		return "embedded long"
+	case LibraryFunctionIdentityConstant:
+		return "identity constant"
+	case LibraryFunctionExtended:
+		return "extended"
+	default:
+		return fmt.Sprintf("kind(%d)", k)
+	}
+}
+
+func newLibraryData() *LibraryData {
+	return &LibraryData{
+		BaseHash:  easyfl.NewBase().LibraryHash(),
+		Functions: make([]*LibraryFunction, 0),
+	}
+}
+
+func (f *LibraryFunction) Bytes() []byte {
+	var funCodeBin [2]byte
+	binary.BigEndian.PutUint16(funCodeBin[:], f.FunCode)
+	return lazybytes.MakeArrayFromDataReadOnly(
+		[]byte{byte(f.Kind)},
+		[]byte(f.Sym),
+		[]byte{byte(int8(f.NumParams))},
+		funCodeBin[:],
+		[]byte(f.Source),
+		f.Bytecode,
+	).Bytes()
+}
+
+func libraryFunctionFromBytes(data []byte) (*LibraryFunction, error) {
+	arr, err := lazybytes.ParseArrayFromBytesReadOnly(data, 6)
+	if err != nil {
+		return nil, err
+	}
+	if arr.NumElements() != 6 {
+		return nil, fmt.Errorf("wrong number of elements in the library function")
+	}
+	if len(arr.At(0)) != 1 || len(arr.At(1)) == 0 || len(arr.At(2)) != 1 || len(arr.At(3)) != 2 {
+		return nil, fmt.Errorf("wrong library function data")
+	}
+	ret := &LibraryFunction{
+		Kind:      LibraryFunctionKind(arr.At(0)[0]),
+		Sym:       string(arr.At(1)),
+		NumParams: int(int8(arr.At(2)[0])),
+		FunCode:   binary.BigEndian.Uint16(arr.At(3)),
+		Source:    string(arr.At(4)),
+		Bytecode:  bytes.Clone(arr.At(5)),
+	}
+	if ret.Kind > LibraryFunctionExtended {
+		return nil, fmt.Errorf("wrong kind of the library function '%s'", ret.Sym)
+	}
+	if ret.Kind == LibraryFunctionExtended && (len(ret.Source) == 0 || len(ret.Bytecode) == 0) {
+		return nil, fmt.Errorf("source and bytecode of the extended function '%s' must not be empty", ret.Sym)
+	}
+	return ret, nil
+}
+
+// Bytes serializes library data as lazy array of base hash and array of functions
+func (ld *LibraryData) Bytes() []byte {
+	funs := lazybytes.EmptyArray(maxLibraryFunctions)
+	for _, f := range ld.Functions {
+		funs.Push(f.Bytes())
+	}
+	return lazybytes.MakeArrayFromDataReadOnly(ld.BaseHash[:], funs.Bytes()).Bytes()
+}
+
+func (ld *LibraryData) Hash() [32]byte {
+	return blake2b.Sum256(ld.Bytes())
+}
+
+func LibraryDataFromBytes(data []byte) (*LibraryData, error) {
+	arr, err := lazybytes.ParseArrayFromBytesReadOnly(data, 2)
+	if err != nil {
+		return nil, fmt.Errorf("LibraryDataFromBytes: %w", err)
+	}
+	if arr.NumElements() != 2 || len(arr.At(0)) != 32 {
+		return nil, fmt.Errorf("LibraryDataFromBytes: wrong data")
+	}
+	funs, err := lazybytes.ParseArrayFromBytesReadOnly(arr.At(1), maxLibraryFunctions)
+	if err != nil {
+		return nil, fmt.Errorf("LibraryDataFromBytes: %w", err)
+	}
+	ret := &LibraryData{Functions: make([]*LibraryFunction, funs.NumElements())}
+	copy(ret.BaseHash[:], arr.At(0))
+	for i := range ret.Functions {
+		if ret.Functions[i], err = libraryFunctionFromBytes(funs.At(i)); err != nil {
+			return nil, fmt.Errorf("LibraryDataFromBytes: function #%d: %w", i, err)
+		}
+	}
+	return ret, nil
+}
+
+func (ld *LibraryData) push(f *LibraryFunction) {
+	ld.Functions = append(ld.Functions, f)
+}
+
+func (ld *LibraryData) Lines(prefix ...string) *lines.Lines {
+	ret := lines.New(prefix...)
+	for _, f := range ld.Functions {
+		if f.Kind == LibraryFunctionExtended {
+			ret.Add("%d: %s(%d) = %s", f.FunCode, f.Sym, f.NumParams, f.Source)
+		} else {
+			ret.Add("%d: %s(%d) -- %s", f.FunCode, f.Sym, f.NumParams, f.Kind)
+		}
+	}
+	return ret
+}
+
+func (ld *LibraryData) String() string {
+	h := ld.Hash()
+	return fmt.Sprintf("library %s: %d functions", hex.EncodeToString(h[:]), len(ld.Functions))
+}
+
+// LibraryData returns data of the library in the order of its construction
+func (lib *Library) LibraryData() *LibraryData {
+	return lib.data
+}
+
+// EmbedShort shadows easyfl.Library.EmbedShort to record the function in the library data
+func (lib *Library) EmbedShort(sym string, requiredNumPar int, evalFun easyfl.EvalFunction, contextDependent ...bool) byte {
+	lib.mustMatchStoredEmbedded(LibraryFunctionEmbeddedShort, sym, requiredNumPar)
+	ret := lib.Library.EmbedShort(sym, requiredNumPar, evalFun, contextDependent...)
+	lib.data.push(&LibraryFunction{
+		Kind:      LibraryFunctionEmbeddedShort,
+		Sym:       sym,
+		NumParams: requiredNumPar,
+		FunCode:   uint16(ret),
+	})
+	return ret
+}
+
+// EmbedLong shadows easyfl.Library.EmbedLong to record the function in the library data
+func (lib *Library) EmbedLong(sym string, requiredNumPar int, evalFun easyfl.EvalFunction) uint16 {
+	lib.mustMatchStoredEmbedded(LibraryFunctionEmbeddedLong, sym, requiredNumPar)
+	ret := lib.Library.EmbedLong(sym, requiredNumPar, evalFun)
+	lib.data.push(&LibraryFunction{
+		Kind:      LibraryFunctionEmbeddedLong,
+		Sym:       sym,
+		NumParams: requiredNumPar,
+		FunCode:   ret,
+	})
+	return ret
+}
+
+// Extend shadows easyfl.Library.Extend to record the function in the library data.
+// When library is loaded from the stored data, the stored source of the function is used instead of the provided one
+func (lib *Library) Extend(sym string, source string) uint16 {
+	return lib.extend(LibraryFunctionExtended, sym, source)
+}
+
+func (lib *Library) Extendf(sym string, template string, args ...any) uint16 {
+	return lib.Extend(sym, fmt.Sprintf(template, args...))
+}
+
+// MustExtendMany shadows easyfl.Library.MustExtendMany. Function definitions are parsed the same way
+func (lib *Library) MustExtendMany(source string) {
+	for _, f := range parseFunctionSources(source) {
+		lib.Extend(f.Sym, f.Source)
+	}
+}
+
+// extendWithIdentityConstant identity constants are not part of the library data, only their names
+func (lib *Library) extendWithIdentityConstant(sym string, template string, args ...any) uint16 {
+	return lib.extend(LibraryFunctionIdentityConstant, sym, fmt.Sprintf(template, args...))
+}
+
+func (lib *Library) extend(kind LibraryFunctionKind, sym, source string) uint16 {
+	if stored := lib.mustMatchStored(kind, sym); stored != nil && kind == LibraryFunctionExtended {
+		source = stored.Source
+	}
+	funCode, err := lib.ExtendErr(sym, source)
+	util.AssertNoError(err)
+	lib.lastFunCode = funCode
+	_, numParams, bytecode, err := lib.CompileExpression(source)
+	util.AssertNoError(err)
+	util.AssertNoError(lib.registerCost(sym, bytecode))
+
+	f := &LibraryFunction{
+		Kind:      kind,
+		Sym:       sym,
+		NumParams: numParams,
+		FunCode:   funCode,
+	}
+	if kind == LibraryFunctionExtended {
+		f.Source = source
+		f.Bytecode = bytecode
+	}
+	lib.data.push(f)
+	return funCode
+}
+
+// mustMatchStored when library is being loaded from the stored data, returns the stored function at the current position.
+// Panics if the library being constructed by the node binary is inconsistent with the stored one
+func (lib *Library) mustMatchStored(kind LibraryFunctionKind, sym string) *LibraryFunction {
+	if lib.stored == nil {
+		return nil
+	}
+	idx := len(lib.data.Functions)
+	util.Assertf(idx < len(lib.stored.Functions), "%s function '%s' of the node binary is not in the ledger library", kind, sym)
+	ret := lib.stored.Functions[idx]
+	util.Assertf(ret.Kind == kind && ret.Sym == sym,
+		"ledger library mismatch at #%d: %s function '%s' in the ledger library, %s function '%s' in the node binary",
+		idx, ret.Kind, ret.Sym, kind, sym)
+	return ret
+}
+
+func (lib *Library) mustMatchStoredEmbedded(kind LibraryFunctionKind, sym string, numParams int) {
+	if stored := lib.mustMatchStored(kind, sym); stored != nil {
+		util.Assertf(stored.NumParams == numParams, "ledger library mismatch: embedded function '%s' has %d parameters in the ledger library and %d in the node binary",
+			sym, stored.NumParams, numParams)
+	}
+}
+
+// finishLoadingFromStored extends library with stored functions beyond those known to the node binary
+// and checks if library compiled by the node is exactly the stored one
+func (lib *Library) finishLoadingFromStored() {
+	util.Assertf(lib.data.BaseHash == lib.stored.BaseHash, "base EasyFL library of the node binary is not the one of the ledger library")
+	for _, f := range lib.stored.Functions[len(lib.data.Functions):] {
+		util.Assertf(f.Kind == LibraryFunctionExtended, "%s function '%s' of the ledger library is not provided by the node binary", f.Kind, f.Sym)
+		lib.Extend(f.Sym, f.Source)
+	}
+	for i, f := range lib.data.Functions {
+		stored := lib.stored.Functions[i]
+		util.Assertf(f.FunCode == stored.FunCode && f.NumParams == stored.NumParams && bytes.Equal(f.Bytecode, stored.Bytecode),
+			"ledger library mismatch: function '%s' compiles to different code", f.Sym)
+	}
+	util.Assertf(bytes.Equal(lib.data.Bytes(), lib.stored.Bytes()), "ledger library mismatch")
+	lib.stored = nil
+}
+
+type functionSource struct {
+	Sym    string
+	Source string
+}
+
+// parseFunctionSources parses function definitions 'func <sym> : <body>' exactly as EasyFL does:
+// comments and whitespaces are stripped from the source
+func parseFunctionSources(source string) []functionSource {
+	stripSpaces := func(str string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, str)
+	}
+	ret := make([]functionSource, 0)
+	var current *functionSource
+	sc := bufio.NewScanner(strings.NewReader(source))
+	for lineno := 0; sc.Scan(); lineno++ {
+		line, _, _ := strings.Cut(sc.Text(), "//")
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "func ") {
+			if current != nil {
+				current.Source = stripSpaces(current.Source)
+				ret = append(ret, *current)
+			}
+			sym, body, found := strings.Cut(strings.TrimPrefix(line, "func "), ":")
+			util.Assertf(found, "':' expected @ line %d", lineno)
+			current = &functionSource{
+				Sym:    strings.TrimSpace(sym),
+				Source: body,
+			}
+			continue
+		}
+		if len(stripSpaces(line)) == 0 {
+			continue
+		}
+		util.Assertf(current != nil, "unexpected symbols @ line %d", lineno)
+		current.Source += line
+	}
+	if current != nil {
+		current.Source = stripSpaces(current.Source)
+		ret = append(ret, *current)
+	}
+	return ret
+}
+
+// LibraryFromData creates library from the stored data and identity without the node binary's constraint definitions.
+// Functions embedded in Go are replaced with stubs, so the library can be used to compile and decompile the code
+// but can't evaluate embedded functions. It is not the global ledger library
+func LibraryFromData(id *IdentityData, data []byte) (*Library, error) {
+	ld, err := LibraryDataFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if id.LibraryHash != ([32]byte{}) && id.LibraryHash != ld.Hash() {
+		return nil, fmt.Errorf("LibraryFromData: library hash does not match the ledger identity")
+	}
+	identityConstants := make(map[string]string)
+	for _, c := range identityConstantSources(id) {
+		identityConstants[c.Sym] = c.Source
+	}
+	ret := newLibrary()
+	ret.ID = id
+	err = util.CatchPanicOrError(func() error {
+		for _, f := range ld.Functions {
+			switch f.Kind {
+			case LibraryFunctionEmbeddedShort:
+				ret.EmbedShort(f.Sym, f.NumParams, embeddedStub(f.Sym))
+			case LibraryFunctionEmbeddedLong:
+				ret.EmbedLong(f.Sym, f.NumParams, embeddedStub(f.Sym))
+			case LibraryFunctionIdentityConstant:
+				src, found := identityConstants[f.Sym]
+				if !found {
+					return fmt.Errorf("unknown identity constant '%s'", f.Sym)
+				}
+				ret.extendWithIdentityConstant(f.Sym, "%s", src)
+			case LibraryFunctionExtended:
+				ret.Extend(f.Sym, f.Source)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LibraryFromData: %w", err)
+	}
+	ret.data.BaseHash = ld.BaseHash
+	if !bytes.Equal(ret.data.Bytes(), data) {
+		return nil, fmt.Errorf("LibraryFromData: inconsistent library data")
+	}
+	return ret, nil
+}
+
+func embeddedStub(sym string) easyfl.EvalFunction {
+	return func(par *easyfl.CallParams) []byte {
+		par.TracePanic("embedded function '%s' is not available", sym)
+		return nil
+	}
+}
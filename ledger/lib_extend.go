@@ -180,13 +180,18 @@ func (lib *Library) extendWithMainFunctions() {
 	lib.Extend("txTimeTick", "timeTickFromTimestamp(txTimestampBytes)")
 	lib.Extend("txSequencerOutputIndex", "byte(@Path(pathToSeqAndStemOutputIndices), 0)")
 	lib.Extend("txStemOutputIndex", "byte(@Path(pathToSeqAndStemOutputIndices), 1)")
+	// local libraries are signed only if the transaction has them. Empty list of local libraries is not part of the essence
+	lib.Extend("txLocalLibrariesEssence", "if(equal(@Path(pathToLocalLibrary), 0x0000), 0x, @Path(pathToLocalLibrary))")
 	lib.Extend("txEssenceBytes", "concat("+
 		"@Path(pathToInputIDs), "+
 		"@Path(pathToProducedOutputs), "+
 		"@Path(pathToTimestamp), "+
 		"@Path(pathToSeqAndStemOutputIndices), "+
 		"@Path(pathToInputCommitment), "+
-		"@Path(pathToEndorsements))")
+		"@Path(pathToEndorsements), "+
+		"txLocalLibrariesEssence)")
+	// $0 - 1-byte index of the local library of the transaction. Returns library binary for callLocalLibrary
+	lib.Extend("txLocalLibrary", "@Array8(@Path(pathToLocalLibrary), $0)")
 	lib.Extend("isSequencerTransaction", "not(equal(txSequencerOutputIndex, 0xff))")
 	lib.Extend("isBranchTransaction", "and(isSequencerTransaction, not(equal(txStemOutputIndex, 0xff)))")
 
@@ -256,6 +261,11 @@ func (lib *Library) extendWithConstraints() {
 	addCommitToSiblingConstraint(lib)
 	addStateIndexConstraint(lib)
 	addTotalAmountConstraint(lib)
+	addMultisigED25519Constraint(lib)
+	addHTLCLockConstraint(lib)
+	addTokenConstraint(lib)
+	addLibraryUpgradeConstraint(lib)
+	addLockConventions(lib)
 }
 
 func runInitTests() {
@@ -268,10 +278,30 @@ func runInitTests() {
 type DataContext struct {
 	tree *lazybytes.Tree
 	path lazybytes.TreePath
+	// execution budget and total cost charged so far
+	budget uint64
+	cost   uint64
+	// local libraries parsed in the context, by library binary
+	localLibraries map[string]*LocalLibrary
+	// library the context is evaluated with
+	lib *Library
 }
 
-func NewDataContext(tree *lazybytes.Tree) *DataContext {
-	return &DataContext{tree: tree}
+// NewDataContext creates data context for the evaluation with the library. Global library is used if not provided
+func NewDataContext(tree *lazybytes.Tree, lib ...*Library) *DataContext {
+	ret := &DataContext{tree: tree}
+	if len(lib) > 0 {
+		ret.lib = lib[0]
+	}
+	return ret
+}
+
+// Library returns library the data context is evaluated with
+func (c *DataContext) Library() *Library {
+	if c.lib == nil {
+		return L()
+	}
+	return c.lib
 }
 
 func (c *DataContext) DataTree() *lazybytes.Tree {
@@ -339,13 +369,16 @@ func (lib *Library) CompileLocalLibrary(source string) ([]byte, error) {
 // arg 1 - 1-byte index of then function in the library
 // arg 2 ... arg 15 optional arguments
 func (lib *Library) evalCallLocalLibrary(ctx *easyfl.CallParams) []byte {
-	arr := lazybytes.ArrayFromBytesReadOnly(ctx.Arg(0))
-	libData := arr.Parsed()
+	libLoc, err := lib.localLibrary(dataContextFromCallParams(ctx), ctx.Arg(0))
+	if err != nil {
+		ctx.TracePanic("evalCallLocalLibrary: %v", err)
+	}
 	idx := ctx.Arg(1)
-	if len(idx) != 1 || int(idx[0]) >= len(libData) {
+	if len(idx) != 1 || int(idx[0]) >= libLoc.NumFunctions() {
 		ctx.TracePanic("evalCallLocalLibrary: wrong function index")
 	}
-	ret := lib.CallLocalLibrary(ctx.Slice(2, ctx.Arity()), libData, int(idx[0]))
+	chargeFromCallParams(ctx, uint64(libLoc.costs[idx[0]]))
+	ret := libLoc.funs[idx[0]](ctx.Slice(2, ctx.Arity()))
 	ctx.Trace("evalCallLocalLibrary: lib#%d -> %s", idx[0], easyfl.Fmt(ret))
 	return ret
 }
@@ -20,6 +20,20 @@ type (
 		constraintByPrefix map[string]*constraintRecord
 		constraintNames    map[string]struct{}
 		inlineTests        []func()
+		// data records functions of the library in the order of construction
+		data *LibraryData
+		// stored is not nil while library is being loaded from the ledger library data
+		stored *LibraryData
+		// upgrades applied to the library after genesis, in the order of function codes
+		upgrades []*LibraryUpgrade
+		// activation slots of functions added by upgrades
+		activation map[string]Slot
+		// names of locks added by upgrades
+		upgradeLocks map[string]struct{}
+		// lastFunCode function code of the last extended function
+		lastFunCode uint16
+		// static execution costs of extended functions
+		costs map[string]int
 	}
 
 	LibraryConst struct {
@@ -57,6 +71,11 @@ func newLibrary() *Library {
 		constraintByPrefix: make(map[string]*constraintRecord),
 		constraintNames:    make(map[string]struct{}),
 		inlineTests:        make([]func(), 0),
+		data:               newLibraryData(),
+		upgrades:           make([]*LibraryUpgrade, 0),
+		activation:         make(map[string]Slot),
+		upgradeLocks:       make(map[string]struct{}),
+		costs:              make(map[string]int),
 	}
 	return ret
 }
@@ -72,23 +91,9 @@ func (lib *Library) TimeFromRealTime(t time.Time) Time {
 func (lib *Library) extendWithBaseConstants(id *IdentityData) {
 	lib.ID = id
 	// constants
-	lib.Extendf("constInitialSupply", "u64/%d", id.InitialSupply)
-	lib.Extendf("constGenesisControllerPublicKey", "0x%s", hex.EncodeToString(id.GenesisControllerPublicKey))
-	lib.Extendf("constGenesisTimeUnix", "u64/%d", id.GenesisTimeUnix)
-	lib.Extendf("constTickDuration", "u64/%d", int64(id.TickDuration))
-	lib.Extendf("constMaxTickValuePerSlot", "u64/%d", id.MaxTickValueInSlot)
-	lib.Extendf("constBranchBonusBase", "u64/%d", id.BranchBonusBase)
-	lib.Extendf("constHalvingEpochs", "u64/%d", id.NumHalvingEpochs)
-	lib.Extendf("constChainInflationFractionBase", "u64/%d", id.ChainInflationPerTickFractionBase)
-	lib.Extendf("constChainInflationOpportunitySlots", "u64/%d", id.ChainInflationOpportunitySlots)
-	lib.Extendf("constMinimumAmountOnSequencer", "u64/%d", id.MinimumAmountOnSequencer)
-
-	lib.Extendf("constSlotsPerLedgerEpoch", "u64/%d", id.SlotsPerHalvingEpoch)
-	lib.Extendf("constTransactionPace", "u64/%d", id.TransactionPace)
-	lib.Extendf("constTransactionPaceSequencer", "u64/%d", id.TransactionPaceSequencer)
-	lib.Extendf("constVBCost16", "u16/%d", id.VBCost) // change to 64
-	lib.Extendf("ticksPerSlot", "%d", id.TicksPerSlot())
-	lib.Extendf("ticksPerSlot64", "u64/%d", id.TicksPerSlot())
+	for _, c := range identityConstantSources(id) {
+		lib.extendWithIdentityConstant(c.Sym, "%s", c.Source)
+	}
 	lib.Extendf("timeSlotSizeBytes", "%d", SlotByteLength)
 	lib.Extendf("timestampByteSize", "%d", TimeByteLength)
 
@@ -108,6 +113,28 @@ func (lib *Library) extendWithBaseConstants(id *IdentityData) {
 	lib.Extend("timestampPrefix", "bitwiseAND(slice($0, 0, 4), 0x3fffffffff)")
 }
 
+// identityConstantSources constants of the library which are derived from the identity data
+func identityConstantSources(id *IdentityData) []functionSource {
+	return []functionSource{
+		{"constInitialSupply", fmt.Sprintf("u64/%d", id.InitialSupply)},
+		{"constGenesisControllerPublicKey", fmt.Sprintf("0x%s", hex.EncodeToString(id.GenesisControllerPublicKey))},
+		{"constGenesisTimeUnix", fmt.Sprintf("u64/%d", id.GenesisTimeUnix)},
+		{"constTickDuration", fmt.Sprintf("u64/%d", int64(id.TickDuration))},
+		{"constMaxTickValuePerSlot", fmt.Sprintf("u64/%d", id.MaxTickValueInSlot)},
+		{"constBranchBonusBase", fmt.Sprintf("u64/%d", id.BranchBonusBase)},
+		{"constHalvingEpochs", fmt.Sprintf("u64/%d", id.NumHalvingEpochs)},
+		{"constChainInflationFractionBase", fmt.Sprintf("u64/%d", id.ChainInflationPerTickFractionBase)},
+		{"constChainInflationOpportunitySlots", fmt.Sprintf("u64/%d", id.ChainInflationOpportunitySlots)},
+		{"constMinimumAmountOnSequencer", fmt.Sprintf("u64/%d", id.MinimumAmountOnSequencer)},
+		{"constSlotsPerLedgerEpoch", fmt.Sprintf("u64/%d", id.SlotsPerHalvingEpoch)},
+		{"constTransactionPace", fmt.Sprintf("u64/%d", id.TransactionPace)},
+		{"constTransactionPaceSequencer", fmt.Sprintf("u64/%d", id.TransactionPaceSequencer)},
+		{"constVBCost16", fmt.Sprintf("u16/%d", id.VBCost)}, // change to 64
+		{"ticksPerSlot", fmt.Sprintf("%d", id.TicksPerSlot())},
+		{"ticksPerSlot64", fmt.Sprintf("u64/%d", id.TicksPerSlot())},
+	}
+}
+
 func (lib *Library) initGeneralFunctions(id *IdentityData) *Library {
 	lib.extendWithBaseConstants(id)
 	lib.extendWithMainFunctions()
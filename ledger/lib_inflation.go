@@ -26,8 +26,13 @@ func (id *IdentityData) _halvingEpoch(epochFromGenesis uint64) uint64 {
 	return uint64(id.NumHalvingEpochs)
 }
 
+// HalvingEpoch returns halving epoch of the slot, from 0 to NumHalvingEpochs
+func (id *IdentityData) HalvingEpoch(slot Slot) uint64 {
+	return id._halvingEpoch(id._epochFromGenesis(slot))
+}
+
 func (id *IdentityData) InflationFractionBySlot(slotIn Slot) uint64 {
-	return id.ChainInflationPerTickFractionBase * (1 << id._halvingEpoch(id._epochFromGenesis(slotIn)))
+	return id.ChainInflationPerTickFractionBase * (1 << id.HalvingEpoch(slotIn))
 }
 
 // ChainInflationAmount mocks inflation amount formula from the constraint library
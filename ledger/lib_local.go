@@ -0,0 +1,225 @@
+package ledger
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/lazybytes"
+)
+
+// Local libraries of the transaction. The transaction carries the list of compiled local EasyFL libraries
+// under the path TxLocalLibraries. Constraints of the transaction can call functions of them with
+//   callLocalLibrary(txLocalLibrary(<library index>), <function index>, <args>...)
+// This way complex one-off scripts do not bloat outputs. Local libraries are part of the transaction essence,
+// so they are signed together with the rest of the transaction. Essence of the transaction without local libraries
+// does not contain them at all, so it is the same as before local libraries were signed. Transactions with
+// local libraries are not valid for nodes which do not sign them, i.e. it is a hard fork for such transactions.
+// Size of local libraries is limited. Each function of the local library has static evaluation cost:
+// the upper bound of the number of EasyFL calls while evaluating it. The cost is limited too
+
+const (
+	// MaxNumberOfLocalLibraries maximum number of local libraries in the transaction
+	MaxNumberOfLocalLibraries = 4
+	// MaxLocalLibrariesSize maximum total size of local libraries in the transaction
+	MaxLocalLibrariesSize = 4096
+	// MaxLocalFunctionCost maximum static evaluation cost of any function of the local library
+	MaxLocalFunctionCost = 10_000
+	// LocalLibraryParseCostPerByte execution cost of parsing the local library, per byte of its binary
+	LocalLibraryParseCostPerByte = 1
+)
+
+// LocalLibrary is the local library parsed for evaluation, with static costs of its functions.
+// It is parsed once per transaction, see DataContext.ParseLocalLibraries
+type LocalLibrary struct {
+	funs  []easyfl.EvalFunction
+	costs []int
+}
+
+// LocalLibraryCosts parses local library and returns static evaluation cost of each function.
+// Cost of the function is the sum of costs of the calls in its expression, where a call of the library function
+// costs as the function of the library and a call of other local function costs as that function. Returns error if cost of any function exceeds MaxLocalFunctionCost
+func (lib *Library) LocalLibraryCosts(libBin []byte) ([]int, error) {
+	libLoc, err := lib.ParseLocalLibrary(libBin)
+	if err != nil {
+		return nil, err
+	}
+	return libLoc.costs, nil
+}
+
+// ParseLocalLibrary parses local library, calculates costs of its functions and prepares them for calls
+func (lib *Library) ParseLocalLibrary(libBin []byte) (*LocalLibrary, error) {
+	var funs [][]byte
+	err := util.CatchPanicOrError(func() error {
+		funs = lazybytes.ArrayFromBytesReadOnly(libBin).Parsed()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("local library: %w", err)
+	}
+	if len(funs) == 0 {
+		return nil, fmt.Errorf("local library: empty library")
+	}
+	// local library is parsed in the order of functions: each function can only call preceding ones
+	libLoc, err := lib.LocalLibraryFromBytes(funs)
+	if err != nil {
+		return nil, fmt.Errorf("local library: %w", err)
+	}
+	ret := &LocalLibrary{
+		funs:  make([]easyfl.EvalFunction, len(funs)),
+		costs: make([]int, len(funs)),
+	}
+	for i, code := range funs {
+		expr, err := lib.ExpressionFromBytecode(code, libLoc)
+		if err != nil {
+			return nil, fmt.Errorf("local library: function #%d: %w", i, err)
+		}
+		if ret.costs[i], err = lib.localExpressionCost(expr, ret.costs[:i]); err != nil {
+			return nil, fmt.Errorf("local library: function #%d: %w", i, err)
+		}
+		// evaluation function of the local function is taken from the parsed call of it
+		call, err := lib.ExpressionFromBytecode(localCallBytecode(i, maxParamIndex(expr)+1), libLoc)
+		if err != nil {
+			return nil, fmt.Errorf("local library: function #%d: %w", i, err)
+		}
+		ret.funs[i] = call.EvalFunc
+	}
+	return ret, nil
+}
+
+// ParseLocalLibraries parses local libraries of the transaction once per data context and charges
+// the parse cost to the execution budget. Calls of the local libraries use the parsed libraries
+func (c *DataContext) ParseLocalLibraries(libs [][]byte) error {
+	for i, libBin := range libs {
+		if _, err := c.Library().localLibrary(c, libBin); err != nil {
+			return fmt.Errorf("local library #%d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// localLibrary returns local library parsed in the data context. The library is parsed and the parse cost
+// is charged only when it is used for the first time in the data context. Without data context, the library
+// is parsed each time and nothing is charged
+func (lib *Library) localLibrary(c *DataContext, libBin []byte) (ret *LocalLibrary, err error) {
+	if c == nil {
+		return lib.ParseLocalLibrary(libBin)
+	}
+	if ret = c.localLibraries[string(libBin)]; ret != nil {
+		return ret, nil
+	}
+	err = util.CatchPanicOrError(func() error {
+		c.charge(uint64(len(libBin)) * LocalLibraryParseCostPerByte)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ret, err = lib.ParseLocalLibrary(libBin); err != nil {
+		return nil, err
+	}
+	if c.localLibraries == nil {
+		c.localLibraries = make(map[string]*LocalLibrary)
+	}
+	c.localLibraries[string(libBin)] = ret
+	return ret, nil
+}
+
+// NumFunctions number of functions in the local library
+func (l *LocalLibrary) NumFunctions() int {
+	return len(l.funs)
+}
+
+// localCallBytecode is the bytecode of the call of the local function with parameters $0..$n-1
+func localCallBytecode(idx, numParams int) []byte {
+	firstByte := easyfl.FirstByteLongCallMask | (byte(numParams) << 2)
+	ret := binary.BigEndian.AppendUint16(nil, (uint16(firstByte)<<8)|easyfl.FirstLocalFunCode)
+	ret = append(ret, byte(idx))
+	for i := 0; i < numParams; i++ {
+		ret = append(ret, byte(i))
+	}
+	return ret
+}
+
+// maxParamIndex returns the maximum index of the parameter referenced in the expression or -1 if none
+func maxParamIndex(expr *easyfl.Expression) int {
+	ret := -1
+	if len(expr.CallPrefix) == 1 && expr.CallPrefix[0] < easyfl.EmbeddedReservedUntil {
+		ret = int(expr.CallPrefix[0])
+	}
+	for _, arg := range expr.Args {
+		ret = max(ret, maxParamIndex(arg))
+	}
+	return ret
+}
+
+// localExpressionCost static cost of the expression of the local function. Call of the library function costs
+// the same as in the constraint (see FunctionCost), call of the preceding local function costs 1 + its cost
+func (lib *Library) localExpressionCost(expr *easyfl.Expression, funCosts []int) (int, error) {
+	var ret int
+	if idx, isLocal := localCallIndex(expr); isLocal {
+		if idx >= len(funCosts) {
+			return 0, fmt.Errorf("wrong call of local function #%d", idx)
+		}
+		ret = 1 + funCosts[idx]
+	} else {
+		ret = lib.FunctionCost(expr.FunctionName)
+	}
+	for _, arg := range expr.Args {
+		c, err := lib.localExpressionCost(arg, funCosts)
+		if err != nil {
+			return 0, err
+		}
+		ret += c
+		if ret > MaxLocalFunctionCost {
+			break
+		}
+	}
+	if ret > MaxLocalFunctionCost {
+		return 0, fmt.Errorf("evaluation cost exceeds limit %d", MaxLocalFunctionCost)
+	}
+	return ret, nil
+}
+
+// localCallIndex returns index of the local function if the expression is a call of it.
+// Call of the local function is a long call with the local function code, followed by the index byte
+func localCallIndex(expr *easyfl.Expression) (int, bool) {
+	prefix := expr.CallPrefix
+	if len(prefix) != 3 || easyfl.IsDataPrefix(prefix) || prefix[0]&easyfl.FirstByteLongCallMask == 0 {
+		return 0, false
+	}
+	if binary.BigEndian.Uint16(prefix[:2])&easyfl.Uint16LongCallCodeMask != easyfl.FirstLocalFunCode {
+		return 0, false
+	}
+	return int(prefix[2]), true
+}
+
+// ValidateLocalLibrariesLimits checks number and total size of local libraries of the transaction.
+// It does not depend on the library, so it can be checked before the transaction is validated in the context
+func ValidateLocalLibrariesLimits(libs [][]byte) error {
+	if len(libs) > MaxNumberOfLocalLibraries {
+		return fmt.Errorf("number of local libraries %d exceeds limit %d", len(libs), MaxNumberOfLocalLibraries)
+	}
+	size := 0
+	for _, libBin := range libs {
+		size += len(libBin)
+	}
+	if size > MaxLocalLibrariesSize {
+		return fmt.Errorf("size of local libraries %d exceeds limit %d", size, MaxLocalLibrariesSize)
+	}
+	return nil
+}
+
+// ValidateLocalLibraries checks local libraries of the transaction against limits and parses them with the library
+func (lib *Library) ValidateLocalLibraries(libs [][]byte) error {
+	if err := ValidateLocalLibrariesLimits(libs); err != nil {
+		return err
+	}
+	for i, libBin := range libs {
+		if _, err := lib.LocalLibraryCosts(libBin); err != nil {
+			return fmt.Errorf("local library #%d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -23,34 +23,75 @@ func L() *Library {
 	return libraryGlobal
 }
 
+// Init initializes global ledger library from the sources of the node binary.
+// If library hash in the identity data is set, it must be equal to the hash of the library data.
+// Identity data is not modified: identities created before the library was committed into the state
+// have no library hash and must keep the ledger ID hash they were created with
 func Init(id *IdentityData, verbose ...bool) {
+	util.AssertNoError(initLibrary(id, nil, verbose...))
+}
+
+// InitFromLibraryData initializes global ledger library from the library data stored in the ledger state.
+// Library data must match the library hash in the identity data.
+// Sources of the extended functions are taken from the library data, embedded functions are provided by the node binary
+func InitFromLibraryData(id *IdentityData, data []byte, verbose ...bool) error {
+	stored, err := LibraryDataFromBytes(data)
+	if err != nil {
+		return err
+	}
+	if stored.Hash() != id.LibraryHash {
+		return fmt.Errorf("InitFromLibraryData: hash of the library data does not match library hash in the ledger identity")
+	}
+	return initLibrary(id, stored, verbose...)
+}
+
+func initLibrary(id *IdentityData, stored *LibraryData, verbose ...bool) error {
 	printStats := false
 	if len(verbose) > 0 && verbose[0] {
 		printStats = true
 	}
-	func() {
+	err := func() error {
 		libraryGlobalMutex.Lock()
 		defer libraryGlobalMutex.Unlock()
 
 		util.Assertf(libraryGlobal == nil, "ledger is already initialized")
 
-		libraryGlobal = newLibrary()
+		lib := newLibrary()
+		lib.stored = stored
 
 		if printStats {
 			fmt.Printf("------ Base EasyFL library:\n")
-			libraryGlobal.PrintLibraryStats()
+			lib.PrintLibraryStats()
 		}
 
-		libraryGlobal.initGeneralFunctions(id)
-		libraryGlobal.extendWithConstraints()
+		err := util.CatchPanicOrError(func() error {
+			lib.initGeneralFunctions(id)
+			lib.extendWithConstraints()
+			if stored != nil {
+				lib.finishLoadingFromStored()
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize ledger library: %w", err)
+		}
+		if id.LibraryHash != ([32]byte{}) && id.LibraryHash != lib.data.Hash() {
+			return fmt.Errorf("library of the node binary does not match library hash %s in the ledger identity. Ledger library must be loaded from the state",
+				id.LibraryHashString())
+		}
 
 		if printStats {
 			fmt.Printf("------ Extended EasyFL library:\n")
-			libraryGlobal.PrintLibraryStats()
+			lib.PrintLibraryStats()
 		}
+		libraryGlobal = lib
+		return nil
 	}()
-
+	if err != nil {
+		return err
+	}
 	libraryGlobal.runInlineTests()
+	return nil
 }
 
 // InitWithTestingLedgerIDData for testing
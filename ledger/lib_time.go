@@ -4,9 +4,11 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/clock"
 )
 
 const (
@@ -95,8 +97,28 @@ func TimeFromRealTime(nowis time.Time) Time {
 	return L().ID.TimeFromRealTime(nowis)
 }
 
+// clockHolder wraps the interface for atomic.Pointer
+type clockHolder struct {
+	clock.Clock
+}
+
+var ledgerClock atomic.Pointer[clockHolder]
+
+// SetClock sets the clock which is the source of ledger time in the process. Default is the real clock
+func SetClock(c clock.Clock) {
+	ledgerClock.Store(&clockHolder{c})
+}
+
+// Clock returns the source of ledger time in the process
+func Clock() clock.Clock {
+	if h := ledgerClock.Load(); h != nil {
+		return h.Clock
+	}
+	return clock.Real()
+}
+
 func TimeNow() Time {
-	return TimeFromRealTime(time.Now())
+	return TimeFromRealTime(Clock().Now())
 }
 
 func ValidTime(ts Time) bool {
@@ -264,7 +286,7 @@ func MaxTime(ts ...Time) Time {
 // SleepDurationUntilFutureLedgerTime returns duration to sleep until the clock becomes to ts.Time()
 func SleepDurationUntilFutureLedgerTime(ts Time) (ret time.Duration) {
 	realTs := ts.Time()
-	nowis := time.Now()
+	nowis := Clock().Now()
 	if realTs.After(nowis) {
 		ret = realTs.Sub(nowis)
 	}
@@ -0,0 +1,505 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/lazybytes"
+	"github.com/lunfardo314/proxima/util/lines"
+)
+
+// Library upgrade is a soft fork of the ledger library: it adds new EasyFL functions to the library without regenesis.
+// The upgrade is an immutable output with the 'libraryUpgrade' constraint, produced by the transaction signed
+// by the genesis controller. The upgrade output can't be consumed, so once committed into the state it stays there forever.
+// New functions become effective from the activation slot: produced outputs can't call them before it.
+// Existing functions are never changed by the upgrade, so old outputs validate the same way as before.
+// Functions of upgrades take consecutive function codes in the order of upgrades, so the library is deterministic
+// on every node which applied the same upgrades.
+// Transaction is validated with the library of its baseline state, i.e. with upgrades committed into that state,
+// so all nodes validate it the same way. The node applies upgrades to the global library only when the branch
+// which contains them becomes final, so upgrades of orphaned branches never get into it
+
+type LibraryUpgrade struct {
+	// ActivationSlot new functions can be used in outputs produced by transactions starting from the slot
+	ActivationSlot Slot
+	// FirstFunCode must be equal to the next function code of the library being upgraded
+	FirstFunCode uint16
+	// Source of function definitions in the form 'func <sym> : <body>'
+	Source string
+	// LockNames functions of the upgrade which are locks. They are parsed as ScriptLock
+	LockNames []string
+}
+
+const (
+	LibraryUpgradeName     = "libraryUpgrade"
+	libraryUpgradeTemplate = LibraryUpgradeName + "(u32/%d, %s)"
+
+	// maxInlineDataChunk is the maximum size of the inline data literal in EasyFL source
+	maxInlineDataChunk = 127
+	// maxConcatArgs maximum number of arguments of EasyFL 'concat'
+	maxConcatArgs = 15
+
+	// LibraryUpgradeMinActivationSlots is the minimum number of slots between the upgrade transaction and
+	// the activation slot. It gives time for the upgrade to become final and to be applied on all nodes
+	LibraryUpgradeMinActivationSlots = 10
+)
+
+func NewLibraryUpgrade(activationSlot Slot, firstFunCode uint16, source string, lockNames ...string) *LibraryUpgrade {
+	return &LibraryUpgrade{
+		ActivationSlot: activationSlot,
+		FirstFunCode:   firstFunCode,
+		Source:         source,
+		LockNames:      lockNames,
+	}
+}
+
+// dataBytes serializes upgrade data, except activation slot, as lazy array
+func (u *LibraryUpgrade) dataBytes() []byte {
+	var funCodeBin [2]byte
+	binary.BigEndian.PutUint16(funCodeBin[:], u.FirstFunCode)
+	names := lazybytes.EmptyArray(256)
+	for _, name := range u.LockNames {
+		names.Push([]byte(name))
+	}
+	return lazybytes.MakeArrayFromDataReadOnly(funCodeBin[:], []byte(u.Source), names.Bytes()).Bytes()
+}
+
+func libraryUpgradeFromDataBytes(slot Slot, data []byte) (*LibraryUpgrade, error) {
+	arr, err := lazybytes.ParseArrayFromBytesReadOnly(data, 3)
+	if err != nil {
+		return nil, err
+	}
+	if arr.NumElements() != 3 || len(arr.At(0)) != 2 || len(arr.At(1)) == 0 {
+		return nil, fmt.Errorf("wrong library upgrade data")
+	}
+	names, err := lazybytes.ParseArrayFromBytesReadOnly(arr.At(2), 256)
+	if err != nil {
+		return nil, err
+	}
+	ret := &LibraryUpgrade{
+		ActivationSlot: slot,
+		FirstFunCode:   binary.BigEndian.Uint16(arr.At(0)),
+		Source:         string(arr.At(1)),
+		LockNames:      make([]string, names.NumElements()),
+	}
+	for i := range ret.LockNames {
+		ret.LockNames[i] = string(names.At(i))
+	}
+	return ret, nil
+}
+
+// key identifies the upgrade. It does not require global library, unlike Bytes
+func (u *LibraryUpgrade) key() string {
+	return string(u.ActivationSlot.Bytes()) + string(u.dataBytes())
+}
+
+func (u *LibraryUpgrade) source() string {
+	return fmt.Sprintf(libraryUpgradeTemplate, u.ActivationSlot, inlineDataSource(u.dataBytes()))
+}
+
+// inlineDataSource EasyFL expression which evaluates to the data. Data longer than inline literal limit
+// is split into chunks which are concatenated
+func inlineDataSource(data []byte) string {
+	parts := make([]string, 0)
+	for i := 0; i < len(data); i += maxInlineDataChunk {
+		parts = append(parts, "0x"+hex.EncodeToString(data[i:min(i+maxInlineDataChunk, len(data))]))
+	}
+	if len(parts) == 0 {
+		return "0x"
+	}
+	for len(parts) > 1 {
+		grouped := make([]string, 0)
+		for i := 0; i < len(parts); i += maxConcatArgs {
+			group := parts[i:min(i+maxConcatArgs, len(parts))]
+			if len(group) == 1 {
+				grouped = append(grouped, group[0])
+			} else {
+				grouped = append(grouped, "concat("+strings.Join(group, ",")+")")
+			}
+		}
+		parts = grouped
+	}
+	return parts[0]
+}
+
+func (u *LibraryUpgrade) Bytes() []byte {
+	return mustBinFromSource(u.source())
+}
+
+func (u *LibraryUpgrade) Name() string {
+	return LibraryUpgradeName
+}
+
+func (u *LibraryUpgrade) String() string {
+	return fmt.Sprintf("%s(slot: %d, first function code: %d, locks: [%s], source: %d bytes)",
+		LibraryUpgradeName, u.ActivationSlot, u.FirstFunCode, strings.Join(u.LockNames, ","), len(u.Source))
+}
+
+func (u *LibraryUpgrade) Lines(prefix ...string) *lines.Lines {
+	ret := lines.New(prefix...)
+	ret.Add("activation slot: %d", u.ActivationSlot).
+		Add("first function code: %d", u.FirstFunCode).
+		Add("locks: [%s]", strings.Join(u.LockNames, ","))
+	for _, f := range parseUpgradeSourceNoPanic(u.Source) {
+		ret.Add("func %s : %s", f.Sym, f.Source)
+	}
+	return ret
+}
+
+func parseUpgradeSourceNoPanic(source string) (ret []functionSource) {
+	_ = util.CatchPanicOrError(func() error {
+		ret = parseFunctionSources(source)
+		return nil
+	})
+	return
+}
+
+func LibraryUpgradeFromBytes(data []byte) (*LibraryUpgrade, error) {
+	sym, _, args, err := L().ParseBytecodeOneLevel(data, 2)
+	if err != nil {
+		return nil, err
+	}
+	if sym != LibraryUpgradeName {
+		return nil, fmt.Errorf("not a library upgrade")
+	}
+	slotBin := easyfl.StripDataPrefix(args[0])
+	if len(slotBin) != SlotByteLength {
+		return nil, fmt.Errorf("can't parse library upgrade: wrong activation slot")
+	}
+	slot, err := SlotFromBytes(slotBin)
+	if err != nil {
+		return nil, err
+	}
+	upgradeData, err := L().EvalFromBinary(nil, args[1])
+	if err != nil {
+		return nil, fmt.Errorf("can't parse library upgrade: %w", err)
+	}
+	return libraryUpgradeFromDataBytes(slot, upgradeData)
+}
+
+func addLibraryUpgradeConstraint(lib *Library) {
+	lib.extendWithConstraint(LibraryUpgradeName, libraryUpgradeSource, 2, func(data []byte) (Constraint, error) {
+		return LibraryUpgradeFromBytes(data)
+	}, initTestLibraryUpgradeConstraint)
+}
+
+func initTestLibraryUpgradeConstraint() {
+	// long enough to be split into many data chunks
+	src := "func _testUpgrade : concat($0, 0x" + strings.Repeat("00", 1000) + ")"
+	example := NewLibraryUpgrade(1337, 1000, src, "_testUpgrade")
+	back, err := LibraryUpgradeFromBytes(example.Bytes())
+	util.AssertNoError(err)
+	util.Assertf(bytes.Equal(back.Bytes(), example.Bytes()), "inconsistency "+LibraryUpgradeName)
+	util.Assertf(back.ActivationSlot == 1337 && back.FirstFunCode == 1000 && back.Source == example.Source,
+		"inconsistency "+LibraryUpgradeName)
+	util.Assertf(slices.Equal(back.LockNames, example.LockNames), "inconsistency "+LibraryUpgradeName)
+
+	_, err = L().ParseBytecodePrefix(example.Bytes())
+	util.AssertNoError(err)
+}
+
+// LibraryUpgrade finds and parses library upgrade constraint. Returns its constraintIndex or 0xff if not found
+func (o *Output) LibraryUpgrade() (*LibraryUpgrade, byte) {
+	var ret *LibraryUpgrade
+	var err error
+	found := byte(0xff)
+	o.ForEachConstraint(func(idx byte, constr []byte) bool {
+		if idx < ConstraintIndexFirstOptionalConstraint {
+			return true
+		}
+		ret, err = LibraryUpgradeFromBytes(constr)
+		if err == nil {
+			found = idx
+			return false
+		}
+		return true
+	})
+	if found != 0xff {
+		return ret, found
+	}
+	return nil, 0xff
+}
+
+// WithLibraryUpgrade can only be used inside r/o override closure
+func (o *Output) WithLibraryUpgrade(u *LibraryUpgrade) *Output {
+	_, err := o.PushConstraint(u.Bytes())
+	util.AssertNoError(err)
+	return o
+}
+
+const libraryUpgradeSource = `
+// constraint libraryUpgrade($0, $1) makes the output an immutable carrier of the ledger library upgrade
+// $0 - activation slot of the upgrade. Must be after the slot of the transaction
+// $1 - upgrade data: function definitions, first function code and names of lock functions
+// The transaction must be signed by the genesis controller. Functions of the upgrade are checked by
+// the transaction validation outside EasyFL constraints
+func libraryUpgrade : or(
+	and(
+		selfIsProducedOutput,
+		require(lessThan(txTimeSlot, mustValidTimeSlot($0)), !!!library_upgrade_activation_slot_must_be_after_the_transaction),
+		require(equal(publicKeyED25519(txSignature), constGenesisControllerPublicKey), !!!library_upgrade_must_be_signed_by_genesis_controller),
+		require(not(isZero(len16($1))), !!!library_upgrade_data_is_empty)
+	),
+	!!!library_upgrade_output_cannot_be_consumed
+)
+`
+
+// Upgrades returns library upgrades applied to the library, in the order of function codes
+func (lib *Library) Upgrades() []*LibraryUpgrade {
+	return slices.Clone(lib.upgrades)
+}
+
+// NextFunCode function code which will be assigned to the next function added to the library.
+// The next library upgrade must start from it
+func (lib *Library) NextFunCode() uint16 {
+	return lib.lastFunCode + 1
+}
+
+// ActivationSlot returns activation slot of the function added by the library upgrade.
+// Returns false if the function is not from an upgrade, i.e. it is always active
+func (lib *Library) ActivationSlot(sym string) (Slot, bool) {
+	ret, found := lib.activation[sym]
+	return ret, found
+}
+
+// CheckActiveAt returns error if bytecode calls functions of library upgrades which are not active yet at the slot.
+// Functions of the upgrades can only call functions of the same or earlier upgrades, which are active not later,
+// so it is enough to check function calls of the bytecode itself
+func (lib *Library) CheckActiveAt(bytecode []byte, slot Slot) error {
+	if len(lib.activation) == 0 || len(bytecode) == 0 {
+		return nil
+	}
+	if bytecode[0] == 0 {
+		// array constraint: bytecode is the first element
+		arr, err := lazybytes.ParseArrayFromBytesReadOnly(bytecode[1:], 256)
+		if err != nil || arr.NumElements() == 0 {
+			return nil
+		}
+		bytecode = arr.At(0)
+	}
+	expr, err := lib.ExpressionFromBytecode(bytecode)
+	if err != nil {
+		return err
+	}
+	return lib.checkExpressionActiveAt(expr, slot)
+}
+
+func (lib *Library) checkExpressionActiveAt(expr *easyfl.Expression, slot Slot) error {
+	if activation, found := lib.activation[expr.FunctionName]; found && slot < activation {
+		return fmt.Errorf("function '%s' of the library upgrade is not active until slot %d", expr.FunctionName, activation)
+	}
+	for _, arg := range expr.Args {
+		if err := lib.checkExpressionActiveAt(arg, slot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lib *Library) hasUpgrade(u *LibraryUpgrade) bool {
+	return slices.ContainsFunc(lib.upgrades, func(u1 *LibraryUpgrade) bool {
+		return u1.key() == u.key()
+	})
+}
+
+// applyUpgrade extends library with functions of the upgrade and registers its locks
+func (lib *Library) applyUpgrade(u *LibraryUpgrade) error {
+	if u.FirstFunCode != lib.NextFunCode() {
+		return fmt.Errorf("library upgrade must start from function code %d, got %d", lib.NextFunCode(), u.FirstFunCode)
+	}
+	var funs []functionSource
+	err := util.CatchPanicOrError(func() error {
+		funs = parseFunctionSources(u.Source)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("library upgrade: %w", err)
+	}
+	if len(funs) == 0 {
+		return fmt.Errorf("library upgrade: no functions")
+	}
+	// upgrade can't become active earlier than upgrades it may depend on
+	activation := u.ActivationSlot
+	for _, s := range lib.activation {
+		activation = max(activation, s)
+	}
+	numParams := make(map[string]int)
+	for _, f := range funs {
+		numParams[f.Sym] = -1
+	}
+	for _, f := range funs {
+		// accounts of the lock are indexed when the output is committed, so convention functions can't be
+		// added to the lock which already exists: outputs locked before the upgrade would be indexed differently
+		if lockName, isConvention := lockNameOfConvention(f.Sym); isConvention {
+			if _, sameUpgrade := numParams[lockName]; !sameUpgrade {
+				return fmt.Errorf("library upgrade: convention function '%s' must be defined in the same upgrade as the lock '%s'", f.Sym, lockName)
+			}
+		}
+	}
+	for _, f := range funs {
+		funCode, err := lib.ExtendErr(f.Sym, f.Source)
+		if err != nil {
+			return fmt.Errorf("library upgrade: %w", err)
+		}
+		var bytecode []byte
+		if _, numParams[f.Sym], bytecode, err = lib.CompileExpression(f.Source); err != nil {
+			return fmt.Errorf("library upgrade: %w", err)
+		}
+		if err = lib.registerCost(f.Sym, bytecode); err != nil {
+			return fmt.Errorf("library upgrade: %w", err)
+		}
+		lib.lastFunCode = funCode
+		lib.activation[f.Sym] = activation
+	}
+	for _, name := range u.LockNames {
+		nArgs, isUpgradeFun := numParams[name]
+		if !isUpgradeFun {
+			return fmt.Errorf("library upgrade: lock '%s' is not a function of the upgrade", name)
+		}
+		prefix, err := lib.FunctionCallPrefixByName(name, byte(nArgs))
+		if err != nil {
+			return fmt.Errorf("library upgrade: %w", err)
+		}
+		lib.constraintByPrefix[string(prefix)] = &constraintRecord{
+			name:   name,
+			prefix: prefix,
+			parser: func(data []byte) (Constraint, error) {
+				return lib.scriptLockFromBytes(data)
+			},
+		}
+		lib.constraintNames[name] = struct{}{}
+		lib.upgradeLocks[name] = struct{}{}
+	}
+	lib.upgrades = append(lib.upgrades, u)
+	return nil
+}
+
+// withUpgrades builds new library from the ledger library data of the library and the upgrades.
+// The library is never changed in place: EasyFL library is not thread safe
+func (lib *Library) withUpgrades(upgrades []*LibraryUpgrade) (*Library, error) {
+	ret := newLibrary()
+	ret.stored = lib.data
+	err := util.CatchPanicOrError(func() error {
+		ret.initGeneralFunctions(lib.ID)
+		ret.extendWithConstraints()
+		ret.finishLoadingFromStored()
+		for _, u := range upgrades {
+			if err := ret.applyUpgrade(u); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ValidateUpgrades checks if upgrades can be applied to the library. The library itself is not changed
+func (lib *Library) ValidateUpgrades(upgrades ...*LibraryUpgrade) error {
+	newUpgrades := lib.newUpgrades(upgrades)
+	if len(newUpgrades) == 0 {
+		return nil
+	}
+	_, err := lib.withUpgrades(append(lib.Upgrades(), newUpgrades...))
+	return err
+}
+
+// newUpgrades filters out repeating upgrades and upgrades already applied to the library.
+// Returns the rest sorted by function codes
+func (lib *Library) newUpgrades(upgrades []*LibraryUpgrade) []*LibraryUpgrade {
+	return slices.DeleteFunc(uniqueUpgrades(upgrades), lib.hasUpgrade)
+}
+
+// uniqueUpgrades filters out repeating upgrades and sorts the rest by function codes
+func uniqueUpgrades(upgrades []*LibraryUpgrade) []*LibraryUpgrade {
+	ret := make([]*LibraryUpgrade, 0, len(upgrades))
+	seen := make(map[string]struct{})
+	for _, u := range upgrades {
+		key := u.key()
+		if _, already := seen[key]; already {
+			continue
+		}
+		seen[key] = struct{}{}
+		ret = append(ret, u)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].FirstFunCode < ret[j].FirstFunCode
+	})
+	return ret
+}
+
+// upgradesKey identifies the set of sorted upgrades
+func upgradesKey(upgrades []*LibraryUpgrade) string {
+	ret := make([]byte, 0)
+	for _, u := range upgrades {
+		key := u.key()
+		ret = binary.BigEndian.AppendUint32(ret, uint32(len(key)))
+		ret = append(ret, key...)
+	}
+	return string(ret)
+}
+
+var (
+	// librariesWithUpgrades libraries with upgrades built so far, by the key of the set of upgrades
+	librariesWithUpgrades      = make(map[string]*Library)
+	librariesWithUpgradesMutex sync.Mutex
+)
+
+// LibraryWithUpgrades returns the ledger library with exactly the upgrades, applied in the order of function codes.
+// Transactions are validated with the library of the state they are validated against, i.e. with the upgrades committed
+// into the state (see multistate.LibraryOfState), so validity of the transaction does not depend on the upgrades
+// applied to the global library of the node.
+// Libraries are cached. The number of different sets of upgrades is small: upgrades are signed by the genesis controller
+func LibraryWithUpgrades(upgrades ...*LibraryUpgrade) (*Library, error) {
+	return libraryWithUpgrades(L(), upgrades)
+}
+
+func libraryWithUpgrades(lib *Library, upgrades []*LibraryUpgrade) (*Library, error) {
+	upgrades = uniqueUpgrades(upgrades)
+	key := upgradesKey(upgrades)
+	if key == upgradesKey(lib.upgrades) {
+		return lib, nil
+	}
+
+	librariesWithUpgradesMutex.Lock()
+	defer librariesWithUpgradesMutex.Unlock()
+
+	if ret, found := librariesWithUpgrades[key]; found {
+		return ret, nil
+	}
+	ret, err := lib.withUpgrades(upgrades)
+	if err != nil {
+		return nil, err
+	}
+	librariesWithUpgrades[key] = ret
+	return ret, nil
+}
+
+// ApplyLibraryUpgrades sets global ledger library to the library with exactly the upgrades.
+// The global library is never changed in place: the library with upgrades replaces the global one.
+// Upgrades must be all upgrades of one final state (see multistate.ApplyLibraryUpgradesFromState), never from states
+// of branches which may be orphaned. The global library is used to parse and display ledger data, while transactions
+// are validated with the library of the baseline state, see LibraryWithUpgrades
+func ApplyLibraryUpgrades(upgrades ...*LibraryUpgrade) error {
+	libraryGlobalMutex.Lock()
+	defer libraryGlobalMutex.Unlock()
+
+	util.Assertf(libraryGlobal != nil, "ledger constraint library not initialized")
+
+	lib, err := libraryWithUpgrades(libraryGlobal, upgrades)
+	if err != nil {
+		return fmt.Errorf("ApplyLibraryUpgrades: %w", err)
+	}
+	libraryGlobal = lib
+	return nil
+}
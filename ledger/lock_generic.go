@@ -0,0 +1,232 @@
+package ledger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/testutil"
+)
+
+// Generic model of the lock. A constraint at the lock index is a lock if it is known to the node binary, declared
+// as a lock by the library upgrade or has convention functions in the library. The node learns accounts of the lock
+// and whether the lock can be unlocked by the account from the EasyFL functions of the library, which follow the naming
+// convention. For the lock with name <lock>:
+//   - <lock>Accounts($0) takes bytecode of the lock and returns list of account IDs of the lock,
+//     each prefixed with 1 byte of its length. The output is indexed in each of the accounts.
+//     A lock without the accounts function has no accounts, i.e. output is not indexed
+//   - <lock>UnlockableWith($0, $1, $2) takes bytecode of the lock, account ID and timestamp (empty if unknown).
+//     It returns non-empty value if the account can unlock the output by signing the transaction.
+//     If the library does not contain the function, the lock can't be unlocked by any account
+// Convention functions may ignore trailing parameters. They must not depend on the transaction context,
+// they are evaluated without it. Convention functions must be defined in the same library upgrade as the lock,
+// so accounts of the lock never change after outputs with it are indexed in the state.
+// Locks known to the node binary implement accounts and unlocking in Go, however the library contains
+// convention functions for them too, so that any lock, including those added by the library upgrades,
+// are treated uniformly
+
+const (
+	LockAccountsFunctionSuffix       = "Accounts"
+	LockUnlockableWithFunctionSuffix = "UnlockableWith"
+)
+
+const lockConventionSource = `
+// $0 - 32 bytes ED25519 address. Returns account ID of the address, i.e. bytecode of the addressED25519 lock
+func ed25519AccountID : concat(#addressED25519, 0xa0, mustSize($0, 32))
+
+// $0 - account ID. Returns element of the list of accounts
+func lockAccount : concat(len8($0), $0)
+
+func addressED25519Accounts : lockAccount($0)
+func addressED25519UnlockableWith : equal($1, $0)
+
+func chainLockAccounts : lockAccount($0)
+func chainLockUnlockableWith : equal($1, $0)
+
+func stemLockAccounts : lockAccount(0x00)
+func stemLockUnlockableWith : true
+
+// $0 - deadlineLock bytecode, $1 - argument index
+func _deadlineLockArg : unwrapBytecodeArg($0, #deadlineLock, $1)
+
+func deadlineLockAccounts : concat(
+	lockAccount(_deadlineLockArg($0, 2)),
+	lockAccount(_deadlineLockArg($0, 3))
+)
+
+func deadlineLockUnlockableWith : if(
+	isZero(len8($2)),
+	or(equal($1, _deadlineLockArg($0, 2)), equal($1, _deadlineLockArg($0, 3))),
+	if(
+		ticksBefore($2, timestamp(_deadlineLockArg($0, 0), _deadlineLockArg($0, 1))),
+		equal($1, _deadlineLockArg($0, 2)),
+		equal($1, _deadlineLockArg($0, 3))
+	)
+)
+
+// $0 - concatenated addresses of the multisig, $1, $2 - bounds of the address of the cosigner
+func _multisigAccount : if(
+	lessThan(concat(0, $2), len16($0)),
+	lockAccount(ed25519AccountID(slice($0, $1, $2))),
+	nil
+)
+
+func _multisigAccounts : concat(
+	_multisigAccount($0, 0, 31),
+	_multisigAccount($0, 32, 63),
+	_multisigAccount($0, 64, 95),
+	_multisigAccount($0, 96, 127),
+	_multisigAccount($0, 128, 159),
+	_multisigAccount($0, 160, 191),
+	_multisigAccount($0, 192, 223),
+	_multisigAccount($0, 224, 255)
+)
+
+func multisigED25519Accounts : _multisigAccounts(evalBytecodeArg($0, #multisigED25519, 1))
+func multisigED25519UnlockableWith : false
+
+// $0 - htlcLock bytecode, $1 - argument index
+func _htlcLockArg : unwrapBytecodeArg($0, #htlcLock, $1)
+
+func htlcLockAccounts : concat(
+	lockAccount(ed25519AccountID(_htlcLockArg($0, 3))),
+	lockAccount(ed25519AccountID(_htlcLockArg($0, 4)))
+)
+
+func htlcLockUnlockableWith : and(
+	equal($1, ed25519AccountID(_htlcLockArg($0, 4))),
+	or(
+		isZero(len8($2)),
+		not(ticksBefore($2, timestamp(_htlcLockArg($0, 0), _htlcLockArg($0, 1))))
+	)
+)
+`
+
+func addLockConventions(lib *Library) {
+	lib.MustExtendMany(lockConventionSource)
+	lib.inlineTests = append(lib.inlineTests, initTestLockConventions)
+}
+
+// initTestLockConventions checks if convention functions of locks known to the node binary are consistent with Go
+func initTestLockConventions() {
+	addr1 := AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(1))
+	addr2 := AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(2))
+	chainLock := ChainLockFromChainID(NilChainID)
+	deadline := MustNewLedgerTime(1337, 5)
+	msig, err := NewMultisigED25519(2, addr1, addr2, AddressED25519Null())
+	util.AssertNoError(err)
+
+	others := []AccountID{AddressED25519Null().AccountID(), StemAccountID}
+	timestamps := [][]Time{nil, {MustNewLedgerTime(1337, 1)}, {MustNewLedgerTime(1338, 1)}}
+	for _, lock := range []Lock{
+		addr1,
+		chainLock,
+		&StemLock{},
+		NewDeadlineLock(deadline, addr1, chainLock),
+		msig,
+		NewHTLCLock(deadline, HTLCHash([]byte("secret")), addr1, addr2),
+	} {
+		accounts, err := L().LockAccounts(lock.Name(), lock.Bytes())
+		util.AssertNoError(err)
+		util.Assertf(len(accounts) == len(lock.Accounts()), "inconsistent accounts of the lock %s", lock.Name())
+		for i, acc := range lock.Accounts() {
+			util.Assertf(EqualAccountIDs(accounts[i], acc.AccountID()), "inconsistent accounts of the lock %s", lock.Name())
+		}
+		for _, acc := range append(accounts, others...) {
+			for _, ts := range timestamps {
+				unlockable, err := L().LockUnlockableWith(lock.Name(), lock.Bytes(), acc, ts...)
+				util.AssertNoError(err)
+				util.Assertf(unlockable == lock.UnlockableWith(acc, ts...), "inconsistent unlocking of the lock %s", lock.Name())
+			}
+		}
+	}
+}
+
+// LockAccounts evaluates accounts of the lock by the convention. Returns nil if the library does not
+// contain the accounts function of the lock
+func (lib *Library) LockAccounts(lockName string, lockBytecode []byte) ([]AccountID, error) {
+	code, ok := lib.lockConventionCall(lockName+LockAccountsFunctionSuffix, 1)
+	if !ok {
+		return nil, nil
+	}
+	res, err := lib.EvalFromBinary(nil, code, lockBytecode)
+	if err != nil {
+		return nil, fmt.Errorf("LockAccounts: lock '%s': %w", lockName, err)
+	}
+	ret := make([]AccountID, 0)
+	for len(res) > 0 {
+		size := int(res[0])
+		if size == 0 || len(res) < 1+size {
+			return nil, fmt.Errorf("LockAccounts: lock '%s': wrong list of accounts %s", lockName, easyfl.Fmt(res))
+		}
+		ret = append(ret, AccountID(res[1:1+size]))
+		res = res[1+size:]
+	}
+	return ret, nil
+}
+
+// lockNameOfConvention returns name of the lock if the function name follows the naming convention of lock functions
+func lockNameOfConvention(sym string) (string, bool) {
+	for _, suffix := range []string{LockAccountsFunctionSuffix, LockUnlockableWithFunctionSuffix} {
+		if lockName, found := strings.CutSuffix(sym, suffix); found && lockName != "" {
+			return lockName, true
+		}
+	}
+	return "", false
+}
+
+// isScriptLock returns true if the constraint can be used as a lock: it is either declared as a lock by the library upgrade
+// or the library contains convention functions of it. Other constraints, for example amount or chain constraint, are not locks
+func (lib *Library) isScriptLock(name string) bool {
+	if _, isUpgradeLock := lib.upgradeLocks[name]; isUpgradeLock {
+		return true
+	}
+	// convention functions are extended functions, all of them have static costs
+	_, hasAccounts := lib.costs[name+LockAccountsFunctionSuffix]
+	_, hasUnlockableWith := lib.costs[name+LockUnlockableWithFunctionSuffix]
+	return hasAccounts || hasUnlockableWith
+}
+
+// LockUnlockableWith evaluates by the convention if the account can unlock the lock. Returns false if the library
+// does not contain the unlock function of the lock
+func (lib *Library) LockUnlockableWith(lockName string, lockBytecode []byte, acc AccountID, ts ...Time) (bool, error) {
+	code, ok := lib.lockConventionCall(lockName+LockUnlockableWithFunctionSuffix, 3)
+	if !ok {
+		return false, nil
+	}
+	var tsBytes []byte
+	if len(ts) > 0 {
+		tsBytes = ts[0].Bytes()
+	}
+	res, err := lib.EvalFromBinary(nil, code, lockBytecode, acc, tsBytes)
+	if err != nil {
+		return false, fmt.Errorf("LockUnlockableWith: lock '%s': %w", lockName, err)
+	}
+	return len(res) > 0, nil
+}
+
+// lockConventionCall compiles call of the convention function with parameters $0..$n-1.
+// The number of parameters of EasyFL function is the number of parameters it uses, so the function
+// may have less parameters than maxArgs
+func (lib *Library) lockConventionCall(sym string, maxArgs int) ([]byte, bool) {
+	for nArgs := maxArgs; nArgs >= 0; nArgs-- {
+		if _, err := lib.FunctionCallPrefixByName(sym, byte(nArgs)); err != nil {
+			continue
+		}
+		src := sym
+		if nArgs > 0 {
+			params := make([]string, nArgs)
+			for i := range params {
+				params[i] = fmt.Sprintf("$%d", i)
+			}
+			src = fmt.Sprintf("%s(%s)", sym, strings.Join(params, ","))
+		}
+		_, _, code, err := lib.CompileExpression(src)
+		if err != nil {
+			return nil, false
+		}
+		return code, true
+	}
+	return nil, false
+}
@@ -0,0 +1,188 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+	"golang.org/x/crypto/blake2b"
+)
+
+// HTLCLock is a hash time-locked lock for atomic swaps. Until the deadline (inclusive), the output can be unlocked
+// by the claim account, which presents the preimage of the hash in the unlock parameters.
+// After the deadline the output can be unlocked by the refund account.
+// Both accounts are ED25519 addresses which must sign the transaction
+type HTLCLock struct {
+	Deadline Time
+	Hash     []byte
+	Claim    AddressED25519
+	Refund   AddressED25519
+}
+
+const (
+	HTLCLockName     = "htlcLock"
+	htlcLockTemplate = HTLCLockName + "(u32/%d, %d, 0x%s, 0x%s, 0x%s)"
+	HTLCHashSize     = 32
+)
+
+func NewHTLCLock(deadline Time, hash []byte, claim, refund AddressED25519) *HTLCLock {
+	return &HTLCLock{
+		Deadline: deadline,
+		Hash:     hash,
+		Claim:    claim,
+		Refund:   refund,
+	}
+}
+
+// HTLCHash blake2b hash of the preimage, the secret of the HTLC
+func HTLCHash(preimage []byte) []byte {
+	ret := blake2b.Sum256(preimage)
+	return ret[:]
+}
+
+func (h *HTLCLock) source() string {
+	return fmt.Sprintf(htlcLockTemplate,
+		h.Deadline.Slot(),
+		h.Deadline.Tick(),
+		hex.EncodeToString(h.Hash),
+		hex.EncodeToString(h.Claim),
+		hex.EncodeToString(h.Refund),
+	)
+}
+
+func (h *HTLCLock) Bytes() []byte {
+	return mustBinFromSource(h.source())
+}
+
+func (h *HTLCLock) String() string {
+	return fmt.Sprintf("%s(%d,%d,%s,%s,%s)", HTLCLockName, h.Deadline.Slot(), h.Deadline.Tick(),
+		hex.EncodeToString(h.Hash), h.Claim, h.Refund)
+}
+
+func (h *HTLCLock) Accounts() []Accountable {
+	return []Accountable{h.Claim, h.Refund}
+}
+
+// UnlockableWith claim requires the preimage, so only the refund account after the deadline
+// can unlock the output with the transaction signature alone
+func (h *HTLCLock) UnlockableWith(acc AccountID, ts ...Time) bool {
+	if len(ts) == 0 {
+		return bytes.Equal(h.Refund.AccountID(), acc)
+	}
+	return ts[0].After(h.Deadline) && bytes.Equal(h.Refund.AccountID(), acc)
+}
+
+func (h *HTLCLock) Name() string {
+	return HTLCLockName
+}
+
+// CanBeClaimedAt returns true if the transaction with the timestamp can claim the output
+func (h *HTLCLock) CanBeClaimedAt(ts Time) bool {
+	return !ts.After(h.Deadline)
+}
+
+// CanBeRefundedAt returns true if the transaction with the timestamp can refund the output
+func (h *HTLCLock) CanBeRefundedAt(ts Time) bool {
+	return ts.After(h.Deadline)
+}
+
+func (h *HTLCLock) MatchesPreimage(preimage []byte) bool {
+	return bytes.Equal(h.Hash, HTLCHash(preimage))
+}
+
+func addHTLCLockConstraint(lib *Library) {
+	lib.extendWithConstraint(HTLCLockName, htlcLockSource, 5, func(data []byte) (Constraint, error) {
+		return HTLCLockFromBytes(data)
+	}, initTestHTLCLockConstraint)
+}
+
+func initTestHTLCLockConstraint() {
+	ts := MustNewLedgerTime(1337, 5)
+	example := NewHTLCLock(ts, HTLCHash([]byte("secret")), AddressED25519Null(), AddressED25519Null())
+	lockBack, err := HTLCLockFromBytes(example.Bytes())
+	util.AssertNoError(err)
+	util.Assertf(EqualConstraints(lockBack, example), "inconsistency "+HTLCLockName)
+	util.Assertf(lockBack.MatchesPreimage([]byte("secret")), "inconsistency "+HTLCLockName)
+
+	_, err = L().ParseBytecodePrefix(example.Bytes())
+	util.AssertNoError(err)
+}
+
+func HTLCLockFromBytes(data []byte) (*HTLCLock, error) {
+	sym, _, args, err := L().ParseBytecodeOneLevel(data, 5)
+	if err != nil {
+		return nil, err
+	}
+	slotBin := easyfl.StripDataPrefix(args[0])
+	tickBin := easyfl.StripDataPrefix(args[1])
+	if sym != HTLCLockName || len(slotBin) != SlotByteLength || len(tickBin) != 1 {
+		return nil, fmt.Errorf("can't parse HTLC lock")
+	}
+	slot, err := SlotFromBytes(slotBin)
+	if err != nil {
+		return nil, err
+	}
+	tick, err := TickFromByte(tickBin[0])
+	if err != nil {
+		return nil, err
+	}
+	ret := &HTLCLock{
+		Deadline: MustNewLedgerTime(slot, tick),
+		Hash:     easyfl.StripDataPrefix(args[2]),
+		Claim:    easyfl.StripDataPrefix(args[3]),
+		Refund:   easyfl.StripDataPrefix(args[4]),
+	}
+	if len(ret.Hash) != HTLCHashSize || len(ret.Claim) != 32 || len(ret.Refund) != 32 {
+		return nil, fmt.Errorf("can't parse HTLC lock: wrong data length")
+	}
+	return ret, nil
+}
+
+func HTLCLockFromSource(src string) (*HTLCLock, error) {
+	bin, err := binFromSource(src)
+	if err != nil {
+		return nil, fmt.Errorf("EasyFL compile error: %v", err)
+	}
+	return HTLCLockFromBytes(bin)
+}
+
+const htlcLockSource = `
+
+// $0 - ED25519 address
+// returns true if the transaction is signed by the address
+func _htlcSignedBy : unlockedWithSigED25519($0, signatureED25519(txSignature), publicKeyED25519(txSignature))
+
+// hash time-locked lock
+// $0, $1 - slot and tick of the deadline
+// $2 - blake2b hash of the secret
+// $3 - ED25519 address of the claim account. Can unlock until the deadline (inclusive) with the secret in the unlock parameters
+// $4 - ED25519 address of the refund account. Can unlock after the deadline
+func htlcLock: and(
+	require(equal(selfBlockIndex,1), !!!locks_must_be_at_block_1),
+	selfMustStandardAmount,
+	or(
+		and(
+			selfIsProducedOutput,
+			mustValidTimeSlot($0),
+			mustValidTimeTick($1),
+			require(equal(len8($2), 32), !!!htlcLock_wrong_hash_size),
+			require(equal(len8($3), 32), !!!htlcLock_wrong_claim_address),
+			require(equal(len8($4), 32), !!!htlcLock_wrong_refund_address)
+		),
+		and(
+			selfIsConsumedOutput,
+			if(
+				ticksBefore(txTimestampBytes, timestamp($0,$1)),
+				and(
+					equal($2, blake2b(selfUnlockParameters)),
+					_htlcSignedBy($3)
+				),
+				_htlcSignedBy($4)
+			)
+		),
+		!!!htlcLock_unlock_failed
+	)
+)
+`
@@ -0,0 +1,290 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/lazybytes"
+)
+
+// MultisigED25519 is M-of-N lock: the output can be consumed only if at least Threshold of Addresses
+// signed the transaction essence. Signatures of cosigners are carried by the unlock parameters of the lock,
+// not by the transaction signature. Addresses are kept sorted, so the lock bytes are canonical
+type MultisigED25519 struct {
+	Threshold byte
+	Addresses []AddressED25519
+}
+
+const (
+	MultisigED25519Name         = "multisigED25519"
+	multisigED25519Template     = MultisigED25519Name + "(%d, concat(%s))"
+	MaxMultisigED25519Cosigners = 8
+	// MultisigED25519SignatureSize size of the cosigner's element in the unlock parameters: signature || public key
+	MultisigED25519SignatureSize = ed25519.SignatureSize + ed25519.PublicKeySize
+)
+
+func NewMultisigED25519(threshold int, addresses ...AddressED25519) (*MultisigED25519, error) {
+	if len(addresses) == 0 || len(addresses) > MaxMultisigED25519Cosigners {
+		return nil, fmt.Errorf("number of cosigners must be from 1 to %d", MaxMultisigED25519Cosigners)
+	}
+	if threshold < 1 || threshold > len(addresses) {
+		return nil, fmt.Errorf("threshold must be from 1 to the number of cosigners %d", len(addresses))
+	}
+	sorted := make([]AddressED25519, len(addresses))
+	for i, addr := range addresses {
+		if len(addr) != 32 {
+			return nil, fmt.Errorf("wrong address length")
+		}
+		sorted[i] = addr.Clone()
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+	for i := 1; i < len(sorted); i++ {
+		if bytes.Equal(sorted[i-1], sorted[i]) {
+			return nil, fmt.Errorf("repeating cosigner address %s", sorted[i].String())
+		}
+	}
+	return &MultisigED25519{
+		Threshold: byte(threshold),
+		Addresses: sorted,
+	}, nil
+}
+
+func MultisigED25519FromBytes(data []byte) (*MultisigED25519, error) {
+	sym, _, args, err := L().ParseBytecodeOneLevel(data, 2)
+	if err != nil {
+		return nil, err
+	}
+	if sym != MultisigED25519Name {
+		return nil, fmt.Errorf("not a MultisigED25519")
+	}
+	thresholdBin := easyfl.StripDataPrefix(args[0])
+	if len(thresholdBin) != 1 {
+		return nil, fmt.Errorf("wrong threshold")
+	}
+	sym, _, addrArgs, err := L().ParseBytecodeOneLevel(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if sym != "concat" {
+		return nil, fmt.Errorf("can't parse cosigners of the MultisigED25519")
+	}
+	addresses := make([]AddressED25519, len(addrArgs))
+	for i, arg := range addrArgs {
+		addresses[i] = easyfl.StripDataPrefix(arg)
+		if i > 0 && bytes.Compare(addresses[i-1], addresses[i]) >= 0 {
+			return nil, fmt.Errorf("cosigners of the MultisigED25519 must be sorted")
+		}
+	}
+	return NewMultisigED25519(int(thresholdBin[0]), addresses...)
+}
+
+func MultisigED25519FromSource(src string) (*MultisigED25519, error) {
+	bin, err := binFromSource(src)
+	if err != nil {
+		return nil, fmt.Errorf("EasyFL compile error: %v", err)
+	}
+	return MultisigED25519FromBytes(bin)
+}
+
+func (m *MultisigED25519) source() string {
+	addrs := make([]string, len(m.Addresses))
+	for i, addr := range m.Addresses {
+		addrs[i] = "0x" + hex.EncodeToString(addr)
+	}
+	return fmt.Sprintf(multisigED25519Template, m.Threshold, strings.Join(addrs, ", "))
+}
+
+func (m *MultisigED25519) Bytes() []byte {
+	return mustBinFromSource(m.source())
+}
+
+// Accounts each cosigner indexes the output, so that it can be found in the accounts of all cosigners
+func (m *MultisigED25519) Accounts() []Accountable {
+	ret := make([]Accountable, len(m.Addresses))
+	for i, addr := range m.Addresses {
+		ret[i] = addr
+	}
+	return ret
+}
+
+// UnlockableWith always false: none of the cosigners can unlock the output alone with the transaction signature
+func (m *MultisigED25519) UnlockableWith(_ AccountID, _ ...Time) bool {
+	return false
+}
+
+func (m *MultisigED25519) Name() string {
+	return MultisigED25519Name
+}
+
+func (m *MultisigED25519) String() string {
+	return m.source()
+}
+
+// CosignerIndex returns index of the address among cosigners or -1
+func (m *MultisigED25519) CosignerIndex(addr AddressED25519) int {
+	for i, a := range m.Addresses {
+		if bytes.Equal(a, addr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewUnlockParams unlock parameters without signatures: array with empty element for each cosigner
+func (m *MultisigED25519) NewUnlockParams() []byte {
+	return lazybytes.MakeArrayFromDataReadOnly(make([][]byte, len(m.Addresses))...).Bytes()
+}
+
+// PutSignature puts signature || public key of the cosigner into the unlock parameters. Signature is not checked
+func (m *MultisigED25519) PutSignature(unlockParams []byte, sigAndPubKey []byte) ([]byte, error) {
+	if len(sigAndPubKey) != MultisigED25519SignatureSize {
+		return nil, fmt.Errorf("wrong signature size")
+	}
+	idx := m.CosignerIndex(AddressED25519FromPublicKey(sigAndPubKey[ed25519.SignatureSize:]))
+	if idx < 0 {
+		return nil, fmt.Errorf("signer is not a cosigner of %s", m.String())
+	}
+	arr, err := lazybytes.ParseArrayFromBytesReadOnly(unlockParams, len(m.Addresses))
+	if err != nil {
+		return nil, err
+	}
+	if arr.NumElements() != len(m.Addresses) {
+		return nil, fmt.Errorf("wrong number of elements in the unlock parameters of %s", MultisigED25519Name)
+	}
+	elems := arr.Parsed()
+	elems[idx] = sigAndPubKey
+	return lazybytes.MakeArrayFromDataReadOnly(elems...).Bytes(), nil
+}
+
+// NumSignatures returns number of non-empty signature elements in the unlock parameters
+func (m *MultisigED25519) NumSignatures(unlockParams []byte) int {
+	arr, err := lazybytes.ParseArrayFromBytesReadOnly(unlockParams, len(m.Addresses))
+	if err != nil {
+		return 0
+	}
+	ret := 0
+	arr.ForEach(func(_ int, data []byte) bool {
+		if len(data) > 0 {
+			ret++
+		}
+		return true
+	})
+	return ret
+}
+
+func addMultisigED25519Constraint(lib *Library) {
+	lib.extendWithConstraint(MultisigED25519Name, multisigED25519ConstraintSource, 2, func(data []byte) (Constraint, error) {
+		return MultisigED25519FromBytes(data)
+	}, initTestMultisigED25519Constraint)
+}
+
+func initTestMultisigED25519Constraint() {
+	addrs := make([]AddressED25519, MaxMultisigED25519Cosigners)
+	for i := range addrs {
+		addrs[i] = AddressED25519Null()
+		addrs[i][0] = byte(MaxMultisigED25519Cosigners - i)
+	}
+	example, err := NewMultisigED25519(2, addrs...)
+	util.AssertNoError(err)
+	lockBack, err := MultisigED25519FromBytes(example.Bytes())
+	util.AssertNoError(err)
+	util.Assertf(EqualConstraints(lockBack, example), "inconsistency "+MultisigED25519Name)
+
+	_, err = L().ParseBytecodePrefix(example.Bytes())
+	util.AssertNoError(err)
+}
+
+const multisigED25519ConstraintSource = `
+
+// M-of-N multi-signature lock
+// $0 - threshold M, 1 byte
+// $1 - concatenation of N 32-byte ED25519 addresses of cosigners, sorted strictly ascending. 1 <= M <= N <= 8
+// Unlock parameters is a lazy array of N elements, one per cosigner in the order of addresses.
+// The element is empty or 96 bytes: signature of the transaction essence || public key of the cosigner.
+// Alternatively, unlock parameters is 1 byte reference to the preceding input with exactly the same lock
+
+// $0 - concatenation of addresses. Returns number of cosigners, 1 byte
+func _msigN : byte(div(len16($0), 32), 7)
+
+// $0 - address, $1 - element of the unlock parameters
+func _msigValidSig : and(
+	equal(len8($1), 96),
+	unlockedWithSigED25519($0, signatureED25519($1), publicKeyED25519($1))
+)
+
+// returns 1 if cosigner signed the transaction, otherwise 0
+// $0 - concatenation of addresses, $1 - unlock parameters, $2 - index of the cosigner
+// $3, $4 - bounds of the cosigner's address in $0
+func _msigSigned : if(
+	and(
+		lessThan($2, _msigN($0)),
+		lessThan($2, ArrayLength8($1)),
+		_msigValidSig(slice($0, $3, $4), @Array8($1, $2))
+	),
+	1,
+	0
+)
+
+// $0 - concatenation of addresses, $1 - unlock parameters. Returns number of valid signatures, 8 bytes
+func _msigNumSigned : add(
+	add(
+		add(_msigSigned($0, $1, 0, 0, 31), _msigSigned($0, $1, 1, 32, 63)),
+		add(_msigSigned($0, $1, 2, 64, 95), _msigSigned($0, $1, 3, 96, 127))
+	),
+	add(
+		add(_msigSigned($0, $1, 4, 128, 159), _msigSigned($0, $1, 5, 160, 191)),
+		add(_msigSigned($0, $1, 6, 192, 223), _msigSigned($0, $1, 7, 224, 255))
+	)
+)
+
+// $0 - concatenation of addresses, $1 - index of the address, $2..$5 - bounds of the address and of the preceding one
+func _msigOrdered : or(
+	not(lessThan($1, _msigN($0))),
+	lessThan(slice($0, $2, $3), slice($0, $4, $5))
+)
+
+// duplicate cosigners would allow one cosigner to sign twice, therefore addresses must be strictly ordered
+func _msigAddressesOrdered : and(
+	_msigOrdered($0, 1, 0, 31, 32, 63),
+	_msigOrdered($0, 2, 32, 63, 64, 95),
+	_msigOrdered($0, 3, 64, 95, 96, 127),
+	_msigOrdered($0, 4, 96, 127, 128, 159),
+	_msigOrdered($0, 5, 128, 159, 160, 191),
+	_msigOrdered($0, 6, 160, 191, 192, 223),
+	_msigOrdered($0, 7, 192, 223, 224, 255)
+)
+
+func multisigED25519: and(
+	require(equal(selfBlockIndex,1), !!!locks_must_be_at_block_1),
+	selfMustStandardAmount,
+	or(
+		and(
+			selfIsProducedOutput,
+			require(equal(len8($0), 1), !!!multisigED25519_wrong_threshold),
+			require(isZero(mod(len16($1), 32)), !!!multisigED25519_wrong_addresses),
+			require(lessThan(0, $0), !!!multisigED25519_threshold_must_be_positive),
+			require(not(lessThan(_msigN($1), $0)), !!!multisigED25519_threshold_exceeds_number_of_cosigners),
+			require(not(lessThan(8, _msigN($1))), !!!multisigED25519_too_many_cosigners),
+			require(_msigAddressesOrdered($1), !!!multisigED25519_addresses_must_be_sorted)
+		),
+		and(
+			selfIsConsumedOutput,
+			or(
+					// if it is unlocked with reference, signatures are not checked
+				and(equal(len16(selfUnlockParameters), u16/1), unlockedByReference),
+					// at least M valid signatures
+				not(lessThan(_msigNumSigned($1, selfUnlockParameters), add($0, 0)))
+			)
+		),
+		!!!multisigED25519_unlock_failed
+	)
+)
+`
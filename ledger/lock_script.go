@@ -0,0 +1,120 @@
+package ledger
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+)
+
+// ScriptLock is a lock constraint not known to the node binary, defined by the library upgrade either as a lock
+// or by the convention functions of the lock.
+// The node validates it as any other EasyFL constraint. Accounts of the lock and unlocking are
+// evaluated by the EasyFL convention functions of the lock, if library contains them (see lock_generic.go)
+type ScriptLock struct {
+	name     string
+	bytecode []byte
+	// library the lock was parsed with. Convention functions of the lock are evaluated with it
+	lib *Library
+}
+
+// ScriptAccount is the account ID, returned by the convention function of the script lock, which is not
+// one of accountable constraints known to the node binary
+type ScriptAccount AccountID
+
+func ScriptLockFromBytes(data []byte) (*ScriptLock, error) {
+	return L().scriptLockFromBytes(data)
+}
+
+func (lib *Library) scriptLockFromBytes(data []byte) (*ScriptLock, error) {
+	if len(data) == 0 || easyfl.IsDataPrefix(data) {
+		return nil, fmt.Errorf("ScriptLockFromBytes: lock must be a function call: '%s'", easyfl.Fmt(data))
+	}
+	expr, err := lib.ExpressionFromBytecode(data)
+	if err != nil {
+		return nil, fmt.Errorf("ScriptLockFromBytes: %w", err)
+	}
+	if !lib.isScriptLock(expr.FunctionName) {
+		return nil, fmt.Errorf("ScriptLockFromBytes: '%s' is not a lock", expr.FunctionName)
+	}
+	return &ScriptLock{name: expr.FunctionName, bytecode: data, lib: lib}, nil
+}
+
+func (s *ScriptLock) Name() string {
+	return s.name
+}
+
+func (s *ScriptLock) Bytes() []byte {
+	return s.bytecode
+}
+
+func (s *ScriptLock) String() string {
+	src, err := s.lib.DecompileBytecode(s.bytecode)
+	if err != nil {
+		return fmt.Sprintf("%s(%s)", s.name, easyfl.Fmt(s.bytecode))
+	}
+	return src
+}
+
+// Accounts of the script lock are evaluated by the convention function <lock name>Accounts.
+// Account IDs which are not bytecodes of known accountable constraints are returned as ScriptAccount.
+// Returns empty list if accounts can't be evaluated. Transaction which produces such lock is invalid
+func (s *ScriptLock) Accounts() []Accountable {
+	ret, err := s.AccountsErr()
+	if err != nil {
+		return nil
+	}
+	return ret
+}
+
+// AccountsErr same as Accounts, only returns error if convention function fails or returns wrong list of accounts
+func (s *ScriptLock) AccountsErr() ([]Accountable, error) {
+	var accounts []AccountID
+	err := util.CatchPanicOrError(func() error {
+		var err1 error
+		accounts, err1 = s.lib.LockAccounts(s.name, s.bytecode)
+		return err1
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Accountable, len(accounts))
+	for i, acc := range accounts {
+		if ret[i], err = AccountableFromBytes(acc); err != nil {
+			ret[i] = ScriptAccount(acc)
+		}
+	}
+	return ret, nil
+}
+
+// UnlockableWith is evaluated by the convention function <lock name>UnlockableWith. False if library does not contain it
+func (s *ScriptLock) UnlockableWith(acc AccountID, ts ...Time) bool {
+	ret, err := s.lib.LockUnlockableWith(s.name, s.bytecode, acc, ts...)
+	return err == nil && ret
+}
+
+func (a ScriptAccount) Name() string {
+	return "scriptAccount"
+}
+
+func (a ScriptAccount) Bytes() []byte {
+	return a
+}
+
+func (a ScriptAccount) String() string {
+	return fmt.Sprintf("scriptAccount(%s)", hex.EncodeToString(a))
+}
+
+func (a ScriptAccount) AccountID() AccountID {
+	return AccountID(a)
+}
+
+// AsLock returns lock if the account ID is a lock bytecode, otherwise nil
+func (a ScriptAccount) AsLock() Lock {
+	ret, err := LockFromBytes(a)
+	if err != nil {
+		return nil
+	}
+	return ret
+}
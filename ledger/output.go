@@ -61,7 +61,12 @@ func OutputBasic(amount uint64, lock Lock) *Output {
 }
 
 func OutputFromBytesReadOnly(data []byte, validateOpt ...func(*Output) error) (*Output, error) {
-	ret, _, _, err := OutputFromBytesMain(data)
+	return L().OutputFromBytesReadOnly(data, validateOpt...)
+}
+
+// OutputFromBytesReadOnly parses output with the library. The lock of the output must be known to the library
+func (lib *Library) OutputFromBytesReadOnly(data []byte, validateOpt ...func(*Output) error) (*Output, error) {
+	ret, _, _, err := lib.OutputFromBytesMain(data)
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +79,10 @@ func OutputFromBytesReadOnly(data []byte, validateOpt ...func(*Output) error) (*
 }
 
 func OutputFromBytesMain(data []byte) (*Output, Amount, Lock, error) {
+	return L().OutputFromBytesMain(data)
+}
+
+func (lib *Library) OutputFromBytesMain(data []byte) (*Output, Amount, Lock, error) {
 	ret := &Output{
 		arr: lazybytes.ArrayFromBytesReadOnly(data, 256),
 	}
@@ -86,7 +95,7 @@ func OutputFromBytesMain(data []byte) (*Output, Amount, Lock, error) {
 	if amount, err = AmountFromBytes(ret.arr.At(int(ConstraintIndexAmount))); err != nil {
 		return nil, 0, nil, err
 	}
-	if lock, err = LockFromBytes(ret.arr.At(int(ConstraintIndexLock))); err != nil {
+	if lock, err = lib.LockFromBytes(ret.arr.At(int(ConstraintIndexLock))); err != nil {
 		return nil, 0, nil, err
 	}
 	return ret, amount, lock, nil
@@ -490,8 +499,10 @@ func (o *Output) MustHaveConstraintAnyOfAt(pos byte, names ...string) {
 	util.Panicf("any of %+v was expected at the position %d, got '%s' instead", names, pos, constr.Name())
 }
 
-// MustValidOutput checks if amount and lock constraints are as expected
+// MustValidOutput checks if amount and lock constraints are as expected. Any constraint
+// at the lock index is a lock, however it must be parseable as a lock
 func (o *Output) MustValidOutput() {
 	o.MustHaveConstraintAnyOfAt(0, AmountConstraintName)
-	o.MustHaveConstraintAnyOfAt(1, AllLockNames...)
+	_, err := LockFromBytes(o.ConstraintAt(ConstraintIndexLock))
+	util.AssertNoError(err)
 }
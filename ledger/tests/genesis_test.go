@@ -54,7 +54,10 @@ func TestInitOrigin(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, ledger.GenesisOutputID(), initSupplyOut.ID)
 
+	// library hash is set at genesis
+	id.LibraryHash = ledger.L().LibraryData().Hash()
 	require.EqualValues(t, id.Bytes(), rdr.MustLedgerIdentityBytes())
+	require.EqualValues(t, ledger.L().LibraryData().Bytes(), multistate.LedgerLibraryBytesFromStore(store))
 }
 
 func TestBoostrapSequencerID(t *testing.T) {
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/lunfardo314/proxima/ledger/txbuilder"
 	"github.com/lunfardo314/proxima/multistate"
 	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/lazybytes"
 	"github.com/lunfardo314/proxima/util/txutils"
 	"github.com/lunfardo314/proxima/util/utxodb"
 	"github.com/stretchr/testify/require"
@@ -1208,3 +1211,755 @@ func TestGGG(t *testing.T) {
 	require.NoError(t, err)
 	t.Logf("bin = %s, prefix = %s", hex.EncodeToString(bin), hex.EncodeToString(prefix))
 }
+
+func TestMultisigED25519(t *testing.T) {
+	u := utxodb.NewUTXODB(genesisPrivateKey, true)
+	privKeys, _, addrs := u.GenerateAddressesWithFaucetAmount(0, 5, 20_000)
+
+	_, err := ledger.NewMultisigED25519(3, addrs[0], addrs[1])
+	require.Error(t, err)
+	_, err = ledger.NewMultisigED25519(2, addrs[0], addrs[0])
+	require.Error(t, err)
+
+	msig, err := ledger.NewMultisigED25519(2, addrs[2], addrs[0], addrs[1])
+	require.NoError(t, err)
+	t.Logf("multisig lock: %s, %d bytes", msig.String(), len(msig.Bytes()))
+
+	msigBack, err := ledger.LockFromBytes(msig.Bytes())
+	require.NoError(t, err)
+	require.True(t, ledger.EqualConstraints(msig, msigBack))
+
+	// fund the lock with two outputs from the sender, which is not a cosigner
+	for i := 0; i < 2; i++ {
+		par, err := u.MakeTransferInputData(privKeys[3], nil, ledger.NilLedgerTime)
+		require.NoError(t, err)
+		_, err = u.DoTransferTx(par.WithAmount(5_000).WithTargetLock(msig))
+		require.NoError(t, err)
+	}
+	var msigOuts []*ledger.OutputWithID
+	for i := 0; i < 3; i++ {
+		outs, err := u.StateReader().GetUTXOsLockedInAccount(addrs[i].AccountID())
+		require.NoError(t, err)
+		outsParsed, err := txutils.ParseAndSortOutputData(outs, func(o *ledger.Output) bool {
+			return ledger.EqualConstraints(o.Lock(), msig)
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 2, len(outsParsed))
+		msigOuts = outsParsed
+	}
+	require.EqualValues(t, 20_000, u.Balance(addrs[0], ledger.TimeNow()))
+
+	txBytes, err := txbuilder.MakeMultisigTransferTransaction(&txbuilder.MultisigTransferData{
+		Lock:             msig,
+		Inputs:           msigOuts,
+		SenderPrivateKey: privKeys[3],
+		Target:           addrs[4],
+		Amount:           7_000,
+	})
+	require.NoError(t, err)
+	err = u.AddTransaction(txBytes)
+	require.Error(t, err)
+
+	_, _, err = txbuilder.CosignTransactionBytes(txBytes, msigOuts, privKeys[4])
+	require.Error(t, err)
+
+	txBytes, n, err := txbuilder.CosignTransactionBytes(txBytes, msigOuts, privKeys[1])
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+	err = u.AddTransaction(txBytes)
+	require.Error(t, err)
+
+	// the same cosigner twice is still one signature
+	txBytes, _, err = txbuilder.CosignTransactionBytes(txBytes, msigOuts, privKeys[1])
+	require.NoError(t, err)
+	err = u.AddTransaction(txBytes)
+	require.Error(t, err)
+
+	txBytes, _, err = txbuilder.CosignTransactionBytes(txBytes, msigOuts, privKeys[2])
+	require.NoError(t, err)
+	err = u.AddTransaction(txBytes)
+	require.NoError(t, err)
+
+	require.EqualValues(t, 27_000, u.Balance(addrs[4]))
+	outs, err := u.StateReader().GetUTXOsLockedInAccount(addrs[0].AccountID())
+	require.NoError(t, err)
+	remainder, err := txutils.ParseAndSortOutputData(outs, func(o *ledger.Output) bool {
+		return ledger.EqualConstraints(o.Lock(), msig)
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, len(remainder))
+	require.EqualValues(t, 3_000, remainder[0].Output.Amount())
+}
+
+func TestHTLCLock(t *testing.T) {
+	u := utxodb.NewUTXODB(genesisPrivateKey, true)
+	privKeys, _, addrs := u.GenerateAddressesWithFaucetAmount(0, 2, 10_000)
+	secret := []byte("atomic swap secret")
+	ts := ledger.TimeNow()
+	deadline := ts.AddSlots(10)
+
+	htlc := ledger.NewHTLCLock(deadline, ledger.HTLCHash(secret), addrs[1], addrs[0])
+	t.Logf("HTLC lock: %s, %d bytes", htlc.String(), len(htlc.Bytes()))
+	lockBack, err := ledger.LockFromBytes(htlc.Bytes())
+	require.NoError(t, err)
+	require.True(t, ledger.EqualConstraints(htlc, lockBack))
+
+	fund := func() *ledger.OutputWithID {
+		par, err := u.MakeTransferInputData(privKeys[0], nil, ts)
+		require.NoError(t, err)
+		txBytes, err := u.DoTransferTx(par.WithAmount(2_000).WithTargetLock(htlc))
+		require.NoError(t, err)
+		tx, err := transaction2.FromBytes(txBytes)
+		require.NoError(t, err)
+		var ret *ledger.OutputWithID
+		tx.ForEachProducedOutput(func(idx byte, o *ledger.Output, oid *ledger.OutputID) bool {
+			if ledger.EqualConstraints(o.Lock(), htlc) {
+				ret = &ledger.OutputWithID{ID: *oid, Output: o}
+			}
+			return true
+		})
+		require.True(t, ret != nil)
+		return ret
+	}
+	// before deadline, HTLC output is not counted in any of balances
+	out := fund()
+	require.EqualValues(t, 8_000, u.Balance(addrs[0], ts))
+	require.EqualValues(t, 10_000, u.Balance(addrs[1], ts))
+	require.EqualValues(t, 10_000, u.Balance(addrs[0], deadline.AddSlots(1)))
+
+	t.Run("claim", func(t *testing.T) {
+		_, err := txbuilder.MakeHTLCClaimTransaction(&txbuilder.HTLCUnlockData{
+			Inputs:     []*ledger.OutputWithID{out},
+			PrivateKey: privKeys[1],
+			Preimage:   []byte("wrong secret"),
+		})
+		require.Error(t, err)
+
+		_, err = txbuilder.MakeHTLCClaimTransaction(&txbuilder.HTLCUnlockData{
+			Inputs:     []*ledger.OutputWithID{out},
+			PrivateKey: privKeys[1],
+			Preimage:   secret,
+			Timestamp:  deadline.AddSlots(1),
+		})
+		require.Error(t, err)
+
+		// refund account can't claim even with the secret
+		txb := txbuilder.NewTransactionBuilder()
+		_, err = txb.ConsumeOutputWithID(out)
+		require.NoError(t, err)
+		txb.PutUnlockParams(0, ledger.ConstraintIndexLock, secret)
+		_, err = txb.ProduceOutput(ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(out.Output.Amount()).WithLock(addrs[0])
+		}))
+		require.NoError(t, err)
+		txb.TransactionData.Timestamp = ledger.MaxTime(ts, out.Timestamp()).AddTicks(ledger.TransactionPace())
+		txb.TransactionData.InputCommitment = txb.InputCommitment()
+		txb.SignED25519(privKeys[0])
+		err = u.AddTransaction(txb.TransactionData.Bytes())
+		require.Error(t, err)
+
+		txBytes, err := txbuilder.MakeHTLCClaimTransaction(&txbuilder.HTLCUnlockData{
+			Inputs:     []*ledger.OutputWithID{out},
+			PrivateKey: privKeys[1],
+			Preimage:   secret,
+			Timestamp:  ts,
+		})
+		require.NoError(t, err)
+		require.NoError(t, u.AddTransaction(txBytes))
+		require.EqualValues(t, 12_000, u.Balance(addrs[1]))
+	})
+	t.Run("refund", func(t *testing.T) {
+		out = fund()
+		_, err := txbuilder.MakeHTLCRefundTransaction(&txbuilder.HTLCUnlockData{
+			Inputs:     []*ledger.OutputWithID{out},
+			PrivateKey: privKeys[0],
+			Timestamp:  ts,
+		})
+		require.Error(t, err)
+
+		txBytes, err := txbuilder.MakeHTLCRefundTransaction(&txbuilder.HTLCUnlockData{
+			Inputs:     []*ledger.OutputWithID{out},
+			PrivateKey: privKeys[0],
+			Timestamp:  deadline.AddSlots(1),
+		})
+		require.NoError(t, err)
+		require.NoError(t, u.AddTransaction(txBytes))
+		require.EqualValues(t, 8_000, u.Balance(addrs[0]))
+	})
+}
+
+func TestToken(t *testing.T) {
+	u := utxodb.NewUTXODB(genesisPrivateKey, true)
+	privKeys, _, addrs := u.GenerateAddressesWithFaucetAmount(0, 3, 10_000)
+	ts := ledger.TimeNow()
+
+	// addrs[2] controls the issuing chain
+	chainID, err := u.CreateChainOrigin(privKeys[2], ts)
+	require.NoError(t, err)
+	tokenID := ledger.TokenIDFromChainID(chainID)
+
+	getChainOutput := func() *ledger.OutputWithChainID {
+		oData, err := u.StateReader().GetUTXOForChainID(&chainID)
+		require.NoError(t, err)
+		chains, err := txutils.ParseChainConstraintsFromData([]*ledger.OutputDataWithID{oData})
+		require.NoError(t, err)
+		return chains[0]
+	}
+	tokenBalance := func(addr ledger.AddressED25519) (ret uint64) {
+		oData, err := u.StateReader().GetUTXOsWithToken(&tokenID)
+		require.NoError(t, err)
+		for _, od := range oData {
+			o, err := ledger.OutputFromBytesReadOnly(od.OutputData)
+			require.NoError(t, err)
+			if ledger.EqualConstraints(o.Lock(), addr) {
+				ret += o.MustToken().Amount
+			}
+		}
+		return
+	}
+	accountOutputs := func(addr ledger.AddressED25519) []*ledger.OutputWithID {
+		oData, err := u.StateReader().GetUTXOsLockedInAccount(addr.AccountID())
+		require.NoError(t, err)
+		outs, err := txutils.ParseAndSortOutputData(oData, nil)
+		require.NoError(t, err)
+		return outs
+	}
+
+	txBytes, err := txbuilder.MakeTokenIssueTransaction(&txbuilder.TokenIssueData{
+		ChainOutput: getChainOutput(),
+		PrivateKey:  privKeys[2],
+		Target:      addrs[0],
+		Amount:      1_000,
+	})
+	require.NoError(t, err)
+	require.NoError(t, u.AddTransaction(txBytes))
+	require.EqualValues(t, 1_000, tokenBalance(addrs[0]))
+
+	t.Run("transfer", func(t *testing.T) {
+		txBytes, err := txbuilder.MakeTokenTransferTransaction(&txbuilder.TokenTransferData{
+			Inputs:     accountOutputs(addrs[0]),
+			PrivateKey: privKeys[0],
+			TokenID:    tokenID,
+			Target:     addrs[1],
+			Amount:     300,
+		})
+		require.NoError(t, err)
+		require.NoError(t, u.AddTransaction(txBytes))
+		require.EqualValues(t, 700, tokenBalance(addrs[0]))
+		require.EqualValues(t, 300, tokenBalance(addrs[1]))
+
+		_, err = txbuilder.MakeTokenTransferTransaction(&txbuilder.TokenTransferData{
+			Inputs:     accountOutputs(addrs[1]),
+			PrivateKey: privKeys[1],
+			TokenID:    tokenID,
+			Target:     addrs[0],
+			Amount:     301,
+		})
+		require.Error(t, err)
+	})
+	t.Run("base transfer does not consume tokens", func(t *testing.T) {
+		err := u.TransferTokens(privKeys[0], addrs[1], u.Balance(addrs[0])-u.Balance(addrs[0])/2)
+		require.NoError(t, err)
+		require.EqualValues(t, 700, tokenBalance(addrs[0]))
+	})
+	t.Run("minting without issuer fails", func(t *testing.T) {
+		inputs := accountOutputs(addrs[1])
+		txb := txbuilder.NewTransactionBuilder()
+		total, inputTs, err := txb.ConsumeOutputs(inputs...)
+		require.NoError(t, err)
+		_, err = txb.ProduceOutput(ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(total).WithLock(addrs[1]).WithToken(tokenID, 1_000_000)
+		}))
+		require.NoError(t, err)
+		txb.PutSignatureUnlock(0)
+		for i := 1; i < len(inputs); i++ {
+			require.NoError(t, txb.PutUnlockReference(byte(i), ledger.ConstraintIndexLock, 0))
+		}
+		txb.TransactionData.Timestamp = inputTs.AddTicks(ledger.TransactionPace())
+		txb.TransactionData.InputCommitment = txb.InputCommitment()
+		txb.SignED25519(privKeys[1])
+		err = u.AddTransaction(txb.TransactionData.Bytes())
+		require.Error(t, err)
+		t.Logf("expected error: %v", err)
+		require.EqualValues(t, 300, tokenBalance(addrs[1]))
+	})
+	t.Run("issuer mints more", func(t *testing.T) {
+		txBytes, err := txbuilder.MakeTokenIssueTransaction(&txbuilder.TokenIssueData{
+			ChainOutput: getChainOutput(),
+			PrivateKey:  privKeys[2],
+			Target:      addrs[1],
+			Amount:      50,
+		})
+		require.NoError(t, err)
+		require.NoError(t, u.AddTransaction(txBytes))
+		require.EqualValues(t, 350, tokenBalance(addrs[1]))
+	})
+}
+
+func TestLibraryUpgrade(t *testing.T) {
+	u := utxodb.NewUTXODB(genesisPrivateKey, true)
+	privKeys, _, addrs := u.GenerateAddressesWithFaucetAmount(0, 1, 10_000)
+	controllerKey, _ := u.GenesisKeys()
+	err := u.TokensFromFaucet(u.GenesisControllerAddress(), 10_000)
+	require.NoError(t, err)
+
+	ts := ledger.TimeNow()
+	// the transaction may be timestamped in the next slot after ts
+	activation := ts.Slot() + ledger.LibraryUpgradeMinActivationSlots + 1
+	const upgradeSource = `
+// the lock is an alias of the ED25519 address lock
+func _testUpgradeLock : addressED25519($0)
+`
+	makeUpgrade := func(funCode uint16) *ledger.LibraryUpgrade {
+		return ledger.NewLibraryUpgrade(activation, funCode, upgradeSource, "_testUpgradeLock")
+	}
+	upgradeTx := func(privKey ed25519.PrivateKey, upgrade *ledger.LibraryUpgrade) error {
+		par, err := u.MakeTransferInputData(privKey, nil, ts)
+		require.NoError(t, err)
+		txBytes, err := txbuilder.MakeLibraryUpgradeTransaction(par, upgrade)
+		require.NoError(t, err)
+		return u.AddTransaction(txBytes)
+	}
+	t.Run("not genesis controller", func(t *testing.T) {
+		err := upgradeTx(privKeys[0], makeUpgrade(u.Library().NextFunCode()))
+		util.RequireErrorWith(t, err, "library upgrade must be signed by genesis controller")
+	})
+	t.Run("wrong function code", func(t *testing.T) {
+		err := upgradeTx(controllerKey, makeUpgrade(u.Library().NextFunCode()+1))
+		util.RequireErrorWith(t, err, "library upgrade must start from function code")
+	})
+	t.Run("activation too early", func(t *testing.T) {
+		early := ledger.NewLibraryUpgrade(ts.Slot()+3, u.Library().NextFunCode(), upgradeSource, "_testUpgradeLock")
+		err := upgradeTx(controllerKey, early)
+		util.RequireErrorWith(t, err, "must be activated at least")
+	})
+
+	upgrade := makeUpgrade(u.Library().NextFunCode())
+	err = upgradeTx(controllerKey, upgrade)
+	require.NoError(t, err)
+	upgrades, err := u.StateReader().GetLibraryUpgrades()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, len(upgrades))
+	require.True(t, bytes.Equal(upgrade.Bytes(), upgrades[0].Bytes()))
+	require.EqualValues(t, 1, len(u.Library().Upgrades()))
+	slot, isUpgrade := u.Library().ActivationSlot("_testUpgradeLock")
+	require.True(t, isUpgrade)
+	require.EqualValues(t, activation, slot)
+
+	lock, err := ledger.LockFromSource(fmt.Sprintf("_testUpgradeLock(0x%s)", hex.EncodeToString(addrs[0])))
+	require.NoError(t, err)
+	require.EqualValues(t, "_testUpgradeLock", lock.Name())
+	t.Logf("upgrade lock: %s", lock.String())
+
+	t.Run("before activation", func(t *testing.T) {
+		par, err := u.MakeTransferInputData(privKeys[0], nil, ts)
+		require.NoError(t, err)
+		_, err = u.DoTransferTx(par.WithAmount(1_000).WithTargetLock(lock))
+		util.RequireErrorWith(t, err, "is not active until slot")
+	})
+	t.Run("after activation", func(t *testing.T) {
+		par, err := u.MakeTransferInputData(privKeys[0], nil, ledger.MustNewLedgerTime(activation, 0))
+		require.NoError(t, err)
+		_, err = u.DoTransferTx(par.WithAmount(1_000).WithTargetLock(lock))
+		require.NoError(t, err)
+	})
+	t.Run("repeated upgrade", func(t *testing.T) {
+		// same upgrade is ignored
+		err := upgradeTx(controllerKey, upgrade)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, len(u.Library().Upgrades()))
+		// another upgrade with the same function code is rejected
+		other := ledger.NewLibraryUpgrade(activation+1, upgrade.FirstFunCode, upgradeSource, "_testUpgradeLock")
+		err = upgradeTx(controllerKey, other)
+		util.RequireErrorWith(t, err, "library upgrade must start from function code")
+	})
+	t.Run("validation does not depend on global library", func(t *testing.T) {
+		next := ledger.NewLibraryUpgrade(activation+1, u.Library().NextFunCode(), "func _testUpgradeNext : concat($0,$0)")
+		par, err := u.MakeTransferInputData(controllerKey, nil, ts)
+		require.NoError(t, err)
+		txBytes, err := txbuilder.MakeLibraryUpgradeTransaction(par, next)
+		require.NoError(t, err)
+
+		// node which applied the upgrade to the global library
+		require.EqualValues(t, 1, len(ledger.L().Upgrades()))
+		ctx, err := u.ValidationContextFromTransaction(txBytes)
+		require.NoError(t, err)
+		require.NoError(t, ctx.Validate())
+
+		// node which did not apply the upgrade to the global library yet validates the same way
+		require.NoError(t, ledger.ApplyLibraryUpgrades())
+		require.EqualValues(t, 0, len(ledger.L().Upgrades()))
+		ctx, err = u.ValidationContextFromTransaction(txBytes)
+		require.NoError(t, err)
+		require.NoError(t, ctx.Validate())
+
+		// with the global library instead of the library of the state the result would be different
+		ctx, err = transaction2.TxContextFromTransferableBytes(txBytes, u.StateReader().GetUTXO)
+		require.NoError(t, err)
+		util.RequireErrorWith(t, ctx.Validate(), "library upgrade must start from function code")
+
+		require.NoError(t, u.AddTransaction(txBytes))
+		require.EqualValues(t, 2, len(u.Library().Upgrades()))
+		require.EqualValues(t, 2, len(ledger.L().Upgrades()))
+	})
+}
+
+func TestGenericLock(t *testing.T) {
+	t.Run("built-in locks", func(t *testing.T) {
+		pub, _, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		claim, refund := ledger.AddressED25519FromPublicKey(pub), ledger.AddressED25519Null()
+		htlc := ledger.NewHTLCLock(ledger.MustNewLedgerTime(100, 5), ledger.HTLCHash([]byte("secret")), claim, refund)
+		accounts, err := ledger.L().LockAccounts(htlc.Name(), htlc.Bytes())
+		require.NoError(t, err)
+		require.EqualValues(t, 2, len(accounts))
+		require.True(t, ledger.EqualAccountIDs(accounts[0], claim.AccountID()))
+		require.True(t, ledger.EqualAccountIDs(accounts[1], refund.AccountID()))
+
+		unlockable, err := ledger.L().LockUnlockableWith(htlc.Name(), htlc.Bytes(), refund.AccountID(), ledger.MustNewLedgerTime(101, 0))
+		require.NoError(t, err)
+		require.True(t, unlockable)
+		unlockable, err = ledger.L().LockUnlockableWith(htlc.Name(), htlc.Bytes(), refund.AccountID(), ledger.MustNewLedgerTime(99, 0))
+		require.NoError(t, err)
+		require.False(t, unlockable)
+	})
+	t.Run("lock defined by upgrade", func(t *testing.T) {
+		u := utxodb.NewUTXODB(genesisPrivateKey, true)
+		privKeys, _, addrs := u.GenerateAddressesWithFaucetAmount(0, 2, 10_000)
+		controllerKey, _ := u.GenesisKeys()
+		err := u.TokensFromFaucet(u.GenesisControllerAddress(), 10_000)
+		require.NoError(t, err)
+
+		ts := ledger.TimeNow()
+		activation := ts.Slot() + ledger.LibraryUpgradeMinActivationSlots + 1
+		// node binary knows nothing about the lock. Accounts and unlocking are defined by the convention functions
+		const upgradeSource = `
+func _testGenericLock : addressED25519($0)
+func _testGenericLockOwner : ed25519AccountID(unwrapBytecodeArg($0, #_testGenericLock, 0))
+func _testGenericLockAccounts : lockAccount(_testGenericLockOwner($0))
+func _testGenericLockUnlockableWith : equal($1, _testGenericLockOwner($0))
+`
+		par, err := u.MakeTransferInputData(controllerKey, nil, ts)
+		require.NoError(t, err)
+		txBytes, err := txbuilder.MakeLibraryUpgradeTransaction(par, ledger.NewLibraryUpgrade(activation, u.Library().NextFunCode(), upgradeSource))
+		require.NoError(t, err)
+		err = u.AddTransaction(txBytes)
+		require.NoError(t, err)
+
+		lock, err := ledger.LockFromSource(fmt.Sprintf("_testGenericLock(0x%s)", hex.EncodeToString(addrs[0])))
+		require.NoError(t, err)
+		require.EqualValues(t, "_testGenericLock", lock.Name())
+		require.EqualValues(t, 1, len(lock.Accounts()))
+		require.True(t, ledger.EqualConstraints(addrs[0], lock.Accounts()[0]))
+		require.True(t, lock.UnlockableWith(addrs[0].AccountID()))
+		require.False(t, lock.UnlockableWith(addrs[1].AccountID()))
+
+		par, err = u.MakeTransferInputData(privKeys[1], nil, ledger.MustNewLedgerTime(activation, 0))
+		require.NoError(t, err)
+		outs, err := u.DoTransferOutputs(par.WithAmount(1_000).WithTargetLock(lock))
+		require.NoError(t, err)
+
+		// the output is indexed in the account of the owner
+		outsData, err := u.StateReader().GetUTXOsLockedInAccount(addrs[0].AccountID())
+		require.NoError(t, err)
+		lockedOuts, err := txutils.ParseAndSortOutputData(outsData, func(o *ledger.Output) bool {
+			return ledger.EqualConstraints(o.Lock(), lock)
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 1, len(lockedOuts))
+		require.True(t, slices.ContainsFunc(outs, func(o *ledger.OutputWithID) bool {
+			return o.ID == lockedOuts[0].ID
+		}))
+
+		// the owner consumes the output by signing the transaction
+		txb := txbuilder.NewTransactionBuilder()
+		_, inputTs, err := txb.ConsumeOutputs(lockedOuts...)
+		require.NoError(t, err)
+		_, err = txb.ProduceOutput(ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(lockedOuts[0].Output.Amount()).WithLock(addrs[1])
+		}))
+		require.NoError(t, err)
+		txb.PutSignatureUnlock(0)
+		txb.TransactionData.Timestamp = inputTs.AddTicks(ledger.TransactionPace())
+		txb.TransactionData.InputCommitment = txb.InputCommitment()
+		txb.SignED25519(privKeys[0])
+		err = u.AddTransaction(txb.TransactionData.Bytes())
+		require.NoError(t, err)
+
+		outsData, err = u.StateReader().GetUTXOsLockedInAccount(addrs[0].AccountID())
+		require.NoError(t, err)
+		require.EqualValues(t, 1, len(outsData))
+	})
+	t.Run("lock with failing accounts function", func(t *testing.T) {
+		u := utxodb.NewUTXODB(genesisPrivateKey, true)
+		privKeys, _, addrs := u.GenerateAddressesWithFaucetAmount(0, 1, 10_000)
+		controllerKey, _ := u.GenesisKeys()
+		err := u.TokensFromFaucet(u.GenesisControllerAddress(), 10_000)
+		require.NoError(t, err)
+
+		ts := ledger.TimeNow()
+		activation := ts.Slot() + ledger.LibraryUpgradeMinActivationSlots + 1
+		// empty account ID is not a valid list of accounts
+		const upgradeSource = `
+func _testBadLock : addressED25519($0)
+func _testBadLockAccounts : 0x00
+`
+		par, err := u.MakeTransferInputData(controllerKey, nil, ts)
+		require.NoError(t, err)
+		txBytes, err := txbuilder.MakeLibraryUpgradeTransaction(par, ledger.NewLibraryUpgrade(activation, u.Library().NextFunCode(), upgradeSource, "_testBadLock"))
+		require.NoError(t, err)
+		err = u.AddTransaction(txBytes)
+		require.NoError(t, err)
+
+		lock, err := ledger.LockFromSource(fmt.Sprintf("_testBadLock(0x%s)", hex.EncodeToString(addrs[0])))
+		require.NoError(t, err)
+		require.EqualValues(t, 0, len(lock.Accounts()))
+
+		par, err = u.MakeTransferInputData(privKeys[0], nil, ledger.MustNewLedgerTime(activation, 0))
+		require.NoError(t, err)
+		_, err = u.DoTransferTx(par.WithAmount(1_000).WithTargetLock(lock))
+		util.RequireErrorWith(t, err, "can't evaluate accounts of the lock")
+	})
+	t.Run("constraint without lock conventions is not a lock", func(t *testing.T) {
+		_, err := ledger.LockFromBytes(ledger.NewAmount(1_000).Bytes())
+		util.RequireErrorWith(t, err, "is not a lock")
+		_, err = ledger.LockFromBytes(ledger.NewChainOrigin().Bytes())
+		util.RequireErrorWith(t, err, "is not a lock")
+	})
+	t.Run("conventions can't be added to existing lock", func(t *testing.T) {
+		u := utxodb.NewUTXODB(genesisPrivateKey, true)
+		controllerKey, _ := u.GenesisKeys()
+		err := u.TokensFromFaucet(u.GenesisControllerAddress(), 10_000)
+		require.NoError(t, err)
+
+		ts := ledger.TimeNow()
+		activation := ts.Slot() + ledger.LibraryUpgradeMinActivationSlots + 1
+		par, err := u.MakeTransferInputData(controllerKey, nil, ts)
+		require.NoError(t, err)
+		txBytes, err := txbuilder.MakeLibraryUpgradeTransaction(par,
+			ledger.NewLibraryUpgrade(activation, u.Library().NextFunCode(), "func _testLaterLock : addressED25519($0)", "_testLaterLock"))
+		require.NoError(t, err)
+		require.NoError(t, u.AddTransaction(txBytes))
+
+		// accounts of outputs already locked with the lock would change
+		par, err = u.MakeTransferInputData(controllerKey, nil, ts)
+		require.NoError(t, err)
+		txBytes, err = txbuilder.MakeLibraryUpgradeTransaction(par,
+			ledger.NewLibraryUpgrade(activation, u.Library().NextFunCode(), "func _testLaterLockAccounts : lockAccount(0x00)"))
+		require.NoError(t, err)
+		err = u.AddTransaction(txBytes)
+		util.RequireErrorWith(t, err, "must be defined in the same upgrade as the lock")
+	})
+}
+
+func TestTxLocalLibraries(t *testing.T) {
+	t.Run("costs", func(t *testing.T) {
+		const source = `
+ func fun1 : concat($0,$1)
+ func fun2 : fun1(fun1($0,$1), fun1($0,$1))
+`
+		libBin, err := ledger.L().CompileLocalLibrary(source)
+		require.NoError(t, err)
+		costs, err := ledger.L().LocalLibraryCosts(libBin)
+		require.NoError(t, err)
+		require.EqualValues(t, []int{3, 16}, costs)
+
+		// each function doubles the cost of the previous one
+		var expensive strings.Builder
+		expensive.WriteString("func f0 : concat($0,$0)\n")
+		for i := 1; i < 16; i++ {
+			expensive.WriteString(fmt.Sprintf("func f%d : f%d(f%d($0))\n", i, i-1, i-1))
+		}
+		libBin, err = ledger.L().CompileLocalLibrary(expensive.String())
+		require.NoError(t, err)
+		_, err = ledger.L().LocalLibraryCosts(libBin)
+		util.RequireErrorWith(t, err, "evaluation cost exceeds limit")
+
+		// local wrapper of the library function costs at least as much as the direct call of it
+		libBin, err = ledger.L().CompileLocalLibrary("func wrapper : lockAccount($0)")
+		require.NoError(t, err)
+		costs, err = ledger.L().LocalLibraryCosts(libBin)
+		require.NoError(t, err)
+		direct := ledger.L().FunctionCost("lockAccount")
+		require.True(t, direct > 1)
+		require.True(t, costs[0] >= direct+1)
+
+		libBin, err = ledger.L().CompileLocalLibrary(expensive.String())
+		require.NoError(t, err)
+		txb := txbuilder.NewTransactionBuilder()
+		_, err = txb.PushLocalLibrary(libBin)
+		util.RequireErrorWith(t, err, "evaluation cost exceeds limit")
+		_, err = txb.PushLocalLibrary([]byte("not a library"))
+		require.Error(t, err)
+	})
+	t.Run("call from constraint", func(t *testing.T) {
+		libBin, err := ledger.L().CompileLocalLibrary("func unlock : and")
+		require.NoError(t, err)
+
+		u := utxodb.NewUTXODB(genesisPrivateKey, true)
+		privKey0, _, addr0 := u.GenerateAddress(0)
+		err = u.TokensFromFaucet(addr0, 10000)
+		require.NoError(t, err)
+
+		// the output can be consumed only by the transaction which carries the local library
+		constr, err := ledger.NewGeneralScriptFromSource("or(isPathToProducedOutput(@),callLocalLibrary(txLocalLibrary(0), 0))")
+		require.NoError(t, err)
+		par, err := u.MakeTransferInputData(privKey0, nil, ledger.NilLedgerTime)
+		require.NoError(t, err)
+		outs, err := u.DoTransferOutputs(par.WithAmount(1000).WithTargetLock(addr0).WithConstraint(constr))
+		require.NoError(t, err)
+		outs = txutils.FilterOutputsSortByAmount(outs, func(o *ledger.Output) bool {
+			return o.Amount() == 1000
+		})
+		require.EqualValues(t, 1, len(outs))
+
+		par = txbuilder.NewTransferData(privKey0, addr0, ledger.NilLedgerTime)
+		par.MustWithInputs(outs...).
+			WithAmount(1000).
+			WithTargetLock(addr0)
+		err = u.DoTransfer(par)
+		require.Error(t, err)
+		txBytesNoLib, err := txbuilder.MakeTransferTransaction(par)
+		require.NoError(t, err)
+		txNoLib, err := transaction2.FromBytes(txBytesNoLib)
+		require.NoError(t, err)
+
+		par.WithLocalLibrary(libBin)
+		txBytes, err := txbuilder.MakeTransferTransaction(par)
+		require.NoError(t, err)
+		tx, err := transaction2.FromBytes(txBytes, transaction2.MainTxValidationOptions...)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, tx.NumLocalLibraries())
+		require.True(t, bytes.Equal(libBin, tx.LocalLibraries()[0]))
+
+		// local libraries are signed. Essence of the transaction without local libraries does not contain them
+		libsBin := lazybytes.MakeArrayFromDataReadOnly(libBin).Bytes()
+		require.True(t, bytes.HasSuffix(tx.EssenceBytes(), libsBin))
+		require.EqualValues(t, len(tx.EssenceBytes())-len(libsBin), len(txNoLib.EssenceBytes()))
+
+		err = u.AddTransaction(txBytes)
+		require.NoError(t, err)
+	})
+	t.Run("limits", func(t *testing.T) {
+		libBin, err := ledger.L().CompileLocalLibrary("func unlock : and")
+		require.NoError(t, err)
+
+		txb := txbuilder.NewTransactionBuilder()
+		for i := 0; i < ledger.MaxNumberOfLocalLibraries; i++ {
+			idx, err := txb.PushLocalLibrary(libBin)
+			require.NoError(t, err)
+			require.EqualValues(t, i, idx)
+		}
+		_, err = txb.PushLocalLibrary(libBin)
+		util.RequireErrorWith(t, err, "too many local libraries")
+
+		libs := make([][]byte, ledger.MaxNumberOfLocalLibraries)
+		for i := range libs {
+			libs[i] = libBin
+		}
+		require.NoError(t, ledger.L().ValidateLocalLibraries(libs))
+		err = ledger.L().ValidateLocalLibraries(append(libs, libBin))
+		util.RequireErrorWith(t, err, "number of local libraries")
+
+		big := make([]byte, ledger.MaxLocalLibrariesSize+1)
+		err = ledger.L().ValidateLocalLibraries([][]byte{big})
+		util.RequireErrorWith(t, err, "size of local libraries")
+	})
+}
+
+func TestExecutionCost(t *testing.T) {
+	t.Run("function costs", func(t *testing.T) {
+		require.EqualValues(t, 1, ledger.L().FunctionCost("concat"))
+		// sizeIs : equal(len8($0), $1)
+		require.EqualValues(t, 5, ledger.L().FunctionCost("sizeIs"))
+	})
+	t.Run("budget", func(t *testing.T) {
+		code, _, err := ledger.L().ExpressionSourceToBinary("concat(1,sizeIs(2,1))")
+		require.NoError(t, err)
+
+		ctx := ledger.NewDataContext(nil)
+		res, cost, err := ledger.L().EvalFromBinaryWithCost(easyfl.NewGlobalDataNoTrace(ctx), code)
+		require.NoError(t, err)
+		require.EqualValues(t, []byte{1, 0xff}, res)
+		// concat + 1 + sizeIs + 2 + 1
+		require.EqualValues(t, 9, cost)
+		require.EqualValues(t, 9, ctx.ExecutionCost())
+
+		ctx = ledger.NewDataContext(nil)
+		ctx.SetExecutionBudget(8)
+		_, _, err = ledger.L().EvalFromBinaryWithCost(easyfl.NewGlobalDataNoTrace(ctx), code)
+		require.True(t, errors.Is(err, ledger.ErrExecutionBudgetExceeded))
+	})
+	t.Run("local library parsed once", func(t *testing.T) {
+		libBin, err := ledger.L().CompileLocalLibrary("func f : concat($0,$1)")
+		require.NoError(t, err)
+		parseCost := uint64(len(libBin) * ledger.LocalLibraryParseCostPerByte)
+
+		ctx := ledger.NewDataContext(nil)
+		require.NoError(t, ctx.ParseLocalLibraries([][]byte{libBin, libBin}))
+		require.EqualValues(t, parseCost, ctx.ExecutionCost())
+
+		ctx = ledger.NewDataContext(nil)
+		ctx.SetExecutionBudget(parseCost - 1)
+		err = ctx.ParseLocalLibraries([][]byte{libBin})
+		require.True(t, errors.Is(err, ledger.ErrExecutionBudgetExceeded))
+
+		// library which is not parsed in advance is charged with the parse cost at the first call only
+		code, _, err := ledger.L().ExpressionSourceToBinary(fmt.Sprintf("callLocalLibrary(0x%s,0,1,2)", hex.EncodeToString(libBin)))
+		require.NoError(t, err)
+		ctx = ledger.NewDataContext(nil)
+		res, cost1, err := ledger.L().EvalFromBinaryWithCost(easyfl.NewGlobalDataNoTrace(ctx), code)
+		require.NoError(t, err)
+		require.EqualValues(t, []byte{1, 2}, res)
+		_, cost2, err := ledger.L().EvalFromBinaryWithCost(easyfl.NewGlobalDataNoTrace(ctx), code)
+		require.NoError(t, err)
+		require.EqualValues(t, parseCost, cost1-cost2)
+	})
+	t.Run("tx budget exceeded", func(t *testing.T) {
+		// cost of each function is 2 * cost of the previous + 3. Cost of f10 is 6141
+		var src strings.Builder
+		src.WriteString("func f0 : equal($0,$0)\n")
+		for i := 1; i <= 10; i++ {
+			src.WriteString(fmt.Sprintf("func f%d : f%d(f%d($0))\n", i, i-1, i-1))
+		}
+		libBin, err := ledger.L().CompileLocalLibrary(src.String())
+		require.NoError(t, err)
+		costs, err := ledger.L().LocalLibraryCosts(libBin)
+		require.NoError(t, err)
+		require.EqualValues(t, 6141, costs[10])
+
+		u := utxodb.NewUTXODB(genesisPrivateKey, true)
+		privKey0, _, addr0 := u.GenerateAddress(0)
+		err = u.TokensFromFaucet(addr0, 10000)
+		require.NoError(t, err)
+
+		// small constraint with 30 calls of the expensive local function
+		calls := make([]string, 15)
+		for i := range calls {
+			calls[i] = "callLocalLibrary(txLocalLibrary(0), 10, 0)"
+		}
+		and15 := "and(" + strings.Join(calls, ",") + ")"
+		constr, err := ledger.NewGeneralScriptFromSource("or(isPathToProducedOutput(@),and(" + and15 + "," + and15 + "))")
+		require.NoError(t, err)
+		par, err := u.MakeTransferInputData(privKey0, nil, ledger.NilLedgerTime)
+		require.NoError(t, err)
+		outs, err := u.DoTransferOutputs(par.WithAmount(1000).WithTargetLock(addr0).WithConstraint(constr))
+		require.NoError(t, err)
+		outs = txutils.FilterOutputsSortByAmount(outs, func(o *ledger.Output) bool {
+			return o.Amount() == 1000
+		})
+		require.EqualValues(t, 1, len(outs))
+
+		par = txbuilder.NewTransferData(privKey0, addr0, ledger.NilLedgerTime)
+		par.MustWithInputs(outs...).
+			WithAmount(1000).
+			WithTargetLock(addr0).
+			WithLocalLibrary(libBin)
+		txBytes, err := txbuilder.MakeTransferTransaction(par)
+		require.NoError(t, err)
+		require.Less(t, ledger.L().ID.ExecutionBudget(len(txBytes)), uint64(30*6141))
+
+		err = u.AddTransaction(txBytes)
+		util.RequireErrorWith(t, err, "execution budget exceeded")
+	})
+}
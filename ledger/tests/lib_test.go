@@ -7,6 +7,7 @@ import (
 
 	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -113,3 +114,58 @@ func TestLedgerIDYAML(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, id.Bytes(), idBack.Bytes())
 }
+
+func TestLedgerLibraryData(t *testing.T) {
+	libData := ledger.L().LibraryData()
+	t.Logf("%s", libData.String())
+	// identity as committed at genesis
+	idWithHash := *ledger.L().ID
+	idWithHash.LibraryHash = libData.Hash()
+
+	t.Run("identity is not modified", func(t *testing.T) {
+		// identity without library hash keeps its original serialization and ledger ID hash
+		id, _ := ledger.GetTestingIdentityData(31415926535)
+		id.GenesisTimeUnix = ledger.L().ID.GenesisTimeUnix
+		require.EqualValues(t, [32]byte{}, ledger.L().ID.LibraryHash)
+		require.EqualValues(t, id.Bytes(), ledger.L().ID.Bytes())
+		require.EqualValues(t, id.Hash(), ledger.L().ID.Hash())
+	})
+
+	t.Run("serialization", func(t *testing.T) {
+		libBack, err := ledger.LibraryDataFromBytes(libData.Bytes())
+		require.NoError(t, err)
+		require.EqualValues(t, libData.Bytes(), libBack.Bytes())
+		require.EqualValues(t, libData.Hash(), libBack.Hash())
+
+		idBack := ledger.MustLedgerIdentityDataFromBytes(idWithHash.Bytes())
+		require.EqualValues(t, libData.Hash(), idBack.LibraryHash)
+	})
+	t.Run("library from data", func(t *testing.T) {
+		lib, err := ledger.LibraryFromData(&idWithHash, libData.Bytes())
+		require.NoError(t, err)
+		require.EqualValues(t, libData.Bytes(), lib.LibraryData().Bytes())
+
+		addr := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey())
+		htlc := ledger.NewHTLCLock(ledger.MustNewLedgerTime(1337, 5), ledger.HTLCHash([]byte("secret")), addr, addr)
+		for _, constr := range []ledger.Constraint{addr, ledger.ChainLockFromChainID(ledger.RandomChainID()), htlc} {
+			src, err := lib.DecompileBytecode(constr.Bytes())
+			require.NoError(t, err)
+			srcExpected, err := ledger.L().DecompileBytecode(constr.Bytes())
+			require.NoError(t, err)
+			require.EqualValues(t, srcExpected, src)
+		}
+	})
+	t.Run("tampered library", func(t *testing.T) {
+		libBack, err := ledger.LibraryDataFromBytes(libData.Bytes())
+		require.NoError(t, err)
+		f := libBack.Functions[len(libBack.Functions)-1]
+		require.EqualValues(t, ledger.LibraryFunctionExtended, f.Kind)
+		f.Source = "concat(" + f.Source + ")"
+
+		_, err = ledger.LibraryFromData(&idWithHash, libBack.Bytes())
+		util.RequireErrorWith(t, err, "library hash does not match")
+
+		err = ledger.InitFromLibraryData(&idWithHash, libBack.Bytes())
+		util.RequireErrorWith(t, err, "does not match library hash")
+	})
+}
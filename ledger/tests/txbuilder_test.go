@@ -211,3 +211,46 @@ func TestManyInputs(t *testing.T) {
 
 	require.EqualValues(t, 1, u.NumUTXOs(addr0))
 }
+
+func TestOfflineSigning(t *testing.T) {
+	u := utxodb.NewUTXODB(genesisPrivateKey, true)
+	privKeys, _, addrs := u.GenerateAddressesWithFaucetAmount(0, 2, 10_000)
+	ts := ledger.TimeNow()
+
+	par, err := u.MakeTransferInputData(privKeys[0], nil, ts)
+	require.NoError(t, err)
+	signedTx, err := txbuilder.MakeTransferTransaction(par.WithAmount(2_000).WithTargetLock(addrs[1]).WithSender())
+	require.NoError(t, err)
+
+	parUnsigned, err := u.MakeTransferInputData(privKeys[0], nil, ts)
+	require.NoError(t, err)
+	parUnsigned.SenderPrivateKey, parUnsigned.SenderPublicKey = nil, nil
+	unsignedTx, err := txbuilder.MakeTransferTransaction(parUnsigned.WithAmount(2_000).WithTargetLock(addrs[1]).WithSender())
+	require.NoError(t, err)
+	require.NotEqualValues(t, signedTx, unsignedTx)
+
+	essence1, err := txbuilder.EssenceBytesFromTransactionBytes(signedTx)
+	require.NoError(t, err)
+	essence2, err := txbuilder.EssenceBytesFromTransactionBytes(unsignedTx)
+	require.NoError(t, err)
+	require.EqualValues(t, essence1, essence2)
+
+	err = u.AddTransaction(unsignedTx)
+	require.Error(t, err)
+
+	// signing with another key produces valid signature, but the transaction fails to unlock inputs
+	wrongSigned, err := txbuilder.SignTransactionBytes(unsignedTx, privKeys[1])
+	require.NoError(t, err)
+	err = u.AddTransaction(wrongSigned)
+	require.Error(t, err)
+
+	// ED25519 signatures are deterministic
+	offlineSigned, err := txbuilder.SignTransactionBytes(unsignedTx, privKeys[0])
+	require.NoError(t, err)
+	require.EqualValues(t, signedTx, offlineSigned)
+
+	err = u.AddTransaction(offlineSigned)
+	require.NoError(t, err)
+	require.EqualValues(t, 8_000, u.Balance(addrs[0]))
+	require.EqualValues(t, 12_000, u.Balance(addrs[1]))
+}
@@ -0,0 +1,186 @@
+package ledger
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/lunfardo314/easyfl"
+	"github.com/lunfardo314/proxima/util"
+)
+
+// Token constraint makes the output a carrier of the native token amount in addition to the base tokens.
+// The TokenID is derived from the chain ID of the issuer. Native tokens are conserved by the transaction:
+// sum of each token in produced outputs must be equal to the sum in consumed outputs,
+// unless the issuing chain is consumed by the transaction. In the latter case tokens can be minted or burned.
+// The conservation is checked by the transaction validation outside EasyFL constraints
+
+type (
+	TokenID [TokenIDLength]byte
+
+	Token struct {
+		ID     TokenID
+		Amount uint64
+	}
+)
+
+const (
+	TokenIDLength = ChainIDLength
+	TokenName     = "token"
+	tokenTemplate = TokenName + "(0x%s, u64/%d)"
+)
+
+// TokenIDFromChainID token ID of the chain is the chain ID itself. Each chain can issue exactly one token
+func TokenIDFromChainID(chainID ChainID) TokenID {
+	return TokenID(chainID)
+}
+
+func TokenIDFromBytes(data []byte) (ret TokenID, err error) {
+	if len(data) != TokenIDLength {
+		err = fmt.Errorf("TokenIDFromBytes: wrong data length %d", len(data))
+		return
+	}
+	copy(ret[:], data)
+	return
+}
+
+func TokenIDFromHexString(str string) (ret TokenID, err error) {
+	data, err := hex.DecodeString(str)
+	if err != nil {
+		return TokenID{}, err
+	}
+	return TokenIDFromBytes(data)
+}
+
+// IssuerChainID chain ID of the issuer of the token
+func (id *TokenID) IssuerChainID() ChainID {
+	return ChainID(*id)
+}
+
+func (id *TokenID) Bytes() []byte {
+	return id[:]
+}
+
+func (id *TokenID) String() string {
+	return fmt.Sprintf("#/%s", hex.EncodeToString(id[:]))
+}
+
+func (id *TokenID) StringHex() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id *TokenID) StringShort() string {
+	return fmt.Sprintf("#/%s..", hex.EncodeToString(id[:6]))
+}
+
+func NewToken(id TokenID, amount uint64) *Token {
+	return &Token{
+		ID:     id,
+		Amount: amount,
+	}
+}
+
+func TokenFromBytes(data []byte) (*Token, error) {
+	sym, _, args, err := L().ParseBytecodeOneLevel(data, 2)
+	if err != nil {
+		return nil, err
+	}
+	if sym != TokenName {
+		return nil, fmt.Errorf("not a token")
+	}
+	id, err := TokenIDFromBytes(easyfl.StripDataPrefix(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	amountBin := easyfl.StripDataPrefix(args[1])
+	if len(amountBin) != 8 {
+		return nil, fmt.Errorf("wrong token amount")
+	}
+	return NewToken(id, binary.BigEndian.Uint64(amountBin)), nil
+}
+
+func (t *Token) source() string {
+	return fmt.Sprintf(tokenTemplate, hex.EncodeToString(t.ID[:]), t.Amount)
+}
+
+func (t *Token) Bytes() []byte {
+	return mustBinFromSource(t.source())
+}
+
+func (t *Token) Name() string {
+	return TokenName
+}
+
+func (t *Token) String() string {
+	return t.source()
+}
+
+func addTokenConstraint(lib *Library) {
+	lib.extendWithConstraint(TokenName, tokenSource, 2, func(data []byte) (Constraint, error) {
+		return TokenFromBytes(data)
+	}, initTestTokenConstraint)
+}
+
+func initTestTokenConstraint() {
+	example := NewToken(TokenIDFromChainID(RandomChainID()), 1337)
+	tokenBack, err := TokenFromBytes(example.Bytes())
+	util.AssertNoError(err)
+	util.Assertf(tokenBack.ID == example.ID, "inconsistency "+TokenName)
+	util.Assertf(tokenBack.Amount == 1337, "inconsistency "+TokenName)
+
+	_, err = L().ParseBytecodePrefix(example.Bytes())
+	util.AssertNoError(err)
+}
+
+// Token finds and parses token constraint. Returns its constraintIndex or 0xff if not found
+func (o *Output) Token() (*Token, byte) {
+	var ret *Token
+	var err error
+	found := byte(0xff)
+	o.ForEachConstraint(func(idx byte, constr []byte) bool {
+		if idx < ConstraintIndexFirstOptionalConstraint {
+			return true
+		}
+		ret, err = TokenFromBytes(constr)
+		if err == nil {
+			found = idx
+			return false
+		}
+		return true
+	})
+	if found != 0xff {
+		return ret, found
+	}
+	return nil, 0xff
+}
+
+func (o *Output) MustToken() *Token {
+	ret, idx := o.Token()
+	util.Assertf(idx != 0xff, "can't find token constraint")
+	return ret
+}
+
+// WithToken can only be used inside r/o override closure
+func (o *Output) WithToken(id TokenID, amount uint64) *Output {
+	_, err := o.PushConstraint(NewToken(id, amount).Bytes())
+	util.AssertNoError(err)
+	return o
+}
+
+const tokenSource = `
+// constraint token($0, $1) makes the output carrier of the native token
+// $0 - 32 bytes token ID, the chain ID of the issuer
+// $1 - 8 bytes amount of tokens, must be positive
+// Conservation of token amounts is enforced by the transaction validation
+
+func token : or(
+	selfIsConsumedOutput,  // the constraint is always satisfied on the 'consumed' side
+	and(
+		selfIsProducedOutput,
+		equal(len8($0), 32),
+		equal(len8($1), 8),
+		not(isZero($1))
+	),
+	!!!token_constraint_failed
+)
+`
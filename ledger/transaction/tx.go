@@ -55,6 +55,7 @@ var MainTxValidationOptions = []TxValidationOption{
 	CheckUniqueness(),
 	ScanOutputs(),
 	CheckSizeOfOutputCommitment(),
+	CheckLocalLibraries(),
 }
 
 func FromBytes(txBytes []byte, opt ...TxValidationOption) (*Transaction, error) {
@@ -386,6 +387,17 @@ func CheckSizeOfOutputCommitment() TxValidationOption {
 	}
 }
 
+// CheckLocalLibraries local libraries must be within limits. They are parsed in the validation context,
+// with the library of the baseline state
+func CheckLocalLibraries() TxValidationOption {
+	return func(tx *Transaction) error {
+		if err := ledger.ValidateLocalLibrariesLimits(tx.LocalLibraries()); err != nil {
+			return fmt.Errorf("tx %s: %w", tx.IDShortString(), err)
+		}
+		return nil
+	}
+}
+
 func ValidateOptionWithFullContext(inputLoaderByIndex func(i byte) (*ledger.Output, error)) TxValidationOption {
 	return func(tx *Transaction) error {
 		var ctx *TxContext
@@ -491,8 +503,11 @@ func (tx *Transaction) TotalAmount() uint64 {
 	return tx.totalAmount
 }
 
+// EssenceBytesFromTransactionDataTree returns bytes of the transaction which are signed.
+// Local libraries are only included if the transaction has them, so the essence of the transaction
+// without local libraries is the same as before local libraries were signed. Must be consistent with 'txEssenceBytes'
 func EssenceBytesFromTransactionDataTree(txTree *lazybytes.Tree) []byte {
-	return common.Concat(
+	ret := common.Concat(
 		txTree.BytesAtPath([]byte{ledger.TxInputIDs}),
 		txTree.BytesAtPath([]byte{ledger.TxOutputs}),
 		txTree.BytesAtPath([]byte{ledger.TxTimestamp}),
@@ -500,6 +515,10 @@ func EssenceBytesFromTransactionDataTree(txTree *lazybytes.Tree) []byte {
 		txTree.BytesAtPath([]byte{ledger.TxInputCommitment}),
 		txTree.BytesAtPath([]byte{ledger.TxEndorsements}),
 	)
+	if txTree.NumElements([]byte{ledger.TxLocalLibraries}) > 0 {
+		ret = common.Concat(ret, txTree.BytesAtPath([]byte{ledger.TxLocalLibraries}))
+	}
+	return ret
 }
 
 func (tx *Transaction) Bytes() []byte {
@@ -522,6 +541,19 @@ func (tx *Transaction) NumEndorsements() int {
 	return tx.tree.NumElements(Path(ledger.TxEndorsements))
 }
 
+func (tx *Transaction) NumLocalLibraries() int {
+	return tx.tree.NumElements(Path(ledger.TxLocalLibraries))
+}
+
+// LocalLibraries returns binaries of local libraries of the transaction
+func (tx *Transaction) LocalLibraries() [][]byte {
+	ret := make([][]byte, tx.NumLocalLibraries())
+	for i := range ret {
+		ret[i] = tx.tree.BytesAtPath(common.Concat(ledger.TxLocalLibraries, byte(i)))
+	}
+	return ret
+}
+
 func (tx *Transaction) MustOutputDataAt(idx byte) []byte {
 	return tx.tree.BytesAtPath(common.Concat(ledger.TxOutputs, idx))
 }
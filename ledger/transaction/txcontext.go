@@ -21,6 +21,8 @@ type TxContext struct {
 	inflationAmount uint64
 	// EasyFL constraint validation context
 	dataContext *ledger.DataContext
+	// library the transaction is validated with
+	lib *ledger.Library
 }
 
 var Path = lazybytes.Path
@@ -31,8 +33,18 @@ const (
 	TraceOptionFailedConstraints
 )
 
+// TxContextFromTransaction creates validation context of the transaction with the global library.
+// Transactions attached to the ledger must be validated with the library of the baseline state,
+// see TxContextFromTransactionWithLibrary
 func TxContextFromTransaction(tx *Transaction, inputLoaderByIndex func(i byte) (*ledger.Output, error), traceOption ...int) (*TxContext, error) {
+	return TxContextFromTransactionWithLibrary(tx, ledger.L(), inputLoaderByIndex, traceOption...)
+}
+
+// TxContextFromTransactionWithLibrary creates validation context of the transaction with the library, usually
+// the library of the state the transaction is validated against (see multistate.LibraryOfState)
+func TxContextFromTransactionWithLibrary(tx *Transaction, lib *ledger.Library, inputLoaderByIndex func(i byte) (*ledger.Output, error), traceOption ...int) (*TxContext, error) {
 	ret := &TxContext{
+		lib:             lib,
 		tree:            nil,
 		traceOption:     TraceOptionNone,
 		dataContext:     nil,
@@ -59,7 +71,11 @@ func TxContextFromTransaction(tx *Transaction, inputLoaderByIndex func(i byte) (
 	}
 	e := lazybytes.MakeArrayReadOnly(consumedOutputsArray) // one level deeper
 	ret.tree = lazybytes.TreeFromTreesReadOnly(tx.tree, e.AsTree())
-	ret.dataContext = ledger.NewDataContext(ret.tree)
+	ret.dataContext = ledger.NewDataContext(ret.tree, lib)
+	ret.dataContext.SetExecutionBudget(lib.ID.ExecutionBudget(len(tx.Bytes())))
+	if err := ret.dataContext.ParseLocalLibraries(tx.LocalLibraries()); err != nil {
+		return nil, fmt.Errorf("TxContextFromTransaction: %w", err)
+	}
 	return ret, nil
 }
 
@@ -87,6 +103,21 @@ func (ctx *TxContext) TransactionBytes() []byte {
 	return ctx.tree.BytesAtPath(Path(ledger.TransactionBranch))
 }
 
+// ExecutionCost total cost of constraints evaluated so far
+func (ctx *TxContext) ExecutionCost() uint64 {
+	return ctx.dataContext.ExecutionCost()
+}
+
+// ExecutionBudget maximum total cost of constraints of the transaction
+func (ctx *TxContext) ExecutionBudget() uint64 {
+	return ctx.lib.ID.ExecutionBudget(len(ctx.TransactionBytes()))
+}
+
+// Library the transaction is validated with
+func (ctx *TxContext) Library() *ledger.Library {
+	return ctx.lib
+}
+
 func (ctx *TxContext) TransactionID() *ledger.TransactionID {
 	return ctx.txid
 }
@@ -129,7 +160,7 @@ func (ctx *TxContext) ForEachProducedOutputData(fun func(idx byte, oData []byte)
 
 func (ctx *TxContext) ForEachProducedOutput(fun func(idx byte, out *ledger.Output, oid *ledger.OutputID) bool) {
 	ctx.ForEachProducedOutputData(func(idx byte, oData []byte) bool {
-		out, _ := ledger.OutputFromBytesReadOnly(oData)
+		out, _ := ctx.lib.OutputFromBytesReadOnly(oData)
 		oid := ctx.OutputID(idx)
 		if !fun(idx, out, &oid) {
 			return false
@@ -153,7 +184,7 @@ func (ctx *TxContext) ConsumedOutputData(idx byte) []byte {
 }
 
 func (ctx *TxContext) ConsumedOutput(idx byte) (*ledger.Output, error) {
-	return ledger.OutputFromBytesReadOnly(ctx.ConsumedOutputData(idx))
+	return ctx.lib.OutputFromBytesReadOnly(ctx.ConsumedOutputData(idx))
 }
 
 func (ctx *TxContext) UnlockDataAt(idx byte) []byte {
@@ -166,7 +197,7 @@ func (ctx *TxContext) ProducedOutputData(idx byte) []byte {
 
 func (ctx *TxContext) ProducedOutput(idx byte) (*ledger.OutputWithID, error) {
 	data := ctx.ProducedOutputData(idx)
-	o, _, _, err := ledger.OutputFromBytesMain(data)
+	o, _, _, err := ctx.lib.OutputFromBytesMain(data)
 	if err != nil {
 		return nil, err
 	}
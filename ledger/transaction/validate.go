@@ -75,7 +75,13 @@ func (ctx *TxContext) Validate() error {
 		return fmt.Errorf("unbalanced amount between inputs and outputs: inputs %s, outputs %s, inflation: %s",
 			util.GoTh(inSum), util.GoTh(outSum), util.GoTh(ctx.inflationAmount))
 	}
-	return nil
+	if err = ctx.validateTokenConservation(); err != nil {
+		return err
+	}
+	if err = ctx.validateScriptLocks(); err != nil {
+		return err
+	}
+	return ctx.validateLibraryUpgrades()
 }
 
 func (ctx *TxContext) writeStateMutationsTo(mut common.KVWriter) {
@@ -128,6 +134,15 @@ func (ctx *TxContext) ValidateWithReportOnConsumedOutputs() ([]byte, error) {
 		return nil, fmt.Errorf("unbalanced amount between inputs and outputs: inputs %s + inflation: %s != outputs %s",
 			util.GoTh(inSum), util.GoTh(ctx.inflationAmount), util.GoTh(outSum))
 	}
+	if err = ctx.validateTokenConservation(); err != nil {
+		return nil, err
+	}
+	if err = ctx.validateScriptLocks(); err != nil {
+		return nil, err
+	}
+	if err = ctx.validateLibraryUpgrades(); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
@@ -156,7 +171,7 @@ func (ctx *TxContext) _validateOutputs(consumedBranch bool, failFast bool) (uint
 	ctx.tree.ForEach(func(i byte, data []byte) bool {
 		var err error
 		path[len(path)-1] = i
-		o, err := ledger.OutputFromBytesReadOnly(data)
+		o, err := ctx.lib.OutputFromBytesReadOnly(data)
 		if err != nil {
 			if !failFast {
 				failedOutputs.WriteByte(i)
@@ -224,18 +239,26 @@ func (ctx *TxContext) runOutput(consumedBranch bool, output *ledger.Output, path
 		var res []byte
 		var name string
 
+		if !consumedBranch {
+			// produced outputs can't use functions of library upgrades before activation
+			if err = ctx.lib.CheckActiveAt(data, ctx.txid.Slot()); err != nil {
+				err = &ConstraintError{Constraint: ctx.constraintName(data), Path: PathToString(blockPath), Err: err}
+				return false
+			}
+		}
+
 		res, name, err = ctx.checkConstraint(data, blockPath)
 		if err != nil {
-			err = fmt.Errorf("constraint '%s' failed with error '%v'. Path: %s", name, err, PathToString(blockPath))
+			err = &ConstraintError{Constraint: name, Path: PathToString(blockPath), Err: err}
 			return false
 		}
 		if len(res) == 0 {
 			var decomp string
-			decomp, err = ledger.L().DecompileBytecode(data)
+			decomp, err = ctx.lib.DecompileBytecode(data)
 			if err != nil {
 				decomp = fmt.Sprintf("(error while decompiling constraint: '%v')", err)
 			}
-			err = fmt.Errorf("constraint '%s' failed. Path: %s", decomp, PathToString(blockPath))
+			err = &ConstraintError{Constraint: decomp, Path: PathToString(blockPath)}
 			return false
 		}
 		if len(res) == 4 {
@@ -250,6 +273,170 @@ func (ctx *TxContext) runOutput(consumedBranch bool, output *ledger.Output, path
 	return extraStorageDepositWeight, nil
 }
 
+// ConstraintError is returned by validation when a constraint of consumed or produced output fails
+type ConstraintError struct {
+	// name of the constraint or its decompiled source
+	Constraint string
+	// path of the constraint in the transaction context tree
+	Path string
+	// nil if constraint returned 'false'
+	Err error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("constraint '%s' failed with error '%v'. Path: %s", e.Constraint, e.Err, e.Path)
+	}
+	return fmt.Sprintf("constraint '%s' failed. Path: %s", e.Constraint, e.Path)
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// validateTokenConservation checks if sum of each native token in produced outputs is equal to the sum
+// in consumed outputs. Tokens can be minted or burned only if the issuing chain is consumed by the transaction.
+// Produced output can carry at most one token constraint
+func (ctx *TxContext) validateTokenConservation() error {
+	inTokens := make(map[ledger.TokenID]uint64)
+	outTokens := make(map[ledger.TokenID]uint64)
+	issuers := make(map[ledger.TokenID]struct{})
+	var err error
+
+	ctx.ForEachConsumedOutput(func(idx byte, oid *ledger.OutputID, out *ledger.Output) bool {
+		if out == nil {
+			err = fmt.Errorf("validateTokenConservation: can't parse consumed output #%d", idx)
+			return false
+		}
+		if chainConstraint, chainIdx := out.ChainConstraint(); chainIdx != 0xff {
+			chainID := chainConstraint.ID
+			if chainConstraint.IsOrigin() {
+				chainID = ledger.MakeOriginChainID(oid)
+			}
+			issuers[ledger.TokenIDFromChainID(chainID)] = struct{}{}
+		}
+		err = sumTokens(out, inTokens)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	ctx.ForEachProducedOutput(func(idx byte, out *ledger.Output, _ *ledger.OutputID) bool {
+		if out == nil {
+			err = fmt.Errorf("validateTokenConservation: can't parse produced output #%d", idx)
+			return false
+		}
+		err = sumTokens(out, outTokens)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	for id, inAmount := range inTokens {
+		if _, isIssuer := issuers[id]; isIssuer {
+			continue
+		}
+		if outAmount := outTokens[id]; outAmount != inAmount {
+			return fmt.Errorf("unbalanced token %s between inputs and outputs: inputs %s, outputs %s",
+				id.StringShort(), util.GoTh(inAmount), util.GoTh(outAmount))
+		}
+	}
+	for id, outAmount := range outTokens {
+		if _, isIssuer := issuers[id]; isIssuer {
+			continue
+		}
+		if _, found := inTokens[id]; !found {
+			return fmt.Errorf("token %s is produced but not consumed and the issuing chain is not consumed: outputs %s",
+				id.StringShort(), util.GoTh(outAmount))
+		}
+	}
+	return nil
+}
+
+// validateScriptLocks checks if accounts of script locks of produced outputs can be evaluated.
+// Accounts of locks are indexed when the output is committed to the state, so it must not fail there
+func (ctx *TxContext) validateScriptLocks() error {
+	var err error
+	ctx.ForEachProducedOutput(func(idx byte, out *ledger.Output, _ *ledger.OutputID) bool {
+		if out == nil {
+			return true
+		}
+		lock, errLock := ctx.lib.LockFromBytes(out.ConstraintAt(ledger.ConstraintIndexLock))
+		if errLock != nil {
+			err = fmt.Errorf("validateScriptLocks: can't parse the lock of output #%d: %w", idx, errLock)
+			return false
+		}
+		scriptLock, isScriptLock := lock.(*ledger.ScriptLock)
+		if !isScriptLock {
+			return true
+		}
+		if _, err = scriptLock.AccountsErr(); err != nil {
+			err = fmt.Errorf("validateScriptLocks: can't evaluate accounts of the lock in output #%d: %w", idx, err)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// validateLibraryUpgrades checks if library upgrades produced by the transaction can be applied to the library of the context
+// and if they are activated not earlier than ledger.LibraryUpgradeMinActivationSlots after the transaction.
+// Constraint 'libraryUpgrade' itself only checks the signature and that the activation slot is after the transaction
+func (ctx *TxContext) validateLibraryUpgrades() error {
+	upgrades := make([]*ledger.LibraryUpgrade, 0)
+	var err error
+	ctx.ForEachProducedOutput(func(idx byte, out *ledger.Output, _ *ledger.OutputID) bool {
+		if out == nil {
+			return true
+		}
+		u, constrIdx := out.LibraryUpgrade()
+		if constrIdx == 0xff {
+			return true
+		}
+		if u.ActivationSlot < ctx.txid.Slot()+ledger.LibraryUpgradeMinActivationSlots {
+			err = fmt.Errorf("validateLibraryUpgrades: library upgrade in output #%d must be activated at least %d slots after the transaction",
+				idx, ledger.LibraryUpgradeMinActivationSlots)
+			return false
+		}
+		upgrades = append(upgrades, u)
+		return true
+	})
+	if err != nil || len(upgrades) == 0 {
+		return err
+	}
+	if err := ctx.lib.ValidateUpgrades(upgrades...); err != nil {
+		return fmt.Errorf("validateLibraryUpgrades: %w", err)
+	}
+	return nil
+}
+
+// sumTokens adds token amount of the output to the sums. Returns error if output has more than one token constraint
+func sumTokens(out *ledger.Output, sums map[ledger.TokenID]uint64) error {
+	var err error
+	found := false
+	out.ForEachConstraint(func(idx byte, constr []byte) bool {
+		if idx < ledger.ConstraintIndexFirstOptionalConstraint {
+			return true
+		}
+		token, errParse := ledger.TokenFromBytes(constr)
+		if errParse != nil {
+			return true
+		}
+		if found {
+			err = fmt.Errorf("output can carry at most one token constraint")
+			return false
+		}
+		found = true
+		if token.Amount > math.MaxUint64-sums[token.ID] {
+			err = fmt.Errorf("token %s: uint64 arithmetic overflow", token.ID.StringShort())
+			return false
+		}
+		sums[token.ID] += token.Amount
+		return true
+	})
+	return err
+}
+
 func (ctx *TxContext) validateInputCommitment() error {
 	consumeOutputHash := ctx.ConsumedOutputHash()
 	inputCommitment := ctx.InputCommitment()
@@ -326,15 +513,15 @@ func PathToString(path []byte) string {
 	return ret
 }
 
-func constraintName(binCode []byte) string {
+func (ctx *TxContext) constraintName(binCode []byte) string {
 	if binCode[0] == 0 {
 		return "array_constraint"
 	}
-	prefix, err := ledger.L().ParseBytecodePrefix(binCode)
+	prefix, err := ctx.lib.ParseBytecodePrefix(binCode)
 	if err != nil {
 		return fmt.Sprintf("unknown_constraint(%s)", easyfl.Fmt(binCode))
 	}
-	name, found := ledger.NameByPrefix(prefix)
+	name, found := ctx.lib.NameByPrefix(prefix)
 	if found {
 		return name
 	}
@@ -346,17 +533,18 @@ func (ctx *TxContext) evalConstraint(constr []byte, path lazybytes.TreePath) ([]
 		return nil, "", fmt.Errorf("constraint can't be empty")
 	}
 	var err error
-	name := constraintName(constr)
+	name := ctx.constraintName(constr)
 	evalCtx := ctx.evalContext(path)
 	if evalCtx.Trace() {
 		evalCtx.PutTrace(fmt.Sprintf("--- check constraint '%s' at path %s", name, PathToString(path)))
 	}
 
 	var ret []byte
+	var cost uint64
 
 	if constr[0] != 0 {
 		// inline constraint. Binary code cannot begin with 0-byte
-		ret, err = ledger.L().EvalFromBinary(evalCtx, constr)
+		ret, cost, err = ctx.lib.EvalFromBinaryWithCost(evalCtx, constr)
 	} else {
 		// array constraint TODO do we need it?
 		arr := lazybytes.ArrayFromBytesReadOnly(constr[1:], 256)
@@ -368,20 +556,21 @@ func (ctx *TxContext) evalConstraint(constr []byte, path lazybytes.TreePath) ([]
 			for i := 1; i < arr.NumElements(); i++ {
 				args[i] = arr.At(i)
 			}
-			ret, err = ledger.L().EvalFromBinary(evalCtx, binCode, args...)
+			ret, cost, err = ctx.lib.EvalFromBinaryWithCost(evalCtx, binCode, args...)
 		}
 	}
 
 	if evalCtx.Trace() {
 		if err != nil {
-			evalCtx.PutTrace(fmt.Sprintf("--- constraint '%s' at path %s: FAILED with '%v'", name, PathToString(path), err))
+			evalCtx.PutTrace(fmt.Sprintf("--- constraint '%s' at path %s: FAILED with '%v'. Cost: %d", name, PathToString(path), err, cost))
 			printTraceIfEnabled(evalCtx)
 		} else {
 			if len(ret) == 0 {
-				evalCtx.PutTrace(fmt.Sprintf("--- constraint '%s' at path %s: FAILED", name, PathToString(path)))
+				evalCtx.PutTrace(fmt.Sprintf("--- constraint '%s' at path %s: FAILED. Cost: %d", name, PathToString(path), cost))
 				printTraceIfEnabled(evalCtx)
 			} else {
-				evalCtx.PutTrace(fmt.Sprintf("--- constraint '%s' at path %s: OK", name, PathToString(path)))
+				evalCtx.PutTrace(fmt.Sprintf("--- constraint '%s' at path %s: OK. Cost: %d, total: %d (budget %d)",
+					name, PathToString(path), cost, ctx.ExecutionCost(), ctx.ExecutionBudget()))
 			}
 		}
 	}
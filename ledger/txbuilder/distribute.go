@@ -14,8 +14,9 @@ import (
 )
 
 type LockBalanceYAMLable struct {
-	LockString string `yaml:"lock"`
-	Balance    uint64 `yaml:"balance"`
+	LockString  string `yaml:"lock"`
+	Balance     uint64 `yaml:"balance"`
+	ChainOrigin bool   `yaml:"chain_origin,omitempty"`
 }
 
 func MustDistributeInitialSupply(stateStore global.StateStore, originPrivateKey ed25519.PrivateKey, genesisDistribution []ledger.LockBalance) []byte {
@@ -55,6 +56,10 @@ func MakeDistributionTransaction(stateStore global.StateStore, originPrivateKey
 		genesisDistributionOutputs[i] = ledger.NewOutput(func(o *ledger.Output) {
 			o.WithAmount(genesisDistribution[i].Balance).
 				WithLock(genesisDistribution[i].Lock)
+			if genesisDistribution[i].ChainOrigin {
+				_, err := o.PushConstraint(ledger.NewChainOrigin().Bytes())
+				util.AssertNoError(err)
+			}
 		})
 	}
 
@@ -157,8 +162,9 @@ func InitialDistributionFromYAMLData(yamlData []byte) ([]ledger.LockBalance, err
 			return nil, err
 		}
 		ret = append(ret, ledger.LockBalance{
-			Lock:    lck,
-			Balance: yamlAble[i].Balance,
+			Lock:        lck,
+			Balance:     yamlAble[i].Balance,
+			ChainOrigin: yamlAble[i].ChainOrigin,
 		})
 	}
 	return ret, nil
@@ -167,7 +173,11 @@ func InitialDistributionFromYAMLData(yamlData []byte) ([]ledger.LockBalance, err
 func DistributionListToLines(lst []ledger.LockBalance, prefix ...string) *lines.Lines {
 	ret := lines.New(prefix...)
 	for i := range lst {
-		ret.Add("%s : %s", lst[i].Lock.String(), util.GoTh(lst[i].Balance))
+		if lst[i].ChainOrigin {
+			ret.Add("%s : %s (chain origin)", lst[i].Lock.String(), util.GoTh(lst[i].Balance))
+		} else {
+			ret.Add("%s : %s", lst[i].Lock.String(), util.GoTh(lst[i].Balance))
+		}
 	}
 	return ret
 }
@@ -0,0 +1,109 @@
+package txbuilder
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/lunfardo314/proxima/ledger"
+)
+
+// HTLC workflow: the output is locked with ledger.HTLCLock by the usual transfer with the HTLC lock as a target.
+// Until the deadline the claim account consumes it with MakeHTLCClaimTransaction, revealing the secret.
+// After the deadline the refund account consumes it with MakeHTLCRefundTransaction
+
+// HTLCUnlockData parameters of the transaction which consumes outputs locked with HTLC locks
+type HTLCUnlockData struct {
+	Inputs     []*ledger.OutputWithID
+	PrivateKey ed25519.PrivateKey
+	// Preimage the secret. Required only for claim
+	Preimage  []byte
+	Timestamp ledger.Time // takes ledger.TimeNow() if ledger.NilLedgerTime
+	// Target lock of the output. If nil, the address of the private key is used
+	Target   ledger.Lock
+	TagAlong *TagAlongData
+}
+
+// MakeHTLCClaimTransaction makes transaction which consumes HTLC outputs with the preimage before the deadline
+func MakeHTLCClaimTransaction(par *HTLCUnlockData) ([]byte, error) {
+	return makeHTLCTransaction(par, true)
+}
+
+// MakeHTLCRefundTransaction makes transaction which consumes HTLC outputs after the deadline
+func MakeHTLCRefundTransaction(par *HTLCUnlockData) ([]byte, error) {
+	return makeHTLCTransaction(par, false)
+}
+
+func makeHTLCTransaction(par *HTLCUnlockData, claim bool) ([]byte, error) {
+	if len(par.Inputs) == 0 || len(par.Inputs) > 256 {
+		return nil, fmt.Errorf("makeHTLCTransaction: wrong number of inputs")
+	}
+	signer := ledger.AddressED25519FromPrivateKey(par.PrivateKey)
+	target := par.Target
+	if target == nil {
+		target = signer
+	}
+
+	txb := NewTransactionBuilder()
+	total, inputTs, err := txb.ConsumeOutputs(par.Inputs...)
+	if err != nil {
+		return nil, err
+	}
+	ts := par.Timestamp
+	if ts == ledger.NilLedgerTime {
+		ts = ledger.TimeNow()
+	}
+	ts = ledger.MaxTime(inputTs, ts).AddTicks(ledger.TransactionPace())
+
+	for i, o := range par.Inputs {
+		htlc, ok := o.Output.Lock().(*ledger.HTLCLock)
+		if !ok {
+			return nil, fmt.Errorf("makeHTLCTransaction: output %s is not locked with HTLC lock", o.ID.StringShort())
+		}
+		if claim {
+			if !ledger.EqualConstraints(htlc.Claim, signer) {
+				return nil, fmt.Errorf("makeHTLCTransaction: %s is not a claim account of %s", signer.String(), o.ID.StringShort())
+			}
+			if !htlc.CanBeClaimedAt(ts) {
+				return nil, fmt.Errorf("makeHTLCTransaction: deadline %s of %s has passed", htlc.Deadline.String(), o.ID.StringShort())
+			}
+			if !htlc.MatchesPreimage(par.Preimage) {
+				return nil, fmt.Errorf("makeHTLCTransaction: preimage does not match hash of %s", o.ID.StringShort())
+			}
+			txb.PutUnlockParams(byte(i), ledger.ConstraintIndexLock, par.Preimage)
+		} else {
+			if !ledger.EqualConstraints(htlc.Refund, signer) {
+				return nil, fmt.Errorf("makeHTLCTransaction: %s is not a refund account of %s", signer.String(), o.ID.StringShort())
+			}
+			if !htlc.CanBeRefundedAt(ts) {
+				return nil, fmt.Errorf("makeHTLCTransaction: %s can't be refunded before deadline %s", o.ID.StringShort(), htlc.Deadline.String())
+			}
+			txb.PutSignatureUnlock(byte(i))
+		}
+	}
+
+	tagAlongFee := uint64(0)
+	if par.TagAlong != nil {
+		tagAlongFee = par.TagAlong.Amount
+	}
+	if total <= tagAlongFee {
+		return nil, fmt.Errorf("makeHTLCTransaction: not enough tokens to pay tag-along fee %d", tagAlongFee)
+	}
+	mainOut := ledger.NewOutput(func(o *ledger.Output) {
+		o.WithAmount(total - tagAlongFee).WithLock(target)
+	})
+	if _, err = txb.ProduceOutput(mainOut); err != nil {
+		return nil, err
+	}
+	if par.TagAlong != nil {
+		tagAlongOut := ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(par.TagAlong.Amount).WithLock(ledger.ChainLockFromChainID(par.TagAlong.SeqID))
+		})
+		if _, err = txb.ProduceOutput(tagAlongOut); err != nil {
+			return nil, err
+		}
+	}
+	txb.TransactionData.Timestamp = ts
+	txb.TransactionData.InputCommitment = txb.InputCommitment()
+	txb.SignED25519(par.PrivateKey)
+	return txb.TransactionData.Bytes(), nil
+}
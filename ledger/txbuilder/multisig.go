@@ -0,0 +1,172 @@
+package txbuilder
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/lazybytes"
+	"github.com/lunfardo314/unitrie/common"
+)
+
+// Multi-signature workflow: transaction which consumes outputs locked with ledger.MultisigED25519 is built with
+// MakeMultisigTransferTransaction. The first multisig input carries empty signatures of all cosigners in the unlock
+// parameters, others reference it. Unlock parameters are not part of the essence, so cosigners
+// add their signatures with CosignTransactionBytes independently, in any order and after the transaction is signed
+
+// MultisigTransferData parameters of the transfer from outputs locked with the multisig lock.
+// The transaction is signed by the sender, who does not need to be a cosigner.
+// If SenderPrivateKey is nil, the transaction is built unsigned, to be signed later with SignTransactionBytes
+type MultisigTransferData struct {
+	Lock             *ledger.MultisigED25519
+	Inputs           []*ledger.OutputWithID
+	SenderPrivateKey ed25519.PrivateKey
+	Timestamp        ledger.Time // takes ledger.TimeNow() if ledger.NilLedgerTime
+	Target           ledger.Lock
+	Amount           uint64
+	TagAlong         *TagAlongData
+}
+
+// MakeMultisigTransferTransaction makes transaction which sends Amount to the Target. Remainder is locked with the same multisig lock
+func MakeMultisigTransferTransaction(par *MultisigTransferData) ([]byte, error) {
+	if par.Lock == nil || par.Target == nil || par.Amount == 0 {
+		return nil, fmt.Errorf("MakeMultisigTransferTransaction: wrong lock, target or amount")
+	}
+	tagAlongFee := uint64(0)
+	if par.TagAlong != nil {
+		tagAlongFee = par.TagAlong.Amount
+	}
+	lockBytes := par.Lock.Bytes()
+	consumedOuts := make([]*ledger.OutputWithID, 0)
+	availableTokens := uint64(0)
+	for _, o := range par.Inputs {
+		if !bytes.Equal(o.Output.Lock().Bytes(), lockBytes) {
+			return nil, fmt.Errorf("MakeMultisigTransferTransaction: output %s is not locked with %s", o.ID.StringShort(), par.Lock.String())
+		}
+		if len(consumedOuts) >= 256 {
+			return nil, fmt.Errorf("MakeMultisigTransferTransaction: exceeded max number of consumed outputs 256")
+		}
+		consumedOuts = append(consumedOuts, o)
+		availableTokens += o.Output.Amount()
+		if availableTokens >= par.Amount+tagAlongFee {
+			break
+		}
+	}
+	if availableTokens < par.Amount+tagAlongFee {
+		return nil, fmt.Errorf("MakeMultisigTransferTransaction: not enough tokens in %s: needed %d, got %d",
+			par.Lock.String(), par.Amount+tagAlongFee, availableTokens)
+	}
+
+	txb := NewTransactionBuilder()
+	_, inputTs, err := txb.ConsumeOutputs(consumedOuts...)
+	if err != nil {
+		return nil, err
+	}
+	ts := par.Timestamp
+	if ts == ledger.NilLedgerTime {
+		ts = ledger.TimeNow()
+	}
+	ts = ledger.MaxTime(inputTs, ts).AddTicks(ledger.TransactionPace())
+
+	if availableTokens > par.Amount+tagAlongFee {
+		remainderOut := ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(availableTokens - par.Amount - tagAlongFee).WithLock(par.Lock)
+		})
+		if _, err = txb.ProduceOutput(remainderOut); err != nil {
+			return nil, err
+		}
+	}
+	mainOut := ledger.NewOutput(func(o *ledger.Output) {
+		o.WithAmount(par.Amount).WithLock(par.Target)
+	})
+	if _, err = txb.ProduceOutput(mainOut); err != nil {
+		return nil, err
+	}
+	if par.TagAlong != nil {
+		tagAlongOut := ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(par.TagAlong.Amount).WithLock(ledger.ChainLockFromChainID(par.TagAlong.SeqID))
+		})
+		if _, err = txb.ProduceOutput(tagAlongOut); err != nil {
+			return nil, err
+		}
+	}
+
+	txb.PutUnlockParams(0, ledger.ConstraintIndexLock, par.Lock.NewUnlockParams())
+	for i := 1; i < len(consumedOuts); i++ {
+		err = txb.PutUnlockReference(byte(i), ledger.ConstraintIndexLock, 0)
+		util.AssertNoError(err)
+	}
+	txb.TransactionData.Timestamp = ts
+	txb.TransactionData.InputCommitment = txb.InputCommitment()
+	if par.SenderPrivateKey != nil {
+		txb.SignED25519(par.SenderPrivateKey)
+	}
+	return txb.TransactionData.Bytes(), nil
+}
+
+// CosignTransactionBytes puts signature of the essence into unlock parameters of each consumed output, which is locked
+// with the multisig lock with the key among cosigners and is not unlocked by reference.
+// Consumed outputs must be in the order of inputs of the transaction. Returns number of cosigned inputs
+func CosignTransactionBytes(txBytes []byte, consumedOutputs []*ledger.OutputWithID, privKey ed25519.PrivateKey) ([]byte, int, error) {
+	arr, err := lazybytes.ParseArrayFromBytesReadOnly(txBytes, int(ledger.TxTreeIndexMax))
+	if err != nil {
+		return nil, 0, fmt.Errorf("CosignTransactionBytes: %w", err)
+	}
+	if arr.NumElements() != int(ledger.TxTreeIndexMax) {
+		return nil, 0, fmt.Errorf("CosignTransactionBytes: wrong number of elements in the transaction")
+	}
+	inputIDs, err := lazybytes.ParseArrayFromBytesReadOnly(arr.At(int(ledger.TxInputIDs)), 256)
+	if err != nil {
+		return nil, 0, fmt.Errorf("CosignTransactionBytes: %w", err)
+	}
+	if inputIDs.NumElements() != len(consumedOutputs) {
+		return nil, 0, fmt.Errorf("CosignTransactionBytes: number of consumed outputs does not match number of inputs")
+	}
+	unlockData, err := lazybytes.ParseArrayFromBytesReadOnly(arr.At(int(ledger.TxUnlockData)), 256)
+	if err != nil {
+		return nil, 0, fmt.Errorf("CosignTransactionBytes: %w", err)
+	}
+	essence, err := EssenceBytesFromTransactionBytes(txBytes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("CosignTransactionBytes: %w", err)
+	}
+	signature := common.Concat(ed25519.Sign(privKey, essence), []byte(privKey.Public().(ed25519.PublicKey)))
+	cosigner := ledger.AddressED25519FromPrivateKey(privKey)
+
+	unlockBlocks := unlockData.Parsed()
+	count := 0
+	for i, o := range consumedOutputs {
+		if !bytes.Equal(inputIDs.At(i), o.ID[:]) {
+			return nil, 0, fmt.Errorf("CosignTransactionBytes: consumed output #%d does not match input ID", i)
+		}
+		msig, ok := o.Output.Lock().(*ledger.MultisigED25519)
+		if !ok || msig.CosignerIndex(cosigner) < 0 {
+			continue
+		}
+		inputUnlock, err := lazybytes.ParseArrayFromBytesReadOnly(unlockBlocks[i], 256)
+		if err != nil {
+			return nil, 0, fmt.Errorf("CosignTransactionBytes: %w", err)
+		}
+		if inputUnlock.NumElements() <= int(ledger.ConstraintIndexLock) || len(inputUnlock.At(int(ledger.ConstraintIndexLock))) <= 1 {
+			// unlocked by reference
+			continue
+		}
+		elems := inputUnlock.Parsed()
+		if elems[ledger.ConstraintIndexLock], err = msig.PutSignature(elems[ledger.ConstraintIndexLock], signature); err != nil {
+			return nil, 0, fmt.Errorf("CosignTransactionBytes: input #%d: %w", i, err)
+		}
+		unlockBlocks[i] = lazybytes.MakeArrayFromDataReadOnly(elems...).Bytes()
+		count++
+	}
+	if count == 0 {
+		return nil, 0, fmt.Errorf("CosignTransactionBytes: %s is not a cosigner of any of the consumed outputs", cosigner.String())
+	}
+	elems := make([]any, arr.NumElements())
+	for i := range elems {
+		elems[i] = arr.At(i)
+	}
+	elems[ledger.TxUnlockData] = lazybytes.MakeArrayFromDataReadOnly(unlockBlocks...).Bytes()
+	return lazybytes.MakeArrayReadOnly(elems...).Bytes(), count, nil
+}
@@ -0,0 +1,47 @@
+package txbuilder
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/lazybytes"
+	"github.com/lunfardo314/unitrie/common"
+)
+
+// Offline signing: transaction is built without signature on the networked host, signed
+// on another host with SignTransactionBytes and submitted by the networked host
+
+// EssenceBytesFromTransactionBytes returns the part of the transaction which is signed
+func EssenceBytesFromTransactionBytes(txBytes []byte) ([]byte, error) {
+	var ret []byte
+	err := util.CatchPanicOrError(func() error {
+		ret = transaction.EssenceBytesFromTransactionDataTree(lazybytes.TreeFromBytesReadOnly(txBytes))
+		return nil
+	})
+	return ret, err
+}
+
+// SignTransactionBytes signs essence of the transaction and puts signature into transaction bytes.
+// Existing signature is replaced. Note, that transaction ID changes with the signature
+func SignTransactionBytes(txBytes []byte, privKey ed25519.PrivateKey) ([]byte, error) {
+	arr, err := lazybytes.ParseArrayFromBytesReadOnly(txBytes, int(ledger.TxTreeIndexMax))
+	if err != nil {
+		return nil, fmt.Errorf("SignTransactionBytes: %w", err)
+	}
+	if arr.NumElements() != int(ledger.TxTreeIndexMax) {
+		return nil, fmt.Errorf("SignTransactionBytes: wrong number of elements in the transaction")
+	}
+	essence, err := EssenceBytesFromTransactionBytes(txBytes)
+	if err != nil {
+		return nil, fmt.Errorf("SignTransactionBytes: %w", err)
+	}
+	elems := make([]any, arr.NumElements())
+	for i := range elems {
+		elems[i] = arr.At(i)
+	}
+	elems[ledger.TxSignature] = common.Concat(ed25519.Sign(privKey, essence), []byte(privKey.Public().(ed25519.PublicKey)))
+	return lazybytes.MakeArrayReadOnly(elems...).Bytes(), nil
+}
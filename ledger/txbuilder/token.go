@@ -0,0 +1,233 @@
+package txbuilder
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/util"
+)
+
+// Native token workflow: the chain issues (mints) its token with MakeTokenIssueTransaction. The token ID is the chain ID.
+// Holders of the token outputs transfer tokens with MakeTokenTransferTransaction. Each token output carries
+// base tokens at least for the storage deposit, which travel together with native tokens
+
+// TokenIssueData parameters of the transaction which mints tokens of the chain and sends them to the target
+type TokenIssueData struct {
+	ChainOutput *ledger.OutputWithChainID
+	PrivateKey  ed25519.PrivateKey
+	Timestamp   ledger.Time // takes ledger.TimeNow() if ledger.NilLedgerTime
+	Target      ledger.Lock
+	Amount      uint64
+	// Deposit base tokens taken from the chain to the token output. If 0, minimum storage deposit is used
+	Deposit  uint64
+	TagAlong *TagAlongData
+}
+
+// TokenTransferData parameters of the transfer of native tokens from the ED25519 address
+type TokenTransferData struct {
+	// Inputs outputs of the sender. Token outputs with the TokenID are consumed for the token amount,
+	// outputs without tokens are consumed for the base tokens if needed
+	Inputs     []*ledger.OutputWithID
+	PrivateKey ed25519.PrivateKey
+	Timestamp  ledger.Time // takes ledger.TimeNow() if ledger.NilLedgerTime
+	TokenID    ledger.TokenID
+	Target     ledger.Lock
+	Amount     uint64
+	// Deposit base tokens sent together with native tokens. If 0, minimum storage deposit is used
+	Deposit  uint64
+	TagAlong *TagAlongData
+}
+
+func makeTokenOutput(lock ledger.Lock, tokenID ledger.TokenID, amount, deposit uint64) *ledger.Output {
+	ret := ledger.NewOutput(func(o *ledger.Output) {
+		o.WithAmount(deposit).WithLock(lock).WithToken(tokenID, amount)
+	})
+	if minDeposit := ledger.MinimumStorageDeposit(ret, 0); deposit < minDeposit {
+		ret = ret.Clone(func(o *ledger.Output) {
+			o.PutAmount(minDeposit)
+		})
+	}
+	return ret
+}
+
+// MakeTokenIssueTransaction makes chain transition which mints Amount of tokens of the chain to the Target
+func MakeTokenIssueTransaction(par *TokenIssueData) ([]byte, error) {
+	if par.ChainOutput == nil || par.Target == nil || par.Amount == 0 {
+		return nil, fmt.Errorf("MakeTokenIssueTransaction: wrong chain output, target or amount")
+	}
+	tokenID := ledger.TokenIDFromChainID(par.ChainOutput.ChainID)
+	tokenOut := makeTokenOutput(par.Target, tokenID, par.Amount, par.Deposit)
+
+	tagAlongFee := uint64(0)
+	if par.TagAlong != nil {
+		tagAlongFee = par.TagAlong.Amount
+	}
+	chainAmount := par.ChainOutput.Output.Amount()
+	if chainAmount <= tokenOut.Amount()+tagAlongFee {
+		return nil, fmt.Errorf("MakeTokenIssueTransaction: not enough tokens on chain %s: needed more than %d, got %d",
+			par.ChainOutput.ChainID.StringShort(), tokenOut.Amount()+tagAlongFee, chainAmount)
+	}
+
+	txb := NewTransactionBuilder()
+	chainInputIndex, err := txb.ConsumeOutput(par.ChainOutput.Output, par.ChainOutput.ID)
+	if err != nil {
+		return nil, err
+	}
+	ts := par.Timestamp
+	if ts == ledger.NilLedgerTime {
+		ts = ledger.TimeNow()
+	}
+	ts = ledger.MaxTime(par.ChainOutput.Timestamp(), ts).AddTicks(ledger.TransactionPace())
+
+	predecessorConstraintIndex := par.ChainOutput.PredecessorConstraintIndex
+	chainConstr := ledger.NewChainConstraint(par.ChainOutput.ChainID, chainInputIndex, predecessorConstraintIndex, 0)
+	chainSuccessor := par.ChainOutput.Output.Clone(func(o *ledger.Output) {
+		o.WithAmount(chainAmount-tokenOut.Amount()-tagAlongFee).
+			PutConstraint(chainConstr.Bytes(), predecessorConstraintIndex)
+	})
+	successorIndex, err := txb.ProduceOutput(chainSuccessor)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = txb.ProduceOutput(tokenOut); err != nil {
+		return nil, err
+	}
+	if par.TagAlong != nil {
+		tagAlongOut := ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(par.TagAlong.Amount).WithLock(ledger.ChainLockFromChainID(par.TagAlong.SeqID))
+		})
+		if _, err = txb.ProduceOutput(tagAlongOut); err != nil {
+			return nil, err
+		}
+	}
+	txb.PutSignatureUnlock(chainInputIndex)
+	txb.PutUnlockParams(chainInputIndex, predecessorConstraintIndex, []byte{successorIndex, predecessorConstraintIndex, 0})
+
+	txb.TransactionData.Timestamp = ts
+	txb.TransactionData.InputCommitment = txb.InputCommitment()
+	txb.SignED25519(par.PrivateKey)
+	return txb.TransactionData.Bytes(), nil
+}
+
+// MakeTokenTransferTransaction makes transaction which sends Amount of native tokens to the Target.
+// Remainder of native tokens and base tokens returns to the sender's address
+func MakeTokenTransferTransaction(par *TokenTransferData) ([]byte, error) {
+	if par.Target == nil || par.Amount == 0 {
+		return nil, fmt.Errorf("MakeTokenTransferTransaction: wrong target or amount")
+	}
+	sender := ledger.AddressED25519FromPrivateKey(par.PrivateKey)
+	targetOut := makeTokenOutput(par.Target, par.TokenID, par.Amount, par.Deposit)
+	tagAlongFee := uint64(0)
+	if par.TagAlong != nil {
+		tagAlongFee = par.TagAlong.Amount
+	}
+
+	consumedOuts := make([]*ledger.OutputWithID, 0)
+	tokensIn, baseIn := uint64(0), uint64(0)
+	// first collecting token outputs for the token amount
+	for _, o := range par.Inputs {
+		if tokensIn >= par.Amount {
+			break
+		}
+		if token, _ := o.Output.Token(); token == nil || token.ID != par.TokenID {
+			continue
+		}
+		if !ledger.EqualConstraints(o.Output.Lock(), sender) {
+			continue
+		}
+		consumedOuts = append(consumedOuts, o)
+		tokensIn += o.Output.MustToken().Amount
+		baseIn += o.Output.Amount()
+	}
+	if tokensIn < par.Amount {
+		return nil, fmt.Errorf("MakeTokenTransferTransaction: not enough tokens %s in %s: needed %d, got %d",
+			par.TokenID.StringShort(), sender.String(), par.Amount, tokensIn)
+	}
+	var tokenRemainderOut, remainderOut *ledger.Output
+	if tokensIn > par.Amount {
+		tokenRemainderOut = makeTokenOutput(sender, par.TokenID, tokensIn-par.Amount, 0)
+	}
+	baseNeeded := targetOut.Amount() + tagAlongFee
+	if tokenRemainderOut != nil {
+		baseNeeded += tokenRemainderOut.Amount()
+	}
+	// then collecting outputs without native tokens for the base tokens
+	for _, o := range par.Inputs {
+		if baseIn >= baseNeeded {
+			break
+		}
+		if token, _ := o.Output.Token(); token != nil {
+			continue
+		}
+		if !ledger.EqualConstraints(o.Output.Lock(), sender) {
+			continue
+		}
+		consumedOuts = append(consumedOuts, o)
+		baseIn += o.Output.Amount()
+	}
+	if baseIn < baseNeeded {
+		return nil, fmt.Errorf("MakeTokenTransferTransaction: not enough base tokens in %s: needed %d, got %d",
+			sender.String(), baseNeeded, baseIn)
+	}
+	if len(consumedOuts) > 256 {
+		return nil, fmt.Errorf("MakeTokenTransferTransaction: exceeded max number of consumed outputs 256")
+	}
+	// remaining base tokens go to the separate output without native tokens. If it is too small for the storage deposit,
+	// it is added to the token remainder or to the target
+	if rest := baseIn - baseNeeded; rest > 0 {
+		remainderOut = ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(rest).WithLock(sender)
+		})
+		if rest < ledger.MinimumStorageDeposit(remainderOut, 0) {
+			remainderOut = nil
+			addRest := func(o *ledger.Output) {
+				o.PutAmount(o.Amount() + rest)
+			}
+			if tokenRemainderOut != nil {
+				tokenRemainderOut = tokenRemainderOut.Clone(addRest)
+			} else {
+				targetOut = targetOut.Clone(addRest)
+			}
+		}
+	}
+
+	txb := NewTransactionBuilder()
+	_, inputTs, err := txb.ConsumeOutputs(consumedOuts...)
+	if err != nil {
+		return nil, err
+	}
+	ts := par.Timestamp
+	if ts == ledger.NilLedgerTime {
+		ts = ledger.TimeNow()
+	}
+	ts = ledger.MaxTime(inputTs, ts).AddTicks(ledger.TransactionPace())
+
+	for _, o := range []*ledger.Output{tokenRemainderOut, remainderOut} {
+		if o != nil {
+			if _, err = txb.ProduceOutput(o); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err = txb.ProduceOutput(targetOut); err != nil {
+		return nil, err
+	}
+	if par.TagAlong != nil {
+		tagAlongOut := ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(par.TagAlong.Amount).WithLock(ledger.ChainLockFromChainID(par.TagAlong.SeqID))
+		})
+		if _, err = txb.ProduceOutput(tagAlongOut); err != nil {
+			return nil, err
+		}
+	}
+	txb.PutSignatureUnlock(0)
+	for i := 1; i < len(consumedOuts); i++ {
+		err = txb.PutUnlockReference(byte(i), ledger.ConstraintIndexLock, 0)
+		util.AssertNoError(err)
+	}
+	txb.TransactionData.Timestamp = ts
+	txb.TransactionData.InputCommitment = txb.InputCommitment()
+	txb.SignED25519(par.PrivateKey)
+	return txb.TransactionData.Bytes(), nil
+}
@@ -139,6 +139,19 @@ func (txb *TransactionBuilder) PushEndorsements(txid ...*ledger.TransactionID) {
 	txb.TransactionData.Endorsements = append(txb.TransactionData.Endorsements, txid...)
 }
 
+// PushLocalLibrary adds compiled local library to the transaction. Returns index of the library,
+// to be used in constraints as txLocalLibrary(<index>)
+func (txb *TransactionBuilder) PushLocalLibrary(libBin []byte) (byte, error) {
+	if len(txb.TransactionData.LocalLibraries) >= ledger.MaxNumberOfLocalLibraries {
+		return 0, fmt.Errorf("too many local libraries")
+	}
+	if _, err := ledger.L().LocalLibraryCosts(libBin); err != nil {
+		return 0, err
+	}
+	txb.TransactionData.LocalLibraries = append(txb.TransactionData.LocalLibraries, libBin)
+	return byte(len(txb.TransactionData.LocalLibraries) - 1), nil
+}
+
 func (txb *TransactionBuilder) ProduceOutput(o *ledger.Output) (byte, error) {
 	o.MustValidOutput()
 	if txb.NumOutputs() >= 256 {
@@ -225,6 +238,8 @@ func (txb *TransactionBuilder) SignED25519(privKey ed25519.PrivateKey) {
 }
 
 type (
+	// TransferData parameters of the transfer transaction. If SenderPrivateKey is nil, the transaction is built
+	// unsigned, to be signed later with SignTransactionBytes
 	TransferData struct {
 		SenderPrivateKey  ed25519.PrivateKey
 		SenderPublicKey   ed25519.PublicKey
@@ -240,6 +255,7 @@ type (
 		MarkAsSequencerTx bool
 		UnlockData        []*UnlockData
 		Endorsements      []*ledger.TransactionID
+		LocalLibraries    [][]byte
 		TagAlong          *TagAlongData
 	}
 
@@ -270,6 +286,26 @@ func NewTransferData(senderKey ed25519.PrivateKey, sourceAccount ledger.Accounta
 	}
 }
 
+// NewTransferDataUnsigned transfer data for the transaction to be signed offline. Only the sender address is known
+func NewTransferDataUnsigned(senderAddress ledger.AddressED25519, ts ledger.Time) *TransferData {
+	return &TransferData{
+		SourceAccount:  senderAddress,
+		Timestamp:      ts,
+		AddConstraints: make([][]byte, 0),
+		UnlockData:     make([]*UnlockData, 0),
+		Endorsements:   make([]*ledger.TransactionID, 0),
+	}
+}
+
+func (t *TransferData) senderAddress() ledger.AddressED25519 {
+	if len(t.SenderPublicKey) > 0 {
+		return ledger.AddressED25519FromPublicKey(t.SenderPublicKey)
+	}
+	addr, ok := t.SourceAccount.(ledger.AddressED25519)
+	util.Assertf(ok, "sender address is unknown")
+	return addr
+}
+
 func (t *TransferData) WithTargetLock(lock ledger.Lock) *TransferData {
 	t.Lock = lock
 	return t
@@ -338,6 +374,13 @@ func (t *TransferData) WithEndorsements(ids ...*ledger.TransactionID) *TransferD
 	return t
 }
 
+// WithLocalLibrary adds compiled local library to the transaction. The n-th added library is callable
+// from the constraints of the transaction with callLocalLibrary(txLocalLibrary(n), ...)
+func (t *TransferData) WithLocalLibrary(libBin []byte) *TransferData {
+	t.LocalLibraries = append(t.LocalLibraries, libBin)
+	return t
+}
+
 func (t *TransferData) WithTagAlong(seqID ledger.ChainID, amount uint64) *TransferData {
 	t.TagAlong = &TagAlongData{
 		SeqID:  seqID,
@@ -406,6 +449,10 @@ func outputsToConsumeSimple(par *TransferData, amount uint64) (uint64, []*ledger
 	numConsumedOutputs := 0
 
 	for _, o := range par.Inputs {
+		if token, _ := o.Output.Token(); token != nil {
+			// outputs with native tokens are consumed only by token transfers, otherwise tokens would be lost
+			continue
+		}
 		if numConsumedOutputs >= 256 {
 			return 0, nil, fmt.Errorf("exceeded max number of consumed outputs 256")
 		}
@@ -475,7 +522,7 @@ func MakeSimpleTransferTransactionWithRemainder(par *TransferData, disableEndors
 	mainOutput := ledger.NewOutput(func(o *ledger.Output) {
 		o.WithAmount(amount).WithLock(par.Lock)
 		if par.AddSender {
-			senderAddr := ledger.AddressED25519FromPublicKey(par.SenderPublicKey)
+			senderAddr := par.senderAddress()
 			if _, err = o.PushConstraint(ledger.NewSenderED25519(senderAddr).Bytes()); err != nil {
 				return
 			}
@@ -534,14 +581,22 @@ func MakeSimpleTransferTransactionWithRemainder(par *TransferData, disableEndors
 	for _, un := range par.UnlockData {
 		txb.PutUnlockParams(un.OutputIndex, un.ConstraintIndex, un.Data)
 	}
+	for _, libBin := range par.LocalLibraries {
+		if _, err = txb.PushLocalLibrary(libBin); err != nil {
+			return nil, nil, err
+		}
+	}
 	txb.TransactionData.Timestamp = adjustedTs
 	txb.TransactionData.Endorsements = par.Endorsements
 	txb.TransactionData.InputCommitment = txb.InputCommitment()
-	txb.SignED25519(par.SenderPrivateKey)
+	if par.SenderPrivateKey != nil {
+		txb.SignED25519(par.SenderPrivateKey)
+	}
 
 	txBytes := txb.TransactionData.Bytes()
 	var rem *ledger.OutputWithID
-	if remainderOut != nil {
+	// ID of the remainder is not known until the transaction is signed
+	if remainderOut != nil && par.SenderPrivateKey != nil {
 		if rem, err = transaction.OutputWithIDFromTransactionBytes(txBytes, remainderIndex); err != nil {
 			return nil, nil, err
 		}
@@ -610,7 +665,7 @@ func MakeChainTransferTransaction(par *TransferData, disableEndorsementChecking
 	mainOutput := ledger.NewOutput(func(o *ledger.Output) {
 		o.WithAmount(amount).WithLock(par.Lock)
 		if par.AddSender {
-			senderAddr := ledger.AddressED25519FromPublicKey(par.SenderPublicKey)
+			senderAddr := par.senderAddress()
 			if _, err = o.PushConstraint(ledger.NewSenderED25519(senderAddr).Bytes()); err != nil {
 				return
 			}
@@ -641,7 +696,9 @@ func MakeChainTransferTransaction(par *TransferData, disableEndorsementChecking
 
 	txb.TransactionData.Timestamp = adjustedTs
 	txb.TransactionData.InputCommitment = txb.InputCommitment()
-	txb.SignED25519(par.SenderPrivateKey)
+	if par.SenderPrivateKey != nil {
+		txb.SignED25519(par.SenderPrivateKey)
+	}
 
 	txBytes := txb.TransactionData.Bytes()
 	return txBytes, nil
@@ -727,5 +784,9 @@ func (txb *TransactionBuilder) String() string {
 	for i, txid := range txb.TransactionData.Endorsements {
 		ret = append(ret, fmt.Sprintf("%d : %s", i, txid.StringShort()))
 	}
+	ret = append(ret, fmt.Sprintf("Local libraries (%d):", len(txb.TransactionData.LocalLibraries)))
+	for i, libBin := range txb.TransactionData.LocalLibraries {
+		ret = append(ret, fmt.Sprintf("%d : %d bytes", i, len(libBin)))
+	}
 	return strings.Join(ret, "\n")
 }
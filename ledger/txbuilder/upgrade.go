@@ -0,0 +1,15 @@
+package txbuilder
+
+import (
+	"github.com/lunfardo314/proxima/ledger"
+)
+
+// MakeLibraryUpgradeTransaction makes transaction which commits the library upgrade into the ledger.
+// The upgrade output carries minimum storage deposit and is locked in the null address: it can't be consumed anyway.
+// The transaction must be signed by the genesis controller
+func MakeLibraryUpgradeTransaction(par *TransferData, upgrade *ledger.LibraryUpgrade) ([]byte, error) {
+	par.WithTargetLock(ledger.AddressED25519Null()).
+		WithAmount(0, true).
+		WithConstraint(upgrade)
+	return MakeSimpleTransferTransaction(par)
+}
@@ -13,11 +13,12 @@ import (
 	"github.com/lunfardo314/unitrie/immutable"
 )
 
-// two additional partitions of the k/v store
+// additional partitions of the k/v store
 const (
 	// rootRecordDBPartition
-	rootRecordDBPartition = immutable.PartitionOther
-	latestSlotDBPartition = rootRecordDBPartition + 1
+	rootRecordDBPartition      = immutable.PartitionOther
+	latestSlotDBPartition      = rootRecordDBPartition + 1
+	finalizedBranchDBPartition = latestSlotDBPartition + 1
 )
 
 func writeRootRecord(w common.KVWriter, branchTxID ledger.TransactionID, rootData RootRecord) {
@@ -40,6 +41,27 @@ func FetchLatestSlot(store common.KVReader) ledger.Slot {
 	return ret
 }
 
+// WriteFinalizedBranch records the latest finalized branch in the store
+func WriteFinalizedBranch(store common.BatchedUpdatable, branchTxID ledger.TransactionID) error {
+	util.Assertf(branchTxID.IsBranchTransaction(), "WriteFinalizedBranch: must be a branch transaction")
+	w := store.BatchedWriter()
+	w.Set([]byte{finalizedBranchDBPartition}, branchTxID[:])
+	return w.Commit()
+}
+
+// FetchFinalizedBranch fetches the latest finalized branch, if any recorded
+func FetchFinalizedBranch(store common.KVReader) (ledger.TransactionID, bool) {
+	bin := store.Get([]byte{finalizedBranchDBPartition})
+	if len(bin) == 0 {
+		return ledger.TransactionID{}, false
+	}
+	ret, err := ledger.TransactionIDFromBytes(bin)
+	if err != nil {
+		return ledger.TransactionID{}, false
+	}
+	return ret, true
+}
+
 const numberOfElementsInRootRecord = 6
 
 func (r *RootRecord) Bytes() []byte {
@@ -0,0 +1,483 @@
+package multistate
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/unitrie/common"
+)
+
+type (
+	// StateDiff is the difference between two ledger states, from the state 'before' to the state 'after'
+	StateDiff struct {
+		// Added outputs are in the state 'after' and not in the state 'before'
+		Added []*ledger.OutputDataWithID
+		// Removed outputs are in the state 'before' and not in the state 'after'
+		Removed  []*ledger.OutputDataWithID
+		Accounts []AccountDiff
+		Chains   []ChainDiff
+	}
+
+	// AccountDiff is the change of the account balance. Added and Removed are totals of
+	// added and removed outputs locked in the account
+	AccountDiff struct {
+		AccountID ledger.AccountID
+		Added     uint64
+		Removed   uint64
+	}
+
+	// ChainDiff is the change of the chain output. Before or After is nil if the chain is not in the state
+	ChainDiff struct {
+		ChainID ledger.ChainID
+		Before  *ledger.OutputID
+		After   *ledger.OutputID
+	}
+
+	StateDiffJSONAble struct {
+		// key is hex-encoded output ID, value is hex-encoded output data
+		Added    map[string]string     `json:"added"`
+		Removed  map[string]string     `json:"removed"`
+		Accounts []AccountDiffJSONAble `json:"accounts"`
+		Chains   []ChainDiffJSONAble   `json:"chains"`
+	}
+
+	AccountDiffJSONAble struct {
+		// hex-encoded account ID
+		AccountID string `json:"account_id"`
+		Added     uint64 `json:"added"`
+		Removed   uint64 `json:"removed"`
+	}
+
+	ChainDiffJSONAble struct {
+		ChainID string `json:"chain_id"`
+		// hex-encoded output IDs. Empty if chain is not in the state
+		Before string `json:"before,omitempty"`
+		After  string `json:"after,omitempty"`
+	}
+
+	// trieNodeReader reads nodes of the immutable trie directly from the store
+	trieNodeReader struct {
+		trieStore  common.KVReader
+		valueStore common.KVReader
+	}
+)
+
+// DiffBranches returns difference between states of two branches
+func DiffBranches(store common.KVReader, before, after ledger.TransactionID) (*StateDiff, error) {
+	rrBefore, found := FetchRootRecord(store, before)
+	if !found {
+		return nil, fmt.Errorf("DiffBranches: root record of %s not found", before.StringShort())
+	}
+	rrAfter, found := FetchRootRecord(store, after)
+	if !found {
+		return nil, fmt.Errorf("DiffBranches: root record of %s not found", after.StringShort())
+	}
+	return DiffStates(store, rrBefore.Root, rrAfter.Root)
+}
+
+// DiffStates returns difference between two ledger states. It traverses both tries in parallel and
+// skips subtries with equal commitments, so the cost is proportional to the size of the difference
+func DiffStates(store common.KVReader, before, after common.VCommitment) (*StateDiff, error) {
+	added := make(map[ledger.OutputID][]byte)
+	removed := make(map[ledger.OutputID][]byte)
+	accountOutputs := make(map[string][]ledger.OutputID)
+	accountOutputsRemoved := make(map[string][]ledger.OutputID)
+	chains := make(map[ledger.ChainID]*ChainDiff)
+
+	err := DiffTries(store, before, after, func(key, valueBefore, valueAfter []byte) error {
+		if len(key) == 0 {
+			return nil
+		}
+		switch key[0] {
+		case PartitionLedgerState:
+			oid, err := ledger.OutputIDFromBytes(key[1:])
+			if err != nil {
+				return err
+			}
+			if len(valueBefore) > 0 {
+				removed[oid] = valueBefore
+			}
+			if len(valueAfter) > 0 {
+				added[oid] = valueAfter
+			}
+		case PartitionAccounts:
+			if len(key) < 2 || len(key) < 2+int(key[1]) {
+				return fmt.Errorf("wrong account key %x", key)
+			}
+			accountID := string(key[2 : 2+key[1]])
+			oid, err := ledger.OutputIDFromBytes(key[2+key[1]:])
+			if err != nil {
+				return err
+			}
+			if len(valueBefore) > 0 {
+				accountOutputsRemoved[accountID] = append(accountOutputsRemoved[accountID], oid)
+			}
+			if len(valueAfter) > 0 {
+				accountOutputs[accountID] = append(accountOutputs[accountID], oid)
+			}
+		case PartitionChainID:
+			chainID, err := ledger.ChainIDFromBytes(key[1:])
+			if err != nil {
+				return err
+			}
+			cd := &ChainDiff{ChainID: chainID}
+			if cd.Before, err = outputIDOrNil(valueBefore); err != nil {
+				return err
+			}
+			if cd.After, err = outputIDOrNil(valueAfter); err != nil {
+				return err
+			}
+			chains[chainID] = cd
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &StateDiff{
+		Added:    sortedOutputs(added),
+		Removed:  sortedOutputs(removed),
+		Accounts: make([]AccountDiff, 0),
+		Chains:   make([]ChainDiff, 0, len(chains)),
+	}
+	accounts := make(map[string]*AccountDiff)
+	account := func(id string) *AccountDiff {
+		ad, ok := accounts[id]
+		if !ok {
+			ad = &AccountDiff{AccountID: ledger.AccountID(id)}
+			accounts[id] = ad
+		}
+		return ad
+	}
+	for id, oids := range accountOutputs {
+		for i := range oids {
+			amount, err := outputAmount(added[oids[i]])
+			if err != nil {
+				return nil, fmt.Errorf("added output %s of account %x: %v", oids[i].StringShort(), id, err)
+			}
+			account(id).Added += amount
+		}
+	}
+	for id, oids := range accountOutputsRemoved {
+		for i := range oids {
+			amount, err := outputAmount(removed[oids[i]])
+			if err != nil {
+				return nil, fmt.Errorf("removed output %s of account %x: %v", oids[i].StringShort(), id, err)
+			}
+			account(id).Removed += amount
+		}
+	}
+	for _, ad := range accounts {
+		ret.Accounts = append(ret.Accounts, *ad)
+	}
+	sort.Slice(ret.Accounts, func(i, j int) bool {
+		return bytes.Compare(ret.Accounts[i].AccountID, ret.Accounts[j].AccountID) < 0
+	})
+	for _, cd := range chains {
+		ret.Chains = append(ret.Chains, *cd)
+	}
+	sort.Slice(ret.Chains, func(i, j int) bool {
+		return bytes.Compare(ret.Chains[i].ChainID[:], ret.Chains[j].ChainID[:]) < 0
+	})
+	return ret, nil
+}
+
+func (d *StateDiff) JSONAble() *StateDiffJSONAble {
+	ret := &StateDiffJSONAble{
+		Added:    make(map[string]string, len(d.Added)),
+		Removed:  make(map[string]string, len(d.Removed)),
+		Accounts: make([]AccountDiffJSONAble, len(d.Accounts)),
+		Chains:   make([]ChainDiffJSONAble, len(d.Chains)),
+	}
+	for _, o := range d.Added {
+		ret.Added[o.ID.StringHex()] = hex.EncodeToString(o.OutputData)
+	}
+	for _, o := range d.Removed {
+		ret.Removed[o.ID.StringHex()] = hex.EncodeToString(o.OutputData)
+	}
+	for i, ad := range d.Accounts {
+		ret.Accounts[i] = AccountDiffJSONAble{
+			AccountID: hex.EncodeToString(ad.AccountID),
+			Added:     ad.Added,
+			Removed:   ad.Removed,
+		}
+	}
+	for i, cd := range d.Chains {
+		ret.Chains[i].ChainID = cd.ChainID.StringHex()
+		if cd.Before != nil {
+			ret.Chains[i].Before = cd.Before.StringHex()
+		}
+		if cd.After != nil {
+			ret.Chains[i].After = cd.After.StringHex()
+		}
+	}
+	return ret
+}
+
+func (d *StateDiffJSONAble) Parse() (*StateDiff, error) {
+	ret := &StateDiff{
+		Accounts: make([]AccountDiff, len(d.Accounts)),
+		Chains:   make([]ChainDiff, len(d.Chains)),
+	}
+	var err error
+	if ret.Added, err = parseOutputs(d.Added); err != nil {
+		return nil, err
+	}
+	if ret.Removed, err = parseOutputs(d.Removed); err != nil {
+		return nil, err
+	}
+	for i, ad := range d.Accounts {
+		if ret.Accounts[i].AccountID, err = hex.DecodeString(ad.AccountID); err != nil {
+			return nil, err
+		}
+		ret.Accounts[i].Added = ad.Added
+		ret.Accounts[i].Removed = ad.Removed
+	}
+	for i, cd := range d.Chains {
+		if ret.Chains[i].ChainID, err = ledger.ChainIDFromHexString(cd.ChainID); err != nil {
+			return nil, err
+		}
+		if ret.Chains[i].Before, err = parseOutputIDOrNil(cd.Before); err != nil {
+			return nil, err
+		}
+		if ret.Chains[i].After, err = parseOutputIDOrNil(cd.After); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func parseOutputs(m map[string]string) ([]*ledger.OutputDataWithID, error) {
+	outputs := make(map[ledger.OutputID][]byte, len(m))
+	for idStr, dataStr := range m {
+		oid, err := ledger.OutputIDFromHexString(idStr)
+		if err != nil {
+			return nil, err
+		}
+		if outputs[oid], err = hex.DecodeString(dataStr); err != nil {
+			return nil, err
+		}
+	}
+	return sortedOutputs(outputs), nil
+}
+
+func parseOutputIDOrNil(str string) (*ledger.OutputID, error) {
+	if str == "" {
+		return nil, nil
+	}
+	ret, err := ledger.OutputIDFromHexString(str)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// DiffTries calls fun for each key with different values in two tries. Absent value is nil
+func DiffTries(store common.KVReader, root1, root2 common.VCommitment, fun func(key, value1, value2 []byte) error) error {
+	nr := newTrieNodeReader(store)
+	n1, err := nr.fetchNode(root1)
+	if err != nil {
+		return err
+	}
+	n2, err := nr.fetchNode(root2)
+	if err != nil {
+		return err
+	}
+	return nr.diffNodes(n1, n2, nil, fun)
+}
+
+func (nr *trieNodeReader) fetchNode(c common.VCommitment) (*common.NodeData, error) {
+	nodeBin := nr.trieStore.Get(common.AsKey(c))
+	if len(nodeBin) == 0 {
+		return nil, fmt.Errorf("trie node %s not found", c.String())
+	}
+	ret, err := common.NodeDataFromBytes(ledger.CommitmentModel, nodeBin, ledger.TrieArity, func(_ []byte) ([]byte, error) {
+		return nil, fmt.Errorf("terminal commitment must be stored in the trie node")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("wrong trie node %s: %v", c.String(), err)
+	}
+	ret.Commitment = c
+	return ret, nil
+}
+
+// fetchChild returns nil, nil if child is absent
+func (nr *trieNodeReader) fetchChild(n *common.NodeData, childIdx byte) (*common.NodeData, error) {
+	if n == nil {
+		return nil, nil
+	}
+	c, found := n.ChildCommitments[childIdx]
+	if !found {
+		return nil, nil
+	}
+	return nr.fetchNode(c)
+}
+
+// value returns nil if node has no terminal
+func (nr *trieNodeReader) value(n *common.NodeData) ([]byte, error) {
+	if n == nil || common.IsNil(n.Terminal) {
+		return nil, nil
+	}
+	if value, valueInCommitment := common.ExtractValue(n.Terminal); valueInCommitment {
+		return value, nil
+	}
+	value := nr.valueStore.Get(common.AsKey(n.Terminal))
+	if len(value) == 0 {
+		return nil, fmt.Errorf("value of terminal %s not found", n.Terminal.String())
+	}
+	return value, nil
+}
+
+// diffNodes compares subtries rooted at the same trie key
+func (nr *trieNodeReader) diffNodes(n1, n2 *common.NodeData, trieKey []byte, fun func(key, value1, value2 []byte) error) error {
+	switch {
+	case n1 == nil && n2 == nil:
+		return nil
+	case n1 != nil && n2 != nil && bytes.Equal(n1.Commitment.Bytes(), n2.Commitment.Bytes()):
+		return nil
+	case n1 == nil || n2 == nil || !bytes.Equal(n1.PathFragment, n2.PathFragment):
+		// subtries are structurally different. Compare all their key/value pairs
+		return nr.diffSubtries(n1, n2, trieKey, fun)
+	}
+	keyPlusPathFragment := common.Concat(trieKey, n1.PathFragment)
+	if !equalTerminals(n1.Terminal, n2.Terminal) {
+		value1, err := nr.value(n1)
+		if err != nil {
+			return err
+		}
+		value2, err := nr.value(n2)
+		if err != nil {
+			return err
+		}
+		key, err := common.PackUnpackedBytes(keyPlusPathFragment, ledger.TrieArity)
+		if err != nil {
+			return err
+		}
+		if err = fun(key, value1, value2); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < 256; i++ {
+		idx := byte(i)
+		c1, found1 := n1.ChildCommitments[idx]
+		c2, found2 := n2.ChildCommitments[idx]
+		if !found1 && !found2 || found1 && found2 && bytes.Equal(c1.Bytes(), c2.Bytes()) {
+			continue
+		}
+		child1, err := nr.fetchChild(n1, idx)
+		if err != nil {
+			return err
+		}
+		child2, err := nr.fetchChild(n2, idx)
+		if err != nil {
+			return err
+		}
+		if err = nr.diffNodes(child1, child2, common.Concat(keyPlusPathFragment, idx), fun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (nr *trieNodeReader) diffSubtries(n1, n2 *common.NodeData, trieKey []byte, fun func(key, value1, value2 []byte) error) error {
+	kv1 := make(map[string][]byte)
+	if err := nr.iterateSubtrie(n1, trieKey, func(key, value []byte) { kv1[string(key)] = value }); err != nil {
+		return err
+	}
+	kv2 := make(map[string][]byte)
+	if err := nr.iterateSubtrie(n2, trieKey, func(key, value []byte) { kv2[string(key)] = value }); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(kv1)+len(kv2))
+	for k, v1 := range kv1 {
+		if v2, ok := kv2[k]; !ok || !bytes.Equal(v1, v2) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range kv2 {
+		if _, ok := kv1[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := fun([]byte(k), kv1[k], kv2[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterateSubtrie calls fun with packed key and value of each terminal in the subtrie
+func (nr *trieNodeReader) iterateSubtrie(n *common.NodeData, trieKey []byte, fun func(key, value []byte)) error {
+	if n == nil {
+		return nil
+	}
+	keyPlusPathFragment := common.Concat(trieKey, n.PathFragment)
+	if !common.IsNil(n.Terminal) {
+		value, err := nr.value(n)
+		if err != nil {
+			return err
+		}
+		key, err := common.PackUnpackedBytes(keyPlusPathFragment, ledger.TrieArity)
+		if err != nil {
+			return err
+		}
+		fun(key, value)
+	}
+	for i := 0; i < 256; i++ {
+		child, err := nr.fetchChild(n, byte(i))
+		if err != nil {
+			return err
+		}
+		if err = nr.iterateSubtrie(child, common.Concat(keyPlusPathFragment, byte(i)), fun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func equalTerminals(t1, t2 common.TCommitment) bool {
+	nil1, nil2 := common.IsNil(t1), common.IsNil(t2)
+	if nil1 || nil2 {
+		return nil1 == nil2
+	}
+	return bytes.Equal(t1.Bytes(), t2.Bytes())
+}
+
+func outputIDOrNil(data []byte) (*ledger.OutputID, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	ret, err := ledger.OutputIDFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func outputAmount(data []byte) (uint64, error) {
+	if len(data) == 0 {
+		return 0, fmt.Errorf("output not found in the state")
+	}
+	_, amount, _, err := ledger.OutputFromBytesMain(data)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(amount), nil
+}
+
+func sortedOutputs(m map[ledger.OutputID][]byte) []*ledger.OutputDataWithID {
+	ret := make([]*ledger.OutputDataWithID, 0, len(m))
+	for oid, data := range m {
+		ret = append(ret, &ledger.OutputDataWithID{ID: oid, OutputData: data})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return bytes.Compare(ret[i].ID[:], ret[j].ID[:]) < 0
+	})
+	return ret
+}
@@ -0,0 +1,164 @@
+package multistate_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/lunfardo314/unitrie/immutable"
+	"github.com/stretchr/testify/require"
+)
+
+var genesisPrivateKey ed25519.PrivateKey
+
+func init() {
+	genesisPrivateKey = ledger.InitWithTestingLedgerIDData()
+}
+
+func TestDiffTries(t *testing.T) {
+	store := common.NewInMemoryKVStore()
+	root0 := immutable.MustInitRoot(store, ledger.CommitmentModel, []byte("identity"))
+
+	update := func(root common.VCommitment, fun func(tr *immutable.TrieUpdatable)) common.VCommitment {
+		tr, err := immutable.NewTrieUpdatable(ledger.CommitmentModel, store, root)
+		require.NoError(t, err)
+		fun(tr)
+		batch := store.BatchedWriter()
+		ret := tr.Commit(batch)
+		require.NoError(t, batch.Commit())
+		return ret
+	}
+	// short and long values, the latter are stored outside the trie node
+	value := func(i int) []byte {
+		if i%2 == 0 {
+			return []byte(fmt.Sprintf("v%d", i))
+		}
+		return bytes.Repeat([]byte{byte(i)}, 100)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		keys[i] = make([]byte, 1+rnd.Intn(10))
+		rnd.Read(keys[i])
+	}
+	root1 := update(root0, func(tr *immutable.TrieUpdatable) {
+		for i, k := range keys {
+			tr.Update(k, value(i))
+		}
+	})
+	root2 := update(root1, func(tr *immutable.TrieUpdatable) {
+		for i, k := range keys {
+			switch i % 10 {
+			case 0:
+				tr.Delete(k)
+			case 1:
+				tr.Update(k, value(i+1))
+			}
+		}
+		for i := 0; i < 100; i++ {
+			k := make([]byte, 1+rnd.Intn(10))
+			rnd.Read(k)
+			tr.Update(k, value(i))
+		}
+	})
+
+	// brute force difference
+	readAll := func(root common.VCommitment) map[string][]byte {
+		tr, err := immutable.NewTrieReader(ledger.CommitmentModel, store, root)
+		require.NoError(t, err)
+		ret := make(map[string][]byte)
+		tr.Iterate(func(k, v []byte) bool {
+			ret[string(k)] = v
+			return true
+		})
+		return ret
+	}
+	for _, roots := range [][2]common.VCommitment{{root1, root2}, {root2, root1}, {root0, root2}, {root1, root1}} {
+		kv1, kv2 := readAll(roots[0]), readAll(roots[1])
+		expected := make(map[string]struct{})
+		for k, v := range kv1 {
+			if !bytes.Equal(v, kv2[k]) {
+				expected[k] = struct{}{}
+			}
+		}
+		for k := range kv2 {
+			if _, ok := kv1[k]; !ok {
+				expected[k] = struct{}{}
+			}
+		}
+		n := 0
+		err := multistate.DiffTries(store, roots[0], roots[1], func(key, value1, value2 []byte) error {
+			_, ok := expected[string(key)]
+			require.True(t, ok, "unexpected key %x", key)
+			require.EqualValues(t, kv1[string(key)], value1)
+			require.EqualValues(t, kv2[string(key)], value2)
+			n++
+			return nil
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, len(expected), n)
+	}
+}
+
+func TestDiffBranches(t *testing.T) {
+	addr := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(100))
+	store := common.NewInMemoryKVStore()
+	multistate.InitStateStore(*ledger.L().ID, store)
+	_, distribTxID, err := txbuilder.DistributeInitialSupplyExt(store, genesisPrivateKey, []ledger.LockBalance{
+		{Lock: addr, Balance: 1_000_000},
+		{Lock: addr, Balance: 2_000_000, ChainOrigin: true},
+	})
+	require.NoError(t, err)
+
+	diff, err := multistate.DiffBranches(store, *ledger.GenesisTransactionID(), distribTxID)
+	require.NoError(t, err)
+
+	// genesis output and genesis stem are consumed
+	require.EqualValues(t, 2, len(diff.Removed))
+	for _, o := range diff.Removed {
+		require.EqualValues(t, *ledger.GenesisTransactionID(), o.ID.TransactionID())
+	}
+	for _, o := range diff.Added {
+		require.EqualValues(t, distribTxID, o.ID.TransactionID())
+	}
+	var addrDiff *multistate.AccountDiff
+	for i := range diff.Accounts {
+		if bytes.Equal(diff.Accounts[i].AccountID, addr.AccountID()) {
+			addrDiff = &diff.Accounts[i]
+		}
+	}
+	require.NotNil(t, addrDiff)
+	require.EqualValues(t, 3_000_000, addrDiff.Added)
+	require.EqualValues(t, 0, addrDiff.Removed)
+
+	// bootstrap chain moved, new chain created
+	require.EqualValues(t, 2, len(diff.Chains))
+	var created, moved int
+	for _, cd := range diff.Chains {
+		require.NotNil(t, cd.After)
+		if cd.Before == nil {
+			created++
+		} else {
+			moved++
+		}
+	}
+	require.EqualValues(t, 1, created)
+	require.EqualValues(t, 1, moved)
+
+	parsed, err := diff.JSONAble().Parse()
+	require.NoError(t, err)
+	require.EqualValues(t, diff, parsed)
+
+	// opposite direction
+	back, err := multistate.DiffBranches(store, distribTxID, *ledger.GenesisTransactionID())
+	require.NoError(t, err)
+	require.EqualValues(t, len(diff.Added), len(back.Removed))
+	require.EqualValues(t, len(diff.Removed), len(back.Added))
+}
@@ -2,6 +2,7 @@ package multistate
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/lunfardo314/proxima/global"
 	"github.com/lunfardo314/proxima/ledger"
@@ -11,9 +12,16 @@ import (
 )
 
 // InitStateStore initializes origin ledger state in the empty store
-// Writes initial supply and origin stem outputs. Plus writes root record into the DB
+// Writes initial supply and origin stem outputs and the ledger library data. Plus writes root record into the DB
+// Ledger must be initialized with the same library. Library hash is set in the identity data if not set yet
 // Returns root commitment to the genesis ledger state and genesis chainID
 func InitStateStore(par ledger.IdentityData, store global.StateStore) (ledger.ChainID, common.VCommitment) {
+	libData := ledger.L().LibraryData()
+	if par.LibraryHash == ([32]byte{}) {
+		par.LibraryHash = libData.Hash()
+	}
+	util.Assertf(par.LibraryHash == libData.Hash(), "InitStateStore: library hash in the identity data does not match the ledger library")
+
 	batch := store.BatchedWriter()
 	emptyRoot := immutable.MustInitRoot(batch, ledger.CommitmentModel, par.Bytes())
 	err := batch.Commit()
@@ -24,7 +32,7 @@ func InitStateStore(par ledger.IdentityData, store global.StateStore) (ledger.Ch
 	gStemOut := ledger.GenesisStemOutput()
 
 	updatable := MustNewUpdatable(store, emptyRoot)
-	updatable.MustUpdate(genesisUpdateMutations(&gout.OutputWithID, gStemOut), &RootRecordParams{
+	updatable.MustUpdate(genesisUpdateMutations(&gout.OutputWithID, gStemOut, libData.Bytes()), &RootRecordParams{
 		StemOutputID:  gStemOut.ID,
 		SeqID:         gout.ChainID,
 		Coverage:      par.InitialSupply,
@@ -34,8 +42,9 @@ func InitStateStore(par ledger.IdentityData, store global.StateStore) (ledger.Ch
 	return gout.ChainID, updatable.Root()
 }
 
-func genesisUpdateMutations(genesisOut, genesisStemOut *ledger.OutputWithID) *Mutations {
+func genesisUpdateMutations(genesisOut, genesisStemOut *ledger.OutputWithID, libraryData []byte) *Mutations {
 	ret := NewMutations()
+	ret.InsertSetLibraryMutation(libraryData)
 	ret.InsertAddOutputMutation(genesisOut.ID, genesisOut.Output)
 	ret.InsertAddOutputMutation(genesisStemOut.ID, genesisStemOut.Output)
 	ret.InsertAddTxMutation(*ledger.GenesisTransactionID(), genesisOut.ID.Slot(), 1)
@@ -76,6 +85,107 @@ func ScanGenesisState(stateStore global.StateStore) (*ledger.IdentityData, commo
 	return stateID, branchData.Root, nil
 }
 
+// InitLedgerFromStore initializes ledger library from the library data committed in the state.
+// States without library data are initialized from the node binary
 func InitLedgerFromStore(stateStore global.StateStore, verbose ...bool) {
-	ledger.Init(ledger.MustLedgerIdentityDataFromBytes(LedgerIdentityBytesFromStore(stateStore)), verbose...)
+	id := ledger.MustLedgerIdentityDataFromBytes(LedgerIdentityBytesFromStore(stateStore))
+	initLedgerLibrary(id, LedgerLibraryBytesFromStore(stateStore), verbose...)
+	util.AssertNoError(ApplyLibraryUpgradesFromFinalizedState(stateStore))
+}
+
+func initLedgerLibrary(id *ledger.IdentityData, libData []byte, verbose ...bool) {
+	if len(libData) == 0 {
+		ledger.Init(id, verbose...)
+	} else {
+		err := ledger.InitFromLibraryData(id, libData, verbose...)
+		util.AssertNoError(err)
+	}
+}
+
+// InitLedgerFromLatestReadableState initializes the ledger library from the latest state in the store which can be read.
+// Unlike InitLedgerFromStore, it does not assume consistency of the multi-state DB, so it can be used
+// to verify and repair the DB after unclean shutdown of the node. Returns branch of the state used
+func InitLedgerFromLatestReadableState(stateStore global.StateStore, verbose ...bool) (ledger.TransactionID, error) {
+	records := make([]rootRecordWithID, 0)
+	IterateRootRecords(stateStore, func(branchID ledger.TransactionID, rootData RootRecord) bool {
+		records = append(records, rootRecordWithID{branchID: branchID, record: rootData})
+		return true
+	})
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].branchID.Slot() > records[j].branchID.Slot()
+	})
+	libraryInitialized := false
+	for _, rec := range records {
+		var idBin, libData []byte
+		err := util.CatchPanicOrError(func() error {
+			trie, err := immutable.NewTrieReader(ledger.CommitmentModel, stateStore, rec.record.Root, 0)
+			if err != nil {
+				return err
+			}
+			idBin, libData = trie.Get(nil), trie.Get(ledgerLibraryKey)
+			return nil
+		})
+		if err != nil || len(idBin) == 0 {
+			continue
+		}
+		if !libraryInitialized {
+			// identity and library data are the same in all states
+			initLedgerLibrary(ledger.MustLedgerIdentityDataFromBytes(idBin), libData, verbose...)
+			libraryInitialized = true
+		}
+		var rdr *Readable
+		err = util.CatchPanicOrError(func() error {
+			var err error
+			rdr, err = NewReadable(stateStore, rec.record.Root)
+			return err
+		})
+		if err != nil {
+			continue
+		}
+		return rec.branchID, ApplyLibraryUpgradesFromState(rdr)
+	}
+	return ledger.TransactionID{}, fmt.Errorf("InitLedgerFromLatestReadableState: no readable state found in the store")
+}
+
+// ApplyLibraryUpgradesFromFinalizedState upgrades the ledger library with library upgrades committed into the state
+// of the latest finalized branch. Upgrades of branches which are not final yet are ignored, because the branch
+// may be orphaned. If finalized branch is not recorded, the library is not upgraded
+func ApplyLibraryUpgradesFromFinalizedState(stateStore global.StateStore) error {
+	branchID, ok := FetchFinalizedBranch(stateStore)
+	if !ok {
+		return nil
+	}
+	return ApplyLibraryUpgradesOfBranch(stateStore, branchID)
+}
+
+// ApplyLibraryUpgradesOfBranch upgrades the ledger library with library upgrades committed into the state of the branch
+func ApplyLibraryUpgradesOfBranch(stateStore global.StateStore, branchID ledger.TransactionID) error {
+	rr, found := FetchRootRecord(stateStore, branchID)
+	if !found {
+		return fmt.Errorf("ApplyLibraryUpgradesOfBranch: root record of the branch %s not found", branchID.StringShort())
+	}
+	rdr, err := NewReadable(stateStore, rr.Root)
+	if err != nil {
+		return err
+	}
+	return ApplyLibraryUpgradesFromState(rdr)
+}
+
+// LibraryOfState returns the ledger library with library upgrades committed into the state.
+// Transactions are validated against the state with it
+func LibraryOfState(rdr global.StateIndexReader) (*ledger.Library, error) {
+	upgrades, err := rdr.GetLibraryUpgrades()
+	if err != nil {
+		return nil, err
+	}
+	return ledger.LibraryWithUpgrades(upgrades...)
+}
+
+// ApplyLibraryUpgradesFromState sets the global ledger library to the library with upgrades committed into the state
+func ApplyLibraryUpgradesFromState(rdr *Readable) error {
+	upgrades, err := rdr.GetLibraryUpgrades()
+	if err != nil {
+		return err
+	}
+	return ledger.ApplyLibraryUpgrades(upgrades...)
 }
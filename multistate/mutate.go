@@ -3,6 +3,7 @@ package multistate
 import (
 	"fmt"
 	"sort"
+	"sync"
 
 	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/util"
@@ -13,7 +14,7 @@ import (
 
 type (
 	mutationCmd interface {
-		mutate(trie *immutable.TrieUpdatable) error
+		mutate(trie *immutable.TrieUpdatable, lib *ledger.Library) error
 		text() string
 		sortOrder() byte
 	}
@@ -33,13 +34,17 @@ type (
 		LastOutputIndex byte
 	}
 
+	mutationSetLibrary struct {
+		Data []byte
+	}
+
 	Mutations struct {
 		mut []mutationCmd
 	}
 )
 
-func (m *mutationDelOutput) mutate(trie *immutable.TrieUpdatable) error {
-	return deleteOutputFromTrie(trie, &m.ID)
+func (m *mutationDelOutput) mutate(trie *immutable.TrieUpdatable, lib *ledger.Library) error {
+	return deleteOutputFromTrie(trie, lib, &m.ID)
 }
 
 func (m *mutationDelOutput) text() string {
@@ -50,8 +55,8 @@ func (m *mutationDelOutput) sortOrder() byte {
 	return 0
 }
 
-func (m *mutationAddOutput) mutate(trie *immutable.TrieUpdatable) error {
-	return addOutputToTrie(trie, &m.ID, m.Output)
+func (m *mutationAddOutput) mutate(trie *immutable.TrieUpdatable, lib *ledger.Library) error {
+	return addOutputToTrie(trie, lib, &m.ID, m.Output)
 }
 
 func (m *mutationAddOutput) text() string {
@@ -62,7 +67,7 @@ func (m *mutationAddOutput) sortOrder() byte {
 	return 1
 }
 
-func (m *mutationAddTx) mutate(trie *immutable.TrieUpdatable) error {
+func (m *mutationAddTx) mutate(trie *immutable.TrieUpdatable, _ *ledger.Library) error {
 	return addTxToTrie(trie, &m.ID, m.TimeSlot, m.LastOutputIndex)
 }
 
@@ -78,6 +83,21 @@ func (m *mutationAddTx) valueBytes() []byte {
 	return common.ConcatBytes(m.TimeSlot.Bytes(), []byte{m.LastOutputIndex})
 }
 
+func (m *mutationSetLibrary) mutate(trie *immutable.TrieUpdatable, _ *ledger.Library) error {
+	if trie.Update(ledgerLibraryKey, m.Data) {
+		return fmt.Errorf("ledger library is already in the state")
+	}
+	return nil
+}
+
+func (m *mutationSetLibrary) text() string {
+	return fmt.Sprintf("LIB   %d bytes", len(m.Data))
+}
+
+func (m *mutationSetLibrary) sortOrder() byte {
+	return 3
+}
+
 func addTxValueFromBytes(data []byte) (ledger.Slot, byte, error) {
 	if len(data) != 5 {
 		return 0, 0, fmt.Errorf("wrong data length")
@@ -125,6 +145,24 @@ func (mut *Mutations) InsertAddTxMutation(id ledger.TransactionID, slot ledger.S
 	})
 }
 
+// InsertSetLibraryMutation puts ledger library data into the state. Only used at genesis
+func (mut *Mutations) InsertSetLibraryMutation(data []byte) {
+	mut.mut = append(mut.mut, &mutationSetLibrary{Data: data})
+}
+
+// LibraryUpgrades returns library upgrades in the outputs added by mutations
+func (mut *Mutations) LibraryUpgrades() []*ledger.LibraryUpgrade {
+	ret := make([]*ledger.LibraryUpgrade, 0)
+	for _, m := range mut.mut {
+		if addOut, ok := m.(*mutationAddOutput); ok {
+			if u, idx := addOut.Output.LibraryUpgrade(); idx != 0xff {
+				ret = append(ret, u)
+			}
+		}
+	}
+	return ret
+}
+
 func (mut *Mutations) Lines(prefix ...string) *lines.Lines {
 	ret := lines.New(prefix...)
 	for _, m := range mut.mut {
@@ -133,7 +171,9 @@ func (mut *Mutations) Lines(prefix ...string) *lines.Lines {
 	return ret
 }
 
-func deleteOutputFromTrie(trie *immutable.TrieUpdatable, oid *ledger.OutputID) error {
+// deleteOutputFromTrie deletes output and its index records. Accounts of the lock are evaluated with the library
+// of the state. Library upgrades can't change accounts of existing locks, so they are the same as when the output was added
+func deleteOutputFromTrie(trie *immutable.TrieUpdatable, lib *ledger.Library, oid *ledger.OutputID) error {
 	var stateKey [1 + ledger.OutputIDLength]byte
 	stateKey[0] = PartitionLedgerState
 	copy(stateKey[1:], oid[:])
@@ -143,22 +183,36 @@ func deleteOutputFromTrie(trie *immutable.TrieUpdatable, oid *ledger.OutputID) e
 		return fmt.Errorf("deleteOutputFromTrie: output not found: %s", oid.StringShort())
 	}
 
-	o, err := ledger.OutputFromBytesReadOnly(oData)
-	util.AssertNoError(err)
+	o, _, lock, err := lib.OutputFromBytesMain(oData)
+	if err != nil {
+		return fmt.Errorf("deleteOutputFromTrie: can't parse output %s: %w", oid.StringShort(), err)
+	}
 
 	var existed bool
 	existed = trie.Delete(stateKey[:])
 	util.Assertf(existed, "deleteOutputFromTrie: inconsistency while deleting output %s", oid.StringShort())
 
-	for _, accountable := range o.Lock().Accounts() {
+	for _, accountable := range lock.Accounts() {
 		existed = trie.Delete(makeAccountKey(accountable.AccountID(), oid))
 		// must exist
 		util.Assertf(existed, "deleteOutputFromTrie: account record for %s wasn't found as expected: output %s", accountable.String(), oid.StringShort())
 	}
+	if token, _ := o.Token(); token != nil {
+		existed = trie.Delete(makeTokenKey(&token.ID, oid))
+		util.Assertf(existed, "deleteOutputFromTrie: token record for %s wasn't found as expected: output %s", token.ID.StringShort(), oid.StringShort())
+	}
+	if _, idx := o.LibraryUpgrade(); idx != 0xff {
+		trie.Delete(makeLibraryUpgradeKey(oid))
+	}
 	return nil
 }
 
-func addOutputToTrie(trie *immutable.TrieUpdatable, oid *ledger.OutputID, out *ledger.Output) error {
+// addOutputToTrie adds output and its index records. Accounts of the lock are evaluated with the library of the state
+func addOutputToTrie(trie *immutable.TrieUpdatable, lib *ledger.Library, oid *ledger.OutputID, out *ledger.Output) error {
+	lock, err := lib.LockFromBytes(out.ConstraintAt(ledger.ConstraintIndexLock))
+	if err != nil {
+		return fmt.Errorf("addOutputToTrie: can't parse lock of the output %s: %w", oid.StringShort(), err)
+	}
 	var stateKey [1 + ledger.OutputIDLength]byte
 	stateKey[0] = PartitionLedgerState
 	copy(stateKey[1:], oid[:])
@@ -166,12 +220,24 @@ func addOutputToTrie(trie *immutable.TrieUpdatable, oid *ledger.OutputID, out *l
 		// key should not exist
 		return fmt.Errorf("addOutputToTrie: UTXO key should not exist: %s", oid.StringShort())
 	}
-	for _, accountable := range out.Lock().Accounts() {
+	for _, accountable := range lock.Accounts() {
 		if trie.Update(makeAccountKey(accountable.AccountID(), oid), []byte{0xff}) {
 			// key should not exist
 			return fmt.Errorf("addOutputToTrie: index key should not exist: %s", oid.StringShort())
 		}
 	}
+	if token, _ := out.Token(); token != nil {
+		if trie.Update(makeTokenKey(&token.ID, oid), []byte{0xff}) {
+			// key should not exist
+			return fmt.Errorf("addOutputToTrie: token index key should not exist: %s", oid.StringShort())
+		}
+	}
+	if _, idx := out.LibraryUpgrade(); idx != 0xff {
+		if trie.Update(makeLibraryUpgradeKey(oid), []byte{0xff}) {
+			// key should not exist
+			return fmt.Errorf("addOutputToTrie: library upgrade index key should not exist: %s", oid.StringShort())
+		}
+	}
 	chainConstraint, _ := out.ChainConstraint()
 	if chainConstraint == nil {
 		// not a chain output
@@ -235,9 +301,22 @@ func makeChainIDKey(chainID *ledger.ChainID) []byte {
 	return common.ConcatBytes([]byte{PartitionChainID}, chainID[:])
 }
 
+func makeTokenKey(tokenID *ledger.TokenID, oid *ledger.OutputID) []byte {
+	return common.ConcatBytes([]byte{PartitionTokens}, tokenID[:], oid[:])
+}
+
+func makeLibraryUpgradeKey(oid *ledger.OutputID) []byte {
+	return common.ConcatBytes([]byte{PartitionLibraryUpgrades}, oid[:])
+}
+
+// UpdateTrie applies mutations to the trie. Outputs are parsed with the library of the state before mutations
 func UpdateTrie(trie *immutable.TrieUpdatable, mut *Mutations) (err error) {
+	var lib *ledger.Library
+	if lib, err = LibraryOfState(&Readable{mutex: &sync.Mutex{}, trie: trie.TrieReader}); err != nil {
+		return
+	}
 	for _, m := range mut.mut {
-		if err = m.mutate(trie); err != nil {
+		if err = m.mutate(trie, lib); err != nil {
 			return
 		}
 	}
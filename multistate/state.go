@@ -2,6 +2,7 @@ package multistate
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/lunfardo314/proxima/global"
@@ -68,8 +69,14 @@ const (
 	PartitionAccounts
 	PartitionChainID
 	PartitionCommittedTransactionID
+	PartitionTokens
+	PartitionLedgerLibrary
+	PartitionLibraryUpgrades
 )
 
+// ledgerLibraryKey the ledger library data is committed into the state at genesis under this key
+var ledgerLibraryKey = []byte{PartitionLedgerLibrary}
+
 func LedgerIdentityBytesFromStore(store global.StateStore) []byte {
 	rr := FetchAnyLatestRootRecord(store)
 	trie, err := immutable.NewTrieReader(ledger.CommitmentModel, store, rr.Root, 0)
@@ -77,6 +84,14 @@ func LedgerIdentityBytesFromStore(store global.StateStore) []byte {
 	return trie.Get(nil)
 }
 
+// LedgerLibraryBytesFromStore returns ledger library data committed in the state. Returns nil if it is not in the state
+func LedgerLibraryBytesFromStore(store global.StateStore) []byte {
+	rr := FetchAnyLatestRootRecord(store)
+	trie, err := immutable.NewTrieReader(ledger.CommitmentModel, store, rr.Root, 0)
+	util.AssertNoError(err)
+	return trie.Get(ledgerLibraryKey)
+}
+
 // NewReadable creates read-only ledger state with the given root
 func NewReadable(store common.KVReader, root common.VCommitment, clearCacheAtSize ...int) (*Readable, error) {
 	trie, err := immutable.NewTrieReader(ledger.CommitmentModel, store, root, clearCacheAtSize...)
@@ -200,6 +215,36 @@ func (r *Readable) GetUTXOsLockedInAccount(addr ledger.AccountID) ([]*ledger.Out
 	return ret, err
 }
 
+// GetUTXOsWithToken returns all outputs which carry the native token
+func (r *Readable) GetUTXOsWithToken(tokenID *ledger.TokenID) ([]*ledger.OutputDataWithID, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tokenPrefix := common.Concat(PartitionTokens, tokenID[:])
+
+	ret := make([]*ledger.OutputDataWithID, 0)
+	var err error
+	var found bool
+	r.trie.Iterator(tokenPrefix).IterateKeys(func(k []byte) bool {
+		o := &ledger.OutputDataWithID{}
+		o.ID, err = ledger.OutputIDFromBytes(k[len(tokenPrefix):])
+		if err != nil {
+			return false
+		}
+		o.OutputData, found = r._getUTXO(&o.ID)
+		if !found {
+			// skip this output ID
+			return true
+		}
+		ret = append(ret, o)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
 func (r *Readable) GetUTXOForChainID(id *ledger.ChainID) (*ledger.OutputDataWithID, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
@@ -261,16 +306,61 @@ func (r *Readable) MustLedgerIdentityBytes() []byte {
 	return r.trie.Get(nil)
 }
 
+// LedgerLibraryBytes ledger library data committed in the state or nil
+func (r *Readable) LedgerLibraryBytes() []byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.trie.Get(ledgerLibraryKey)
+}
+
+// GetLibraryUpgrades returns library upgrades committed into the state, in the order of function codes
+func (r *Readable) GetLibraryUpgrades() ([]*ledger.LibraryUpgrade, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ret := make([]*ledger.LibraryUpgrade, 0)
+	var err error
+	r.trie.Iterator([]byte{PartitionLibraryUpgrades}).IterateKeys(func(k []byte) bool {
+		var oid ledger.OutputID
+		if oid, err = ledger.OutputIDFromBytes(k[1:]); err != nil {
+			return false
+		}
+		oData, found := r._getUTXO(&oid)
+		if !found {
+			err = fmt.Errorf("GetLibraryUpgrades: library upgrade output %s not found", oid.StringShort())
+			return false
+		}
+		var o *ledger.Output
+		if o, err = ledger.OutputFromBytesReadOnly(oData); err != nil {
+			return false
+		}
+		u, idx := o.LibraryUpgrade()
+		if idx == 0xff {
+			err = fmt.Errorf("GetLibraryUpgrades: output %s is not a library upgrade", oid.StringShort())
+			return false
+		}
+		ret = append(ret, u)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].FirstFunCode < ret[j].FirstFunCode
+	})
+	return ret, nil
+}
+
 // IterateKnownCommittedTransactions utility function to collect old transaction IDs which may be purged from the state
 // Those txid serve no purpose after corresponding branches become committed and may appear only as virtual transactions
 func (r *Readable) IterateKnownCommittedTransactions(fun func(txid *ledger.TransactionID, slot ledger.Slot) bool) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	iter := common.MakeTraversableReaderPartition(r.trie, PartitionCommittedTransactionID).Iterator(nil)
 	var slot ledger.Slot
-	iter.Iterate(func(k, v []byte) bool {
-		txid, err := ledger.TransactionIDFromBytes(k)
+	r.trie.Iterator([]byte{PartitionCommittedTransactionID}).Iterate(func(k, v []byte) bool {
+		txid, err := ledger.TransactionIDFromBytes(k[1:])
 		util.AssertNoError(err)
 		slot, err = ledger.SlotFromBytes(v)
 		util.AssertNoError(err)
@@ -0,0 +1,475 @@
+package multistate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/lines"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/lunfardo314/unitrie/immutable"
+)
+
+type (
+	// VerifyReport is the result of the consistency check of the multi-state DB
+	VerifyReport struct {
+		NumRootRecords int
+		// NumStatesChecked number of states which were fully traversed and checked against the UTXO set
+		NumStatesChecked int
+		// DanglingRoots are root records which do not point to a complete and valid ledger state
+		DanglingRoots []RootIssue
+		// IndexMismatches are states with account, token, library upgrade or chain index inconsistent with the UTXO set.
+		// The state is usable, index mismatches are only reported
+		IndexMismatches []RootIssue
+		// LatestSlot is the recorded latest slot. MaxSlot is the maximum slot of root records in the DB
+		LatestSlot ledger.Slot
+		MaxSlot    ledger.Slot
+		// FinalizedBranchMissing is set if the recorded finalized branch has no root record
+		FinalizedBranchMissing *ledger.TransactionID
+	}
+
+	RootIssue struct {
+		BranchID ledger.TransactionID
+		Record   RootRecord
+		Problems []string
+	}
+
+	// RepairResult is the summary of changes made by RepairStateStore
+	RepairResult struct {
+		RemovedRoots []ledger.TransactionID
+		// KeptRoots are dangling root records of the genesis and finalized branches, which are never removed
+		KeptRoots              []ledger.TransactionID
+		LatestSlotFixed        bool
+		FinalizedBranchRemoved bool
+	}
+
+	rootRecordWithID struct {
+		branchID ledger.TransactionID
+		record   RootRecord
+	}
+)
+
+// maxProblemsPerRoot limits number of index mismatches reported for one state
+const maxProblemsPerRoot = 20
+
+// VerifyStateStore checks consistency of the multi-state DB, usually after unclean shutdown of the node:
+// - root node of each root record is in the store
+// - the state of each root record contains the stem output of the branch and the sequencer output
+// - states of root records in the latest nSlotsBack slots (all, if nSlotsBack <= 0) are traversed
+// completely: all trie nodes and values must be present, index partitions are reconstructed from the
+// UTXO set with the library of the state and compared with those in the state
+// - recorded latest slot and finalized branch are consistent with root records
+func VerifyStateStore(store global.StateStoreReader, nSlotsBack int) *VerifyReport {
+	records := make([]rootRecordWithID, 0)
+	IterateRootRecords(store, func(branchID ledger.TransactionID, rootData RootRecord) bool {
+		records = append(records, rootRecordWithID{branchID: branchID, record: rootData})
+		return true
+	})
+	ret := &VerifyReport{
+		NumRootRecords: len(records),
+		LatestSlot:     FetchLatestSlot(store),
+	}
+	deepCheckSince := slotToCheckSince(records, nSlotsBack)
+
+	for _, rec := range records {
+		if rec.branchID.Slot() > ret.MaxSlot {
+			ret.MaxSlot = rec.branchID.Slot()
+		}
+		var problems, mismatches []string
+		if rec.branchID.Slot() >= deepCheckSince {
+			problems, mismatches = checkStateFully(store, rec.branchID, rec.record)
+			if len(problems) == 0 {
+				ret.NumStatesChecked++
+			}
+		} else {
+			problems = checkStateShallow(store, rec.branchID, rec.record)
+		}
+		if len(problems) > 0 {
+			ret.DanglingRoots = append(ret.DanglingRoots, RootIssue{BranchID: rec.branchID, Record: rec.record, Problems: problems})
+			continue
+		}
+		if len(mismatches) > 0 {
+			ret.IndexMismatches = append(ret.IndexMismatches, RootIssue{BranchID: rec.branchID, Record: rec.record, Problems: mismatches})
+		}
+	}
+	if finalized, ok := FetchFinalizedBranch(store); ok {
+		if _, found := FetchRootRecord(store, finalized); !found {
+			ret.FinalizedBranchMissing = &finalized
+		}
+	}
+	return ret
+}
+
+// slotToCheckSince returns the earliest slot of nSlotsBack latest slots with root records
+func slotToCheckSince(records []rootRecordWithID, nSlotsBack int) ledger.Slot {
+	if nSlotsBack <= 0 {
+		return 0
+	}
+	slots := make(map[ledger.Slot]struct{})
+	for _, rec := range records {
+		slots[rec.branchID.Slot()] = struct{}{}
+	}
+	sorted := util.SortKeys(slots, func(s1, s2 ledger.Slot) bool { return s1 > s2 })
+	if len(sorted) <= nSlotsBack {
+		return 0
+	}
+	return sorted[nSlotsBack-1]
+}
+
+// checkStateShallow checks presence of the root node, the stem and the sequencer output without traversing the trie
+func checkStateShallow(store global.StateStoreReader, branchID ledger.TransactionID, rr RootRecord) []string {
+	nr := newTrieNodeReader(store)
+	if _, err := nr.fetchNode(rr.Root); err != nil {
+		return []string{err.Error()}
+	}
+	err := util.CatchPanicOrError(func() error {
+		rdr, err := NewSugaredReadableState(store, rr.Root, 0)
+		if err != nil {
+			return err
+		}
+		stem := rdr.GetStemOutput()
+		if stem.ID.TransactionID() != branchID {
+			return fmt.Errorf("stem output %s does not belong to the branch", stem.ID.StringShort())
+		}
+		seqOut, err := rdr.GetChainOutput(&rr.SequencerID)
+		if err != nil {
+			return fmt.Errorf("sequencer output of %s not found: %v", rr.SequencerID.StringShort(), err)
+		}
+		if seqOut.ID.TransactionID() != branchID {
+			return fmt.Errorf("sequencer output %s does not belong to the branch", seqOut.ID.StringShort())
+		}
+		return nil
+	})
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// checkStateFully traverses the whole trie of the state. Returns problems which make the state unusable
+// and mismatches of index partitions with the UTXO set
+func checkStateFully(store global.StateStoreReader, branchID ledger.TransactionID, rr RootRecord) (problems, mismatches []string) {
+	ix, err := collectStateIndices(store, rr.Root)
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+	if len(ix.badOutputs) > 0 {
+		return ix.badOutputs, nil
+	}
+	if len(ix.stems) != 1 {
+		return []string{fmt.Sprintf("expected exactly 1 stem output in the state, found %d", len(ix.stems))}, nil
+	}
+	if ix.stems[0].TransactionID() != branchID {
+		return []string{fmt.Sprintf("stem output %s does not belong to the branch", ix.stems[0].StringShort())}, nil
+	}
+	seqOid, found := ix.chainsExpected[rr.SequencerID]
+	if !found {
+		return []string{fmt.Sprintf("sequencer output of %s not found", rr.SequencerID.StringShort())}, nil
+	}
+	if seqOid.TransactionID() != branchID {
+		return []string{fmt.Sprintf("sequencer output %s does not belong to the branch", seqOid.StringShort())}, nil
+	}
+	return nil, ix.mismatches()
+}
+
+type stateIndices struct {
+	// library of the state. Index records are derived from outputs with it
+	lib   *ledger.Library
+	stems []ledger.OutputID
+	// index keys derived from the UTXO set and present in the trie
+	expected map[string]struct{}
+	actual   map[string]struct{}
+	// chain records derived from the UTXO set and present in the trie
+	chainsExpected map[ledger.ChainID]ledger.OutputID
+	chainsActual   map[ledger.ChainID][]byte
+	badOutputs     []string
+	// outputs, index records of which can't be derived with the library of the state
+	unindexable []string
+}
+
+// collectStateIndices traverses the trie and collects index records together with those derived from the UTXO set
+func collectStateIndices(store common.KVReader, root common.VCommitment) (*stateIndices, error) {
+	upgrades, err := libraryUpgradesOfRoot(store, root)
+	if err != nil {
+		return nil, err
+	}
+	ret := &stateIndices{
+		stems:          make([]ledger.OutputID, 0),
+		expected:       make(map[string]struct{}),
+		actual:         make(map[string]struct{}),
+		chainsExpected: make(map[ledger.ChainID]ledger.OutputID),
+		chainsActual:   make(map[ledger.ChainID][]byte),
+	}
+	if ret.lib, err = ledger.LibraryWithUpgrades(upgrades...); err != nil {
+		// library upgrades in the state are not accepted by the node binary. Indices can't be checked precisely,
+		// however the state itself is not broken
+		ret.lib = ledger.L()
+		ret.unindexable = append(ret.unindexable, fmt.Sprintf("can't apply library upgrades of the state: %v", err))
+	}
+	nr := newTrieNodeReader(store)
+	rootNode, err := nr.fetchNode(root)
+	if err != nil {
+		return nil, err
+	}
+	err = nr.iterateSubtrie(rootNode, nil, func(key, value []byte) {
+		if len(key) == 0 {
+			return
+		}
+		switch key[0] {
+		case PartitionLedgerState:
+			oid, err := ledger.OutputIDFromBytes(key[1:])
+			if err != nil {
+				ret.badOutputs = append(ret.badOutputs, fmt.Sprintf("wrong output ID %x: %v", key[1:], err))
+				return
+			}
+			ret.addOutput(&oid, value)
+		case PartitionAccounts, PartitionTokens, PartitionLibraryUpgrades:
+			ret.actual[string(key)] = struct{}{}
+		case PartitionChainID:
+			chainID, err := ledger.ChainIDFromBytes(key[1:])
+			if err != nil {
+				ret.badOutputs = append(ret.badOutputs, fmt.Sprintf("wrong chain ID %x: %v", key[1:], err))
+				return
+			}
+			ret.chainsActual[chainID] = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// libraryUpgradesOfRoot returns library upgrades committed into the state
+func libraryUpgradesOfRoot(store common.KVReader, root common.VCommitment) (ret []*ledger.LibraryUpgrade, err error) {
+	err = util.CatchPanicOrError(func() error {
+		rdr, err := NewReadable(store, root)
+		if err != nil {
+			return err
+		}
+		ret, err = rdr.GetLibraryUpgrades()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't load library upgrades of the state: %v", err)
+	}
+	return
+}
+
+// addOutput derives index records of the output with the library of the state the same way as addOutputToTrie does.
+// Output and its lock are parsed and evaluated, so panic is caught. Output, index records of which can't be derived,
+// does not make the state unusable, it is reported as index mismatch
+func (ix *stateIndices) addOutput(oid *ledger.OutputID, data []byte) {
+	var o *ledger.Output
+	var accounts []ledger.Accountable
+	err := util.CatchPanicOrError(func() error {
+		var lock ledger.Lock
+		var err error
+		if o, _, lock, err = ix.lib.OutputFromBytesMain(data); err != nil {
+			return err
+		}
+		accounts = lock.Accounts()
+		return nil
+	})
+	if err != nil {
+		ix.unindexable = append(ix.unindexable, fmt.Sprintf("can't derive index records of output %s: %v", oid.StringShort(), err))
+		return
+	}
+	for _, accountable := range accounts {
+		ix.expected[string(makeAccountKey(accountable.AccountID(), oid))] = struct{}{}
+	}
+	if _, isStem := o.StemLock(); isStem {
+		ix.stems = append(ix.stems, *oid)
+	}
+	if token, _ := o.Token(); token != nil {
+		ix.expected[string(makeTokenKey(&token.ID, oid))] = struct{}{}
+	}
+	if _, idx := o.LibraryUpgrade(); idx != 0xff {
+		ix.expected[string(makeLibraryUpgradeKey(oid))] = struct{}{}
+	}
+	if chainID, _, ok := (&ledger.OutputWithID{ID: *oid, Output: o}).ExtractChainID(); ok {
+		ix.chainsExpected[chainID] = *oid
+	}
+}
+
+// mismatches compares index records in the trie with those derived from the UTXO set.
+// Chain records are not deleted when chain output is consumed, so only presence of
+// the record for each chain output in the UTXO set is checked
+func (ix *stateIndices) mismatches() []string {
+	ret := append(make([]string, 0), ix.unindexable...)
+	for _, k := range util.SortKeys(ix.expected, func(k1, k2 string) bool { return k1 < k2 }) {
+		if _, found := ix.actual[k]; !found {
+			ret = append(ret, "missing "+indexKeyString([]byte(k)))
+		}
+	}
+	for _, k := range util.SortKeys(ix.actual, func(k1, k2 string) bool { return k1 < k2 }) {
+		if _, found := ix.expected[k]; !found {
+			ret = append(ret, "dangling "+indexKeyString([]byte(k)))
+		}
+	}
+	for chainID, oid := range ix.chainsExpected {
+		bin, found := ix.chainsActual[chainID]
+		switch {
+		case !found:
+			ret = append(ret, fmt.Sprintf("missing chain record %s -> %s", chainID.StringShort(), oid.StringShort()))
+		case string(bin) != string(oid[:]):
+			ret = append(ret, fmt.Sprintf("wrong chain record %s -> %x, expected %s", chainID.StringShort(), bin, oid.StringShort()))
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func indexKeyString(key []byte) string {
+	switch key[0] {
+	case PartitionAccounts:
+		if len(key) > 2 && len(key) >= 2+int(key[1]) {
+			if oid, err := ledger.OutputIDFromBytes(key[2+key[1]:]); err == nil {
+				return fmt.Sprintf("account record %x -> %s", key[2:2+key[1]], oid.StringShort())
+			}
+		}
+	case PartitionTokens:
+		if len(key) == 1+ledger.TokenIDLength+ledger.OutputIDLength {
+			if oid, err := ledger.OutputIDFromBytes(key[1+ledger.TokenIDLength:]); err == nil {
+				return fmt.Sprintf("token record %x -> %s", key[1:1+ledger.TokenIDLength], oid.StringShort())
+			}
+		}
+	case PartitionLibraryUpgrades:
+		if oid, err := ledger.OutputIDFromBytes(key[1:]); err == nil {
+			return fmt.Sprintf("library upgrade record %s", oid.StringShort())
+		}
+	}
+	return fmt.Sprintf("index record %x", key)
+}
+
+// IsConsistent returns true if no inconsistencies were found
+func (r *VerifyReport) IsConsistent() bool {
+	return len(r.DanglingRoots) == 0 &&
+		len(r.IndexMismatches) == 0 &&
+		r.LatestSlot == r.MaxSlot &&
+		r.FinalizedBranchMissing == nil
+}
+
+func (r *VerifyReport) Lines(prefix ...string) *lines.Lines {
+	ret := lines.New(prefix...)
+	ret.Add("root records: %d, states checked fully: %d", r.NumRootRecords, r.NumStatesChecked)
+	for _, issue := range r.DanglingRoots {
+		ret.Add("dangling root of branch %s (%s):", issue.BranchID.StringShort(), issue.Record.Root.String())
+		for _, p := range issue.Problems {
+			ret.Add("    %s", p)
+		}
+	}
+	for _, issue := range r.IndexMismatches {
+		ret.Add("index mismatch in the state of branch %s (%s), %d record(s):",
+			issue.BranchID.StringShort(), issue.Record.Root.String(), len(issue.Problems))
+		for i, p := range issue.Problems {
+			if i >= maxProblemsPerRoot {
+				ret.Add("    ...")
+				break
+			}
+			ret.Add("    %s", p)
+		}
+	}
+	if r.LatestSlot != r.MaxSlot {
+		ret.Add("recorded latest slot %d, latest slot of root records %d", r.LatestSlot, r.MaxSlot)
+	}
+	if r.FinalizedBranchMissing != nil {
+		ret.Add("finalized branch %s has no root record", r.FinalizedBranchMissing.StringShort())
+	}
+	return ret
+}
+
+// RepairStateStore fixes inconsistencies found by VerifyStateStore:
+// - removes dangling root records, except those of the genesis branch and of finalized branches.
+// Dangling roots of finalized branches can't be re-synced from peers, they are kept and reported
+// - sets the recorded latest slot to the latest slot of remaining root records
+// - removes the finalized branch record if its root record is missing
+// Index mismatches are not repaired: states with them are usable and committed states are never rewritten.
+// Trie nodes are not removed, orphaned nodes remain in the store
+func RepairStateStore(store global.StateStore, report *VerifyReport) (*RepairResult, error) {
+	ret := &RepairResult{
+		RemovedRoots: make([]ledger.TransactionID, 0),
+		KeptRoots:    make([]ledger.TransactionID, 0),
+	}
+	finalized, hasFinalized := FetchFinalizedBranch(store)
+	// the branch is final if it is on the chain of the finalized branch. If the state of the finalized branch
+	// can't be read, any branch not later than the finalized one is treated as final
+	isFinal := func(branchID *ledger.TransactionID) bool {
+		if !hasFinalized || finalized.Timestamp().Before(branchID.Timestamp()) {
+			return false
+		}
+		if finalized == *branchID {
+			return true
+		}
+		known := false
+		err := util.CatchPanicOrError(func() error {
+			rr, found := FetchRootRecord(store, finalized)
+			if !found {
+				return fmt.Errorf("root record of the finalized branch %s not found", finalized.StringShort())
+			}
+			rdr, err := NewReadable(store, rr.Root)
+			if err != nil {
+				return err
+			}
+			known = rdr.KnowsCommittedTransaction(branchID)
+			return nil
+		})
+		return err != nil || known
+	}
+	batch := store.BatchedWriter()
+	removed := make(map[ledger.TransactionID]struct{})
+	for _, issue := range report.DanglingRoots {
+		if issue.BranchID == *ledger.GenesisTransactionID() || isFinal(&issue.BranchID) {
+			ret.KeptRoots = append(ret.KeptRoots, issue.BranchID)
+			continue
+		}
+		batch.Set(common.Concat(rootRecordDBPartition, issue.BranchID[:]), nil)
+		removed[issue.BranchID] = struct{}{}
+		ret.RemovedRoots = append(ret.RemovedRoots, issue.BranchID)
+	}
+	if hasFinalized && report.FinalizedBranchMissing != nil {
+		batch.Set([]byte{finalizedBranchDBPartition}, nil)
+		ret.FinalizedBranchRemoved = true
+	}
+	var maxSlot ledger.Slot
+	IterateRootRecords(store, func(branchID ledger.TransactionID, _ RootRecord) bool {
+		if _, isRemoved := removed[branchID]; !isRemoved && branchID.Slot() > maxSlot {
+			maxSlot = branchID.Slot()
+		}
+		return true
+	})
+	if FetchLatestSlot(store) != maxSlot {
+		writeLatestSlot(batch, maxSlot)
+		ret.LatestSlotFixed = true
+	}
+	if err := batch.Commit(); err != nil {
+		return ret, err
+	}
+	return ret, nil
+}
+
+func (r *RepairResult) Lines(prefix ...string) *lines.Lines {
+	ret := lines.New(prefix...)
+	for _, txid := range r.RemovedRoots {
+		ret.Add("removed root record of branch %s", txid.StringShort())
+	}
+	for _, txid := range r.KeptRoots {
+		ret.Add("dangling root record of the genesis or finalized branch %s was not removed", txid.StringShort())
+	}
+	if r.LatestSlotFixed {
+		ret.Add("latest slot record fixed")
+	}
+	if r.FinalizedBranchRemoved {
+		ret.Add("finalized branch record removed")
+	}
+	return ret
+}
+
+func newTrieNodeReader(store common.KVReader) *trieNodeReader {
+	return &trieNodeReader{
+		trieStore:  common.MakeReaderPartition(store, immutable.PartitionTrieNodes),
+		valueStore: common.MakeReaderPartition(store, immutable.PartitionValues),
+	}
+}
@@ -0,0 +1,153 @@
+package multistate_test
+
+import (
+	"testing"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/lunfardo314/unitrie/immutable"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyStateStore(t *testing.T) {
+	initStore := func() (*common.InMemoryKVStore, ledger.TransactionID, ledger.AddressED25519) {
+		addr := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(100))
+		store := common.NewInMemoryKVStore()
+		multistate.InitStateStore(*ledger.L().ID, store)
+		_, distribTxID, err := txbuilder.DistributeInitialSupplyExt(store, genesisPrivateKey, []ledger.LockBalance{
+			{Lock: addr, Balance: 1_000_000},
+			{Lock: addr, Balance: 2_000_000, ChainOrigin: true},
+		})
+		require.NoError(t, err)
+		return store, distribTxID, addr
+	}
+	writeRootRecord := func(store common.KVWriter, branchID ledger.TransactionID, rr multistate.RootRecord) {
+		store.Set(common.Concat(immutable.PartitionOther, branchID[:]), rr.Bytes())
+	}
+	t.Run("consistent", func(t *testing.T) {
+		store, _, _ := initStore()
+		report := multistate.VerifyStateStore(store, 0)
+		t.Logf("\n%s", report.Lines().String())
+		require.True(t, report.IsConsistent())
+		require.EqualValues(t, 2, report.NumRootRecords)
+		require.EqualValues(t, 2, report.NumStatesChecked)
+
+		report = multistate.VerifyStateStore(store, 1)
+		require.True(t, report.IsConsistent())
+		require.EqualValues(t, 1, report.NumStatesChecked)
+	})
+	t.Run("index mismatch", func(t *testing.T) {
+		store, distribTxID, addr := initStore()
+		rr, found := multistate.FetchRootRecord(store, distribTxID)
+		require.True(t, found)
+
+		// remove account record of one of the outputs and add a record of non-existing output
+		accountID := addr.AccountID()
+		outs, err := multistate.MustNewSugaredReadableState(store, rr.Root).GetOutputsForAccount(accountID)
+		require.NoError(t, err)
+		require.True(t, len(outs) > 0)
+		accountKey := func(oid ledger.OutputID) []byte {
+			return common.ConcatBytes([]byte{multistate.PartitionAccounts, byte(len(accountID))}, accountID, oid[:])
+		}
+		trie, err := immutable.NewTrieUpdatable(ledger.CommitmentModel, store, rr.Root)
+		require.NoError(t, err)
+		require.True(t, trie.Delete(accountKey(outs[0].ID)))
+		trie.Update(accountKey(ledger.NewOutputID(&distribTxID, 100)), []byte{0xff})
+		corrupted := rr
+		corrupted.Root = trie.Commit(store)
+		writeRootRecord(store, distribTxID, corrupted)
+
+		report := multistate.VerifyStateStore(store, 0)
+		t.Logf("\n%s", report.Lines().String())
+		require.False(t, report.IsConsistent())
+		require.EqualValues(t, 0, len(report.DanglingRoots))
+		require.EqualValues(t, 1, len(report.IndexMismatches))
+		require.EqualValues(t, distribTxID, report.IndexMismatches[0].BranchID)
+		require.EqualValues(t, 2, len(report.IndexMismatches[0].Problems))
+
+		// index mismatches are only reported, the state is usable and committed states are never rewritten
+		res, err := multistate.RepairStateStore(store, report)
+		require.NoError(t, err)
+		t.Logf("\n%s", res.Lines().String())
+		require.EqualValues(t, 0, len(res.RemovedRoots))
+
+		_, found = multistate.FetchRootRecord(store, distribTxID)
+		require.True(t, found)
+		report = multistate.VerifyStateStore(store, 0)
+		require.EqualValues(t, 1, len(report.IndexMismatches))
+		require.EqualValues(t, 2, report.NumRootRecords)
+	})
+	t.Run("dangling root", func(t *testing.T) {
+		store, distribTxID, _ := initStore()
+		rr, found := multistate.FetchRootRecord(store, distribTxID)
+		require.True(t, found)
+
+		// root record of the later branch with the root not in the store
+		otherRoot := immutable.MustInitRoot(common.NewInMemoryKVStore(), ledger.CommitmentModel, []byte("other"))
+		danglingID := ledger.NewTransactionID(ledger.MustNewLedgerTime(5, 0), ledger.TransactionIDShort{}, true)
+		dangling := rr
+		dangling.Root = otherRoot
+		writeRootRecord(store, danglingID, dangling)
+		// root record pointing to the state of another branch
+		wrongStemID := ledger.NewTransactionID(ledger.MustNewLedgerTime(4, 0), ledger.TransactionIDShort{}, true)
+		writeRootRecord(store, wrongStemID, rr)
+
+		for _, nSlotsBack := range []int{0, 1} {
+			report := multistate.VerifyStateStore(store, nSlotsBack)
+			t.Logf("\n%s", report.Lines().String())
+			require.False(t, report.IsConsistent())
+			require.EqualValues(t, 2, len(report.DanglingRoots))
+			require.EqualValues(t, 0, len(report.IndexMismatches))
+			require.EqualValues(t, 5, report.MaxSlot)
+		}
+		res, err := multistate.RepairStateStore(store, multistate.VerifyStateStore(store, 0))
+		require.NoError(t, err)
+		t.Logf("\n%s", res.Lines().String())
+		require.EqualValues(t, 2, len(res.RemovedRoots))
+		require.False(t, res.FinalizedBranchRemoved)
+
+		_, found = multistate.FetchRootRecord(store, danglingID)
+		require.False(t, found)
+		report := multistate.VerifyStateStore(store, 0)
+		require.True(t, report.IsConsistent())
+		require.EqualValues(t, 2, report.NumRootRecords)
+	})
+	t.Run("genesis and finalized roots are kept", func(t *testing.T) {
+		store, _, _ := initStore()
+		genesisRR, found := multistate.FetchRootRecord(store, *ledger.GenesisTransactionID())
+		require.True(t, found)
+
+		otherRoot := immutable.MustInitRoot(common.NewInMemoryKVStore(), ledger.CommitmentModel, []byte("other"))
+		genesisDangling := genesisRR
+		genesisDangling.Root = otherRoot
+		writeRootRecord(store, *ledger.GenesisTransactionID(), genesisDangling)
+		// dangling finalized branch and dangling branch later than the finalized one
+		finalizedID := ledger.NewTransactionID(ledger.MustNewLedgerTime(3, 0), ledger.TransactionIDShort{}, true)
+		writeRootRecord(store, finalizedID, genesisDangling)
+		require.NoError(t, multistate.WriteFinalizedBranch(store, finalizedID))
+		laterID := ledger.NewTransactionID(ledger.MustNewLedgerTime(5, 0), ledger.TransactionIDShort{}, true)
+		writeRootRecord(store, laterID, genesisDangling)
+
+		report := multistate.VerifyStateStore(store, 0)
+		t.Logf("\n%s", report.Lines().String())
+		require.EqualValues(t, 3, len(report.DanglingRoots))
+
+		res, err := multistate.RepairStateStore(store, report)
+		require.NoError(t, err)
+		t.Logf("\n%s", res.Lines().String())
+		require.EqualValues(t, []ledger.TransactionID{laterID}, res.RemovedRoots)
+		require.EqualValues(t, 2, len(res.KeptRoots))
+		require.False(t, res.FinalizedBranchRemoved)
+
+		_, found = multistate.FetchRootRecord(store, *ledger.GenesisTransactionID())
+		require.True(t, found)
+		_, found = multistate.FetchRootRecord(store, finalizedID)
+		require.True(t, found)
+		branchID, found := multistate.FetchFinalizedBranch(store)
+		require.True(t, found)
+		require.EqualValues(t, finalizedID, branchID)
+	})
+}
@@ -2,6 +2,7 @@ package node
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lunfardo314/proxima/api/server"
 	"github.com/lunfardo314/proxima/core/txmetadata"
@@ -10,23 +11,51 @@ import (
 	"github.com/lunfardo314/proxima/global"
 	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util"
 	"github.com/spf13/viper"
 )
 
 func (p *ProximaNode) startAPIServer() {
-	port := viper.GetInt("api.server.port")
-	addr := fmt.Sprintf(":%d", port)
-	p.Log().Infof("starting API server on %s", addr)
+	srv := server.New(p)
 
-	go server.RunOn(addr, p)
+	publicCfg := apiListenerConfigFromViper("api.server")
+	lst, err := srv.ListenPublic(publicCfg)
+	util.AssertNoError(err)
+	p.apiListeners = append(p.apiListeners, lst)
+
+	if viper.GetInt("api.admin.port") != 0 {
+		adminCfg := apiListenerConfigFromViper("api.admin")
+		if adminCfg.BearerToken == "" && adminCfg.ClientCAFile == "" {
+			p.Log().Warnf("admin API listener is neither protected by bearer token nor by client certificates")
+		}
+		lst, err = srv.ListenAdmin(adminCfg)
+		util.AssertNoError(err)
+		p.apiListeners = append(p.apiListeners, lst)
+	}
 	go func() {
 		<-p.Ctx().Done()
 		p.stopAPIServer()
 	}()
 }
 
+// apiListenerConfigFromViper reads listener configuration from the config subtree, for example 'api.server'
+func apiListenerConfigFromViper(prefix string) server.ListenerConfig {
+	return server.ListenerConfig{
+		Addr:              fmt.Sprintf("%s:%d", viper.GetString(prefix+".host"), viper.GetInt(prefix+".port")),
+		CertFile:          viper.GetString(prefix + ".tls.cert_file"),
+		KeyFile:           viper.GetString(prefix + ".tls.key_file"),
+		ClientCAFile:      viper.GetString(prefix + ".tls.client_ca_file"),
+		BearerToken:       viper.GetString(prefix + ".auth.bearer_token"),
+		SubmitTxPerMinute: viper.GetInt(prefix + ".rate_limit.submit_tx_per_minute"),
+	}
+}
+
 func (p *ProximaNode) stopAPIServer() {
-	// do we need to do something else here?
+	for _, lst := range p.apiListeners {
+		if err := lst.Stop(); err != nil {
+			p.Log().Warnf("stopping %s API listener: %v", lst.Name(), err)
+		}
+	}
 	p.Log().Debugf("API server has been stopped")
 }
 
@@ -62,6 +91,10 @@ func (p *ProximaNode) SubmitTxBytesFromAPI(txBytes []byte, trace ...bool) (*ledg
 	)
 }
 
+func (p *ProximaNode) MaxDurationInTheFuture() time.Duration {
+	return p.workflow.MaxDurationInTheFuture()
+}
+
 func (p *ProximaNode) QueryTxIDStatusJSONAble(txid *ledger.TransactionID) vertex.TxIDStatusJSONAble {
 	return p.workflow.QueryTxIDStatusJSONAble(txid)
 }
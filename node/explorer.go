@@ -0,0 +1,41 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lunfardo314/proxima/explorer"
+	"github.com/spf13/viper"
+)
+
+// startExplorerIfEnabled serves ledger explorer web pages on 'explorer.port'. Disabled if port is 0
+func (p *ProximaNode) startExplorerIfEnabled() {
+	port := viper.GetInt("explorer.port")
+	if port == 0 {
+		return
+	}
+	slotsBack := viper.GetInt("explorer.slots_back")
+	if slotsBack == 0 {
+		slotsBack = explorer.DefaultSlotsBack
+	}
+	srv := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", viper.GetString("explorer.host"), port),
+		Handler:           explorer.New(p.StateStore(), p.TxBytesStore(), slotsBack),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.Log().Errorf("ledger explorer: %v", err)
+		}
+	}()
+	go func() {
+		<-p.Ctx().Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}()
+	p.Log().Infof("ledger explorer is served on '%s'", srv.Addr)
+}
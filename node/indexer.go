@@ -0,0 +1,60 @@
+package node
+
+import (
+	"time"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/core/work_process/indexer"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/unitrie/adaptors/badger_adaptor"
+	"github.com/spf13/viper"
+)
+
+// startIndexerIfEnabled starts account and chain history indexer with its separate database, if 'indexer.enable' is true
+func (p *ProximaNode) startIndexerIfEnabled() {
+	if !viper.GetBool("indexer.enable") {
+		return
+	}
+	if viper.GetString(global.ConfigKeyTxStoreType) == "dummy" {
+		p.Log().Warnf("indexer is not started: it requires the transaction store")
+		return
+	}
+	dbname := global.IndexerDBName
+	p.indexerDB = badger_adaptor.New(badger_adaptor.MustCreateOrOpenBadgerDB(dbname))
+	p.dbClosedWG.Add(1)
+	p.Log().Infof("opened indexer DB '%s'", dbname)
+
+	go func() {
+		<-p.workProcessesStopStepChan
+		select {
+		case <-p.workProcessesStopStepChan:
+		case <-time.After(10 * time.Second):
+			p.Log().Warnf("forced close of indexer DB")
+		}
+		_ = p.indexerDB.Close()
+		p.Log().Infof("indexer database has been closed")
+		p.dbClosedWG.Done()
+	}()
+
+	p.indexer = indexer.New(p, p.indexerDB)
+	// only the finalized chain is indexed. Booked branches may be orphaned later
+	p.workflow.ListenToFinalizedBranches(func(branchID ledger.TransactionID) {
+		p.indexer.Push(indexer.Input{BranchID: branchID})
+	})
+	p.indexer.Start()
+}
+
+func (p *ProximaNode) AccountHistory(accountID ledger.AccountID, maxItems int) ([]api.AccountTx, error) {
+	if p.indexer == nil {
+		return nil, api.ErrIndexerNotEnabled
+	}
+	return p.indexer.AccountHistory(accountID, maxItems), nil
+}
+
+func (p *ProximaNode) ChainHistory(chainID ledger.ChainID, maxItems int) ([]api.ChainHistoryOutput, error) {
+	if p.indexer == nil {
+		return nil, api.ErrIndexerNotEnabled
+	}
+	return p.indexer.ChainHistory(chainID, maxItems), nil
+}
@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/lunfardo314/proxima/api/server"
+	"github.com/lunfardo314/proxima/core/work_process/indexer"
 	"github.com/lunfardo314/proxima/core/workflow"
 	"github.com/lunfardo314/proxima/global"
 	"github.com/lunfardo314/proxima/ledger"
@@ -26,9 +28,12 @@ type ProximaNode struct {
 	multiStateDB              *badger_adaptor.DB
 	txStoreDB                 *badger_adaptor.DB
 	txBytesStore              global.TxBytesStore
+	indexerDB                 *badger_adaptor.DB
+	indexer                   *indexer.Indexer
 	peers                     *peering.Peers
 	workflow                  *workflow.Workflow
 	Sequencers                []*sequencer.Sequencer
+	apiListeners              []*server.Listener
 	stopOnce                  sync.Once
 	workProcessesStopStepChan chan struct{}
 	dbClosedWG                sync.WaitGroup
@@ -94,8 +99,11 @@ func (p *ProximaNode) Start() {
 		p.initPeering()
 
 		p.startWorkflow()
+		p.startIndexerIfEnabled()
 		p.startSequencers()
 		p.startAPIServer()
+		p.startExplorerIfEnabled()
+		p.startVisualizerIfEnabled()
 		p.startPProfIfEnabled()
 		return nil
 	})
@@ -201,7 +209,10 @@ func (p *ProximaNode) initPeering() {
 }
 
 func (p *ProximaNode) startWorkflow() {
-	p.workflow = workflow.New(p, p.peers)
+	p.workflow = workflow.New(p, p.peers,
+		workflow.OptionFinalityThreshold(viper.GetInt("finality.threshold_numerator"), viper.GetInt("finality.threshold_denominator")),
+		workflow.OptionFinalitySlotsBack(viper.GetInt("finality.slots_back")),
+	)
 	p.workflow.Start()
 }
 
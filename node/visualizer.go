@@ -0,0 +1,35 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lunfardo314/proxima/visualizer"
+	"github.com/spf13/viper"
+)
+
+// startVisualizerIfEnabled serves live UTXO tangle visualizer on 'visualizer.port'. Disabled if port is 0
+func (p *ProximaNode) startVisualizerIfEnabled() {
+	port := viper.GetInt("visualizer.port")
+	if port == 0 {
+		return
+	}
+	srv := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", viper.GetString("visualizer.host"), port),
+		Handler:           visualizer.New(p.workflow),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.Log().Errorf("visualizer: %v", err)
+		}
+	}()
+	go func() {
+		<-p.Ctx().Done()
+		// event streams never end by themselves, so connections are closed without waiting
+		_ = srv.Close()
+	}()
+	p.Log().Infof("UTXO tangle visualizer is served on '%s'", srv.Addr)
+}
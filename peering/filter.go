@@ -0,0 +1,67 @@
+//go:build netsim
+
+package peering
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// The out message filter is only compiled with the 'netsim' build tag. It is used by the network simulator
+// in tests and is not part of the production message path
+
+type (
+	// OutMessageFilter is called before each message is sent to the peer. It returns false if message must be dropped,
+	// otherwise it returns delay before sending it.
+	// Protocol is one of ProtocolGossip, ProtocolPull, ProtocolHeartbeat
+	OutMessageFilter func(to peer.ID, protocol string) (pass bool, delay time.Duration)
+
+	outFilterHook struct {
+		outFilterMutex sync.RWMutex
+		outFilter      OutMessageFilter
+	}
+)
+
+const (
+	ProtocolGossip    = lppProtocolGossip
+	ProtocolPull      = lppProtocolPull
+	ProtocolHeartbeat = lppProtocolHeartbeat
+)
+
+// SetOutMessageFilter sets filter for outgoing messages. nil removes the filter
+func (ps *Peers) SetOutMessageFilter(filter OutMessageFilter) {
+	ps.outFilterMutex.Lock()
+	defer ps.outFilterMutex.Unlock()
+
+	ps.outFilter = filter
+}
+
+// sendOut sends message with the send function according to the filter. Delayed message is sent
+// asynchronously and reported as sent
+func (ps *Peers) sendOut(id peer.ID, protocol string, send func() bool) bool {
+	ps.outFilterMutex.RLock()
+	filter := ps.outFilter
+	ps.outFilterMutex.RUnlock()
+
+	if filter == nil {
+		return send()
+	}
+	pass, delay := filter(id, protocol)
+	if !pass {
+		ps.Tracef(TraceTag, "message %s to %s dropped by filter", protocol, func() any { return ShortPeerIDString(id) })
+		return false
+	}
+	if delay <= 0 {
+		return send()
+	}
+	go func() {
+		select {
+		case <-ps.Ctx().Done():
+		case <-time.After(delay):
+			send()
+		}
+	}()
+	return true
+}
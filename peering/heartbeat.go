@@ -112,6 +112,13 @@ func (ps *Peers) blockCommsWithPeer(p *Peer) {
 	ps.Log().Warnf("blocked communications with peer %s (%s) for %v", ShortPeerIDString(p.id), p.name, commBlockDuration)
 }
 
+// BlockCommsWithPeer blocks communications with the peer for some time. It is used to penalize misbehaving peers
+func (ps *Peers) BlockCommsWithPeer(id peer.ID) {
+	if p := ps.getPeer(id); p != nil {
+		ps.blockCommsWithPeer(p)
+	}
+}
+
 func (ps *Peers) NumPeers() (alive, configured int) {
 	ps.mutex.RLock()
 	defer ps.mutex.RUnlock()
@@ -140,8 +147,8 @@ func (ps *Peers) logInactivityIfNeeded(id peer.ID) {
 	}
 }
 
-func checkRemoteClockTolerance(remoteTime time.Time) (bool, bool) {
-	nowis := time.Now() // local clock
+func (ps *Peers) checkRemoteClockTolerance(remoteTime time.Time) (bool, bool) {
+	nowis := ps.Clock().Now() // local clock
 	var diff time.Duration
 
 	var behind bool
@@ -200,7 +207,7 @@ func (ps *Peers) heartbeatStreamHandler(stream network.Stream) {
 		return
 
 	}
-	if clockOk, behind := checkRemoteClockTolerance(hbInfo.clock); !clockOk {
+	if clockOk, behind := ps.checkRemoteClockTolerance(hbInfo.clock); !clockOk {
 		b := "ahead"
 		if behind {
 			b = "behind"
@@ -231,18 +238,20 @@ func (ps *Peers) sendHeartbeatToPeer(id peer.ID) {
 		ps.Tracef(TraceTag, "sendHeartbeatToPeer from %s to %s", ps.host.ID().String, id.String)
 	}
 
-	stream, err := ps.host.NewStream(ps.Ctx(), id, lppProtocolHeartbeat)
-	if err != nil {
-		return
-	}
-	defer stream.Close()
+	ps.sendOut(id, lppProtocolHeartbeat, func() bool {
+		stream, err := ps.host.NewStream(ps.Ctx(), id, lppProtocolHeartbeat)
+		if err != nil {
+			return false
+		}
+		defer stream.Close()
 
-	hbInfo := heartbeatInfo{
-		clock:        time.Now(),
-		ledgerIDHash: ps.ledgerIDHash,
-		hasTxStore:   true,
-	}
-	_ = writeFrame(stream, hbInfo.Bytes())
+		hbInfo := heartbeatInfo{
+			clock:        ps.Clock().Now(),
+			ledgerIDHash: ps.ledgerIDHash,
+			hasTxStore:   true,
+		}
+		return writeFrame(stream, hbInfo.Bytes()) == nil
+	})
 }
 
 const (
@@ -46,6 +46,8 @@ type (
 		onReceiveTx       func(from peer.ID, txBytes []byte, mdata *txmetadata.TransactionMetadata)
 		onReceivePullTx   func(from peer.ID, txids []ledger.TransactionID)
 		onReceivePullTips func(from peer.ID)
+		// hook for simulation of network conditions. Empty unless built with 'netsim' tag
+		outFilterHook
 	}
 
 	Peer struct {
@@ -81,13 +81,15 @@ func (ps *Peers) processPullFrame(msgData []byte, p *Peer) error {
 }
 
 func (ps *Peers) sendPullTransactionsToPeer(id peer.ID, txLst ...ledger.TransactionID) {
-	stream, err := ps.host.NewStream(ps.Ctx(), id, lppProtocolPull)
-	if err != nil {
-		return
-	}
-	defer stream.Close()
+	ps.sendOut(id, lppProtocolPull, func() bool {
+		stream, err := ps.host.NewStream(ps.Ctx(), id, lppProtocolPull)
+		if err != nil {
+			return false
+		}
+		defer stream.Close()
 
-	_ = writeFrame(stream, encodePullTransactionsMsg(txLst...))
+		return writeFrame(stream, encodePullTransactionsMsg(txLst...)) == nil
+	})
 }
 
 // PullTransactionsFromRandomPeer sends pull request to the random peer which has txStore
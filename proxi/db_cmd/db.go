@@ -20,6 +20,10 @@ func Init() *cobra.Command {
 		initMainChainCmd(),
 		initAccountsCmd(),
 		initBranchesCmd(),
+		initExplorerCmd(),
+		initDiffCmd(),
+		initVerifyCmd(),
+		initRepairCmd(),
 	)
 	return dbCmd
 }
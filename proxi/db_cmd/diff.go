@@ -0,0 +1,74 @@
+package db_cmd
+
+import (
+	"encoding/hex"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/spf13/cobra"
+)
+
+func initDiffCmd() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff <branch1> <branch2>",
+		Short: "displays difference from the state of branch1 to the state of branch2. Outputs are listed with -v",
+		Args:  cobra.ExactArgs(2),
+		Run:   runDiffCmd,
+	}
+	diffCmd.InitDefaultHelpCmd()
+	return diffCmd
+}
+
+func runDiffCmd(_ *cobra.Command, args []string) {
+	glb.InitLedger()
+	defer glb.CloseDatabases()
+
+	branch1, err := ledger.TransactionIDFromHexString(args[0])
+	glb.AssertNoError(err)
+	branch2, err := ledger.TransactionIDFromHexString(args[1])
+	glb.AssertNoError(err)
+
+	diff, err := multistate.DiffBranches(glb.StateStore(), branch1, branch2)
+	glb.AssertNoError(err)
+
+	glb.Infof("state diff from %s to %s", branch1.StringShort(), branch2.StringShort())
+	glb.Infof("outputs added: %d, removed: %d", len(diff.Added), len(diff.Removed))
+	for _, o := range diff.Added {
+		glb.Verbosef("   + %s", outputString(o))
+	}
+	for _, o := range diff.Removed {
+		glb.Verbosef("   - %s", outputString(o))
+	}
+
+	glb.Infof("accounts changed: %d", len(diff.Accounts))
+	for _, ad := range diff.Accounts {
+		sign, delta := "+", ad.Added-ad.Removed
+		if ad.Removed > ad.Added {
+			sign, delta = "-", ad.Removed-ad.Added
+		}
+		glb.Infof("   %s  added: %s, removed: %s, balance: %s%s",
+			hex.EncodeToString(ad.AccountID), util.GoTh(ad.Added), util.GoTh(ad.Removed), sign, util.GoTh(delta))
+	}
+
+	glb.Infof("chains moved: %d", len(diff.Chains))
+	for _, cd := range diff.Chains {
+		glb.Infof("   %s  %s -> %s", cd.ChainID.String(), outputIDString(cd.Before), outputIDString(cd.After))
+	}
+}
+
+func outputString(o *ledger.OutputDataWithID) string {
+	out, err := ledger.OutputFromBytesReadOnly(o.OutputData)
+	if err != nil {
+		return o.ID.String() + " (can't parse output)"
+	}
+	return o.ID.String() + "  " + util.GoTh(out.Amount()) + "  " + out.Lock().String()
+}
+
+func outputIDString(oid *ledger.OutputID) string {
+	if oid == nil {
+		return "(none)"
+	}
+	return oid.String()
+}
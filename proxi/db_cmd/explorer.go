@@ -0,0 +1,49 @@
+package db_cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lunfardo314/proxima/explorer"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+var (
+	explorerPort      int
+	explorerSlotsBack int
+)
+
+func initExplorerCmd() *cobra.Command {
+	explorerCmd := &cobra.Command{
+		Use:   "explorer",
+		Short: "serves ledger explorer web pages from the state DB and the transaction store DB",
+		Long: `serves ledger explorer web pages from the state DB and the transaction store DB in the current directory.
+Databases are opened exclusively, so the node must be stopped. Alternatively, the explorer can be served by the node itself`,
+		Args: cobra.NoArgs,
+		Run:  runExplorerCmd,
+	}
+	explorerCmd.PersistentFlags().IntVarP(&explorerPort, "port", "p", 8800, "port of the explorer web server")
+	explorerCmd.PersistentFlags().IntVar(&explorerSlotsBack, "slots_back", explorer.DefaultSlotsBack, "how many slots back the heaviest branch chain is explored. -1 means all")
+	explorerCmd.InitDefaultHelpCmd()
+	return explorerCmd
+}
+
+func runExplorerCmd(_ *cobra.Command, _ []string) {
+	glb.InitLedger()
+	glb.InitTxStoreDB()
+	defer glb.CloseDatabases()
+
+	addr := fmt.Sprintf("127.0.0.1:%d", explorerPort)
+	go func() {
+		glb.AssertNoError(http.ListenAndServe(addr, explorer.New(glb.StateStore(), glb.TxBytesStore(), explorerSlotsBack)))
+	}()
+	glb.Infof("ledger explorer is served on http://%s. Press Ctrl-C to stop", addr)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+}
@@ -0,0 +1,76 @@
+package db_cmd
+
+import (
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+var verifySlotsBack int
+
+func initVerifyCmd() *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "checks consistency of the state DB: root records, stem and sequencer outputs, indices of the UTXO set",
+		Args:  cobra.NoArgs,
+		Run:   runVerifyCmd,
+	}
+	verifyCmd.PersistentFlags().IntVarP(&verifySlotsBack, "slots", "s", -1, "number of latest slots with states checked fully. Default: all")
+	verifyCmd.InitDefaultHelpCmd()
+	return verifyCmd
+}
+
+func initRepairCmd() *cobra.Command {
+	repairCmd := &cobra.Command{
+		Use:   "repair",
+		Short: "removes dangling root records of not finalized branches in the state DB. Index mismatches are only reported. Node must be stopped",
+		Args:  cobra.NoArgs,
+		Run:   runRepairCmd,
+	}
+	repairCmd.PersistentFlags().IntVarP(&verifySlotsBack, "slots", "s", -1, "number of latest slots with states checked fully. Default: all")
+	repairCmd.InitDefaultHelpCmd()
+	return repairCmd
+}
+
+func runVerifyCmd(_ *cobra.Command, _ []string) {
+	glb.InitLedgerForRecovery()
+	defer glb.CloseDatabases()
+
+	report := multistate.VerifyStateStore(glb.StateStore(), verifySlotsBack)
+	glb.Infof(report.Lines().String())
+	if report.IsConsistent() {
+		glb.Infof("state DB is consistent")
+	} else {
+		glb.Infof("state DB is NOT consistent. Run 'proxi db repair' to fix it")
+	}
+}
+
+func runRepairCmd(_ *cobra.Command, _ []string) {
+	glb.InitLedgerForRecovery()
+	defer glb.CloseDatabases()
+
+	report := multistate.VerifyStateStore(glb.StateStore(), verifySlotsBack)
+	glb.Infof(report.Lines().String())
+	if report.IsConsistent() {
+		glb.Infof("state DB is consistent, nothing to repair")
+		return
+	}
+	if !glb.YesNoPrompt("repair the state DB?", true, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		return
+	}
+	res, err := multistate.RepairStateStore(glb.StateStore(), report)
+	glb.Infof(res.Lines().String())
+	glb.AssertNoError(err)
+
+	report = multistate.VerifyStateStore(glb.StateStore(), verifySlotsBack)
+	glb.Assertf(len(report.DanglingRoots) == len(res.KeptRoots), "state DB still has dangling roots after repair:\n%s", report.Lines().String())
+	if len(res.RemovedRoots) > 0 {
+		glb.Infof("branches of removed root records will be re-synced from peers when the node starts")
+	}
+	if report.IsConsistent() {
+		glb.Infof("state DB repaired")
+		return
+	}
+	glb.Infof("remaining inconsistencies can't be repaired, the state DB may need to be restored from the snapshot:\n%s", report.Lines().String())
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 func Infof(format string, args ...any) {
@@ -68,3 +69,49 @@ func YesNoPrompt(label string, def bool, force ...bool) bool {
 		}
 	}
 }
+
+// PassphraseEnvVar if set, the keystore passphrase is taken from the environment instead of the prompt
+const PassphraseEnvVar = "PROXI_PASSPHRASE"
+
+// ReadPassphrase reads passphrase from the environment or from the terminal without echo
+func ReadPassphrase(prompt string) string {
+	if ret, ok := os.LookupEnv(PassphraseEnvVar); ok {
+		return ret
+	}
+	return readSecret(prompt)
+}
+
+// ReadNewPassphrase prompts new passphrase twice
+func ReadNewPassphrase() string {
+	if ret, ok := os.LookupEnv(PassphraseEnvVar); ok {
+		return ret
+	}
+	ret := readSecret("new keystore passphrase: ")
+	Assertf(readSecret("repeat passphrase: ") == ret, "passphrases do not match")
+	if ret == "" {
+		Infof("WARNING: empty passphrase")
+	}
+	return ret
+}
+
+// ReadSecretLine reads line from the terminal without echo, for example private key or mnemonic
+func ReadSecretLine(prompt string) string {
+	return strings.TrimSpace(readSecret(prompt))
+}
+
+// stdinReader is shared, so that consecutive reads from piped stdin do not lose buffered lines
+var stdinReader = bufio.NewReader(os.Stdin)
+
+func readSecret(prompt string) string {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		s, err := stdinReader.ReadString('\n')
+		Assertf(err == nil || s != "", "can't read from stdin: %v", err)
+		return strings.TrimRight(s, "\r\n")
+	}
+	fmt.Print(prompt)
+	ret, err := term.ReadPassword(fd)
+	fmt.Println()
+	AssertNoError(err)
+	return string(ret)
+}
@@ -24,6 +24,19 @@ func InitLedger(verbose ...bool) {
 	multistate.InitLedgerFromStore(stateStore, verbose...)
 }
 
+// InitLedgerForRecovery opens the multi-state DB, which may be inconsistent, and initializes
+// the ledger from the latest readable state
+func InitLedgerForRecovery(verbose ...bool) {
+	dbName := global.MultiStateDBName
+	Infof("Multi-state store database: %s", dbName)
+	FileMustExist(dbName)
+	stateDB = badger_adaptor.MustCreateOrOpenBadgerDB(dbName)
+	stateStore = badger_adaptor.New(stateDB)
+	branchID, err := multistate.InitLedgerFromLatestReadableState(stateStore, verbose...)
+	AssertNoError(err)
+	Infof("ledger initialized from the state of the branch %s", branchID.StringShort())
+}
+
 func StateStore() global.StateStore {
 	return stateStore
 }
@@ -1,6 +1,9 @@
 package glb
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
 	"sync"
 
 	"github.com/lunfardo314/proxima/api/client"
@@ -16,12 +19,56 @@ func GetClient() *client.APIClient {
 	displayEndpointOnce.Do(func() {
 		Infof("using API endpoint: %s", endpoint)
 	})
-	return client.New(endpoint)
+	ret := client.New(endpoint)
+	if token := viper.GetString("api.bearer_token"); token != "" {
+		ret.WithBearerToken(token)
+	}
+	if tlsCfg := getClientTLSConfig(); tlsCfg != nil {
+		ret.WithTLSConfig(tlsCfg)
+	}
+	return ret
+}
+
+// getClientTLSConfig returns nil if neither server CA nor client certificate is specified in the profile
+func getClientTLSConfig() *tls.Config {
+	caFile := viper.GetString("api.tls.ca_file")
+	certFile := viper.GetString("api.tls.cert_file")
+	keyFile := viper.GetString("api.tls.key_file")
+	if caFile == "" && certFile == "" {
+		return nil
+	}
+	ret := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		caBytes, err := os.ReadFile(caFile)
+		AssertNoError(err)
+		ret.RootCAs = x509.NewCertPool()
+		Assertf(ret.RootCAs.AppendCertsFromPEM(caBytes), "can't parse CA certificates from %s", caFile)
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		AssertNoError(err)
+		ret.Certificates = []tls.Certificate{cert}
+	}
+	return ret
 }
 
 func InitLedgerFromNode() {
 	ledgerID, err := GetClient().GetLedgerID()
 	AssertNoError(err)
-	ledger.Init(ledgerID)
+	libData, upgradesData, err := GetClient().GetLedgerLibrary()
+	AssertNoError(err)
+	if ledgerID.LibraryHash == ([32]byte{}) {
+		ledger.Init(ledgerID)
+	} else {
+		// ledger library is taken from the node, so proxi does not need to match the node binary
+		AssertNoError(ledger.InitFromLibraryData(ledgerID, libData))
+	}
+	// library upgrades applied by the node
+	upgrades := make([]*ledger.LibraryUpgrade, len(upgradesData))
+	for i, data := range upgradesData {
+		upgrades[i], err = ledger.LibraryUpgradeFromBytes(data)
+		AssertNoError(err)
+	}
+	AssertNoError(ledger.ApplyLibraryUpgrades(upgrades...))
 	Infof("successfully connected to the node at %s", viper.GetString("api.endpoint"))
 }
@@ -2,10 +2,12 @@ package glb
 
 import (
 	"crypto/ed25519"
+	"sync"
 	"time"
 
 	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/proxi/keystore"
 	"github.com/lunfardo314/proxima/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -30,13 +32,97 @@ func MustGetPrivateKey() ed25519.PrivateKey {
 	return ret
 }
 
+var (
+	privateKeyOnce sync.Once
+	privateKey     ed25519.PrivateKey
+)
+
+// GetPrivateKey returns private key of the selected account from the keystore, if 'wallet.keystore' is specified
+// in the profile. Otherwise, it falls back to the plaintext 'wallet.private_key'. The key is loaded once
 func GetPrivateKey() (ed25519.PrivateKey, bool) {
+	privateKeyOnce.Do(func() {
+		privateKey = loadPrivateKey()
+	})
+	return privateKey, privateKey != nil
+}
+
+func loadPrivateKey() ed25519.PrivateKey {
+	if ksFile := KeystoreFileName(); ksFile != "" {
+		name := GetAccountName()
+		ret, err := OpenKeystore().PrivateKey(name)
+		AssertNoError(err)
+		Verbosef("using account '%s' from keystore '%s'", name, ksFile)
+		return ret
+	}
 	privateKeyStr := viper.GetString("wallet.private_key")
 	if privateKeyStr == "" {
-		return nil, false
+		return nil
 	}
 	ret, err := util.ED25519PrivateKeyFromHexString(privateKeyStr)
-	return ret, err == nil
+	if err != nil {
+		return nil
+	}
+	Infof("WARNING: private key is stored in plaintext in the profile. Use 'proxi wallet import --from_profile' to move it to the encrypted keystore")
+	return ret
+}
+
+// GetWalletAccount returns address of the wallet without access to the private key: from the keystore account
+// list, if keystore is specified, otherwise from 'wallet.account' in the profile. Used to build unsigned transactions
+func GetWalletAccount() ledger.AddressED25519 {
+	var addrStr string
+	if ksFile := KeystoreFileName(); ksFile != "" {
+		accounts, err := keystore.ReadAccounts(ksFile)
+		AssertNoError(err)
+		name := GetAccountName()
+		for _, acc := range accounts {
+			if acc.Name == name {
+				addrStr = acc.Address
+				break
+			}
+		}
+		Assertf(addrStr != "", "account '%s' not found in the keystore '%s'", name, ksFile)
+	} else {
+		addrStr = viper.GetString("wallet.account")
+		Assertf(addrStr != "", "wallet account is not specified in the profile ('wallet.account')")
+	}
+	ret, err := ledger.AddressED25519FromSource(addrStr)
+	AssertNoError(err)
+	return ret
+}
+
+func KeystoreFileName() string {
+	return viper.GetString("wallet.keystore")
+}
+
+func MustKeystoreFileName() string {
+	ret := KeystoreFileName()
+	Assertf(ret != "", "keystore is not specified in the profile ('wallet.keystore')")
+	return ret
+}
+
+// OpenKeystore opens keystore specified in the profile. Passphrase is taken from the environment or prompted
+func OpenKeystore() *keystore.Keystore {
+	ks, err := keystore.Open(MustKeystoreFileName(), ReadPassphrase("keystore passphrase: "))
+	AssertNoError(err)
+	return ks
+}
+
+// without Var does not work
+var accountName string
+
+func AddFlagAccount(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&accountName, "account", "", "name of the keystore account. Defaults to 'wallet.account_name' in the profile")
+}
+
+// GetAccountName returns name of the selected keystore account
+func GetAccountName() string {
+	if accountName != "" {
+		return accountName
+	}
+	if ret := viper.GetString("wallet.account_name"); ret != "" {
+		return ret
+	}
+	return keystore.DefaultAccountName
 }
 
 // without Var does not work
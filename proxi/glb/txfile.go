@@ -0,0 +1,135 @@
+package glb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"gopkg.in/yaml.v2"
+)
+
+// TransactionFile is used by the offline signing workflow: 'proxi node tx build' writes unsigned transaction
+// with consumed outputs, 'proxi tx sign' signs it on the offline host, 'proxi node tx submit' submits it.
+// Ledger identity is included, so that transaction can be parsed and validated without access to the node
+type (
+	TransactionFile struct {
+		Description string                 `yaml:"description"`
+		LedgerID    string                 `yaml:"ledger_id"`
+		Signer      string                 `yaml:"signer"`
+		Signed      bool                   `yaml:"signed"`
+		Transaction string                 `yaml:"transaction"`
+		Inputs      []TransactionFileInput `yaml:"inputs"`
+	}
+
+	TransactionFileInput struct {
+		ID   string `yaml:"id"`
+		Data string `yaml:"data"`
+	}
+)
+
+const DefaultUnsignedTxFileName = "tx.unsigned.yaml"
+
+func NewTransactionFile(description string, signer ledger.AddressED25519, txBytes []byte, inputs []*ledger.OutputWithID) *TransactionFile {
+	ret := &TransactionFile{
+		Description: description,
+		LedgerID:    hex.EncodeToString(ledger.L().ID.Bytes()),
+		Signer:      signer.String(),
+		Transaction: hex.EncodeToString(txBytes),
+		Inputs:      make([]TransactionFileInput, len(inputs)),
+	}
+	for i, o := range inputs {
+		ret.Inputs[i] = TransactionFileInput{
+			ID:   o.ID.StringHex(),
+			Data: hex.EncodeToString(o.Output.Bytes()),
+		}
+	}
+	return ret
+}
+
+func LoadTransactionFile(fname string) (*TransactionFile, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	ret := &TransactionFile{}
+	if err = yaml.Unmarshal(data, ret); err != nil {
+		return nil, fmt.Errorf("can't parse transaction file '%s': %w", fname, err)
+	}
+	return ret, nil
+}
+
+func MustLoadTransactionFile(fname string) *TransactionFile {
+	ret, err := LoadTransactionFile(fname)
+	AssertNoError(err)
+	return ret
+}
+
+func (f *TransactionFile) Save(fname string) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, data, 0644)
+}
+
+func (f *TransactionFile) LedgerIDBytes() ([]byte, error) {
+	return hex.DecodeString(f.LedgerID)
+}
+
+// InitLedger initializes ledger library from the ledger identity in the file
+func (f *TransactionFile) InitLedger() {
+	idBytes, err := f.LedgerIDBytes()
+	AssertNoError(err)
+	ledger.Init(ledger.MustLedgerIdentityDataFromBytes(idBytes))
+}
+
+// MustCheckLedgerID checks if the file was built for the ledger the library is initialized with
+func (f *TransactionFile) MustCheckLedgerID() {
+	idBytes, err := f.LedgerIDBytes()
+	AssertNoError(err)
+	Assertf(bytes.Equal(idBytes, ledger.L().ID.Bytes()), "transaction was built for another ledger")
+}
+
+func (f *TransactionFile) TxBytes() ([]byte, error) {
+	return hex.DecodeString(f.Transaction)
+}
+
+// SetTxBytes replaces the transaction, for example, after it was cosigned
+func (f *TransactionFile) SetTxBytes(txBytes []byte) {
+	f.Transaction = hex.EncodeToString(txBytes)
+}
+
+// ConsumedOutputs parses consumed outputs. Ledger library must be initialized
+func (f *TransactionFile) ConsumedOutputs() ([]*ledger.OutputWithID, error) {
+	ret := make([]*ledger.OutputWithID, len(f.Inputs))
+	for i, inp := range f.Inputs {
+		oid, err := ledger.OutputIDFromHexString(inp.ID)
+		if err != nil {
+			return nil, err
+		}
+		data, err := hex.DecodeString(inp.Data)
+		if err != nil {
+			return nil, err
+		}
+		o, err := ledger.OutputFromBytesReadOnly(data)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = &ledger.OutputWithID{ID: oid, Output: o}
+	}
+	return ret, nil
+}
+
+// MustTxContext parses transaction with consumed outputs
+func (f *TransactionFile) MustTxContext() *transaction.TxContext {
+	txBytes, err := f.TxBytes()
+	AssertNoError(err)
+	inputs, err := f.ConsumedOutputs()
+	AssertNoError(err)
+	ctx, err := transaction.TxContextFromTransferableBytes(txBytes, transaction.PickOutputFromListFunc(inputs))
+	AssertNoError(err)
+	return ctx
+}
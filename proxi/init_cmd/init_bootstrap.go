@@ -26,6 +26,7 @@ func initBootstrapAccountCmd() *cobra.Command {
 		Args: cobra.MaximumNArgs(1),
 		Run:  runBootstrapAccount,
 	}
+	glb.AddFlagAccount(bootstrapAccountCmd)
 	return bootstrapAccountCmd
 }
 
@@ -68,7 +69,7 @@ func runBootstrapAccount(_ *cobra.Command, args []string) {
 	defer func() { _ = txStoreDB.Close() }()
 
 	txBytesBootstrapBalance, txid, err := txbuilder.DistributeInitialSupplyExt(stateStore, privKey, []ledger.LockBalance{
-		{addr, bootstrapBalance},
+		{Lock: addr, Balance: bootstrapBalance},
 	})
 	glb.AssertNoError(err)
 
@@ -26,6 +26,8 @@ func initIDCmd() *cobra.Command {
 	err := viper.BindPFlag("config", initLedgerIDCmd.PersistentFlags().Lookup("config"))
 	glb.AssertNoError(err)
 
+	glb.AddFlagAccount(initLedgerIDCmd)
+
 	return initLedgerIDCmd
 }
 
@@ -10,8 +10,10 @@ import (
 
 	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/peering"
 	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util"
 	"github.com/lunfardo314/proxima/util/lines"
 	"github.com/lunfardo314/proxima/util/testutil"
 	"github.com/spf13/cobra"
@@ -31,10 +33,52 @@ func initNodeConfigCmd() *cobra.Command {
 const (
 	proximaNodeProfile               = "proxima.yaml"
 	deterministicSeedForTestingNodes = 31415926535 + 2718281828
-	peeringPort                      = 4000
-	apiPortStart                     = 8000
+	// PeeringPortStart peering port of the test node with index 0. Node with index i uses PeeringPortStart+i
+	PeeringPortStart = 4000
+	// APIPortStart API port of the test node with index 0. Node with index i uses APIPortStart+i
+	APIPortStart = 8000
+	// MetricsPortStart Prometheus metrics port of the test node with index 0. Node with index i uses MetricsPortStart+i
+	MetricsPortStart = 14000
 )
 
+type (
+	// NodeConfigParams is data for the node configuration file
+	NodeConfigParams struct {
+		// comment lines at the beginning of the file
+		Intro          string
+		HostPrivateKey ed25519.PrivateKey
+		HostPort       int
+		// known peers: <name>: <multiaddr>
+		Peers       map[string]string
+		APIPort     int
+		MetricsPort int
+		// if nil, disabled 'boot' sequencer section is generated
+		Sequencer *SequencerConfigParams
+	}
+
+	SequencerConfigParams struct {
+		Name              string
+		SequencerID       ledger.ChainID
+		ControllerKey     ed25519.PrivateKey
+		Pace              int
+		MaxTagAlongInputs int
+	}
+)
+
+// TestingHostPrivateKeys deterministic host ID keys of testing nodes
+func TestingHostPrivateKeys(n int) []ed25519.PrivateKey {
+	return testutil.GetTestingPrivateKeys(n, deterministicSeedForTestingNodes)
+}
+
+// HostIDFromPrivateKey libp2p host ID of the host ID private key
+func HostIDFromPrivateKey(privateKey ed25519.PrivateKey) peer.ID {
+	lppPK, err := crypto.UnmarshalEd25519PrivateKey(privateKey)
+	glb.AssertNoError(err)
+	ret, err := peer.IDFromPrivateKey(lppPK)
+	glb.AssertNoError(err)
+	return ret
+}
+
 func runNodeConfigCommand(_ *cobra.Command, args []string) {
 	if glb.FileExists(proximaNodeProfile) {
 		prompt := fmt.Sprintf("file %s already exists. Overwrite?", proximaNodeProfile)
@@ -43,67 +87,82 @@ func runNodeConfigCommand(_ *cobra.Command, args []string) {
 		}
 	}
 
-	generatePeering := len(args) > 0
-	var err error
-	var peerConfig string
-	var hostPrivateKey ed25519.PrivateKey
-	var hostID peer.ID
-
-	hostPort := peeringPort
-	apiPort := apiPortStart
-	var intro string
-	if generatePeering {
-		intro = "# Testing configuration of the Proxima node with 4 other peers on the same machine\n" +
+	par := NodeConfigParams{
+		HostPort:    PeeringPortStart,
+		APIPort:     APIPortStart,
+		MetricsPort: MetricsPortStart,
+		Peers:       make(map[string]string),
+	}
+	if len(args) > 0 {
+		par.Intro = "# Testing configuration of the Proxima node with 4 other peers on the same machine\n" +
 			"# All private keys are auto-generated deterministically"
-		var hostPeerIndex int
-		var idPrivateKeys []ed25519.PrivateKey
-		hostPeerIndex, err = strconv.Atoi(args[0])
+		hostPeerIndex, err := strconv.Atoi(args[0])
 		glb.AssertNoError(err)
 		glb.Assertf(0 <= hostPeerIndex && hostPeerIndex <= 4, "argument must be one of: 0,1,2,3 or 4")
-		idPrivateKeys = testutil.GetTestingPrivateKeys(5, deterministicSeedForTestingNodes)
 
-		peeringCfgLines := lines.New("    ")
-		for i := 0; i < 5; i++ {
-			lppPK, err := crypto.UnmarshalEd25519PrivateKey(idPrivateKeys[i])
-			glb.AssertNoError(err)
-			peerID, err := peer.IDFromPrivateKey(lppPK)
-			glb.AssertNoError(err)
+		for i, privateKey := range TestingHostPrivateKeys(5) {
 			if i != hostPeerIndex {
-				peeringCfgLines.Add("peer%d: %s", i, peering.TestMultiAddrString(peerID, peeringPort+i))
+				par.Peers[fmt.Sprintf("peer%d", i)] = peering.TestMultiAddrString(HostIDFromPrivateKey(privateKey), PeeringPortStart+i)
 			} else {
-				hostID = peerID
-				hostPrivateKey = idPrivateKeys[i]
-				hostPort = peeringPort + i
-				apiPort = apiPortStart + i
+				par.HostPrivateKey = privateKey
+				par.HostPort = PeeringPortStart + i
+				par.APIPort = APIPortStart + i
+				par.MetricsPort = MetricsPortStart + i
 			}
 		}
-		peerConfig = peeringCfgLines.String()
 	} else {
-		intro = "# Configuration of the Proxima node"
-		_, hostPrivateKey, err = ed25519.GenerateKey(rand.Reader)
-		glb.AssertNoError(err)
-		lppHostPrivateKey, err := crypto.UnmarshalEd25519PrivateKey(hostPrivateKey)
-		glb.AssertNoError(err)
-		hostID, err = peer.IDFromPrivateKey(lppHostPrivateKey)
+		par.Intro = "# Configuration of the Proxima node"
+		var err error
+		_, par.HostPrivateKey, err = ed25519.GenerateKey(rand.Reader)
 		glb.AssertNoError(err)
+	}
+
+	err := os.WriteFile(proximaNodeProfile, NodeConfigYAML(par), 0666)
+	glb.AssertNoError(err)
+
+	glb.Infof("initial Proxima node configuration file has been saved as 'proxima.yaml'")
+}
+
+// NodeConfigYAML makes content of the node configuration file
+func NodeConfigYAML(par NodeConfigParams) []byte {
+	var peerConfig string
+	if len(par.Peers) > 0 {
+		peeringCfgLines := lines.New("    ")
+		for _, name := range util.KeysSorted(par.Peers, func(k1, k2 string) bool { return k1 < k2 }) {
+			peeringCfgLines.Add("%s: %s", name, par.Peers[name])
+		}
+		peerConfig = peeringCfgLines.String()
+	} else {
 		peerConfig =
 			`    # peer0: "<multiaddr0>"
     # peer1: "<multiaddr1>"
 `
 	}
 
-	yamlStr := fmt.Sprintf(configFileTemplate,
-		intro,
-		hex.EncodeToString(hostPrivateKey),
-		hostID.String(),
-		hostPort,
-		peerConfig,
-		apiPort,
-	)
-	err = os.WriteFile(proximaNodeProfile, []byte(yamlStr), 0666)
-	glb.AssertNoError(err)
+	var seqConfig string
+	if par.Sequencer != nil {
+		seqConfig = fmt.Sprintf(sequencerConfigTemplate,
+			par.Sequencer.Name,
+			"true",
+			par.Sequencer.SequencerID.StringHex(),
+			hex.EncodeToString(par.Sequencer.ControllerKey),
+			par.Sequencer.Pace,
+			par.Sequencer.MaxTagAlongInputs,
+		)
+	} else {
+		seqConfig = fmt.Sprintf(sequencerConfigTemplate, "boot", "false", "", "", 5, 50)
+	}
 
-	glb.Infof("initial Proxima node configuration file has been saved as 'proxima.yaml'")
+	return []byte(fmt.Sprintf(configFileTemplate,
+		par.Intro,
+		hex.EncodeToString(par.HostPrivateKey),
+		HostIDFromPrivateKey(par.HostPrivateKey).String(),
+		par.HostPort,
+		peerConfig,
+		par.APIPort,
+		seqConfig,
+		par.MetricsPort,
+	))
 }
 
 const configFileTemplate = `# FOR TESTING ONLY!!! PRIVATE KEYS AND DERIVED DATA SHOULD NOT BE USED IN PRODUCTION
@@ -128,28 +187,78 @@ peering:
 
 # Node's API config
 api:
+  # public listener with read-only endpoints and transaction submission
   server:
     # server port
     port: %d
+    # TLS is enabled when both certificate and key files are specified.
+    # If client CA file is specified, client certificates are required (mTLS)
+    tls:
+      cert_file:
+      key_file:
+      client_ca_file:
+    auth:
+      # if not empty, requests must contain header 'Authorization: Bearer <token>'
+      bearer_token:
+    rate_limit:
+      # maximum number of 'submit_tx' and 'simulate_tx' requests per minute from one IP. 0 means no limit
+      submit_tx_per_minute: 0
+  # operator listener. Disabled if port is 0. Has the same tls/auth options as the public listener.
+  # Serves all public endpoints without rate limits plus expensive operator queries such as 'get_state_diff'
+  admin:
+    # listen on localhost only by default
+    host: 127.0.0.1
+    port: 0
 
 
 # map of maps of sequencers <seq name>: <seq config>
 # usually none or 1 sequencer is configured for the node
 sequencers:
-  boot:
-    enable: false # will not start if false 
-    # chain ID of the sequencer
-    sequencer_id: 
-    # chain controller's private key (hex-encoded)
-    controller_key: 
-    # sequencer pace
-    pace: 5
-    # maximum tag-along inputs allowed in the sequencer milestone transaction
-    max_fee_inputs: 50
-
+%s
 # Other parameters used for tracing and debugging
+# Prometheus metrics exposure
+metrics:
+  port: %d
+
+# Ledger explorer web pages. Disabled if port is 0
+explorer:
+  host:
+  port: 0
+  # how many slots back the heaviest branch chain is explored when looking for branches and consumers of outputs
+  slots_back: 100
+
+# Finality tracker. Branch is final when it is included in all branches with coverage above
+# threshold_numerator/threshold_denominator of the double supply in the latest slots_back slots.
+# The latest finalized branch is served by 'get_finalized_branch' API
+finality:
+  threshold_numerator: 2
+  threshold_denominator: 3
+  slots_back: 2
+
+# Account and chain history indexer with its separate database. Serves 'get_account_history' and 'get_chain_history' API.
+# Requires transaction store
+indexer:
+  enable: false
+
+# Live UTXO tangle visualizer web page. Disabled if port is 0
+visualizer:
+  host:
+  port: 0
+
 # pprof config
 pprof:
   enable: false
   port: 8080
 `
+
+const sequencerConfigTemplate = `  %s:
+    enable: %s # will not start if false 
+    # chain ID of the sequencer
+    sequencer_id: %s
+    # chain controller's private key (hex-encoded)
+    controller_key: %s
+    # sequencer pace
+    pace: %d
+    # maximum tag-along inputs allowed in the sequencer milestone transaction
+    max_tag_along_inputs: %d
+`
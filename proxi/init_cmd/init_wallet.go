@@ -1,40 +1,54 @@
 package init_cmd
 
 import (
-	"encoding/hex"
 	"fmt"
 	"os"
 
-	"github.com/lunfardo314/proxima/ledger"
 	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/proxi/keystore"
+	"github.com/lunfardo314/proxima/util"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+)
+
+var (
+	restoreFromMnemonic bool
+	importPrivateKey    bool
 )
 
 func initProfileCmd() *cobra.Command {
 	initProfCmd := &cobra.Command{
 		Use:   "wallet [<profile name. Default: 'proxi'>]",
 		Args:  cobra.MaximumNArgs(1),
-		Short: "initializes proxi profile with the private key",
-		Run:   runInitProfileCommand,
+		Short: "initializes proxi profile with the new passphrase-encrypted keystore",
+		Long: `initializes proxi profile and the keystore '<profile name>.keystore'.
+By default, new mnemonic is generated and the 'default' account is derived from it. 
+With '--restore' the mnemonic is prompted, with '--import_key' the hex-encoded private key of the 'default' account is prompted.
+Passphrase is prompted or taken from the environment variable ` + glb.PassphraseEnvVar,
+		Run: runInitProfileCommand,
 	}
-	initProfCmd.PersistentFlags().String("wallet.private_key", "", "mandatory hex-encoded private key")
-	err := viper.BindPFlag("wallet.private_key", initProfCmd.PersistentFlags().Lookup("wallet.private_key"))
-	glb.AssertNoError(err)
-
+	initProfCmd.PersistentFlags().BoolVar(&restoreFromMnemonic, "restore", false, "restore HD wallet from the existing mnemonic")
+	initProfCmd.PersistentFlags().BoolVar(&importPrivateKey, "import_key", false, "import existing private key as the 'default' account")
 	return initProfCmd
 }
 
-const minimumSeedLength = 8
-
 const profileTemplate = `# proxi profile 
 wallet:
-    private_key: %s
+    # passphrase-encrypted keystore. Managed with 'proxi wallet' subcommands
+    keystore: %s
+    # keystore account used to sign transactions. Can be overridden with '--account'
+    account_name: %s
     account: %s
     # own sequencer (controlled by the private key). Defaults to bootstrap sequencer ID
-    sequencer: 
+    sequencer: %s
 api:
-    endpoint:
+    endpoint: %s
+    # bearer token, if required by the node's API listener
+    bearer_token:
+    # TLS: CA of the server certificate and client certificate/key for mTLS. Not used if empty
+    tls:
+        ca_file:
+        cert_file:
+        key_file:
 `
 
 func runInitProfileCommand(_ *cobra.Command, args []string) {
@@ -44,15 +58,43 @@ func runInitProfileCommand(_ *cobra.Command, args []string) {
 	}
 	profileFname := profileName + ".yaml"
 	glb.Assertf(!glb.FileExists(profileFname), "file %s already exists", profileFname)
+	keystoreFname := profileName + ".keystore"
+	glb.Assertf(!glb.FileExists(keystoreFname), "file %s already exists", keystoreFname)
 
-	privKey := glb.MustGetPrivateKey()
-	addr := ledger.AddressED25519FromPrivateKey(privKey)
-	profile := fmt.Sprintf(profileTemplate, hex.EncodeToString(privKey), addr.String())
-	err := os.WriteFile(profileFname, []byte(profile), 0666)
+	var mnemonic string
+	var err error
+	if restoreFromMnemonic {
+		mnemonic = glb.ReadSecretLine("mnemonic: ")
+	} else {
+		mnemonic, err = keystore.NewMnemonic()
+		glb.AssertNoError(err)
+	}
+	ks, err := keystore.Create(keystoreFname, mnemonic, glb.ReadNewPassphrase())
+	glb.AssertNoError(err)
+
+	var acc *keystore.Account
+	if importPrivateKey {
+		privKey, err := util.ED25519PrivateKeyFromHexString(glb.ReadSecretLine("hex-encoded private key: "))
+		glb.AssertNoError(err)
+		acc, err = ks.Import(keystore.DefaultAccountName, privKey)
+		glb.AssertNoError(err)
+	} else {
+		acc, err = ks.Derive(keystore.DefaultAccountName)
+		glb.AssertNoError(err)
+	}
+	glb.AssertNoError(ks.Save())
+	glb.Infof("keystore '%s' has been created successfully", keystoreFname)
+
+	err = os.WriteFile(profileFname, ProfileYAML(keystoreFname, acc, "", ""), 0666)
 	glb.AssertNoError(err)
 	glb.Infof("proxi profile '%s' has been created successfully", profileFname)
 
-	viper.SetConfigFile(profileFname)
-	wd := glb.GetWalletData()
-	glb.Assertf(wd.PrivateKey.Equal(privKey), "inconsistency")
+	if !restoreFromMnemonic {
+		glb.Infof("\nmnemonic of the HD wallet. Write it down and keep it in a safe place:\n\n%s\n", mnemonic)
+	}
+}
+
+// ProfileYAML makes content of the proxi profile with the keystore account. Sequencer ID and API endpoint may be empty
+func ProfileYAML(keystoreFname string, acc *keystore.Account, sequencerID, endpoint string) []byte {
+	return []byte(fmt.Sprintf(profileTemplate, keystoreFname, acc.Name, acc.Address, sequencerID, endpoint))
 }
@@ -0,0 +1,68 @@
+package keystore
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+)
+
+// SLIP-0010 hierarchical derivation of ED25519 keys. ED25519 supports hardened derivation only.
+// Account keys are derived along the path m/44'/CoinType'/<index>'
+
+const (
+	HardenedOffset = uint32(0x80000000)
+	Purpose        = uint32(44)
+	// CoinType Proxima is not registered in SLIP-0044. The value is arbitrary, but must never change
+	CoinType = uint32(1314)
+
+	slip10Curve = "ed25519 seed"
+)
+
+// DerivationPath of the account key with the index
+func DerivationPath(index uint32) []uint32 {
+	return []uint32{Purpose | HardenedOffset, CoinType | HardenedOffset, index | HardenedOffset}
+}
+
+func DerivationPathString(index uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'", Purpose, CoinType, index)
+}
+
+// DeriveAccountKey derives private key of the account from the BIP-39 seed
+func DeriveAccountKey(seed []byte, index uint32) (ed25519.PrivateKey, error) {
+	if index >= HardenedOffset {
+		return nil, fmt.Errorf("account index %d is too big", index)
+	}
+	return DeriveKey(seed, DerivationPath(index)...)
+}
+
+// DeriveKey derives private key from the seed along the path. All path elements must be hardened
+func DeriveKey(seed []byte, path ...uint32) (ed25519.PrivateKey, error) {
+	key, chainCode := slip10Master(seed)
+	for _, idx := range path {
+		if idx < HardenedOffset {
+			return nil, fmt.Errorf("ED25519 supports only hardened derivation. Non-hardened index: %d", idx)
+		}
+		key, chainCode = slip10Child(key, chainCode, idx)
+	}
+	return ed25519.NewKeyFromSeed(key), nil
+}
+
+func slip10Master(seed []byte) ([]byte, []byte) {
+	h := hmac.New(sha512.New, []byte(slip10Curve))
+	h.Write(seed)
+	sum := h.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+func slip10Child(key, chainCode []byte, idx uint32) ([]byte, []byte) {
+	var data [1 + 32 + 4]byte
+	copy(data[1:33], key)
+	binary.BigEndian.PutUint32(data[33:], idx)
+
+	h := hmac.New(sha512.New, chainCode)
+	h.Write(data[:])
+	sum := h.Sum(nil)
+	return sum[:32], sum[32:]
+}
@@ -0,0 +1,393 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Keystore file keeps the BIP-39 mnemonic of the HD wallet and imported private keys encrypted with the passphrase.
+// Account names, derivation indices and addresses are kept in plaintext, so accounts can be listed without the passphrase.
+// Plaintext part of the file is authenticated as additional data of the cipher, so any modification of it is
+// detected when the keystore is opened with the passphrase.
+// Private keys of HD accounts are derived from the mnemonic with SLIP-0010 (see hd.go)
+
+const (
+	CurrentVersion     = 2
+	DefaultAccountName = "default"
+
+	kdfScrypt     = "scrypt"
+	cipherAESGCM  = "aes-256-gcm"
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	keyLen        = 32
+	saltLen       = 32
+	mnemonicBits  = 256
+	filePermWrite = 0600
+)
+
+var (
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+	ErrAccountNotFound = errors.New("account not found")
+	ErrAccountExists   = errors.New("account already exists")
+)
+
+type (
+	// File is the on-disk JSON format of the keystore
+	File struct {
+		Version    int       `json:"version"`
+		KDF        KDFParams `json:"kdf"`
+		Cipher     string    `json:"cipher"`
+		Nonce      string    `json:"nonce"`
+		Ciphertext string    `json:"ciphertext"`
+		Accounts   []Account `json:"accounts"`
+	}
+
+	KDFParams struct {
+		Name string `json:"name"`
+		Salt string `json:"salt"`
+		N    int    `json:"n"`
+		R    int    `json:"r"`
+		P    int    `json:"p"`
+	}
+
+	Account struct {
+		Name string `json:"name"`
+		// derivation index of the HD account. Nil for imported keys
+		Index *uint32 `json:"index,omitempty"`
+		// address in EasyFL source form
+		Address string `json:"address"`
+	}
+
+	// secrets is the encrypted part of the keystore
+	secrets struct {
+		Mnemonic string `json:"mnemonic"`
+		// imported private keys, hex-encoded, by account name
+		Imported map[string]string `json:"imported,omitempty"`
+	}
+
+	// authenticatedData is the plaintext part of the file authenticated by the cipher
+	authenticatedData struct {
+		Version  int       `json:"version"`
+		KDF      KDFParams `json:"kdf"`
+		Cipher   string    `json:"cipher"`
+		Accounts []Account `json:"accounts"`
+	}
+
+	// Keystore is an unlocked keystore
+	Keystore struct {
+		fname      string
+		passphrase string
+		accounts   []Account
+		secrets    secrets
+	}
+)
+
+// NewMnemonic generates new random 24 words mnemonic
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(mnemonicBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// Create creates new keystore with the mnemonic. It does not contain any accounts and is not saved until Save
+func Create(fname, mnemonic, passphrase string) (*Keystore, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, fmt.Errorf("invalid mnemonic")
+	}
+	if _, err := os.Stat(fname); err == nil {
+		return nil, fmt.Errorf("keystore file '%s' already exists", fname)
+	}
+	return &Keystore{
+		fname:      fname,
+		passphrase: passphrase,
+		accounts:   make([]Account, 0),
+		secrets:    secrets{Mnemonic: mnemonic},
+	}, nil
+}
+
+// Open reads and decrypts the keystore file
+func Open(fname, passphrase string) (*Keystore, error) {
+	f, err := readFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := f.decrypt(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	ret := &Keystore{
+		fname:      fname,
+		passphrase: passphrase,
+		accounts:   f.Accounts,
+	}
+	if err = json.Unmarshal(plain, &ret.secrets); err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return ret, nil
+}
+
+// ReadAccounts reads accounts from the keystore file. Does not require passphrase, so the accounts are not
+// authenticated: modification of the file is only detected by Open
+func ReadAccounts(fname string) ([]Account, error) {
+	f, err := readFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return f.Accounts, nil
+}
+
+func readFile(fname string) (*File, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var ret File
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return nil, fmt.Errorf("can't parse keystore file '%s': %w", fname, err)
+	}
+	if ret.Version != CurrentVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", ret.Version)
+	}
+	return &ret, nil
+}
+
+// Save encrypts and writes the keystore with fresh salt and nonce
+func (ks *Keystore) Save() error {
+	plain, err := json.Marshal(&ks.secrets)
+	if err != nil {
+		return err
+	}
+	f, err := encrypt(plain, ks.passphrase, ks.accounts)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(ks.fname, data)
+}
+
+// writeFileAtomic writes data to the temporary file in the same directory and renames it, so that
+// the existing file is never left partially written
+func writeFileAtomic(fname string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(fname), filepath.Base(fname)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	if err = tmp.Chmod(filePermWrite); err == nil {
+		if _, err = tmp.Write(data); err == nil {
+			err = tmp.Sync()
+		}
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpName, fname)
+}
+
+func (ks *Keystore) FileName() string {
+	return ks.fname
+}
+
+func (ks *Keystore) Mnemonic() string {
+	return ks.secrets.Mnemonic
+}
+
+func (ks *Keystore) ChangePassphrase(passphrase string) {
+	ks.passphrase = passphrase
+}
+
+func (ks *Keystore) Accounts() []Account {
+	return ks.accounts
+}
+
+func (ks *Keystore) Account(name string) (*Account, bool) {
+	for i := range ks.accounts {
+		if ks.accounts[i].Name == name {
+			return &ks.accounts[i], true
+		}
+	}
+	return nil, false
+}
+
+// Derive adds new HD account with the next unused derivation index
+func (ks *Keystore) Derive(name string) (*Account, error) {
+	if _, exists := ks.Account(name); exists {
+		return nil, fmt.Errorf("%w: '%s'", ErrAccountExists, name)
+	}
+	var idx uint32
+	for _, acc := range ks.accounts {
+		if acc.Index != nil && *acc.Index >= idx {
+			idx = *acc.Index + 1
+		}
+	}
+	privKey, err := ks.hdKey(idx)
+	if err != nil {
+		return nil, err
+	}
+	ks.accounts = append(ks.accounts, Account{
+		Name:    name,
+		Index:   &idx,
+		Address: ledger.AddressED25519FromPrivateKey(privKey).String(),
+	})
+	return &ks.accounts[len(ks.accounts)-1], nil
+}
+
+// Import adds account with the private key not derived from the mnemonic
+func (ks *Keystore) Import(name string, privateKey ed25519.PrivateKey) (*Account, error) {
+	if _, exists := ks.Account(name); exists {
+		return nil, fmt.Errorf("%w: '%s'", ErrAccountExists, name)
+	}
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("wrong private key size")
+	}
+	if ks.secrets.Imported == nil {
+		ks.secrets.Imported = make(map[string]string)
+	}
+	ks.secrets.Imported[name] = hex.EncodeToString(privateKey)
+	ks.accounts = append(ks.accounts, Account{
+		Name:    name,
+		Address: ledger.AddressED25519FromPrivateKey(privateKey).String(),
+	})
+	return &ks.accounts[len(ks.accounts)-1], nil
+}
+
+// PrivateKey returns private key of the account
+func (ks *Keystore) PrivateKey(name string) (ed25519.PrivateKey, error) {
+	acc, found := ks.Account(name)
+	if !found {
+		return nil, fmt.Errorf("%w: '%s'", ErrAccountNotFound, name)
+	}
+	if acc.Index != nil {
+		return ks.hdKey(*acc.Index)
+	}
+	keyStr, found := ks.secrets.Imported[name]
+	if !found {
+		return nil, fmt.Errorf("inconsistent keystore: private key of the imported account '%s' not found", name)
+	}
+	keyBin, err := hex.DecodeString(keyStr)
+	if err != nil || len(keyBin) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("inconsistent keystore: wrong private key of the imported account '%s'", name)
+	}
+	return keyBin, nil
+}
+
+func (ks *Keystore) hdKey(idx uint32) (ed25519.PrivateKey, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(ks.secrets.Mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	return DeriveAccountKey(seed, idx)
+}
+
+func encrypt(plain []byte, passphrase string, accounts []Account) (*File, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	ret := &File{
+		Version: CurrentVersion,
+		KDF: KDFParams{
+			Name: kdfScrypt,
+			Salt: hex.EncodeToString(salt),
+			N:    scryptN,
+			R:    scryptR,
+			P:    scryptP,
+		},
+		Cipher:   cipherAESGCM,
+		Accounts: accounts,
+	}
+	aead, err := ret.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	additionalData, err := ret.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	ret.Nonce = hex.EncodeToString(nonce)
+	ret.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, plain, additionalData))
+	return ret, nil
+}
+
+func (f *File) decrypt(passphrase string) ([]byte, error) {
+	aead, err := f.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(f.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("wrong nonce in the keystore")
+	}
+	ciphertext, err := hex.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("wrong ciphertext in the keystore")
+	}
+	additionalData, err := f.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plain, nil
+}
+
+// additionalData returns plaintext part of the file to be authenticated by the cipher
+func (f *File) additionalData() ([]byte, error) {
+	return json.Marshal(&authenticatedData{
+		Version:  f.Version,
+		KDF:      f.KDF,
+		Cipher:   f.Cipher,
+		Accounts: f.Accounts,
+	})
+}
+
+func (f *File) aead(passphrase string) (cipher.AEAD, error) {
+	if f.KDF.Name != kdfScrypt {
+		return nil, fmt.Errorf("unsupported key derivation function '%s'", f.KDF.Name)
+	}
+	if f.Cipher != cipherAESGCM {
+		return nil, fmt.Errorf("unsupported cipher '%s'", f.Cipher)
+	}
+	salt, err := hex.DecodeString(f.KDF.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("wrong salt in the keystore")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, f.KDF.N, f.KDF.R, f.KDF.P, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
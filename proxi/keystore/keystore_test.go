@@ -0,0 +1,140 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLIP10(t *testing.T) {
+	// SLIP-0010 test vector 1 for ed25519
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	key, err := DeriveKey(seed)
+	require.NoError(t, err)
+	require.EqualValues(t, "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7", hex.EncodeToString(key.Seed()))
+
+	key, err = DeriveKey(seed, 0|HardenedOffset)
+	require.NoError(t, err)
+	require.EqualValues(t, "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3", hex.EncodeToString(key.Seed()))
+
+	key, err = DeriveKey(seed, 0|HardenedOffset, 1|HardenedOffset)
+	require.NoError(t, err)
+	require.EqualValues(t, "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2", hex.EncodeToString(key.Seed()))
+
+	_, err = DeriveKey(seed, 1)
+	require.Error(t, err)
+}
+
+func TestKeystore(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.keystore")
+	mnemonic, err := NewMnemonic()
+	require.NoError(t, err)
+
+	t.Run("create and open", func(t *testing.T) {
+		ks, err := Create(fname, mnemonic, "secret")
+		require.NoError(t, err)
+		acc0, err := ks.Derive(DefaultAccountName)
+		require.NoError(t, err)
+		acc1, err := ks.Derive("second")
+		require.NoError(t, err)
+		require.EqualValues(t, 0, *acc0.Index)
+		require.EqualValues(t, 1, *acc1.Index)
+		_, err = ks.Derive("second")
+		require.True(t, errors.Is(err, ErrAccountExists))
+		require.NoError(t, ks.Save())
+
+		data, err := os.ReadFile(fname)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), mnemonic)
+
+		_, err = Open(fname, "wrong")
+		require.True(t, errors.Is(err, ErrWrongPassphrase))
+
+		ks, err = Open(fname, "secret")
+		require.NoError(t, err)
+		require.EqualValues(t, mnemonic, ks.Mnemonic())
+		key, err := ks.PrivateKey("second")
+		require.NoError(t, err)
+		require.EqualValues(t, acc1.Address, ledger.AddressED25519FromPrivateKey(key).String())
+
+		_, err = ks.PrivateKey("unknown")
+		require.True(t, errors.Is(err, ErrAccountNotFound))
+
+		accounts, err := ReadAccounts(fname)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, len(accounts))
+	})
+	t.Run("import and change passphrase", func(t *testing.T) {
+		privKey := testutil.GetTestingPrivateKey(1)
+		ks, err := Open(fname, "secret")
+		require.NoError(t, err)
+		_, err = ks.Import("imported", privKey)
+		require.NoError(t, err)
+		ks.ChangePassphrase("secret2")
+		require.NoError(t, ks.Save())
+
+		_, err = Open(fname, "secret")
+		require.True(t, errors.Is(err, ErrWrongPassphrase))
+		ks, err = Open(fname, "secret2")
+		require.NoError(t, err)
+		key, err := ks.PrivateKey("imported")
+		require.NoError(t, err)
+		require.True(t, key.Equal(privKey))
+
+		// same mnemonic derives same keys
+		ks2, err := Create(filepath.Join(t.TempDir(), "other.keystore"), mnemonic, "")
+		require.NoError(t, err)
+		_, err = ks2.Derive("a")
+		require.NoError(t, err)
+		acc, err := ks2.Derive("b")
+		require.NoError(t, err)
+		orig, _ := ks.Account("second")
+		require.EqualValues(t, orig.Address, acc.Address)
+	})
+	t.Run("tampered accounts", func(t *testing.T) {
+		data, err := os.ReadFile(fname)
+		require.NoError(t, err)
+		var f File
+		require.NoError(t, json.Unmarshal(data, &f))
+		f.Accounts[0].Address, f.Accounts[1].Address = f.Accounts[1].Address, f.Accounts[0].Address
+		data, err = json.Marshal(&f)
+		require.NoError(t, err)
+		tampered := filepath.Join(t.TempDir(), "tampered.keystore")
+		require.NoError(t, os.WriteFile(tampered, data, 0600))
+
+		_, err = Open(tampered, "secret2")
+		require.True(t, errors.Is(err, ErrWrongPassphrase))
+
+		// downgraded version is rejected
+		require.NoError(t, json.Unmarshal(data, &f))
+		f.Version = 1
+		data, err = json.Marshal(&f)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(tampered, data, 0600))
+		_, err = Open(tampered, "secret2")
+		require.Error(t, err)
+
+		_, err = Open(fname, "secret2")
+		require.NoError(t, err)
+
+		// no temporary files left after save
+		entries, err := os.ReadDir(filepath.Dir(fname))
+		require.NoError(t, err)
+		require.EqualValues(t, 1, len(entries))
+	})
+	t.Run("create over existing", func(t *testing.T) {
+		_, err := Create(fname, mnemonic, "secret")
+		require.Error(t, err)
+		_, err = Create(filepath.Join(t.TempDir(), "x"), "abandon abandon", "secret")
+		require.Error(t, err)
+	})
+}
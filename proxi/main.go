@@ -8,6 +8,9 @@ import (
 	"github.com/lunfardo314/proxima/proxi/glb"
 	"github.com/lunfardo314/proxima/proxi/init_cmd"
 	"github.com/lunfardo314/proxima/proxi/node_cmd"
+	"github.com/lunfardo314/proxima/proxi/testnet_cmd"
+	"github.com/lunfardo314/proxima/proxi/tx_cmd"
+	"github.com/lunfardo314/proxima/proxi/wallet_cmd"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -20,9 +23,12 @@ func main() {
 		Short: "a simple CLI for the Proxima project",
 		Long: `proxi is a CLI tool for the Proxima project. It provides:
       - initialization of the ledger, node and wallet
+      - passphrase-encrypted keystore with HD wallet accounts
+      - offline signing of transactions
       - database level access to the Proxima ledger for admin purposes, including genesis creation
       - access to ledger via the Proxima node API. This includes simple wallet functions to access usual accounts 
 and withdraw funds from the sequencer chain
+      - local testnet with several nodes and sequencers for development and testing
 `,
 		Run: func(cmd *cobra.Command, _ []string) {
 			_ = cmd.Help()
@@ -41,6 +47,9 @@ and withdraw funds from the sequencer chain
 		init_cmd.CmdInit(),
 		db_cmd.Init(),
 		node_cmd.Init(),
+		wallet_cmd.Init(),
+		tx_cmd.Init(),
+		testnet_cmd.Init(),
 	)
 	rootCmd.InitDefaultHelpCmd()
 	if err = rootCmd.Execute(); err != nil {
@@ -0,0 +1,53 @@
+package node_cmd
+
+import (
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyChainIDStr string
+	historyMaxItems   int
+)
+
+func initHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: `displays transaction history of the account or output history of the chain. Requires indexer enabled on the node`,
+		Args:  cobra.NoArgs,
+		Run:   runHistoryCmd,
+	}
+	historyCmd.PersistentFlags().StringVar(&historyChainIDStr, "chain", "", "hex-encoded chain ID. If not specified, history of the target account is displayed")
+	historyCmd.PersistentFlags().IntVarP(&historyMaxItems, "max", "m", 20, "maximum number of items to display")
+	historyCmd.InitDefaultHelpCmd()
+	return historyCmd
+}
+
+func runHistoryCmd(_ *cobra.Command, _ []string) {
+	glb.InitLedgerFromNode()
+
+	if historyChainIDStr != "" {
+		chainID, err := ledger.ChainIDFromHexString(historyChainIDStr)
+		glb.AssertNoError(err)
+
+		outs, err := glb.GetClient().GetChainHistory(chainID, historyMaxItems)
+		glb.AssertNoError(err)
+
+		glb.Infof("history of the chain %s, latest first:", chainID.String())
+		for _, o := range outs {
+			glb.Infof("   %s  %s", o.ID.String(), util.GoTh(o.Amount))
+		}
+		return
+	}
+
+	accountable := glb.MustGetTarget()
+	txs, err := glb.GetClient().GetAccountHistory(accountable, historyMaxItems)
+	glb.AssertNoError(err)
+
+	glb.Infof("transactions of the account %s, latest first:", accountable.String())
+	for _, tx := range txs {
+		glb.Infof("   %s  in: %s, out: %s", tx.TxID.String(), util.GoTh(tx.In), util.GoTh(tx.Out))
+	}
+}
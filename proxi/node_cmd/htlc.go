@@ -0,0 +1,241 @@
+package node_cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lunfardo314/proxima/api/client"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/spf13/cobra"
+)
+
+const defaultHTLCDeadlineSlots = 360
+
+// without Var does not work
+var (
+	htlcClaimStr      string
+	htlcHashStr       string
+	htlcSecretStr     string
+	htlcDeadlineSlots int
+)
+
+func initHTLCCmd() *cobra.Command {
+	htlcCmd := &cobra.Command{
+		Use:   "htlc [<subcommand>]",
+		Short: `hash time-locked contracts for atomic swaps`,
+		Long: `hash time-locked contracts for atomic swaps. Until the deadline the output can be claimed by the claim account,
+which presents the secret, the preimage of the hash. After the deadline it can be refunded by the wallet account, which created it`,
+		Args: cobra.NoArgs,
+	}
+	htlcCmd.AddCommand(
+		initHTLCCreateCmd(),
+		initHTLCClaimCmd(),
+		initHTLCRefundCmd(),
+		initHTLCListCmd(),
+	)
+	htlcCmd.InitDefaultHelpCmd()
+	return htlcCmd
+}
+
+func initHTLCCreateCmd() *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:   "create <amount>",
+		Short: `sends tokens from the wallet's account to the HTLC lock. Wallet account is the refund account`,
+		Long: `sends tokens from the wallet's account to the HTLC lock. Wallet account is the refund account.
+If hash is not specified, random secret is generated and displayed`,
+		Args: cobra.ExactArgs(1),
+		Run:  runHTLCCreateCmd,
+	}
+	createCmd.Flags().StringVar(&htlcClaimStr, "claim", "", "ED25519 address of the claim account in EasyFL source format")
+	createCmd.Flags().StringVar(&htlcHashStr, "hash", "", "blake2b hash of the secret, hex encoded")
+	createCmd.Flags().IntVar(&htlcDeadlineSlots, "deadline", defaultHTLCDeadlineSlots, "deadline in slots from now")
+	glb.AddFlagTraceTx(createCmd)
+	createCmd.InitDefaultHelpCmd()
+	return createCmd
+}
+
+func initHTLCClaimCmd() *cobra.Command {
+	claimCmd := &cobra.Command{
+		Use:   "claim <output ID>",
+		Short: `claims HTLC output to the wallet account with the secret before the deadline`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runHTLCClaimCmd,
+	}
+	claimCmd.Flags().StringVar(&htlcSecretStr, "secret", "", "secret, the preimage of the hash, hex encoded")
+	glb.AddFlagTraceTx(claimCmd)
+	claimCmd.InitDefaultHelpCmd()
+	return claimCmd
+}
+
+func initHTLCRefundCmd() *cobra.Command {
+	refundCmd := &cobra.Command{
+		Use:   "refund <output ID>",
+		Short: `refunds HTLC output to the wallet account after the deadline`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runHTLCRefundCmd,
+	}
+	glb.AddFlagTraceTx(refundCmd)
+	refundCmd.InitDefaultHelpCmd()
+	return refundCmd
+}
+
+func initHTLCListCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: `lists HTLC outputs where wallet account is the claim or the refund account`,
+		Args:  cobra.NoArgs,
+		Run:   runHTLCListCmd,
+	}
+	listCmd.InitDefaultHelpCmd()
+	return listCmd
+}
+
+func runHTLCCreateCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+
+	amount, err := strconv.ParseUint(args[0], 10, 64)
+	glb.AssertNoError(err)
+	glb.Assertf(htlcClaimStr != "", "claim account must be specified with --claim")
+	claim, err := ledger.AddressED25519FromSource(htlcClaimStr)
+	glb.AssertNoError(err)
+	glb.Assertf(htlcDeadlineSlots > 0, "deadline must be positive")
+
+	var hash []byte
+	if htlcHashStr == "" {
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		glb.AssertNoError(err)
+		hash = ledger.HTLCHash(secret)
+		glb.Infof("generated secret: %s\nKEEP IT SAFE. The secret will be revealed on the ledger when the output is claimed", hex.EncodeToString(secret))
+	} else {
+		hash, err = hex.DecodeString(htlcHashStr)
+		glb.AssertNoError(err)
+		glb.Assertf(len(hash) == ledger.HTLCHashSize, "hash must be %d bytes long", ledger.HTLCHashSize)
+	}
+	deadline := ledger.TimeNow().AddSlots(htlcDeadlineSlots)
+	htlc := ledger.NewHTLCLock(deadline, hash, claim, walletData.Account)
+	glb.Infof("HTLC lock: %s", htlc.String())
+
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+	prompt := fmt.Sprintf("send %s to HTLC claimable by %s until %s? Tag-along fee %s to %s",
+		util.GoTh(amount), claim.String(), deadline.String(), util.GoTh(feeAmount), tagAlongSeqID.StringShort())
+	if !glb.YesNoPrompt(prompt, true, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		os.Exit(0)
+	}
+
+	txCtx, err := glb.GetClient().TransferFromED25519Wallet(client.TransferFromED25519WalletParams{
+		WalletPrivateKey: walletData.PrivateKey,
+		TagAlongSeqID:    tagAlongSeqID,
+		TagAlongFee:      feeAmount,
+		Amount:           amount,
+		Target:           htlc,
+		TraceTx:          glb.TraceTx(),
+	})
+	if txCtx != nil {
+		glb.Verbosef("-------- transfer transaction ---------\n%s\n----------------", txCtx.String())
+	}
+	glb.AssertNoError(err)
+	txCtx.ForEachProducedOutput(func(_ byte, o *ledger.Output, oid *ledger.OutputID) bool {
+		if ledger.EqualConstraints(o.Lock(), htlc) {
+			glb.Infof("HTLC output ID: %s", oid.StringHex())
+		}
+		return true
+	})
+	glb.Infof("transaction submitted successfully")
+
+	if glb.NoWait() {
+		return
+	}
+	glb.ReportTxInclusion(*txCtx.TransactionID(), time.Second)
+}
+
+func runHTLCClaimCmd(_ *cobra.Command, args []string) {
+	glb.Assertf(htlcSecretStr != "", "secret must be specified with --secret")
+	secret, err := hex.DecodeString(htlcSecretStr)
+	glb.AssertNoError(err)
+	submitHTLCTransaction(args[0], func(par *txbuilder.HTLCUnlockData) ([]byte, error) {
+		par.Preimage = secret
+		return txbuilder.MakeHTLCClaimTransaction(par)
+	})
+}
+
+func runHTLCRefundCmd(_ *cobra.Command, args []string) {
+	submitHTLCTransaction(args[0], txbuilder.MakeHTLCRefundTransaction)
+}
+
+func submitHTLCTransaction(oidStr string, makeTx func(par *txbuilder.HTLCUnlockData) ([]byte, error)) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+
+	oid, err := ledger.OutputIDFromHexString(oidStr)
+	glb.AssertNoError(err)
+	oData, err := glb.GetClient().GetOutputDataFromHeaviestState(&oid)
+	glb.AssertNoError(err)
+	o, err := ledger.OutputFromBytesReadOnly(oData)
+	glb.AssertNoError(err)
+
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+	txBytes, err := makeTx(&txbuilder.HTLCUnlockData{
+		Inputs:     []*ledger.OutputWithID{{ID: oid, Output: o}},
+		PrivateKey: walletData.PrivateKey,
+		TagAlong: &txbuilder.TagAlongData{
+			SeqID:  *tagAlongSeqID,
+			Amount: feeAmount,
+		},
+	})
+	glb.AssertNoError(err)
+
+	prompt := fmt.Sprintf("transfer %s from HTLC output %s to %s? Tag-along fee %s to %s",
+		util.GoTh(o.Amount()), oid.StringShort(), walletData.Account.String(), util.GoTh(feeAmount), tagAlongSeqID.StringShort())
+	if !glb.YesNoPrompt(prompt, true, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		os.Exit(0)
+	}
+	txid, err := transaction.IDFromTransactionBytes(txBytes)
+	glb.AssertNoError(err)
+	err = glb.GetClient().SubmitTransaction(txBytes, glb.TraceTx())
+	glb.AssertNoError(err)
+	glb.Infof("transaction %s submitted successfully", txid.StringShort())
+
+	if glb.NoWait() {
+		return
+	}
+	glb.ReportTxInclusion(txid, time.Second)
+}
+
+func runHTLCListCmd(_ *cobra.Command, _ []string) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+
+	outs, err := glb.GetClient().GetAccountOutputs(walletData.Account, func(o *ledger.Output) bool {
+		return o.Lock().Name() == ledger.HTLCLockName
+	})
+	glb.AssertNoError(err)
+
+	now := ledger.TimeNow()
+	glb.Infof("%d HTLC outputs in the account %s", len(outs), walletData.Account.String())
+	for _, o := range outs {
+		htlc := o.Output.Lock().(*ledger.HTLCLock)
+		var status string
+		switch {
+		case ledger.EqualConstraints(htlc.Claim, walletData.Account) && htlc.CanBeClaimedAt(now):
+			status = "can be claimed with the secret"
+		case ledger.EqualConstraints(htlc.Refund, walletData.Account) && htlc.CanBeRefundedAt(now):
+			status = "can be refunded"
+		default:
+			status = "locked"
+		}
+		glb.Infof("%s: %s, deadline %s, hash %s: %s",
+			o.ID.StringHex(), util.GoTh(o.Output.Amount()), htlc.Deadline.String(), hex.EncodeToString(htlc.Hash), status)
+	}
+}
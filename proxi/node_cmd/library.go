@@ -0,0 +1,44 @@
+package node_cmd
+
+import (
+	"encoding/hex"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+func initLibraryCmd() *cobra.Command {
+	libraryCmd := &cobra.Command{
+		Use:   "library [<hex-encoded bytecode>]",
+		Short: `displays ledger library committed in the state of the node. With the argument, decompiles the bytecode with the library`,
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runLibraryCmd,
+	}
+	libraryCmd.InitDefaultHelpCmd()
+	return libraryCmd
+}
+
+func runLibraryCmd(_ *cobra.Command, args []string) {
+	ledgerID, err := glb.GetClient().GetLedgerID()
+	glb.AssertNoError(err)
+	libBytes, upgrades, err := glb.GetClient().GetLedgerLibrary()
+	glb.AssertNoError(err)
+
+	// the library is constructed from the data only, without the ledger definitions of proxi binary
+	lib, err := ledger.LibraryFromData(ledgerID, libBytes)
+	glb.AssertNoError(err)
+
+	if len(args) == 0 {
+		libData := lib.LibraryData()
+		glb.Infof("ledger %s", libData.String())
+		glb.Verbosef("%s", libData.Lines("   ").String())
+		glb.Infof("library upgrades applied by the node: %d. Use 'proxi node upgrade list' to display them", len(upgrades))
+		return
+	}
+	code, err := hex.DecodeString(args[0])
+	glb.AssertNoError(err)
+	src, err := lib.DecompileBytecode(code)
+	glb.AssertNoError(err)
+	glb.Infof("%s", src)
+}
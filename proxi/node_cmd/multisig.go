@@ -0,0 +1,248 @@
+package node_cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lunfardo314/proxima/api/client"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/spf13/cobra"
+)
+
+const defaultMultisigTxFileName = "tx.multisig.yaml"
+
+// without Var does not work
+var (
+	multisigLockStr    string
+	multisigOutputFile string
+)
+
+func initMultisigCmd() *cobra.Command {
+	multisigCmd := &cobra.Command{
+		Use:   "multisig [<subcommand>]",
+		Short: `M-of-N multi-signature ED25519 lock`,
+		Long: `M-of-N multi-signature ED25519 lock. Workflow:
+   'proxi node multisig lock <M> <address>...' displays the lock and its balance,
+   'proxi node multisig fund <amount> --lock <lock>' sends tokens from the wallet to the lock,
+   'proxi node multisig create <amount> --lock <lock> -t <target>' builds the transaction and saves it to the file,
+   'proxi tx cosign <file>' adds signature of the cosigner, may be done on the offline host,
+   'proxi node tx submit <file>' submits the transaction when at least M cosigners signed it`,
+		Args: cobra.NoArgs,
+	}
+	multisigCmd.AddCommand(
+		initMultisigLockCmd(),
+		initMultisigFundCmd(),
+		initMultisigCreateCmd(),
+	)
+	multisigCmd.InitDefaultHelpCmd()
+	return multisigCmd
+}
+
+func initMultisigLockCmd() *cobra.Command {
+	lockCmd := &cobra.Command{
+		Use:   "lock <threshold> <address>...",
+		Short: `displays multisig lock with cosigners' addresses and outputs locked with it`,
+		Args:  cobra.MinimumNArgs(2),
+		Run:   runMultisigLockCmd,
+	}
+	lockCmd.InitDefaultHelpCmd()
+	return lockCmd
+}
+
+func initMultisigFundCmd() *cobra.Command {
+	fundCmd := &cobra.Command{
+		Use:   "fund <amount>",
+		Short: `sends tokens from the wallet's account to the multisig lock`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runMultisigFundCmd,
+	}
+	addFlagMultisigLock(fundCmd)
+	glb.AddFlagTraceTx(fundCmd)
+	fundCmd.InitDefaultHelpCmd()
+	return fundCmd
+}
+
+func initMultisigCreateCmd() *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:   "create <amount>",
+		Short: `builds transaction which sends tokens from the multisig lock to the target and saves it to the file`,
+		Long: `builds transaction which sends tokens from the multisig lock to the target and saves it to the file.
+Transaction is signed by the wallet account, which does not need to be a cosigner. If it is, the transaction is cosigned too.
+Remainder stays in the multisig lock. Tag-along fee is paid from the multisig lock`,
+		Args: cobra.ExactArgs(1),
+		Run:  runMultisigCreateCmd,
+	}
+	addFlagMultisigLock(createCmd)
+	glb.AddFlagTarget(createCmd)
+	createCmd.Flags().StringVarP(&multisigOutputFile, "output", "o", defaultMultisigTxFileName, "file to save the transaction")
+	createCmd.InitDefaultHelpCmd()
+	return createCmd
+}
+
+func addFlagMultisigLock(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&multisigLockStr, "lock", "", "multisig lock in EasyFL source format")
+}
+
+func mustGetMultisigLock() *ledger.MultisigED25519 {
+	glb.Assertf(multisigLockStr != "", "multisig lock must be specified with --lock")
+	ret, err := ledger.MultisigED25519FromSource(multisigLockStr)
+	glb.AssertNoError(err)
+	return ret
+}
+
+// parseCosignerAddress accepts address in EasyFL source format or 32 bytes hex
+func parseCosignerAddress(str string) ledger.AddressED25519 {
+	if strings.HasPrefix(str, ledger.AddressED25519Name) {
+		ret, err := ledger.AddressED25519FromSource(str)
+		glb.AssertNoError(err)
+		return ret
+	}
+	ret, err := hex.DecodeString(strings.TrimPrefix(str, "0x"))
+	glb.AssertNoError(err)
+	glb.Assertf(len(ret) == 32, "wrong address length: '%s'", str)
+	return ret
+}
+
+func getMultisigOutputs(lock *ledger.MultisigED25519) []*ledger.OutputWithID {
+	ret, err := glb.GetClient().GetAccountOutputs(lock.Addresses[0], func(o *ledger.Output) bool {
+		return ledger.EqualConstraints(o.Lock(), lock)
+	})
+	glb.AssertNoError(err)
+	return ret
+}
+
+func runMultisigLockCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+
+	threshold, err := strconv.Atoi(args[0])
+	glb.AssertNoError(err)
+	addrs := make([]ledger.AddressED25519, len(args)-1)
+	for i := range addrs {
+		addrs[i] = parseCosignerAddress(args[i+1])
+	}
+	lock, err := ledger.NewMultisigED25519(threshold, addrs...)
+	glb.AssertNoError(err)
+
+	glb.Infof("%d-of-%d multisig lock:\n%s", lock.Threshold, len(lock.Addresses), lock.String())
+	outs := getMultisigOutputs(lock)
+	sum := uint64(0)
+	for _, o := range outs {
+		sum += o.Output.Amount()
+		glb.Verbosef("%s: %s", o.ID.StringShort(), util.GoTh(o.Output.Amount()))
+	}
+	glb.Infof("%d outputs locked, total %s", len(outs), util.GoTh(sum))
+}
+
+func runMultisigFundCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+	lock := mustGetMultisigLock()
+
+	amount, err := strconv.ParseUint(args[0], 10, 64)
+	glb.AssertNoError(err)
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+
+	prompt := fmt.Sprintf("send %s from %s to %d-of-%d multisig lock? Tag-along fee %s to %s",
+		util.GoTh(amount), walletData.Account.String(), lock.Threshold, len(lock.Addresses), util.GoTh(feeAmount), tagAlongSeqID.StringShort())
+	if !glb.YesNoPrompt(prompt, true, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		os.Exit(0)
+	}
+
+	txCtx, err := glb.GetClient().TransferFromED25519Wallet(client.TransferFromED25519WalletParams{
+		WalletPrivateKey: walletData.PrivateKey,
+		TagAlongSeqID:    tagAlongSeqID,
+		TagAlongFee:      feeAmount,
+		Amount:           amount,
+		Target:           lock,
+		TraceTx:          glb.TraceTx(),
+	})
+	if txCtx != nil {
+		glb.Verbosef("-------- transfer transaction ---------\n%s\n----------------", txCtx.String())
+	}
+	glb.AssertNoError(err)
+	glb.Infof("transaction submitted successfully")
+
+	if glb.NoWait() {
+		return
+	}
+	glb.ReportTxInclusion(*txCtx.TransactionID(), time.Second)
+}
+
+func runMultisigCreateCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+	lock := mustGetMultisigLock()
+
+	amount, err := strconv.ParseUint(args[0], 10, 64)
+	glb.AssertNoError(err)
+	target := glb.MustGetTarget()
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+
+	outs := getMultisigOutputs(lock)
+	txBytes, err := txbuilder.MakeMultisigTransferTransaction(&txbuilder.MultisigTransferData{
+		Lock:             lock,
+		Inputs:           outs,
+		SenderPrivateKey: walletData.PrivateKey,
+		Target:           target.AsLock(),
+		Amount:           amount,
+		TagAlong: &txbuilder.TagAlongData{
+			SeqID:  *tagAlongSeqID,
+			Amount: feeAmount,
+		},
+	})
+	glb.AssertNoError(err)
+
+	inputs, err := consumedMultisigOutputs(txBytes, outs)
+	glb.AssertNoError(err)
+	if lock.CosignerIndex(walletData.Account) >= 0 {
+		txBytes, _, err = txbuilder.CosignTransactionBytes(txBytes, inputs, walletData.PrivateKey)
+		glb.AssertNoError(err)
+		glb.Infof("transaction has been cosigned by %s", walletData.Account.String())
+	}
+
+	description := fmt.Sprintf("transfer %s from %d-of-%d multisig lock to %s. Tag-along fee %s to %s",
+		util.GoTh(amount), lock.Threshold, len(lock.Addresses), target.String(), util.GoTh(feeAmount), tagAlongSeqID.StringShort())
+	f := glb.NewTransactionFile(description, walletData.Account, txBytes, inputs)
+	f.Signed = true
+	glb.Verbosef("-------- multisig transaction ---------\n%s\n----------------", f.MustTxContext().String())
+
+	if glb.FileExists(multisigOutputFile) {
+		if !glb.YesNoPrompt(fmt.Sprintf("file '%s' already exists. Overwrite?", multisigOutputFile), false, glb.BypassYesNoPrompt()) {
+			os.Exit(0)
+		}
+	}
+	glb.AssertNoError(f.Save(multisigOutputFile))
+	glb.Infof("%s", description)
+	glb.Infof("transaction has been saved to '%s'. Cosigners sign it with 'proxi tx cosign %s'", multisigOutputFile, multisigOutputFile)
+}
+
+// consumedMultisigOutputs selects outputs consumed by the transaction, in the order of inputs
+func consumedMultisigOutputs(txBytes []byte, outs []*ledger.OutputWithID) ([]*ledger.OutputWithID, error) {
+	tx, err := transaction.FromBytes(txBytes)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*ledger.OutputWithID, 0, tx.NumInputs())
+	tx.ForEachInput(func(_ byte, oid *ledger.OutputID) bool {
+		for _, o := range outs {
+			if o.ID == *oid {
+				ret = append(ret, o)
+				break
+			}
+		}
+		return true
+	})
+	if len(ret) != tx.NumInputs() {
+		return nil, fmt.Errorf("inconsistency: consumed output not found")
+	}
+	return ret, nil
+}
@@ -27,6 +27,8 @@ func Init() *cobra.Command {
 	err = viper.BindPFlag("private_key", nodeCmd.PersistentFlags().Lookup("private_key"))
 	glb.AssertNoError(err)
 
+	glb.AddFlagAccount(nodeCmd)
+
 	nodeCmd.PersistentFlags().String("api.endpoint", "", "<DNS name>:port")
 	err = viper.BindPFlag("api.endpoint", nodeCmd.PersistentFlags().Lookup("api.endpoint"))
 	glb.AssertNoError(err)
@@ -45,13 +47,20 @@ func Init() *cobra.Command {
 		initGetChainOutputCmd(),
 		initCompactOutputsCmd(),
 		initBalanceCmd(),
+		initHistoryCmd(),
 		initTransferCmd(),
 		initSpamCmd(),
 		initMakeChainCmd(),
 		initChainsCmd(),
 		initNodeInfoCmd(),
+		initLibraryCmd(),
 		seq_cmd.Init(),
 		initScoreCmd(),
+		initTxCmd(),
+		initMultisigCmd(),
+		initHTLCCmd(),
+		initTokenCmd(),
+		initUpgradeCmd(),
 	)
 
 	//node_cmd.Init(nodeCmd) ????
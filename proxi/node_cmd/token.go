@@ -0,0 +1,191 @@
+package node_cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/spf13/cobra"
+)
+
+func initTokenCmd() *cobra.Command {
+	tokenCmd := &cobra.Command{
+		Use:   "token [<subcommand>]",
+		Short: `native tokens issued by chains`,
+		Long: `native tokens issued by chains. The token ID is the chain ID of the issuer.
+Only the controller of the chain can mint tokens, all other transactions must conserve token amounts`,
+		Args: cobra.NoArgs,
+	}
+	tokenCmd.AddCommand(
+		initTokenIssueCmd(),
+		initTokenTransferCmd(),
+		initTokenBalanceCmd(),
+	)
+	tokenCmd.InitDefaultHelpCmd()
+	return tokenCmd
+}
+
+func initTokenIssueCmd() *cobra.Command {
+	issueCmd := &cobra.Command{
+		Use:   "issue <chain ID> <amount>",
+		Short: `mints tokens of the chain controlled by the wallet and sends them to the target. Default target is the wallet account`,
+		Args:  cobra.ExactArgs(2),
+		Run:   runTokenIssueCmd,
+	}
+	glb.AddFlagTarget(issueCmd)
+	glb.AddFlagTraceTx(issueCmd)
+	issueCmd.InitDefaultHelpCmd()
+	return issueCmd
+}
+
+func initTokenTransferCmd() *cobra.Command {
+	transferCmd := &cobra.Command{
+		Use:   "transfer <token ID> <amount>",
+		Short: `sends native tokens from the wallet account to the target`,
+		Args:  cobra.ExactArgs(2),
+		Run:   runTokenTransferCmd,
+	}
+	glb.AddFlagTarget(transferCmd)
+	glb.AddFlagTraceTx(transferCmd)
+	transferCmd.InitDefaultHelpCmd()
+	return transferCmd
+}
+
+func initTokenBalanceCmd() *cobra.Command {
+	balanceCmd := &cobra.Command{
+		Use:   "balance [<token ID>]",
+		Short: `displays native token balances of the wallet account. With token ID, also displays circulating amount of the token`,
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runTokenBalanceCmd,
+	}
+	balanceCmd.InitDefaultHelpCmd()
+	return balanceCmd
+}
+
+func runTokenIssueCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+
+	chainID, err := ledger.ChainIDFromHexString(args[0])
+	glb.AssertNoError(err)
+	amount, err := strconv.ParseUint(args[1], 10, 64)
+	glb.AssertNoError(err)
+	target := glb.MustGetTarget()
+
+	chainOut, _, err := glb.GetClient().GetChainOutputFromHeaviestState(chainID)
+	glb.AssertNoError(err)
+	glb.Assertf(ledger.EqualConstraints(chainOut.Output.Lock(), walletData.Account),
+		"chain %s is not controlled by the wallet account %s", chainID.StringShort(), walletData.Account.String())
+
+	tokenID := ledger.TokenIDFromChainID(chainID)
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+	prompt := fmt.Sprintf("mint %s of token %s to %s? Tag-along fee %s to %s",
+		util.GoTh(amount), tokenID.StringShort(), target.String(), util.GoTh(feeAmount), tagAlongSeqID.StringShort())
+	if !glb.YesNoPrompt(prompt, true, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		os.Exit(0)
+	}
+	txBytes, err := txbuilder.MakeTokenIssueTransaction(&txbuilder.TokenIssueData{
+		ChainOutput: chainOut,
+		PrivateKey:  walletData.PrivateKey,
+		Target:      target.AsLock(),
+		Amount:      amount,
+		TagAlong: &txbuilder.TagAlongData{
+			SeqID:  *tagAlongSeqID,
+			Amount: feeAmount,
+		},
+	})
+	glb.AssertNoError(err)
+	submitTransaction(txBytes)
+}
+
+func runTokenTransferCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+
+	tokenID, err := ledger.TokenIDFromHexString(args[0])
+	glb.AssertNoError(err)
+	amount, err := strconv.ParseUint(args[1], 10, 64)
+	glb.AssertNoError(err)
+	target := glb.MustGetTarget()
+
+	outs, err := glb.GetClient().GetAccountOutputs(walletData.Account)
+	glb.AssertNoError(err)
+
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+	prompt := fmt.Sprintf("transfer %s of token %s to %s? Tag-along fee %s to %s",
+		util.GoTh(amount), tokenID.StringShort(), target.String(), util.GoTh(feeAmount), tagAlongSeqID.StringShort())
+	if !glb.YesNoPrompt(prompt, true, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		os.Exit(0)
+	}
+	txBytes, err := txbuilder.MakeTokenTransferTransaction(&txbuilder.TokenTransferData{
+		Inputs:     outs,
+		PrivateKey: walletData.PrivateKey,
+		TokenID:    tokenID,
+		Target:     target.AsLock(),
+		Amount:     amount,
+		TagAlong: &txbuilder.TagAlongData{
+			SeqID:  *tagAlongSeqID,
+			Amount: feeAmount,
+		},
+	})
+	glb.AssertNoError(err)
+	submitTransaction(txBytes)
+}
+
+func submitTransaction(txBytes []byte) {
+	txid, err := transaction.IDFromTransactionBytes(txBytes)
+	glb.AssertNoError(err)
+	err = glb.GetClient().SubmitTransaction(txBytes, glb.TraceTx())
+	glb.AssertNoError(err)
+	glb.Infof("transaction %s submitted successfully", txid.StringShort())
+
+	if glb.NoWait() {
+		return
+	}
+	glb.ReportTxInclusion(txid, time.Second)
+}
+
+func runTokenBalanceCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+
+	outs, err := glb.GetClient().GetAccountOutputs(walletData.Account, func(o *ledger.Output) bool {
+		token, _ := o.Token()
+		return token != nil
+	})
+	glb.AssertNoError(err)
+
+	balances := make(map[ledger.TokenID]uint64)
+	for _, o := range outs {
+		token := o.Output.MustToken()
+		balances[token.ID] += token.Amount
+	}
+	if len(args) == 0 {
+		glb.Infof("native tokens in the account %s:", walletData.Account.String())
+		for _, id := range util.KeysSorted(balances, func(id1, id2 ledger.TokenID) bool {
+			return string(id1[:]) < string(id2[:])
+		}) {
+			glb.Infof("   %s: %s", id.StringHex(), util.GoTh(balances[id]))
+		}
+		return
+	}
+	tokenID, err := ledger.TokenIDFromHexString(args[0])
+	glb.AssertNoError(err)
+	glb.Infof("token %s in the account %s: %s", tokenID.StringShort(), walletData.Account.String(), util.GoTh(balances[tokenID]))
+
+	tokenOuts, err := glb.GetClient().GetTokenOutputs(tokenID)
+	glb.AssertNoError(err)
+	circulating := uint64(0)
+	for _, o := range tokenOuts {
+		circulating += o.Output.MustToken().Amount
+	}
+	glb.Infof("circulating amount of the token: %s in %d outputs", util.GoTh(circulating), len(tokenOuts))
+}
@@ -0,0 +1,46 @@
+package node_cmd
+
+import (
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+func initTxCmd() *cobra.Command {
+	txCmd := &cobra.Command{
+		Use:   "tx [<subcommand>]",
+		Short: `offline signing workflow: builds unsigned transactions and submits signed ones`,
+		Long: `offline signing workflow:
+   'proxi node tx build ...' fetches inputs from the node and writes unsigned transaction to the file,
+   'proxi tx sign <file>' signs it on the host with the private key, which does not need access to the node,
+   'proxi node tx submit <file>' validates and submits the signed transaction.
+Transactions are built for the wallet account without access to the private key`,
+		Args: cobra.NoArgs,
+	}
+	txCmd.AddCommand(
+		initTxBuildCmd(),
+		initTxSubmitCmd(),
+	)
+	txCmd.InitDefaultHelpCmd()
+	return txCmd
+}
+
+// without Var does not work
+var txOutputFile string
+
+func initTxBuildCmd() *cobra.Command {
+	buildCmd := &cobra.Command{
+		Use:   "build [<subcommand>]",
+		Short: `builds unsigned transaction and saves it to the file`,
+		Args:  cobra.NoArgs,
+	}
+	buildCmd.PersistentFlags().StringVarP(&txOutputFile, "output", "o", glb.DefaultUnsignedTxFileName, "file to save unsigned transaction")
+
+	buildCmd.AddCommand(
+		initTxBuildTransferCmd(),
+		initTxBuildCompactCmd(),
+		initTxBuildMakeChainCmd(),
+		initTxBuildWithdrawCmd(),
+	)
+	buildCmd.InitDefaultHelpCmd()
+	return buildCmd
+}
@@ -0,0 +1,194 @@
+package node_cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/lunfardo314/proxima/api/client"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/sequencer/factory/commands"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/spf13/cobra"
+)
+
+func initTxBuildTransferCmd() *cobra.Command {
+	transferCmd := &cobra.Command{
+		Use:   "transfer <amount>",
+		Short: `builds unsigned transaction which sends tokens from the wallet's account to the target`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runTxBuildTransferCmd,
+	}
+	glb.AddFlagTarget(transferCmd)
+	transferCmd.InitDefaultHelpCmd()
+	return transferCmd
+}
+
+func initTxBuildCompactCmd() *cobra.Command {
+	compactCmd := &cobra.Command{
+		Use:   "compact",
+		Short: `builds unsigned transaction which compacts ED25519 outputs of the wallet account into one`,
+		Args:  cobra.NoArgs,
+		Run:   runTxBuildCompactCmd,
+	}
+	compactCmd.InitDefaultHelpCmd()
+	return compactCmd
+}
+
+func initTxBuildMakeChainCmd() *cobra.Command {
+	makeChainCmd := &cobra.Command{
+		Use:   "mkchain <initial on-chain balance>",
+		Short: `builds unsigned transaction which creates new chain origin (not a sequencer)`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runTxBuildMakeChainCmd,
+	}
+	glb.AddFlagTarget(makeChainCmd)
+	makeChainCmd.InitDefaultHelpCmd()
+	return makeChainCmd
+}
+
+func initTxBuildWithdrawCmd() *cobra.Command {
+	withdrawCmd := &cobra.Command{
+		Use:   "withdraw <amount>",
+		Short: `builds unsigned transaction with the command to withdraw tokens from the own sequencer to the target`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runTxBuildWithdrawCmd,
+	}
+	glb.AddFlagTarget(withdrawCmd)
+	withdrawCmd.InitDefaultHelpCmd()
+	return withdrawCmd
+}
+
+func runTxBuildTransferCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletAccount := glb.GetWalletAccount()
+	glb.Infof("source is the wallet account: %s", walletAccount.String())
+
+	amount, err := strconv.ParseUint(args[0], 10, 64)
+	glb.AssertNoError(err)
+	target := glb.MustGetTarget()
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+
+	txBytes, inputs, err := glb.GetClient().MakeTransferFromED25519Wallet(client.TransferFromED25519WalletParams{
+		WalletAccount: walletAccount,
+		TagAlongSeqID: tagAlongSeqID,
+		TagAlongFee:   feeAmount,
+		Amount:        amount,
+		Target:        target.AsLock(),
+	})
+	glb.AssertNoError(err)
+
+	saveUnsignedTx(fmt.Sprintf("transfer %s from %s to %s. Tag-along fee %s to %s",
+		util.GoTh(amount), walletAccount.String(), target.String(), util.GoTh(feeAmount), tagAlongSeqID.StringShort()),
+		walletAccount, txBytes, inputs)
+}
+
+func runTxBuildCompactCmd(_ *cobra.Command, _ []string) {
+	glb.InitLedgerFromNode()
+	walletAccount := glb.GetWalletAccount()
+	glb.Infof("wallet account: %s", walletAccount.String())
+
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+
+	txBytes, inputs, err := glb.GetClient().MakeCompactTransactionUnsigned(walletAccount, tagAlongSeqID, feeAmount)
+	glb.AssertNoError(err)
+	if txBytes == nil {
+		glb.Infof("no need for compacting")
+		os.Exit(0)
+	}
+	saveUnsignedTx(fmt.Sprintf("compact %d outputs of %s. Tag-along fee %s to %s",
+		len(inputs), walletAccount.String(), util.GoTh(feeAmount), tagAlongSeqID.StringShort()),
+		walletAccount, txBytes, inputs)
+}
+
+func runTxBuildMakeChainCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletAccount := glb.GetWalletAccount()
+	glb.Infof("source is the wallet account: %s", walletAccount.String())
+
+	onChainAmount, err := strconv.ParseUint(args[0], 10, 64)
+	glb.AssertNoError(err)
+	target := glb.MustGetTarget()
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+
+	txBytes, inputs, err := glb.GetClient().MakeChainOriginTransaction(client.TransferFromED25519WalletParams{
+		WalletAccount: walletAccount,
+		TagAlongSeqID: tagAlongSeqID,
+		TagAlongFee:   feeAmount,
+		Amount:        onChainAmount,
+		Target:        target.AsLock(),
+	})
+	glb.AssertNoError(err)
+
+	saveUnsignedTx(fmt.Sprintf("create chain origin with on-chain balance %s controlled by %s. Tag-along fee %s to %s",
+		util.GoTh(onChainAmount), target.String(), util.GoTh(feeAmount), tagAlongSeqID.StringShort()),
+		walletAccount, txBytes, inputs)
+}
+
+const ownSequencerCmdFee = 500
+
+func runTxBuildWithdrawCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletAccount := glb.GetWalletAccount()
+	seqID := glb.GetOwnSequencerID()
+	glb.Assertf(seqID != nil, "can't get own sequencer ID")
+	glb.Infof("sequencer ID (source): %s", seqID.String())
+
+	amount, err := strconv.ParseUint(args[0], 10, 64)
+	glb.AssertNoError(err)
+	targetLock := glb.MustGetTarget()
+
+	walletOutputs, err := glb.GetClient().GetAccountOutputs(walletAccount, func(o *ledger.Output) bool {
+		// filter out chain outputs controlled by the wallet
+		_, idx := o.ChainConstraint()
+		return idx == 0xff
+	})
+	glb.AssertNoError(err)
+
+	cmdConstr, err := commands.MakeSequencerWithdrawCommand(amount, targetLock.AsLock())
+	glb.AssertNoError(err)
+
+	transferData := txbuilder.NewTransferDataUnsigned(walletAccount, ledger.TimeNow()).
+		WithAmount(ownSequencerCmdFee).
+		WithTargetLock(ledger.ChainLockFromChainID(*seqID)).
+		MustWithInputs(walletOutputs...).
+		WithSender().
+		WithConstraint(cmdConstr)
+
+	txBytes, err := txbuilder.MakeSimpleTransferTransaction(transferData)
+	glb.AssertNoError(err)
+
+	saveUnsignedTx(fmt.Sprintf("withdraw %s from sequencer %s to %s. Command fee %d",
+		util.GoTh(amount), seqID.StringShort(), targetLock.String(), ownSequencerCmdFee),
+		walletAccount, txBytes, transferData.Inputs)
+}
+
+func mustGetTagAlongFee() (*ledger.ChainID, uint64) {
+	feeAmount := getTagAlongFee()
+	glb.Assertf(feeAmount > 0, "tag-along fee is configured 0. Fee-less option not supported yet")
+	tagAlongSeqID := GetTagAlongSequencerID()
+	glb.Assertf(tagAlongSeqID != nil, "tag-along sequencer not specified")
+
+	md, err := glb.GetClient().GetMilestoneDataFromHeaviestState(*tagAlongSeqID)
+	glb.AssertNoError(err)
+	if md != nil && md.MinimumFee > feeAmount {
+		feeAmount = md.MinimumFee
+	}
+	return tagAlongSeqID, feeAmount
+}
+
+func saveUnsignedTx(description string, signer ledger.AddressED25519, txBytes []byte, inputs []*ledger.OutputWithID) {
+	f := glb.NewTransactionFile(description, signer, txBytes, inputs)
+	glb.Verbosef("-------- unsigned transaction ---------\n%s\n----------------", f.MustTxContext().String())
+
+	if glb.FileExists(txOutputFile) {
+		if !glb.YesNoPrompt(fmt.Sprintf("file '%s' already exists. Overwrite?", txOutputFile), false, glb.BypassYesNoPrompt()) {
+			os.Exit(0)
+		}
+	}
+	glb.AssertNoError(f.Save(txOutputFile))
+	glb.Infof("%s", description)
+	glb.Infof("unsigned transaction with %d input(s) has been saved to '%s'. Sign it with 'proxi tx sign %s'", len(inputs), txOutputFile, txOutputFile)
+}
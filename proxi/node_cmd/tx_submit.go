@@ -0,0 +1,70 @@
+package node_cmd
+
+import (
+	"time"
+
+	"github.com/lunfardo314/proxima/api"
+	"github.com/lunfardo314/proxima/api/client"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+func initTxSubmitCmd() *cobra.Command {
+	submitCmd := &cobra.Command{
+		Use:   "submit <file>",
+		Short: `validates signed transaction from the file in the latest state of the node and submits it`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runTxSubmitCmd,
+	}
+	glb.AddFlagTraceTx(submitCmd)
+	submitCmd.InitDefaultHelpCmd()
+	return submitCmd
+}
+
+func runTxSubmitCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+
+	f := glb.MustLoadTransactionFile(args[0])
+	f.MustCheckLedgerID()
+	glb.Assertf(f.Signed, "transaction in the file '%s' is not signed", args[0])
+	glb.Infof("%s", f.Description)
+
+	txBytes, err := f.TxBytes()
+	glb.AssertNoError(err)
+	tx, err := transaction.FromBytes(txBytes, transaction.CheckSender())
+	glb.AssertNoError(err)
+	glb.Assertf(tx.SenderAddress().String() == f.Signer, "transaction is not signed by %s", f.Signer)
+
+	res, err := glb.GetClient().SimulateTransaction(txBytes)
+	glb.AssertNoError(err)
+	if !res.Valid {
+		glb.Fatalf("transaction %s is not valid in the latest state of the node: %s", tx.IDShortString(), res.ValidationError)
+	}
+
+	glb.Infof("submitting transaction %s..", tx.IDShortString())
+	if glb.NoWait() {
+		err = glb.GetClient().SubmitTransaction(txBytes, glb.TraceTx())
+		glb.AssertNoError(err)
+		glb.Infof("transaction submitted successfully")
+		return
+	}
+	numerator, denominator := glb.GetInclusionThreshold()
+	waitRes, err := glb.GetClient().SubmitTransactionWait(txBytes, client.SubmitTransactionWaitParams{
+		ThresholdNumerator:   numerator,
+		ThresholdDenominator: denominator,
+		Weak:                 glb.GetIsWeakFinality(),
+		Trace:                glb.TraceTx(),
+	})
+	glb.AssertNoError(err)
+	switch waitRes.Status {
+	case api.SubmitStatusIncluded:
+		glb.Infof("transaction %s is included. Weak score: %d%%, strong score: %d%%",
+			tx.IDShortString(), waitRes.Score.WeakScore, waitRes.Score.StrongScore)
+	case api.SubmitStatusRejected:
+		glb.Fatalf("transaction %s was rejected by the node: %s", tx.IDShortString(), waitRes.RejectReason)
+	default:
+		glb.Infof("transaction %s is not included yet", tx.IDShortString())
+		glb.ReportTxInclusion(*tx.ID(), time.Second)
+	}
+}
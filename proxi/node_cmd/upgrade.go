@@ -0,0 +1,115 @@
+package node_cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/spf13/cobra"
+)
+
+// upgrade transaction may be timestamped a slot later than activation is calculated
+const defaultUpgradeActivationSlots = ledger.LibraryUpgradeMinActivationSlots + 5
+
+// without Var does not work
+var (
+	upgradeActivationSlots int
+	upgradeLockNames       string
+)
+
+func initUpgradeCmd() *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade [<subcommand>]",
+		Short: `soft-fork upgrades of the ledger library`,
+		Long: `soft-fork upgrades of the ledger library. Upgrade adds new EasyFL functions and lock types to the ledger library
+without regenesis. New functions become active from the activation slot. Only genesis controller can submit upgrades`,
+		Args: cobra.NoArgs,
+	}
+	upgradeCmd.AddCommand(
+		initUpgradeSubmitCmd(),
+		initUpgradeListCmd(),
+	)
+	upgradeCmd.InitDefaultHelpCmd()
+	return upgradeCmd
+}
+
+func initUpgradeSubmitCmd() *cobra.Command {
+	submitCmd := &cobra.Command{
+		Use:   "submit <source file>",
+		Short: `submits library upgrade with EasyFL function definitions from the file. Wallet must be the genesis controller`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runUpgradeSubmitCmd,
+	}
+	submitCmd.Flags().IntVar(&upgradeActivationSlots, "activation", defaultUpgradeActivationSlots, "activation slot of the upgrade, in slots from now")
+	submitCmd.Flags().StringVar(&upgradeLockNames, "locks", "", "comma-separated names of functions of the upgrade which are locks")
+	glb.AddFlagTraceTx(submitCmd)
+	submitCmd.InitDefaultHelpCmd()
+	return submitCmd
+}
+
+func initUpgradeListCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: `lists library upgrades applied by the node`,
+		Args:  cobra.NoArgs,
+		Run:   runUpgradeListCmd,
+	}
+	listCmd.InitDefaultHelpCmd()
+	return listCmd
+}
+
+func runUpgradeSubmitCmd(_ *cobra.Command, args []string) {
+	glb.InitLedgerFromNode()
+	walletData := glb.GetWalletData()
+	glb.Assertf(bytes.Equal(walletData.PrivateKey.Public().(ed25519.PublicKey), ledger.L().ID.GenesisControllerPublicKey),
+		"wallet account %s is not the genesis controller", walletData.Account.String())
+	glb.Assertf(upgradeActivationSlots > ledger.LibraryUpgradeMinActivationSlots, "activation must be more than %d slots from now",
+		ledger.LibraryUpgradeMinActivationSlots)
+
+	source, err := os.ReadFile(args[0])
+	glb.AssertNoError(err)
+	var lockNames []string
+	if upgradeLockNames != "" {
+		lockNames = strings.Split(upgradeLockNames, ",")
+	}
+	activation := ledger.TimeNow().Slot() + ledger.Slot(upgradeActivationSlots)
+	upgrade := ledger.NewLibraryUpgrade(activation, ledger.L().NextFunCode(), string(source), lockNames...)
+	glb.AssertNoError(ledger.L().ValidateUpgrades(upgrade))
+	glb.Infof("library upgrade:\n%s", upgrade.Lines("   ").String())
+
+	outs, err := glb.GetClient().GetAccountOutputs(walletData.Account, func(o *ledger.Output) bool {
+		return ledger.EqualConstraints(o.Lock(), walletData.Account)
+	})
+	glb.AssertNoError(err)
+
+	tagAlongSeqID, feeAmount := mustGetTagAlongFee()
+	prompt := fmt.Sprintf("submit library upgrade activated at slot %d? Tag-along fee %s to %s",
+		activation, util.GoTh(feeAmount), tagAlongSeqID.StringShort())
+	if !glb.YesNoPrompt(prompt, true, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		os.Exit(0)
+	}
+	par := txbuilder.NewTransferData(walletData.PrivateKey, nil, ledger.TimeNow()).
+		MustWithInputs(outs...).
+		WithTagAlong(*tagAlongSeqID, feeAmount)
+	txBytes, err := txbuilder.MakeLibraryUpgradeTransaction(par, upgrade)
+	glb.AssertNoError(err)
+	submitTransaction(txBytes)
+}
+
+func runUpgradeListCmd(_ *cobra.Command, _ []string) {
+	glb.InitLedgerFromNode()
+
+	upgrades := ledger.L().Upgrades()
+	glb.Infof("library upgrades applied by the node: %d", len(upgrades))
+	for i, u := range upgrades {
+		glb.Infof("#%d: %s", i, u.String())
+		glb.Verbosef("%s", u.Lines("      ").String())
+	}
+}
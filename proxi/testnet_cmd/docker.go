@@ -0,0 +1,48 @@
+package testnet_cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/proxi/init_cmd"
+	"github.com/lunfardo314/proxima/util/lines"
+)
+
+const dockerImage = "proxima:testnet"
+
+func serviceName(idx int) string {
+	return fmt.Sprintf("%s%d", nodeDirPrefix, idx)
+}
+
+// writeComposeFile generates docker-compose file with the service for each node. The node directory is mounted
+// as the working directory of the container. Peering and API ports are the same as for local processes,
+// API ports are published on the host
+func writeComposeFile(nodes []*testnetNode, srcDir string) {
+	ln := lines.New()
+	ln.Add("# Local testnet of %d Proxima nodes. Generated by 'proxi testnet up --docker'", len(nodes))
+	ln.Add("services:")
+	for i, node := range nodes {
+		name := serviceName(node.idx)
+		ln.Add("  %s:", name)
+		if i == 0 {
+			ln.Add("    build:")
+			ln.Add("      context: %s", srcDir)
+		}
+		ln.Add("    image: %s", dockerImage)
+		ln.Add("    container_name: proxima_%s", name)
+		ln.Add("    working_dir: /node")
+		ln.Add("    volumes:")
+		ln.Add("      - ./%s:/node", name)
+		ln.Add("    ports:")
+		ln.Add("      - \"%d:%d\"", init_cmd.APIPortStart+node.idx, init_cmd.APIPortStart+node.idx)
+		ln.Add("    stop_grace_period: 20s")
+		if i > 0 {
+			ln.Add("    depends_on:")
+			ln.Add("      - %s", serviceName(nodes[0].idx))
+		}
+	}
+	err := os.WriteFile(composeFilePath(), []byte(ln.String()+"\n"), 0666)
+	glb.AssertNoError(err)
+	glb.Infof("docker-compose file has been saved as '%s'", composeFilePath())
+}
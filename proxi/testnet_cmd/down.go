@@ -0,0 +1,93 @@
+package testnet_cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+var purgeDown bool
+
+const stopNodeTimeout = 20 * time.Second
+
+func initDownCmd() *cobra.Command {
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "stops all nodes of the local testnet",
+		Args:  cobra.NoArgs,
+		Run:   runDownCmd,
+	}
+	downCmd.PersistentFlags().BoolVar(&purgeDown, "purge", false, "remove the testnet directory after nodes are stopped")
+	return downCmd
+}
+
+func runDownCmd(_ *cobra.Command, _ []string) {
+	glb.FileMustExist(testnetDir)
+
+	if isDockerized() {
+		runDockerCompose("down")
+	} else {
+		for _, idx := range testnetNodes() {
+			stopNode(idx)
+		}
+	}
+	if !purgeDown {
+		return
+	}
+	if !glb.YesNoPrompt("remove testnet directory '"+testnetDir+"' with all databases?", true, glb.BypassYesNoPrompt()) {
+		return
+	}
+	glb.AssertNoError(os.RemoveAll(testnetDir))
+	glb.Infof("testnet directory '%s' has been removed", testnetDir)
+}
+
+// stopNode sends SIGTERM to the node process and waits until it stops. Kills the process after timeout
+func stopNode(idx int) {
+	pid, running := nodeProcess(idx)
+	if !running {
+		glb.Infof("node %d is not running", idx)
+		return
+	}
+	proc, err := os.FindProcess(pid)
+	glb.AssertNoError(err)
+	glb.AssertNoError(proc.Signal(syscall.SIGTERM))
+
+	deadline := time.Now().Add(stopNodeTimeout)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			glb.Infof("node %d did not stop in %v. Killing it", idx, stopNodeTimeout)
+			glb.AssertNoError(proc.Kill())
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	_ = os.Remove(filepath.Join(nodeDir(idx), nodePIDFileName))
+	glb.Infof("node %d has been stopped", idx)
+}
+
+// nodeProcess returns PID of the node process from the PID file and whether the process is alive
+func nodeProcess(idx int) (int, bool) {
+	data, err := os.ReadFile(filepath.Join(nodeDir(idx), nodePIDFileName))
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, false
+	}
+	return pid, processAlive(pid)
+}
+
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,227 @@
+package testnet_cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/lunfardo314/proxima/core/txmetadata"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/proxi/init_cmd"
+	"github.com/lunfardo314/proxima/proxi/keystore"
+	"github.com/lunfardo314/proxima/txstore"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/adaptors/badger_adaptor"
+)
+
+const (
+	// deterministicSeedForControllers is the seed of sequencer controller keys. Controller of the node 0 is also the genesis controller
+	deterministicSeedForControllers = 27182818 + 31415926
+	// walletBalance is the amount on the ordinary account of each controller. The rest of supply is on sequencer chains
+	walletBalance     = uint64(1_000_000)
+	sequencerPace     = 5
+	maxTagAlongInputs = 50
+	ledgerIDFileName  = "proxi.genesis.id.yaml"
+	profileName       = "proxi"
+)
+
+type (
+	generateParams struct {
+		numNodes int
+		docker   bool
+		// directory with the Proxima sources, the docker build context
+		srcDir string
+	}
+
+	testnetNode struct {
+		idx            int
+		controllerKey  ed25519.PrivateKey
+		hostPrivateKey ed25519.PrivateKey
+		seqName        string
+		sequencerID    ledger.ChainID
+	}
+)
+
+// generateTestnet creates directories of all nodes with ledger identity, genesis state, transaction store,
+// node configuration and proxi profile.
+// All nodes start from the same state, which contains the genesis distribution transaction. The distribution
+// creates origins of sequencer chains for every node except node 0, which runs the bootstrap sequencer.
+// All private keys are deterministic
+func generateTestnet(par generateParams) []*testnetNode {
+	controllerKeys := testutil.GetTestingPrivateKeys(par.numNodes, deterministicSeedForControllers)
+	hostKeys := init_cmd.TestingHostPrivateKeys(par.numNodes)
+
+	idData := ledger.DefaultIdentityData(controllerKeys[0])
+	ledger.Init(idData)
+
+	nodes := make([]*testnetNode, par.numNodes)
+	for i := range nodes {
+		nodes[i] = &testnetNode{
+			idx:            i,
+			controllerKey:  controllerKeys[i],
+			hostPrivateKey: hostKeys[i],
+			seqName:        fmt.Sprintf("seq%d", i),
+		}
+	}
+	nodes[0].seqName = "boot"
+	nodes[0].sequencerID = ledger.L().ID.OriginChainID()
+
+	distribution := makeDistribution(controllerKeys)
+	glb.Infof("genesis distribution:\n%s", txbuilder.DistributionListToLines(distribution, "    ").String())
+
+	passphrase := glb.ReadNewPassphrase()
+
+	var distributionTxBytes []byte
+	for _, node := range nodes {
+		dir := nodeDir(node.idx)
+		glb.AssertNoError(os.MkdirAll(dir, 0777))
+		glb.AssertNoError(os.WriteFile(filepath.Join(dir, ledgerIDFileName), idData.YAML(), 0666))
+
+		txBytes := makeGenesisDBs(dir, controllerKeys[0], distribution)
+		if node.idx == 0 {
+			distributionTxBytes = txBytes
+		}
+		glb.Assertf(bytes.Equal(txBytes, distributionTxBytes), "inconsistency: distribution transaction differs between nodes")
+	}
+	assignSequencerIDs(nodes, distributionTxBytes)
+
+	for _, node := range nodes {
+		writeNodeConfig(node, nodes, par.docker)
+		writeProfile(node, passphrase)
+		glb.Infof("node %d: sequencer '%s' %s, API endpoint %s",
+			node.idx, node.seqName, node.sequencerID.String(), nodeAPIEndpoint(node.idx))
+	}
+	if par.docker {
+		writeComposeFile(nodes, par.srcDir)
+	}
+	return nodes
+}
+
+// makeDistribution makes genesis distribution: each controller receives a wallet balance and each
+// controller, except the genesis controller, receives a chain origin for its sequencer
+func makeDistribution(controllerKeys []ed25519.PrivateKey) []ledger.LockBalance {
+	onChain := ledger.L().ID.InitialSupply / uint64(2*len(controllerKeys))
+	ret := make([]ledger.LockBalance, 0, 2*len(controllerKeys))
+	for i, privateKey := range controllerKeys {
+		addr := ledger.AddressED25519FromPrivateKey(privateKey)
+		ret = append(ret, ledger.LockBalance{Lock: addr, Balance: walletBalance})
+		if i > 0 {
+			ret = append(ret, ledger.LockBalance{Lock: addr, Balance: onChain, ChainOrigin: true})
+		}
+	}
+	return ret
+}
+
+// makeGenesisDBs creates multi-state DB with genesis state and distribution branch, and transaction store with
+// the distribution transaction in it. Returns the distribution transaction
+func makeGenesisDBs(dir string, genesisPrivateKey ed25519.PrivateKey, distribution []ledger.LockBalance) []byte {
+	stateDBName := filepath.Join(dir, global.MultiStateDBName)
+	glb.FileMustNotExist(stateDBName)
+	stateDB := badger_adaptor.MustCreateOrOpenBadgerDB(stateDBName, badger.DefaultOptions(stateDBName))
+	stateStore := badger_adaptor.New(stateDB)
+	defer func() { _ = stateDB.Close() }()
+
+	multistate.InitStateStore(*ledger.L().ID, stateStore)
+	txBytes, txid, err := txbuilder.DistributeInitialSupplyExt(stateStore, genesisPrivateKey, distribution)
+	glb.AssertNoError(err)
+
+	txStoreDBName := filepath.Join(dir, global.TxStoreDBName)
+	glb.FileMustNotExist(txStoreDBName)
+	txStoreDB := badger_adaptor.New(badger_adaptor.MustCreateOrOpenBadgerDB(txStoreDBName, badger.DefaultOptions(txStoreDBName)))
+	defer func() { _ = txStoreDB.Close() }()
+
+	rr, found := multistate.FetchRootRecord(stateStore, txid)
+	glb.Assertf(found, "inconsistency: can't find root record")
+	_, err = txstore.NewSimpleTxBytesStore(txStoreDB).PersistTxBytesWithMetadata(txBytes, &txmetadata.TransactionMetadata{
+		StateRoot:      rr.Root,
+		LedgerCoverage: util.Ref(rr.LedgerCoverage),
+		SlotInflation:  util.Ref(rr.SlotInflation),
+		Supply:         util.Ref(rr.Supply),
+	})
+	glb.AssertNoError(err)
+	return txBytes
+}
+
+// assignSequencerIDs finds chain origins produced by the distribution transaction
+func assignSequencerIDs(nodes []*testnetNode, distributionTxBytes []byte) {
+	tx, err := transaction.FromBytes(distributionTxBytes, transaction.MainTxValidationOptions...)
+	glb.AssertNoError(err)
+
+	for _, node := range nodes[1:] {
+		addr := ledger.AddressED25519FromPrivateKey(node.controllerKey)
+		tx.ForEachProducedOutput(func(_ byte, o *ledger.Output, oid *ledger.OutputID) bool {
+			cc, idx := o.ChainConstraint()
+			if idx == 0xff || !cc.IsOrigin() || !ledger.EqualConstraints(o.Lock(), addr) {
+				return true
+			}
+			node.sequencerID = ledger.MakeOriginChainID(oid)
+			return false
+		})
+		glb.Assertf(node.sequencerID != ledger.NilChainID, "inconsistency: chain origin of node %d not found", node.idx)
+	}
+}
+
+func writeNodeConfig(node *testnetNode, nodes []*testnetNode, docker bool) {
+	par := init_cmd.NodeConfigParams{
+		Intro: fmt.Sprintf("# Configuration of the node %d of the local testnet with %d nodes\n", node.idx, len(nodes)) +
+			"# All private keys are auto-generated deterministically",
+		HostPrivateKey: node.hostPrivateKey,
+		HostPort:       init_cmd.PeeringPortStart + node.idx,
+		Peers:          make(map[string]string),
+		APIPort:        init_cmd.APIPortStart + node.idx,
+		MetricsPort:    init_cmd.MetricsPortStart + node.idx,
+		Sequencer: &init_cmd.SequencerConfigParams{
+			Name:              node.seqName,
+			SequencerID:       node.sequencerID,
+			ControllerKey:     node.controllerKey,
+			Pace:              sequencerPace,
+			MaxTagAlongInputs: maxTagAlongInputs,
+		},
+	}
+	for _, peer := range nodes {
+		if peer.idx == node.idx {
+			continue
+		}
+		peerID := init_cmd.HostIDFromPrivateKey(peer.hostPrivateKey)
+		port := init_cmd.PeeringPortStart + peer.idx
+		if docker {
+			// in the docker network peers are reachable by the service name
+			par.Peers[fmt.Sprintf("peer%d", peer.idx)] = fmt.Sprintf("/dns4/%s/tcp/%d/p2p/%s", serviceName(peer.idx), port, peerID.String())
+		} else {
+			par.Peers[fmt.Sprintf("peer%d", peer.idx)] = fmt.Sprintf("/ip4/127.0.0.1/tcp/%d/p2p/%s", port, peerID.String())
+		}
+	}
+	err := os.WriteFile(filepath.Join(nodeDir(node.idx), nodeConfigFileName), init_cmd.NodeConfigYAML(par), 0666)
+	glb.AssertNoError(err)
+}
+
+// writeProfile creates proxi profile with the keystore, which contains the controller key of the node
+func writeProfile(node *testnetNode, passphrase string) {
+	dir := nodeDir(node.idx)
+	keystoreFname := profileName + ".keystore"
+
+	mnemonic, err := keystore.NewMnemonic()
+	glb.AssertNoError(err)
+	ks, err := keystore.Create(filepath.Join(dir, keystoreFname), mnemonic, passphrase)
+	glb.AssertNoError(err)
+	acc, err := ks.Import(keystore.DefaultAccountName, node.controllerKey)
+	glb.AssertNoError(err)
+	glb.AssertNoError(ks.Save())
+
+	profile := init_cmd.ProfileYAML(keystoreFname, acc, node.sequencerID.StringHex(), nodeAPIEndpoint(node.idx))
+	err = os.WriteFile(filepath.Join(dir, profileName+".yaml"), profile, 0666)
+	glb.AssertNoError(err)
+}
+
+func nodeAPIEndpoint(idx int) string {
+	return fmt.Sprintf("http://127.0.0.1:%d", init_cmd.APIPortStart+idx)
+}
@@ -0,0 +1,131 @@
+package testnet_cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/proxi/init_cmd"
+	"github.com/lunfardo314/proxima/proxi/keystore"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/adaptors/badger_adaptor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTestnet(t *testing.T) {
+	const (
+		numNodes   = 3
+		passphrase = "testnet passphrase"
+		srcDir     = "/proxima/src"
+	)
+	t.Setenv(glb.PassphraseEnvVar, passphrase)
+	testnetDir = filepath.Join(t.TempDir(), defaultTestnetDir)
+
+	nodes := generateTestnet(generateParams{
+		numNodes: numNodes,
+		docker:   true,
+		srcDir:   srcDir,
+	})
+	require.EqualValues(t, numNodes, len(nodes))
+	require.EqualValues(t, []int{0, 1, 2}, testnetNodes())
+	require.True(t, isDockerized())
+
+	t.Run("distribution", func(t *testing.T) {
+		controllerKeys := testutil.GetTestingPrivateKeys(numNodes, deterministicSeedForControllers)
+		distribution := makeDistribution(controllerKeys)
+		require.EqualValues(t, 2*numNodes-1, len(distribution))
+
+		onChain := ledger.L().ID.InitialSupply / uint64(2*numNodes)
+		numChainOrigins := 0
+		for _, lb := range distribution {
+			if lb.ChainOrigin {
+				require.EqualValues(t, onChain, lb.Balance)
+				numChainOrigins++
+			} else {
+				require.EqualValues(t, walletBalance, lb.Balance)
+			}
+		}
+		require.EqualValues(t, numNodes-1, numChainOrigins)
+
+		require.True(t, nodes[0].sequencerID == ledger.L().ID.OriginChainID())
+		seqIDs := make(map[ledger.ChainID]struct{})
+		for _, node := range nodes {
+			seqIDs[node.sequencerID] = struct{}{}
+		}
+		require.EqualValues(t, numNodes, len(seqIDs))
+
+		// every node starts from the same state with a sequencer chain for each node
+		var root []byte
+		for _, node := range nodes {
+			stateDBName := filepath.Join(nodeDir(node.idx), global.MultiStateDBName)
+			stateDB := badger_adaptor.MustCreateOrOpenBadgerDB(stateDBName, badger.DefaultOptions(stateDBName))
+			stateStore := badger_adaptor.New(stateDB)
+
+			branches := multistate.FetchLatestBranches(stateStore)
+			require.EqualValues(t, 1, len(branches))
+			if root == nil {
+				root = branches[0].Root.Bytes()
+			}
+			require.EqualValues(t, root, branches[0].Root.Bytes())
+
+			rdr := multistate.MakeSugared(multistate.MustNewReadable(stateStore, branches[0].Root))
+			for _, n := range nodes[1:] {
+				require.EqualValues(t, onChain, multistate.BalanceOnChainOutput(rdr, &n.sequencerID))
+			}
+			_ = stateDB.Close()
+		}
+	})
+	t.Run("node configs", func(t *testing.T) {
+		for _, node := range nodes {
+			cfg := readNodeConfig(node.idx)
+			require.EqualValues(t, init_cmd.PeeringPortStart+node.idx, cfg.GetInt("peering.host.port"))
+			require.EqualValues(t, init_cmd.APIPortStart+node.idx, cfg.GetInt("api.server.port"))
+
+			peers := cfg.GetStringMapString("peering.peers")
+			require.EqualValues(t, numNodes-1, len(peers))
+			for _, addr := range peers {
+				// in the docker network peers are reachable by the service name
+				require.True(t, strings.HasPrefix(addr, "/dns4/"+nodeDirPrefix))
+			}
+			seqCfg := "sequencers." + node.seqName
+			require.True(t, cfg.GetBool(seqCfg+".enable"))
+			require.EqualValues(t, node.sequencerID.StringHex(), cfg.GetString(seqCfg+".sequencer_id"))
+		}
+	})
+	t.Run("profiles", func(t *testing.T) {
+		for _, node := range nodes {
+			dir := nodeDir(node.idx)
+			require.True(t, glb.FileExists(filepath.Join(dir, ledgerIDFileName)))
+			require.True(t, glb.FileExists(filepath.Join(dir, global.TxStoreDBName)))
+
+			profile, err := os.ReadFile(filepath.Join(dir, profileName+".yaml"))
+			require.NoError(t, err)
+			require.Contains(t, string(profile), node.sequencerID.StringHex())
+			require.Contains(t, string(profile), nodeAPIEndpoint(node.idx))
+
+			ks, err := keystore.Open(filepath.Join(dir, profileName+".keystore"), passphrase)
+			require.NoError(t, err)
+			acc, found := ks.Account(keystore.DefaultAccountName)
+			require.True(t, found)
+			require.EqualValues(t, ledger.AddressED25519FromPrivateKey(node.controllerKey).String(), acc.Address)
+		}
+	})
+	t.Run("compose", func(t *testing.T) {
+		data, err := os.ReadFile(composeFilePath())
+		require.NoError(t, err)
+		compose := string(data)
+		require.Contains(t, compose, "context: "+srcDir)
+		require.EqualValues(t, 1, strings.Count(compose, "build:"))
+		require.EqualValues(t, numNodes-1, strings.Count(compose, "depends_on:"))
+		for _, node := range nodes {
+			require.Contains(t, compose, "  "+serviceName(node.idx)+":")
+			require.Contains(t, compose, "- ./"+serviceName(node.idx)+":/node")
+		}
+	})
+}
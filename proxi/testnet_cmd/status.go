@@ -0,0 +1,74 @@
+package testnet_cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lunfardo314/proxima/api/client"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const statusAPITimeout = 2 * time.Second
+
+func initStatusCmd() *cobra.Command {
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "displays status of nodes of the local testnet",
+		Args:  cobra.NoArgs,
+		Run:   runStatusCmd,
+	}
+	return statusCmd
+}
+
+func runStatusCmd(_ *cobra.Command, _ []string) {
+	glb.FileMustExist(testnetDir)
+
+	dockerized := isDockerized()
+	if dockerized {
+		glb.Infof("dockerized testnet in '%s'", testnetDir)
+	} else {
+		glb.Infof("local testnet in '%s'", testnetDir)
+	}
+	for _, idx := range testnetNodes() {
+		glb.Infof("node %d: %s", idx, nodeStatusString(idx, dockerized))
+	}
+}
+
+func nodeStatusString(idx int, dockerized bool) string {
+	var proc string
+	if !dockerized {
+		pid, running := nodeProcess(idx)
+		if !running {
+			return "stopped"
+		}
+		proc = fmt.Sprintf("PID: %d, ", pid)
+	}
+	cfg := readNodeConfig(idx)
+	endpoint := fmt.Sprintf("http://127.0.0.1:%d", cfg.GetInt("api.server.port"))
+	clnt := client.New(endpoint, statusAPITimeout)
+	nodeInfo, err := clnt.GetNodeInfo()
+	if err != nil {
+		return fmt.Sprintf("%sAPI %s not available: %v", proc, endpoint, err)
+	}
+	return fmt.Sprintf("%sAPI: %s, peers: %d/%d, sequencers: %d, %s",
+		proc, endpoint, nodeInfo.NumActivePeers, nodeInfo.NumStaticPeers, len(nodeInfo.Sequencers), sequencerStatusString(cfg, clnt))
+}
+
+// sequencerStatusString latest output of the node's sequencer chain in the heaviest state of the node
+func sequencerStatusString(cfg *viper.Viper, clnt *client.APIClient) string {
+	for name := range cfg.GetStringMap("sequencers") {
+		seqID, err := ledger.ChainIDFromHexString(cfg.GetString("sequencers." + name + ".sequencer_id"))
+		if err != nil {
+			continue
+		}
+		oData, err := clnt.GetChainOutputData(seqID)
+		if err != nil {
+			return fmt.Sprintf("sequencer '%s': %v", name, err)
+		}
+		return fmt.Sprintf("sequencer '%s' at %s", name, oData.ID.StringShort())
+	}
+	return "no sequencer configured"
+}
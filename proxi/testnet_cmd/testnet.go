@@ -0,0 +1,88 @@
+package testnet_cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// testnetDir is the directory of the local testnet. Each node has own subdirectory 'node<idx>'
+var testnetDir string
+
+const (
+	defaultTestnetDir  = "testnet"
+	nodeDirPrefix      = "node"
+	nodeConfigFileName = "proxima.yaml"
+	nodeLogFileName    = "node.log"
+	nodePIDFileName    = "proxima.pid"
+	composeFileName    = "docker-compose.yaml"
+)
+
+func Init() *cobra.Command {
+	testnetCmd := &cobra.Command{
+		Use:   "testnet [<subcommand>]",
+		Short: "specifies subcommand on the local testnet",
+		Long: `creates, starts and stops the local testnet of Proxima nodes with sequencers.
+Each node is configured in own subdirectory of the testnet directory. Nodes run either as local processes or
+as docker containers`,
+		Args: cobra.NoArgs,
+		Run:  func(_ *cobra.Command, _ []string) {},
+	}
+	testnetCmd.PersistentFlags().StringVarP(&testnetDir, "dir", "d", defaultTestnetDir, "testnet directory")
+
+	testnetCmd.InitDefaultHelpCmd()
+	testnetCmd.AddCommand(
+		initUpCmd(),
+		initDownCmd(),
+		initStatusCmd(),
+	)
+	return testnetCmd
+}
+
+func nodeDir(idx int) string {
+	return filepath.Join(testnetDir, fmt.Sprintf("%s%d", nodeDirPrefix, idx))
+}
+
+func composeFilePath() string {
+	return filepath.Join(testnetDir, composeFileName)
+}
+
+// isDockerized testnet is dockerized if it was created with the docker-compose file
+func isDockerized() bool {
+	return glb.FileExists(composeFilePath())
+}
+
+// testnetNodes returns sorted indices of nodes in the testnet directory
+func testnetNodes() []int {
+	entries, err := os.ReadDir(testnetDir)
+	glb.AssertNoError(err)
+
+	ret := make([]int, 0)
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), nodeDirPrefix) {
+			continue
+		}
+		idx, err := strconv.Atoi(strings.TrimPrefix(e.Name(), nodeDirPrefix))
+		if err != nil {
+			continue
+		}
+		ret = append(ret, idx)
+	}
+	sort.Ints(ret)
+	return ret
+}
+
+// readNodeConfig reads node configuration file from the node directory
+func readNodeConfig(idx int) *viper.Viper {
+	ret := viper.New()
+	ret.SetConfigFile(filepath.Join(nodeDir(idx), nodeConfigFileName))
+	glb.AssertNoError(ret.ReadInConfig())
+	return ret
+}
@@ -0,0 +1,108 @@
+package testnet_cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+var (
+	numNodesUp   int
+	dockerUp     bool
+	srcDirUp     string
+	nodeBinary   string
+	generateOnly bool
+)
+
+const (
+	defaultNumNodes = 5
+	maxNumNodes     = 50
+)
+
+func initUpCmd() *cobra.Command {
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "creates local testnet if it does not exist and starts all its nodes",
+		Long: `creates the testnet directory with ledger identity, genesis databases, node configurations and proxi profiles
+for each node, unless the testnet directory already exists. Then starts all nodes as local processes or,
+for the dockerized testnet, with 'docker compose'.
+Each node runs a sequencer. The node 0 runs the bootstrap sequencer. Proxi profile in the node directory
+contains the controller key of the sequencer. Passphrase of keystores is prompted or taken from the environment variable ` + glb.PassphraseEnvVar,
+		Args: cobra.NoArgs,
+		Run:  runUpCmd,
+	}
+	upCmd.PersistentFlags().IntVarP(&numNodesUp, "nodes", "n", defaultNumNodes, "number of nodes in the new testnet")
+	upCmd.PersistentFlags().BoolVar(&dockerUp, "docker", false, "run nodes in docker containers. Generates docker-compose file")
+	upCmd.PersistentFlags().StringVar(&srcDirUp, "src", ".", "directory of the Proxima sources. Used as docker build context")
+	upCmd.PersistentFlags().StringVar(&nodeBinary, "bin", "proxima", "node executable for local processes")
+	upCmd.PersistentFlags().BoolVar(&generateOnly, "generate_only", false, "only create the testnet, do not start nodes")
+	return upCmd
+}
+
+func runUpCmd(_ *cobra.Command, _ []string) {
+	if glb.FileExists(testnetDir) {
+		glb.Infof("testnet directory '%s' already exists. Nodes will be started from the existing state", testnetDir)
+	} else {
+		glb.Assertf(1 <= numNodesUp && numNodesUp <= maxNumNodes, "number of nodes must be from 1 to %d", maxNumNodes)
+		srcDir, err := filepath.Abs(srcDirUp)
+		glb.AssertNoError(err)
+		if dockerUp {
+			glb.Assertf(glb.FileExists(filepath.Join(srcDir, "Dockerfile")), "Dockerfile not found in '%s'. Use --src", srcDir)
+		}
+		glb.Infof("creating testnet with %d nodes in '%s'", numNodesUp, testnetDir)
+		generateTestnet(generateParams{
+			numNodes: numNodesUp,
+			docker:   dockerUp,
+			srcDir:   srcDir,
+		})
+		glb.Infof("testnet has been created successfully")
+	}
+	if generateOnly {
+		return
+	}
+
+	if isDockerized() {
+		runDockerCompose("up", "-d", "--build")
+		return
+	}
+	bin, err := exec.LookPath(nodeBinary)
+	glb.AssertNoError(err)
+	for _, idx := range testnetNodes() {
+		startNode(idx, bin)
+	}
+}
+
+// startNode starts node as a background process in the node directory. Node output goes to the log file
+func startNode(idx int, bin string) {
+	if pid, running := nodeProcess(idx); running {
+		glb.Infof("node %d is already running. PID: %d", idx, pid)
+		return
+	}
+	dir := nodeDir(idx)
+	logFile, err := os.Create(filepath.Join(dir, nodeLogFileName))
+	glb.AssertNoError(err)
+	defer func() { _ = logFile.Close() }()
+
+	cmd := exec.Command(bin)
+	cmd.Dir = dir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	glb.AssertNoError(cmd.Start())
+
+	pid := cmd.Process.Pid
+	err = os.WriteFile(filepath.Join(dir, nodePIDFileName), []byte(strconv.Itoa(pid)), 0666)
+	glb.AssertNoError(err)
+	glb.AssertNoError(cmd.Process.Release())
+	glb.Infof("node %d has been started. PID: %d, log: %s", idx, pid, filepath.Join(dir, nodeLogFileName))
+}
+
+func runDockerCompose(args ...string) {
+	cmd := exec.Command("docker", append([]string{"compose", "-f", composeFilePath()}, args...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	glb.AssertNoError(cmd.Run())
+}
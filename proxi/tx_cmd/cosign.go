@@ -0,0 +1,76 @@
+package tx_cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util/lazybytes"
+	"github.com/spf13/cobra"
+)
+
+func initCosignCmd() *cobra.Command {
+	cosignCmd := &cobra.Command{
+		Use:   "cosign <file>",
+		Short: "adds signature of the wallet account to the multisig inputs of the transaction in the file",
+		Long: `adds signature of the wallet account to the multisig inputs of the transaction in the file.
+Does not require access to the node. The file is updated in place`,
+		Args: cobra.ExactArgs(1),
+		Run:  runCosignCmd,
+	}
+	cosignCmd.InitDefaultHelpCmd()
+	return cosignCmd
+}
+
+func runCosignCmd(_ *cobra.Command, args []string) {
+	f := glb.MustLoadTransactionFile(args[0])
+	f.InitLedger()
+
+	ctx := f.MustTxContext()
+	glb.Infof("-------- transaction to cosign ---------\n%s\n----------------", ctx.String())
+	glb.Infof("%s", f.Description)
+
+	privateKey := glb.MustGetPrivateKey()
+	cosigner := ledger.AddressED25519FromPrivateKey(privateKey)
+
+	if !glb.YesNoPrompt(fmt.Sprintf("cosign transaction with the private key of %s?", cosigner.String()), false, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		os.Exit(0)
+	}
+
+	txBytes, err := f.TxBytes()
+	glb.AssertNoError(err)
+	inputs, err := f.ConsumedOutputs()
+	glb.AssertNoError(err)
+	txBytes, n, err := txbuilder.CosignTransactionBytes(txBytes, inputs, privateKey)
+	glb.AssertNoError(err)
+
+	f.SetTxBytes(txBytes)
+	glb.AssertNoError(f.Save(args[0]))
+	glb.Infof("%d input(s) cosigned by %s. Transaction has been saved to '%s'", n, cosigner.String(), args[0])
+
+	displayMultisigStatus(f.MustTxContext(), inputs)
+}
+
+// displayMultisigStatus displays number of signatures of each multisig lock which is not unlocked by reference
+func displayMultisigStatus(ctx *transaction.TxContext, inputs []*ledger.OutputWithID) {
+	for i, o := range inputs {
+		msig, ok := o.Output.Lock().(*ledger.MultisigED25519)
+		if !ok {
+			continue
+		}
+		unlockParams, err := lazybytes.ArrayFromBytesReadOnly(ctx.UnlockDataAt(byte(i))).AtSafe(int(ledger.ConstraintIndexLock))
+		if err != nil || len(unlockParams) <= 1 {
+			continue
+		}
+		numSigs := msig.NumSignatures(unlockParams)
+		if numSigs >= int(msig.Threshold) {
+			glb.Infof("input #%d: %d of required %d signatures. Ready to submit", i, numSigs, msig.Threshold)
+		} else {
+			glb.Infof("input #%d: %d of required %d signatures", i, numSigs, msig.Threshold)
+		}
+	}
+}
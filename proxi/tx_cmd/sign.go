@@ -0,0 +1,77 @@
+package tx_cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+// without Var does not work
+var signedOutputFile string
+
+func initSignCmd() *cobra.Command {
+	signCmd := &cobra.Command{
+		Use:   "sign <file>",
+		Short: "signs unsigned transaction from the file with the private key of the wallet account",
+		Long: `signs unsigned transaction from the file with the private key of the wallet account.
+Does not require access to the node. Signed transaction is validated with consumed outputs from the file
+and saved to the '<file>.signed.yaml', unless output file is specified`,
+		Args: cobra.ExactArgs(1),
+		Run:  runSignCmd,
+	}
+	signCmd.Flags().StringVarP(&signedOutputFile, "output", "o", "", "file to save signed transaction")
+	signCmd.InitDefaultHelpCmd()
+	return signCmd
+}
+
+func runSignCmd(_ *cobra.Command, args []string) {
+	f := glb.MustLoadTransactionFile(args[0])
+	glb.Assertf(!f.Signed, "transaction in the file '%s' is already signed", args[0])
+	f.InitLedger()
+
+	ctx := f.MustTxContext()
+	glb.Infof("-------- transaction to sign ---------\n%s\n----------------", ctx.String())
+	glb.Infof("%s", f.Description)
+
+	privateKey := glb.MustGetPrivateKey()
+	signer := ledger.AddressED25519FromPrivateKey(privateKey)
+	glb.Assertf(signer.String() == f.Signer, "transaction must be signed by %s, the wallet account is %s", f.Signer, signer.String())
+
+	if !glb.YesNoPrompt(fmt.Sprintf("sign transaction with the private key of %s?", signer.String()), false, glb.BypassYesNoPrompt()) {
+		glb.Infof("exit")
+		os.Exit(0)
+	}
+
+	txBytes, err := f.TxBytes()
+	glb.AssertNoError(err)
+	txBytes, err = txbuilder.SignTransactionBytes(txBytes, privateKey)
+	glb.AssertNoError(err)
+
+	inputs, err := f.ConsumedOutputs()
+	glb.AssertNoError(err)
+	ctx, err = transaction.TxContextFromTransferableBytes(txBytes, transaction.PickOutputFromListFunc(inputs))
+	glb.AssertNoError(err)
+	glb.AssertNoError(ctx.Validate())
+
+	signed := glb.NewTransactionFile(f.Description, signer, txBytes, inputs)
+	signed.Signed = true
+
+	fname := signedOutputFile
+	if fname == "" {
+		fname = strings.TrimSuffix(strings.TrimSuffix(args[0], ".yaml"), ".unsigned") + ".signed.yaml"
+	}
+	if glb.FileExists(fname) {
+		if !glb.YesNoPrompt(fmt.Sprintf("file '%s' already exists. Overwrite?", fname), false, glb.BypassYesNoPrompt()) {
+			os.Exit(0)
+		}
+	}
+	glb.AssertNoError(signed.Save(fname))
+	glb.Infof("transaction %s has been signed and saved to '%s'. Submit it with 'proxi node tx submit %s'",
+		ctx.TransactionID().StringShort(), fname, fname)
+}
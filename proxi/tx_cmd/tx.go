@@ -0,0 +1,34 @@
+package tx_cmd
+
+import (
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func Init() *cobra.Command {
+	txCmd := &cobra.Command{
+		Use:   "tx [<subcommand>]",
+		Short: "specifies subcommands to process transaction files without access to the node",
+		Long: `specifies subcommands to process transaction files without access to the node.
+Transaction file is built with 'proxi node tx build ...' and submitted with 'proxi node tx submit <file>'.
+Transaction file contains ledger identity and consumed outputs, so it can be parsed and validated on the offline host`,
+		Args: cobra.NoArgs,
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {
+			glb.ReadInConfig()
+		},
+	}
+
+	txCmd.PersistentFlags().StringP("config", "c", "", "proxi config profile name")
+	err := viper.BindPFlag("config", txCmd.PersistentFlags().Lookup("config"))
+	glb.AssertNoError(err)
+
+	glb.AddFlagAccount(txCmd)
+
+	txCmd.InitDefaultHelpCmd()
+	txCmd.AddCommand(
+		initSignCmd(),
+		initCosignCmd(),
+	)
+	return txCmd
+}
@@ -0,0 +1,44 @@
+package wallet_cmd
+
+import (
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var importFromProfile bool
+
+func initImportCmd() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import <account name>",
+		Short: "imports hex-encoded private key into the keystore. The key is prompted",
+		Args:  cobra.ExactArgs(1),
+		Run:   runImportCmd,
+	}
+	importCmd.PersistentFlags().BoolVar(&importFromProfile, "from_profile", false, "import plaintext 'wallet.private_key' from the profile")
+	importCmd.InitDefaultHelpCmd()
+	return importCmd
+}
+
+func runImportCmd(_ *cobra.Command, args []string) {
+	var privateKeyStr string
+	if importFromProfile {
+		privateKeyStr = viper.GetString("wallet.private_key")
+		glb.Assertf(privateKeyStr != "", "'wallet.private_key' not found in the profile")
+	} else {
+		privateKeyStr = glb.ReadSecretLine("hex-encoded private key: ")
+	}
+	privateKey, err := util.ED25519PrivateKeyFromHexString(privateKeyStr)
+	glb.AssertNoError(err)
+
+	ks := glb.OpenKeystore()
+	acc, err := ks.Import(args[0], privateKey)
+	glb.AssertNoError(err)
+	glb.AssertNoError(ks.Save())
+
+	glb.Infof("imported account '%s': %s", acc.Name, acc.Address)
+	if importFromProfile {
+		glb.Infof("now remove 'wallet.private_key' from the profile '%s'", viper.ConfigFileUsed())
+	}
+}
@@ -0,0 +1,38 @@
+package wallet_cmd
+
+import (
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/proxi/keystore"
+	"github.com/spf13/cobra"
+)
+
+func initListCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "lists accounts in the keystore. Does not require passphrase",
+		Args:  cobra.NoArgs,
+		Run:   runListCmd,
+	}
+	listCmd.InitDefaultHelpCmd()
+	return listCmd
+}
+
+func runListCmd(_ *cobra.Command, _ []string) {
+	fname := glb.MustKeystoreFileName()
+	accounts, err := keystore.ReadAccounts(fname)
+	glb.AssertNoError(err)
+
+	selected := glb.GetAccountName()
+	glb.Infof("%d account(s) in the keystore '%s':", len(accounts), fname)
+	for _, acc := range accounts {
+		mark := " "
+		if acc.Name == selected {
+			mark = "*"
+		}
+		path := "imported"
+		if acc.Index != nil {
+			path = keystore.DerivationPathString(*acc.Index)
+		}
+		glb.Infof("%s %-16s %-16s %s", mark, acc.Name, path, acc.Address)
+	}
+}
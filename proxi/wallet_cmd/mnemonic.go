@@ -0,0 +1,26 @@
+package wallet_cmd
+
+import (
+	"os"
+
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+func initMnemonicCmd() *cobra.Command {
+	mnemonicCmd := &cobra.Command{
+		Use:   "mnemonic",
+		Short: "displays mnemonic of the HD wallet",
+		Args:  cobra.NoArgs,
+		Run:   runMnemonicCmd,
+	}
+	mnemonicCmd.InitDefaultHelpCmd()
+	return mnemonicCmd
+}
+
+func runMnemonicCmd(_ *cobra.Command, _ []string) {
+	if !glb.YesNoPrompt("mnemonic gives full control over all derived accounts. Display it?", false, glb.BypassYesNoPrompt()) {
+		os.Exit(0)
+	}
+	glb.Infof("%s", glb.OpenKeystore().Mnemonic())
+}
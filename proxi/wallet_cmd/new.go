@@ -0,0 +1,27 @@
+package wallet_cmd
+
+import (
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/lunfardo314/proxima/proxi/keystore"
+	"github.com/spf13/cobra"
+)
+
+func initNewAccountCmd() *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   "new <account name>",
+		Short: "derives new account from the mnemonic with the next unused derivation index",
+		Args:  cobra.ExactArgs(1),
+		Run:   runNewAccountCmd,
+	}
+	newCmd.InitDefaultHelpCmd()
+	return newCmd
+}
+
+func runNewAccountCmd(_ *cobra.Command, args []string) {
+	ks := glb.OpenKeystore()
+	acc, err := ks.Derive(args[0])
+	glb.AssertNoError(err)
+	glb.AssertNoError(ks.Save())
+
+	glb.Infof("new account '%s' (%s): %s", acc.Name, keystore.DerivationPathString(*acc.Index), acc.Address)
+}
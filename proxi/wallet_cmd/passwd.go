@@ -0,0 +1,24 @@
+package wallet_cmd
+
+import (
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+)
+
+func initPasswdCmd() *cobra.Command {
+	passwdCmd := &cobra.Command{
+		Use:   "passwd",
+		Short: "changes passphrase of the keystore",
+		Args:  cobra.NoArgs,
+		Run:   runPasswdCmd,
+	}
+	passwdCmd.InitDefaultHelpCmd()
+	return passwdCmd
+}
+
+func runPasswdCmd(_ *cobra.Command, _ []string) {
+	ks := glb.OpenKeystore()
+	ks.ChangePassphrase(glb.ReadNewPassphrase())
+	glb.AssertNoError(ks.Save())
+	glb.Infof("passphrase of the keystore '%s' has been changed", ks.FileName())
+}
@@ -0,0 +1,36 @@
+package wallet_cmd
+
+import (
+	"github.com/lunfardo314/proxima/proxi/glb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func Init() *cobra.Command {
+	walletCmd := &cobra.Command{
+		Use:   "wallet [<subcommand>]",
+		Short: "specifies subcommands to manage accounts in the passphrase-encrypted keystore",
+		Long: `specifies subcommands to manage accounts in the passphrase-encrypted keystore.
+Keystore is specified in the profile as 'wallet.keystore'. Passphrase is prompted or taken from the environment variable ` + glb.PassphraseEnvVar,
+		Args: cobra.NoArgs,
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {
+			glb.ReadInConfig()
+		},
+	}
+
+	walletCmd.PersistentFlags().StringP("config", "c", "", "proxi config profile name")
+	err := viper.BindPFlag("config", walletCmd.PersistentFlags().Lookup("config"))
+	glb.AssertNoError(err)
+
+	glb.AddFlagAccount(walletCmd)
+
+	walletCmd.InitDefaultHelpCmd()
+	walletCmd.AddCommand(
+		initListCmd(),
+		initNewAccountCmd(),
+		initImportCmd(),
+		initPasswdCmd(),
+		initMnemonicCmd(),
+	)
+	return walletCmd
+}
@@ -110,6 +110,13 @@ func (b *InputBacklog) checkAndReferenceCandidate(wOut vertex.WrappedOutput) boo
 			wOut.VID.UnReference()
 			return false
 		}
+		if token, _ := o.Token(); token != nil {
+			// filter out native token outputs. Milestone does not carry native tokens further,
+			// so consuming them would violate token conservation
+			b.TraceTx(&wOut.VID.ID, "[%s] backlog::checkAndReferenceCandidate: #%d carries native tokens", b.SequencerName, wOut.Index)
+			wOut.VID.UnReference()
+			return false
+		}
 	}
 	// it is referenced
 	b.TraceTx(&wOut.VID.ID, "[%s] backlog::checkAndReferenceCandidate: #%d success", b.SequencerName, wOut.Index)
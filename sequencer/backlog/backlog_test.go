@@ -0,0 +1,97 @@
+package backlog
+
+import (
+	"testing"
+
+	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type backlogDummyEnvironment struct {
+	*global.Global
+	seqID    ledger.ChainID
+	onOutput func(wOut vertex.WrappedOutput)
+}
+
+func (d *backlogDummyEnvironment) ListenToAccount(_ ledger.Accountable, fun func(wOut vertex.WrappedOutput)) {
+	d.onOutput = fun
+}
+
+func (d *backlogDummyEnvironment) SequencerID() ledger.ChainID {
+	return d.seqID
+}
+
+func (d *backlogDummyEnvironment) SequencerName() string {
+	return "seq"
+}
+
+func (d *backlogDummyEnvironment) GetLatestMilestone(_ ledger.ChainID) *vertex.WrappedTx {
+	return nil
+}
+
+func (d *backlogDummyEnvironment) LatestMilestonesDescending(_ ...func(seqID ledger.ChainID, vid *vertex.WrappedTx) bool) []*vertex.WrappedTx {
+	return nil
+}
+
+func (d *backlogDummyEnvironment) NumSequencerTips() int {
+	return 0
+}
+
+func (d *backlogDummyEnvironment) BacklogTTLSlots() int {
+	return 10
+}
+
+func TestTagAlongOutputs(t *testing.T) {
+	ledger.InitWithTestingLedgerIDData()
+	env := &backlogDummyEnvironment{
+		Global: global.NewDefault(),
+		seqID:  ledger.RandomChainID(),
+	}
+	b, err := New(env)
+	require.NoError(t, err)
+	require.NotNil(t, env.onOutput)
+
+	// transaction sends plain and native token outputs to the tag-along lock of the sequencer
+	privKey := testutil.GetTestingPrivateKey(1)
+	addr := ledger.AddressED25519FromPrivateKey(privKey)
+	inTxID := ledger.RandomTransactionID(false)
+	input := ledger.NewOutput(func(o *ledger.Output) {
+		o.WithAmount(1_000_000).WithLock(addr)
+	})
+	txb := txbuilder.NewTransactionBuilder()
+	_, err = txb.ConsumeOutput(input, ledger.NewOutputID(&inTxID, 0))
+	require.NoError(t, err)
+	txb.PutSignatureUnlock(0)
+	tagAlongLock := env.seqID.AsChainLock()
+	_, err = txb.ProduceOutputs(
+		ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(500_000).WithLock(tagAlongLock)
+		}),
+		ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(500_000).WithLock(tagAlongLock).WithToken(ledger.TokenIDFromChainID(ledger.RandomChainID()), 1000)
+		}),
+	)
+	require.NoError(t, err)
+	txb.TransactionData.Timestamp = inTxID.Timestamp().AddTicks(ledger.TransactionPace())
+	txb.TransactionData.InputCommitment = txb.InputCommitment()
+	txb.SignED25519(privKey)
+	tx, err := transaction.FromBytes(txb.TransactionData.Bytes())
+	require.NoError(t, err)
+	vid := vertex.New(tx).Wrap()
+
+	env.onOutput(vertex.WrappedOutput{VID: vid, Index: 0})
+	env.onOutput(vertex.WrappedOutput{VID: vid, Index: 1})
+
+	// the output with native tokens is not a tag-along candidate
+	outs := b.FilterAndSortOutputs(func(_ vertex.WrappedOutput) bool { return true })
+	require.EqualValues(t, 1, len(outs))
+	require.EqualValues(t, 0, outs[0].Index)
+
+	env.Stop()
+	env.MustWaitAllWorkProcessesStop()
+}
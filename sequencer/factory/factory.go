@@ -140,6 +140,11 @@ func (mf *MilestoneFactory) bootstrapOwnMilestoneOutput() vertex.WrappedOutput {
 		}
 		mf.AssertNoError(err)
 		ret := attacher.AttachOutputID(o.ID, mf, attacher.OptionInvokedBy("tippool"))
+		// the output may be produced by the branch, which is virtual transaction with only sequencer outputs known,
+		// for example chain origin in the genesis distribution
+		out, err := o.Parse()
+		mf.AssertNoError(err)
+		mf.Assertf(ret.VID.EnsureOutput(ret.Index, out.Output), "can't ensure output %s", o.ID.StringShort)
 		return ret
 	}
 	return vertex.WrappedOutput{}
@@ -181,7 +186,7 @@ func (mf *MilestoneFactory) AddOwnMilestone(vid *vertex.WrappedTx) {
 
 func (mf *MilestoneFactory) StartProposingForTargetLogicalTime(targetTs ledger.Time) (*transaction.Transaction, *txmetadata.TransactionMetadata) {
 	deadline := targetTs.Time()
-	nowis := time.Now()
+	nowis := mf.Clock().Now()
 	mf.Tracef(TraceTag, "StartProposingForTargetLogicalTime: target: %s, deadline: %s, nowis: %s",
 		targetTs.String, deadline.Format("15:04:05.999"), nowis.Format("15:04:05.999"))
 
@@ -192,8 +197,16 @@ func (mf *MilestoneFactory) StartProposingForTargetLogicalTime(targetTs ledger.T
 	}
 	// start worker(s)
 	mf.setNewTarget(targetTs)
-	ctx, cancel := context.WithDeadline(mf.Ctx(), deadline)
+	// deadline is measured by the clock of the node, which is not necessarily the real clock
+	ctx, cancel := context.WithCancel(mf.Ctx())
 	defer cancel() // to prevent context leak
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-mf.Clock().After(deadline.Sub(nowis)):
+			cancel()
+		}
+	}()
 	mf.startProposerWorkers(targetTs, ctx)
 
 	<-ctx.Done()
@@ -185,8 +185,8 @@ func (seq *Sequencer) ensureFirstMilestone() bool {
 
 	sleepDuration := ledger.SleepDurationUntilFutureLedgerTime(startingMilestoneOutput.Timestamp())
 	if sleepDuration > 0 {
-		seq.log.Infof("will delay start for %v to sync starting milestone with the real clock", sleepDuration)
-		time.Sleep(sleepDuration)
+		seq.log.Infof("will delay start for %v to sync starting milestone with the clock", sleepDuration)
+		seq.Clock().Sleep(sleepDuration)
 	}
 	return true
 }
@@ -213,10 +213,10 @@ func (seq *Sequencer) Log() *zap.SugaredLogger {
 
 func (seq *Sequencer) mainLoop() {
 	beginAt := seq.Workflow.SyncData().WhenStarted().Add(seq.config.DelayStart)
-	if beginAt.After(time.Now()) {
+	if nowis := seq.Clock().Now(); beginAt.After(nowis) {
 		seq.log.Infof("wait for %v before starting the main loop", seq.config.DelayStart)
+		seq.Clock().Sleep(beginAt.Sub(nowis))
 	}
-	time.Sleep(time.Until(beginAt))
 
 	seq.Log().Infof("STARTING sequencer")
 	defer func() {
@@ -301,13 +301,13 @@ func (seq *Sequencer) getNextTargetTime() (ledger.Time, ledger.Time) {
 		waitDuration := time.Duration(ledger.DiffTicks(prevMilestoneTs, nowis)) * ledger.TickDuration()
 		seq.log.Warnf("nowis (%s) is before last milestone ts (%s). Sleep %v",
 			nowis.String(), prevMilestoneTs.String(), waitDuration)
-		time.Sleep(waitDuration)
+		seq.Clock().Sleep(waitDuration)
 	}
 	nowis = ledger.TimeNow()
 	for ; nowis.Before(prevMilestoneTs); nowis = ledger.TimeNow() {
 		seq.log.Warnf("nowis (%s) is before last milestone ts (%s). Sleep %v",
 			nowis.String(), prevMilestoneTs.String(), sleepWaitingCurrentMilestoneTime)
-		time.Sleep(sleepWaitingCurrentMilestoneTime)
+		seq.Clock().Sleep(sleepWaitingCurrentMilestoneTime)
 	}
 	// ledger time now is approximately equal to the clock time
 	nowis = ledger.TimeNow()
@@ -0,0 +1,230 @@
+//go:build netsim
+
+package tests
+
+import (
+	"crypto/ed25519"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/core/attacher"
+	"github.com/lunfardo314/proxima/core/txmetadata"
+	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/core/workflow"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/tests/netsim"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/stretchr/testify/require"
+)
+
+// Fault injection. Helpers below produce transactions, metadata and peers of misbehaving participants
+// on top of the conflict test data and assert how the honest nodes treat them
+
+const (
+	adversaryPollPeriod = 5 * time.Millisecond
+	adversaryTimeout    = 5 * time.Second
+)
+
+// makeDoubleSpendingMilestone makes sequencer milestone which extends the chain output and consumes
+// outputs of mutually conflicting transactions
+func (td *workflowTestData) makeDoubleSpendingMilestone(chainIn *ledger.OutputWithChainID, privKey ed25519.PrivateKey, conflicting ...*ledger.OutputWithID) *transaction.Transaction {
+	inTS := []ledger.Time{chainIn.Timestamp()}
+	for _, o := range conflicting {
+		inTS = append(inTS, o.Timestamp())
+	}
+	txBytes, err := txbuilder.MakeSequencerTransaction(txbuilder.MakeSequencerTransactionParams{
+		SeqName:          "double spender",
+		ChainInput:       chainIn,
+		Timestamp:        ledger.MaxTime(inTS...).AddTicks(ledger.TransactionPaceSequencer()),
+		AdditionalInputs: conflicting,
+		PrivateKey:       privKey,
+	})
+	require.NoError(td.t, err)
+	tx, err := transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+	require.NoError(td.t, err)
+	return tx
+}
+
+// makeNextBranch makes branch of the bootstrap sequencer on the next slot after the heaviest branch
+func (td *workflowTestData) makeNextBranch() *transaction.Transaction {
+	rdr := multistate.MakeSugared(td.wrk.HeaviestStateForLatestTimeSlot())
+	chainOut, err := rdr.GetChainOutput(&td.bootstrapChainID)
+	require.NoError(td.t, err)
+	stem := rdr.GetStemOutput()
+
+	txBytes, err := txbuilder.MakeSequencerTransaction(txbuilder.MakeSequencerTransactionParams{
+		SeqName:    "boot",
+		ChainInput: chainOut.MustAsChainOutput(),
+		StemInput:  stem,
+		Timestamp:  ledger.MustNewLedgerTime(ledger.MaxTime(chainOut.Timestamp(), stem.Timestamp()).Slot()+1, 0),
+		PrivateKey: td.genesisPrivKey,
+	})
+	require.NoError(td.t, err)
+	tx, err := transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+	require.NoError(td.t, err)
+	return tx
+}
+
+// makeConflictingBranches makes branch of each sequencer chain on the same stem, so branches are mutually conflicting.
+// Sequencer chains must be started with makeSeqBeginnings
+func (td *longConflictTestData) makeConflictingBranches() []*transaction.Transaction {
+	chainIn := make([]*ledger.OutputWithChainID, len(td.seqChain))
+	var ts ledger.Time
+	for seqNr := range td.seqChain {
+		tx := td.seqChain[seqNr][0]
+		chainIn[seqNr] = tx.MustProducedOutputWithIDAt(tx.SequencerTransactionData().SequencerOutputIndex).MustAsChainOutput()
+		ts = ledger.MaxTime(ts, chainIn[seqNr].Timestamp())
+	}
+	ts = ts.NextSlotBoundary()
+
+	stem := multistate.MakeSugared(td.wrk.HeaviestStateForLatestTimeSlot()).GetStemOutput()
+	ret := make([]*transaction.Transaction, len(chainIn))
+	for i := range chainIn {
+		txBytes, err := txbuilder.MakeSequencerTransaction(txbuilder.MakeSequencerTransactionParams{
+			SeqName:    "seq",
+			StemInput:  stem,
+			ChainInput: chainIn[i],
+			Timestamp:  ts,
+			PrivateKey: td.privKeyAux,
+		})
+		require.NoError(td.t, err)
+		ret[i], err = transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+		require.NoError(td.t, err)
+	}
+	return ret
+}
+
+// makeMilestoneEndorsingConflict makes milestone which extends sequencer output of one transaction
+// and endorses another one, which is in conflict with the first
+func (td *longConflictTestData) makeMilestoneEndorsingConflict(extend, endorse *transaction.Transaction) *transaction.Transaction {
+	txBytes, err := txbuilder.MakeSequencerTransaction(txbuilder.MakeSequencerTransactionParams{
+		SeqName:      "conflicting endorser",
+		ChainInput:   extend.SequencerOutput().MustAsChainOutput(),
+		Timestamp:    ledger.MaxTime(extend.Timestamp(), endorse.Timestamp()).AddTicks(ledger.TransactionPaceSequencer()),
+		Endorsements: util.List(endorse.ID()),
+		PrivateKey:   td.privKeyAux,
+	})
+	require.NoError(td.t, err)
+	tx, err := transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+	require.NoError(td.t, err)
+	return tx
+}
+
+// metadataWithWrongCoverage is metadata of the milestone with the ledger coverage no honest node can compute
+func metadataWithWrongCoverage() *txmetadata.TransactionMetadata {
+	return &txmetadata.TransactionMetadata{
+		LedgerCoverage:          util.Ref(uint64(1)),
+		SourceTypeNonPersistent: txmetadata.SourceTypePeer,
+	}
+}
+
+// metadataWithWrongStateRoot is metadata of the branch with the state root of another state
+func metadataWithWrongStateRoot(root common.VCommitment) *txmetadata.TransactionMetadata {
+	return &txmetadata.TransactionMetadata{
+		StateRoot:               root,
+		SourceTypeNonPersistent: txmetadata.SourceTypePeer,
+	}
+}
+
+// attachAndWait attaches transaction with the options and waits until its status becomes defined
+func (td *workflowTestData) attachAndWait(tx *transaction.Transaction, opts ...attacher.Option) *vertex.WrappedTx {
+	vid, err := attacher.AttachTransactionFromBytes(tx.Bytes(), td.wrk, opts...)
+	require.NoError(td.t, err)
+	_, err = td.wrk.WaitTxIDDefined(&vid.ID, adversaryPollPeriod, adversaryTimeout)
+	require.NoError(td.t, err)
+	return vid
+}
+
+// replayTransaction submits the same transaction bytes n times, as if received from peers.
+// Returns vertices of each submission
+func (td *workflowTestData) replayTransaction(tx *transaction.Transaction, n int) []*vertex.WrappedTx {
+	ret := make([]*vertex.WrappedTx, n)
+	for i := range ret {
+		txid, err := td.wrk.TxBytesIn(tx.Bytes(), workflow.WithSourceType(txmetadata.SourceTypePeer))
+		require.NoError(td.t, err)
+		_, err = td.wrk.WaitTxIDDefined(txid, adversaryPollPeriod, adversaryTimeout)
+		require.NoError(td.t, err)
+		ret[i] = td.wrk.GetVertex(txid)
+		require.True(td.t, ret[i] != nil)
+	}
+	return ret
+}
+
+// requireBad asserts vertex is invalidated with the error which contains all fragments
+func requireBad(t *testing.T, vid *vertex.WrappedTx, fragments ...string) {
+	require.EqualValues(t, vertex.Bad.String(), vid.GetTxStatus().String())
+	t.Logf("%s is BAD as expected: %v", vid.IDShortString(), vid.GetError())
+	util.RequireErrorWith(t, vid.GetError(), fragments...)
+}
+
+// requireGood asserts vertex is valid
+func requireGood(t *testing.T, vid *vertex.WrappedTx) {
+	require.EqualValues(t, vertex.Good.String(), vid.GetTxStatus().String(), "%s: %v", vid.IDShortString(), vid.GetError())
+}
+
+// requireBranchCommitted asserts branch was committed to the state
+func requireBranchCommitted(t *testing.T, store global.StateStoreReader, branchID ledger.TransactionID) {
+	_, found := multistate.FetchRootRecord(store, branchID)
+	require.True(t, found, "branch %s must be committed", branchID.StringShort())
+}
+
+// metadataMismatchCounter counts reported inconsistencies of milestones with the metadata.
+// Metadata is advisory, so the inconsistency only penalizes the sender
+type metadataMismatchCounter struct {
+	mutex sync.Mutex
+	errs  []error
+}
+
+func (c *metadataMismatchCounter) option() attacher.Option {
+	return attacher.OptionOnMetadataMismatch(func(err error) {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.errs = append(c.errs, err)
+	})
+}
+
+// requireReported asserts exactly one inconsistency was reported with the error which contains all fragments
+func (c *metadataMismatchCounter) requireReported(t *testing.T, fragments ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	require.EqualValues(t, 1, len(c.errs))
+	util.RequireErrorWith(t, c.errs[0], fragments...)
+}
+
+// garbageTxBytesStore keeps transactions as usual, but returns them corrupted. The node with such a store
+// answers pull requests with garbage
+type garbageTxBytesStore struct {
+	global.TxBytesStore
+}
+
+func withGarbageTxBytesStore(store global.TxBytesStore) global.TxBytesStore {
+	return garbageTxBytesStore{store}
+}
+
+func (s garbageTxBytesStore) GetTxBytesWithMetadata(id *ledger.TransactionID) []byte {
+	txBytesWithMetadata := s.TxBytesStore.GetTxBytesWithMetadata(id)
+	metadataBytes, txBytes, err := txmetadata.SplitTxBytesWithMetadata(txBytesWithMetadata)
+	if err != nil {
+		return txBytesWithMetadata
+	}
+	garbage := make([]byte, len(txBytes))
+	for i := range garbage {
+		garbage[i] = ^txBytes[i]
+	}
+	return common.ConcatBytes(metadataBytes, garbage)
+}
+
+// requireHonestConvergence asserts that honest nodes converge to the same heaviest branch after the slot
+func requireHonestConvergence(t *testing.T, nw *netsim.Network, fromSlot ledger.Slot, timeout time.Duration, honest ...int) ledger.TransactionID {
+	txid, err := nw.WaitConvergence(fromSlot, timeout, honest...)
+	require.NoError(t, err)
+	t.Logf("honest nodes %v converged on branch %s", honest, txid.StringShort())
+	return txid
+}
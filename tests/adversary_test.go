@@ -0,0 +1,122 @@
+//go:build netsim
+
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/core/attacher"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/tests/netsim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdversary(t *testing.T) {
+	t.Run("double spending sequencer", func(t *testing.T) {
+		const nConflicts = 3
+		testData := initWorkflowTestWithConflicts(t, nConflicts, 1, true)
+		for _, txBytes := range testData.txBytesConflicting {
+			_, err := attacher.AttachTransactionFromBytes(txBytes, testData.wrk)
+			require.NoError(t, err)
+		}
+
+		bd := multistate.FetchLatestBranches(testData.wrk.StateStore())[0]
+		tx := testData.makeDoubleSpendingMilestone(bd.SequencerOutput.MustAsChainOutput(), testData.privKey, testData.conflictingOutputs...)
+		vid := testData.attachAndWait(tx)
+		requireBad(t, vid, "conflicts with existing consumers in the baseline state", testData.forkOutput.IDShort())
+
+		testData.stopAndWait()
+		testData.logDAGInfo()
+	})
+	t.Run("endorsing conflicting branch", func(t *testing.T) {
+		const (
+			nConflicts = 3
+			howLong    = 3
+		)
+		testData := initLongConflictTestData(t, nConflicts, nConflicts, howLong)
+		testData.makeSeqBeginnings(true)
+		testData.txBytesToStore()
+		for seqNr := range testData.seqChain {
+			testData.storeTransactions(testData.seqChain[seqNr]...)
+		}
+		branches := testData.makeConflictingBranches()
+		testData.storeTransactions(branches...)
+
+		tx := testData.makeMilestoneEndorsingConflict(branches[0], branches[1])
+		vid := testData.attachAndWait(tx)
+		requireBad(t, vid, "is incompatible with the baseline branch", branches[1].IDShortString())
+
+		testData.stopAndWait()
+		testData.logDAGInfo()
+	})
+	t.Run("wrong coverage in metadata", func(t *testing.T) {
+		testData := initWorkflowTest(t, 1)
+		branch := testData.makeNextBranch()
+		var mismatch metadataMismatchCounter
+		vid := testData.attachAndWait(branch, attacher.OptionWithTransactionMetadata(metadataWithWrongCoverage()), mismatch.option())
+		requireGood(t, vid)
+		requireBranchCommitted(t, testData.wrk.StateStore(), vid.ID)
+		mismatch.requireReported(t, "major inconsistency", "coverage")
+
+		testData.stopAndWait()
+	})
+	t.Run("wrong state root in metadata", func(t *testing.T) {
+		testData := initWorkflowTest(t, 1)
+		wrongRoot := testData.wrk.HeaviestStateForLatestTimeSlot().Root()
+		branch := testData.makeNextBranch()
+		var mismatch metadataMismatchCounter
+		vid := testData.attachAndWait(branch, attacher.OptionWithTransactionMetadata(metadataWithWrongStateRoot(wrongRoot)), mismatch.option())
+		requireGood(t, vid)
+		requireBranchCommitted(t, testData.wrk.StateStore(), vid.ID)
+		mismatch.requireReported(t, "major inconsistency", "state root")
+
+		testData.stopAndWait()
+	})
+	t.Run("honest branch with honest metadata", func(t *testing.T) {
+		testData := initWorkflowTest(t, 1)
+		branch := testData.makeNextBranch()
+		var mismatch metadataMismatchCounter
+		vid := testData.attachAndWait(branch, mismatch.option())
+		requireGood(t, vid)
+		requireBranchCommitted(t, testData.wrk.StateStore(), vid.ID)
+		require.EqualValues(t, 0, len(mismatch.errs))
+
+		testData.stopAndWait()
+	})
+	t.Run("replayed transaction", func(t *testing.T) {
+		const nReplays = 5
+		testData := initWorkflowTest(t, 1)
+		branch := testData.makeNextBranch()
+		vid := testData.attachAndWait(branch)
+		requireGood(t, vid)
+		numVertices := testData.wrk.NumVertices()
+
+		for _, replayed := range testData.replayTransaction(branch, nReplays) {
+			require.True(t, replayed == vid)
+			requireGood(t, replayed)
+		}
+		require.EqualValues(t, numVertices, testData.wrk.NumVertices())
+
+		testData.stopAndWait()
+	})
+}
+
+func TestAdversaryNetSim(t *testing.T) {
+	t.Run("garbage pull responses", func(t *testing.T) {
+		const (
+			numNodes  = 4
+			byzantine = 3
+		)
+		nw, err := netsim.New(netsim.DefaultConfig(numNodes, genesisPrivateKey))
+		require.NoError(t, err)
+		defer nw.Stop(5 * time.Second)
+
+		nw.Nodes[byzantine].WrapTxBytesStore(withGarbageTxBytesStore)
+		require.NoError(t, nw.Start(10*time.Second))
+
+		from := ledger.TimeNow().Slot() + 3
+		requireHonestConvergence(t, nw, from, 30*time.Second, 0, 1, 2)
+	})
+}
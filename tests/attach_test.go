@@ -124,6 +124,40 @@ func TestBasic(t *testing.T) {
 		balChain = multistate.BalanceOnChainOutput(rdr, &bootstrapChainID)
 		require.EqualValues(t, ledger.DefaultInitialSupply-1_000_000-2_000_000, int(balChain))
 	})
+	t.Run("with chain origin in distribution", func(t *testing.T) {
+		privKey := testutil.GetTestingPrivateKey()
+		par := ledger.DefaultIdentityData(privKey)
+		addr1 := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(1))
+		distrib := []ledger.LockBalance{
+			{Lock: addr1, Balance: 1_000_000},
+			{Lock: addr1, Balance: 2_000_000, ChainOrigin: true},
+		}
+
+		stateStore := common.NewInMemoryKVStore()
+		multistate.InitStateStore(*par, stateStore)
+		txBytes, distribTxID, err := txbuilder.DistributeInitialSupplyExt(stateStore, privKey, distrib)
+		require.NoError(t, err)
+
+		tx, err := transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+		require.NoError(t, err)
+		var chainID ledger.ChainID
+		tx.ForEachProducedOutput(func(_ byte, o *ledger.Output, oid *ledger.OutputID) bool {
+			if cc, idx := o.ChainConstraint(); idx != 0xff && cc.IsOrigin() {
+				chainID = ledger.MakeOriginChainID(oid)
+				return false
+			}
+			return true
+		})
+		require.True(t, chainID != ledger.NilChainID)
+
+		rr, ok := multistate.FetchRootRecord(stateStore, distribTxID)
+		require.True(t, ok)
+		rdr := multistate.MakeSugared(multistate.MustNewReadable(stateStore, rr.Root))
+		bal1, n1 := multistate.BalanceOnLock(rdr, addr1)
+		require.EqualValues(t, 3_000_000, int(bal1))
+		require.EqualValues(t, 2, n1)
+		require.EqualValues(t, 2_000_000, int(multistate.BalanceOnChainOutput(rdr, &chainID)))
+	})
 	t.Run("sync scenario", func(t *testing.T) {
 		//attacher.SetTraceOn()
 		privKey := testutil.GetTestingPrivateKey()
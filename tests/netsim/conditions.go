@@ -0,0 +1,151 @@
+//go:build netsim
+
+package netsim
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/peering"
+)
+
+// outMessageFilter applies conditions of links from the node to other nodes
+func (nw *Network) outMessageFilter(from int) peering.OutMessageFilter {
+	return func(to peer.ID, _ string) (bool, time.Duration) {
+		nw.mutex.RLock()
+		defer nw.mutex.RUnlock()
+
+		idx, found := nw.nodeByID[to]
+		if !found {
+			return true, 0
+		}
+		cond := nw.links[link{from: from, to: idx}]
+		return !cond.Drop, cond.Delay
+	}
+}
+
+// SetLinkCondition sets condition for messages sent from one node to another. Zero condition passes messages without delay
+func (nw *Network) SetLinkCondition(from, to int, cond LinkCondition) {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+
+	if cond == (LinkCondition{}) {
+		delete(nw.links, link{from: from, to: to})
+	} else {
+		nw.links[link{from: from, to: to}] = cond
+	}
+}
+
+// DropMessages drops all messages sent from one node to another
+func (nw *Network) DropMessages(from, to int) {
+	nw.SetLinkCondition(from, to, LinkCondition{Drop: true})
+}
+
+// SetDelay delays all messages sent from one node to another
+func (nw *Network) SetDelay(from, to int, delay time.Duration) {
+	nw.SetLinkCondition(from, to, LinkCondition{Delay: delay})
+}
+
+// Partition splits the network into groups of nodes. Messages between different groups are dropped.
+// Nodes not listed in any group are isolated. Previous link conditions are removed
+func (nw *Network) Partition(groups ...[]int) {
+	group := make(map[int]int)
+	for i, g := range groups {
+		for _, idx := range g {
+			group[idx] = i
+		}
+	}
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+
+	nw.links = make(map[link]LinkCondition)
+	for from := range nw.Nodes {
+		for to := range nw.Nodes {
+			if from == to {
+				continue
+			}
+			gFrom, okFrom := group[from]
+			gTo, okTo := group[to]
+			if !okFrom || !okTo || gFrom != gTo {
+				nw.links[link{from: from, to: to}] = LinkCondition{Drop: true}
+			}
+		}
+	}
+}
+
+// Heal removes all link conditions
+func (nw *Network) Heal() {
+	nw.mutex.Lock()
+	defer nw.mutex.Unlock()
+
+	nw.links = make(map[link]LinkCondition)
+}
+
+// LatestSlot latest slot with committed branches in the state of the node
+func (n *Node) LatestSlot() ledger.Slot {
+	return multistate.FetchLatestSlot(n.StateStore())
+}
+
+// HeaviestBranches returns branch IDs of the heaviest chain of branches of the node, by slot
+func (n *Node) HeaviestBranches() map[ledger.Slot]ledger.TransactionID {
+	ret := make(map[ledger.Slot]ledger.TransactionID)
+	for _, bd := range multistate.FetchHeaviestBranchChainNSlotsBack(n.StateStore(), -1) {
+		ret[bd.Stem.ID.Slot()] = bd.Stem.ID.TransactionID()
+	}
+	return ret
+}
+
+// LatestCommonBranch returns the branch with the latest slot which is in the heaviest chain of all
+// specified nodes. No nodes specified means all nodes
+func (nw *Network) LatestCommonBranch(nodes ...int) (ledger.TransactionID, bool) {
+	var common map[ledger.Slot]ledger.TransactionID
+	for _, node := range nw.selectNodes(nodes) {
+		branches := node.HeaviestBranches()
+		if common == nil {
+			common = branches
+			continue
+		}
+		for slot, txid := range common {
+			if branches[slot] != txid {
+				delete(common, slot)
+			}
+		}
+	}
+	var ret ledger.TransactionID
+	found := false
+	for slot, txid := range common {
+		if !found || slot > ret.Slot() {
+			ret, found = txid, true
+		}
+	}
+	return ret, found
+}
+
+// WaitConvergence waits until heaviest chains of branches of all specified nodes contain the same branch in the slot
+// not earlier than fromSlot. Returns that branch. No nodes specified means all nodes
+func (nw *Network) WaitConvergence(fromSlot ledger.Slot, timeout time.Duration, nodes ...int) (ledger.TransactionID, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if txid, ok := nw.LatestCommonBranch(nodes...); ok && txid.Slot() >= fromSlot {
+			return txid, nil
+		}
+		if time.Now().After(deadline) {
+			return ledger.TransactionID{}, fmt.Errorf("netsim: nodes did not converge on branch in slot >= %d in %v", fromSlot, timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (nw *Network) selectNodes(nodes []int) []*Node {
+	if len(nodes) == 0 {
+		return nw.Nodes
+	}
+	ret := make([]*Node, len(nodes))
+	for i, idx := range nodes {
+		ret[i] = nw.Nodes[idx]
+	}
+	return ret
+}
@@ -0,0 +1,366 @@
+//go:build netsim
+
+// Package netsim runs a network of full Proxima nodes in one process for testing of the consensus behaviour.
+// Each node has its own in-memory state store, transaction store, libp2p host on localhost, workflow and sequencer.
+// Messages between nodes can be dropped or delayed, the network can be partitioned and healed.
+// The package requires the 'netsim' build tag, e.g.: go test -tags netsim ./tests/...
+package netsim
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/lunfardo314/proxima/core/attacher"
+	"github.com/lunfardo314/proxima/core/workflow"
+	"github.com/lunfardo314/proxima/global"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/peering"
+	"github.com/lunfardo314/proxima/sequencer"
+	"github.com/lunfardo314/proxima/txstore"
+	"github.com/lunfardo314/proxima/util"
+	"github.com/lunfardo314/proxima/util/clock"
+	"github.com/lunfardo314/proxima/util/testutil/inittest"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/multiformats/go-multiaddr"
+	"go.uber.org/zap/zapcore"
+)
+
+type (
+	Config struct {
+		NumNodes int
+		// GenesisPrivateKey controller key of the ledger. Node #0 runs bootstrap sequencer with it
+		GenesisPrivateKey ed25519.PrivateKey
+		// InitBalance is distributed at genesis to the controller of the sequencer of each node except #0
+		InitBalance uint64
+		// TagAlongFee paid by chain origin transactions to the bootstrap sequencer
+		TagAlongFee uint64
+		// SequencerPace pace of sequencers in ticks
+		SequencerPace int
+		// MaxTagAlongInputs of sequencers
+		MaxTagAlongInputs int
+		LogLevel          zapcore.Level
+		// Clock shared by all nodes. nil means real clock.
+		// Non-nil clock must be set as the source of ledger time with ledger.SetClock by the caller
+		Clock clock.Clock
+	}
+
+	Network struct {
+		cfg   Config
+		Nodes []*Node
+		// network conditions
+		mutex    sync.RWMutex
+		links    map[link]LinkCondition
+		nodeByID map[peer.ID]int
+	}
+
+	// Node is in-process Proxima node. It implements workflow.Environment
+	Node struct {
+		*global.Global
+		Index                int
+		Peers                *peering.Peers
+		Workflow             *workflow.Workflow
+		Sequencer            *sequencer.Sequencer
+		SequencerID          ledger.ChainID
+		ControllerPrivateKey ed25519.PrivateKey
+		stateStore           global.StateStore
+		txBytesStore         global.TxBytesStore
+		hostPort             int
+		hostID               peer.ID
+	}
+
+	// LinkCondition of messages from one node to another
+	LinkCondition struct {
+		Drop  bool
+		Delay time.Duration
+	}
+
+	link struct {
+		from, to int
+	}
+)
+
+const (
+	DefaultInitBalance       = 10_000_000_000
+	DefaultTagAlongFee       = 500
+	DefaultSequencerPace     = 5
+	DefaultMaxTagAlongInputs = 30
+)
+
+// DefaultConfig network of n nodes with default parameters. Global ledger library must be initialized
+// with the ledger identity controlled by the genesisPrivateKey
+func DefaultConfig(n int, genesisPrivateKey ed25519.PrivateKey) Config {
+	return Config{
+		NumNodes:          n,
+		GenesisPrivateKey: genesisPrivateKey,
+		InitBalance:       DefaultInitBalance,
+		TagAlongFee:       DefaultTagAlongFee,
+		SequencerPace:     DefaultSequencerPace,
+		MaxTagAlongInputs: DefaultMaxTagAlongInputs,
+		LogLevel:          zapcore.InfoLevel,
+	}
+}
+
+// New creates nodes of the network with the same genesis ledger state. Nodes are not started
+func New(cfg Config) (*Network, error) {
+	if cfg.NumNodes < 1 {
+		return nil, fmt.Errorf("netsim: at least 1 node expected")
+	}
+	if !ledger.AddressED25519MatchesPrivateKey(ledger.AddressED25519FromPublicKey(ledger.L().ID.GenesisControllerPublicKey), cfg.GenesisPrivateKey) {
+		return nil, fmt.Errorf("netsim: genesis private key does not match ledger identity")
+	}
+	if cfg.Clock != nil && ledger.Clock() != cfg.Clock {
+		return nil, fmt.Errorf("netsim: clock of the network is not the source of ledger time")
+	}
+	ret := &Network{
+		cfg:      cfg,
+		Nodes:    make([]*Node, cfg.NumNodes),
+		links:    make(map[link]LinkCondition),
+		nodeByID: make(map[peer.ID]int),
+	}
+	var distrib []ledger.LockBalance
+	var privKeys []ed25519.PrivateKey
+	if cfg.NumNodes > 1 {
+		balances := make([]uint64, cfg.NumNodes-1)
+		for i := range balances {
+			balances[i] = cfg.InitBalance
+		}
+		distrib, privKeys, _ = inittest.GenesisParamsWithPreDistribution(balances...)
+	}
+
+	var distributionTxID ledger.TransactionID
+	for i := range ret.Nodes {
+		node, txid, err := ret.newNode(i, distrib)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			distributionTxID = txid
+			node.ControllerPrivateKey = cfg.GenesisPrivateKey
+			node.SequencerID = ledger.L().ID.OriginChainID()
+		} else {
+			util.Assertf(txid == distributionTxID, "netsim: distribution transaction must be the same in all nodes")
+			node.ControllerPrivateKey = privKeys[i-1]
+		}
+		ret.Nodes[i] = node
+		ret.nodeByID[node.hostID] = i
+	}
+	// each node knows all others
+	for _, node := range ret.Nodes {
+		var err error
+		cfgPeers := &peering.Config{
+			HostIDPrivateKey: hostPrivateKey(node.Index),
+			HostID:           node.hostID,
+			HostPort:         node.hostPort,
+			KnownPeers:       make(map[string]multiaddr.Multiaddr),
+		}
+		for _, other := range ret.Nodes {
+			if other.Index == node.Index {
+				continue
+			}
+			if cfgPeers.KnownPeers[other.Name()], err = multiaddr.NewMultiaddr(peering.TestMultiAddrString(other.hostID, other.hostPort)); err != nil {
+				return nil, err
+			}
+		}
+		if node.Peers, err = peering.New(node, cfgPeers); err != nil {
+			return nil, err
+		}
+		node.Peers.SetOutMessageFilter(ret.outMessageFilter(node.Index))
+	}
+	return ret, nil
+}
+
+func (nw *Network) newNode(idx int, distrib []ledger.LockBalance) (*Node, ledger.TransactionID, error) {
+	pk, err := crypto.UnmarshalEd25519PrivateKey(hostPrivateKey(idx))
+	if err != nil {
+		return nil, ledger.TransactionID{}, err
+	}
+	hostID, err := peer.IDFromPrivateKey(pk)
+	if err != nil {
+		return nil, ledger.TransactionID{}, err
+	}
+	port, err := freePort()
+	if err != nil {
+		return nil, ledger.TransactionID{}, err
+	}
+	ret := &Node{
+		Global:       global.NewDefaultWithLogLevel(nw.cfg.LogLevel),
+		Index:        idx,
+		stateStore:   common.NewInMemoryKVStore(),
+		txBytesStore: txstore.NewSimpleTxBytesStore(common.NewInMemoryKVStore()),
+		hostPort:     port,
+		hostID:       hostID,
+	}
+	ret.Global.SugaredLogger = ret.Global.SugaredLogger.Named(ret.Name())
+	if nw.cfg.Clock != nil {
+		ret.SetClock(nw.cfg.Clock)
+	}
+
+	multistate.InitStateStore(*ledger.L().ID, ret.stateStore)
+	txBytes, txid, err := txbuilder.DistributeInitialSupplyExt(ret.stateStore, nw.cfg.GenesisPrivateKey, distrib)
+	if err != nil {
+		return nil, ledger.TransactionID{}, err
+	}
+	if _, err = ret.txBytesStore.PersistTxBytesWithMetadata(txBytes, nil); err != nil {
+		return nil, ledger.TransactionID{}, err
+	}
+	return ret, txid, nil
+}
+
+// Start starts peering and workflow of all nodes, bootstrap sequencer on node #0 and,
+// when chain origins of other sequencers are in the ledger state of each node, sequencers of other nodes
+func (nw *Network) Start(timeout time.Duration) error {
+	for _, node := range nw.Nodes {
+		node.Peers.Run()
+		node.Workflow = workflow.New(node, node.Peers, workflow.OptionDoNotStartPruner)
+		node.Workflow.Start()
+		if err := attacher.EnsureLatestBranches(node.Workflow); err != nil {
+			return err
+		}
+	}
+	if err := nw.Nodes[0].startSequencer(nw.cfg, "boot"); err != nil {
+		return err
+	}
+	if len(nw.Nodes) == 1 {
+		return nil
+	}
+	txids, err := nw.makeChainOrigins()
+	if err != nil {
+		return err
+	}
+	for _, node := range nw.Nodes[1:] {
+		for _, txid := range txids {
+			if _, err = node.Workflow.WaitUntilTransactionInHeaviestState(txid, timeout); err != nil {
+				return fmt.Errorf("netsim: chain origin %s not in %s: %w", txid.StringShort(), node.Name(), err)
+			}
+		}
+		if err = node.startSequencer(nw.cfg, fmt.Sprintf("seq%d", node.Index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeChainOrigins creates chain origin for sequencer of each node except #0 from genesis distribution
+// and submits them to node #0. Chain origin transactions tag along the bootstrap sequencer
+func (nw *Network) makeChainOrigins() ([]ledger.TransactionID, error) {
+	node0 := nw.Nodes[0]
+	rdr := node0.Workflow.HeaviestStateForLatestTimeSlot()
+	ret := make([]ledger.TransactionID, 0, len(nw.Nodes)-1)
+	for _, node := range nw.Nodes[1:] {
+		addr := ledger.AddressED25519FromPrivateKey(node.ControllerPrivateKey)
+		oDatas, err := rdr.GetUTXOsLockedInAccount(addr.AccountID())
+		if err != nil {
+			return nil, err
+		}
+		if len(oDatas) != 1 {
+			return nil, fmt.Errorf("netsim: one output expected in %s", addr.String())
+		}
+		out, err := oDatas[0].Parse()
+		if err != nil {
+			return nil, err
+		}
+		txb := txbuilder.NewTransactionBuilder()
+		if _, err = txb.ConsumeOutputWithID(out); err != nil {
+			return nil, err
+		}
+		txb.PutSignatureUnlock(0)
+
+		chainOrigin := ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(out.Output.Amount() - nw.cfg.TagAlongFee).WithLock(addr)
+			_, _ = o.PushConstraint(ledger.NewChainOrigin().Bytes())
+		})
+		if _, err = txb.ProduceOutput(chainOrigin); err != nil {
+			return nil, err
+		}
+		tagAlong := ledger.NewOutput(func(o *ledger.Output) {
+			o.WithAmount(nw.cfg.TagAlongFee).WithLock(ledger.ChainLockFromChainID(node0.SequencerID))
+		})
+		if _, err = txb.ProduceOutput(tagAlong); err != nil {
+			return nil, err
+		}
+		txb.TransactionData.Timestamp = ledger.MaxTime(out.Timestamp().AddTicks(ledger.TransactionPace()), ledger.TimeNow())
+		txb.TransactionData.InputCommitment = txb.InputCommitment()
+		txb.SignED25519(node.ControllerPrivateKey)
+
+		tx, err := transaction.FromBytes(txb.TransactionData.Bytes(), transaction.MainTxValidationOptions...)
+		if err != nil {
+			return nil, err
+		}
+		node.SequencerID = ledger.MakeOriginChainID(util.Ref(tx.MustProducedOutputWithIDAt(0).ID))
+		if _, err = node0.Workflow.TxBytesIn(tx.Bytes()); err != nil {
+			return nil, err
+		}
+		ret = append(ret, *tx.ID())
+	}
+	return ret, nil
+}
+
+// Stop stops all nodes and waits until all work processes stop
+func (nw *Network) Stop(timeout ...time.Duration) {
+	for _, node := range nw.Nodes {
+		if node.Sequencer != nil {
+			node.Sequencer.Stop()
+		}
+		node.Global.Stop()
+	}
+	for _, node := range nw.Nodes {
+		node.MustWaitAllWorkProcessesStop(timeout...)
+	}
+}
+
+func (n *Node) startSequencer(cfg Config, name string) error {
+	var err error
+	n.Sequencer, err = sequencer.New(n.Workflow, n.SequencerID, n.ControllerPrivateKey,
+		sequencer.WithName(name),
+		sequencer.WithPace(cfg.SequencerPace),
+		sequencer.WithMaxTagAlongInputs(cfg.MaxTagAlongInputs),
+	)
+	if err != nil {
+		return fmt.Errorf("netsim: can't start sequencer on %s: %w", n.Name(), err)
+	}
+	n.Sequencer.Start()
+	return nil
+}
+
+// WrapTxBytesStore replaces transaction store of the node with the wrapper, for example to simulate byzantine behavior.
+// Must be called before the network is started
+func (n *Node) WrapTxBytesStore(wrap func(store global.TxBytesStore) global.TxBytesStore) {
+	util.Assertf(n.Workflow == nil, "WrapTxBytesStore: node %s is already started", n.Name())
+	n.txBytesStore = wrap(n.txBytesStore)
+}
+
+func (n *Node) Name() string {
+	return fmt.Sprintf("node%d", n.Index)
+}
+
+func (n *Node) StateStore() global.StateStore {
+	return n.stateStore
+}
+
+func (n *Node) TxBytesStore() global.TxBytesStore {
+	return n.txBytesStore
+}
+
+// hostPrivateKey deterministic libp2p host key of the node
+func hostPrivateKey(idx int) ed25519.PrivateKey {
+	var seed [ed25519.SeedSize]byte
+	copy(seed[:], fmt.Sprintf("netsim host %d", idx))
+	return ed25519.NewKeyFromSeed(seed[:])
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,93 @@
+//go:build netsim
+
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/tests/netsim"
+	"github.com/lunfardo314/proxima/util/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetSim(t *testing.T) {
+	t.Run("converge", func(t *testing.T) {
+		const numNodes = 3
+		nw, err := netsim.New(netsim.DefaultConfig(numNodes, genesisPrivateKey))
+		require.NoError(t, err)
+		defer nw.Stop(5 * time.Second)
+
+		require.NoError(t, nw.Start(10*time.Second))
+		from := nw.Nodes[0].LatestSlot() + 3
+		txid, err := nw.WaitConvergence(from, 20*time.Second)
+		require.NoError(t, err)
+		t.Logf("converged on branch %s", txid.StringShort())
+	})
+	t.Run("partition and heal", func(t *testing.T) {
+		const numNodes = 4
+		nw, err := netsim.New(netsim.DefaultConfig(numNodes, genesisPrivateKey))
+		require.NoError(t, err)
+		defer nw.Stop(5 * time.Second)
+
+		require.NoError(t, nw.Start(10*time.Second))
+		_, err = nw.WaitConvergence(nw.Nodes[0].LatestSlot()+1, 20*time.Second)
+		require.NoError(t, err)
+
+		nw.Partition([]int{0, 1}, []int{2, 3})
+		time.Sleep(5 * ledger.SlotDuration())
+		t.Logf("latest slots during partition: %d, %d, %d, %d",
+			nw.Nodes[0].LatestSlot(), nw.Nodes[1].LatestSlot(), nw.Nodes[2].LatestSlot(), nw.Nodes[3].LatestSlot())
+
+		nw.Heal()
+		healSlot := ledger.TimeNow().Slot()
+		txid, err := nw.WaitConvergence(healSlot+2, 30*time.Second)
+		require.NoError(t, err)
+		t.Logf("converged after heal on branch %s", txid.StringShort())
+	})
+	t.Run("delay and drop", func(t *testing.T) {
+		const numNodes = 3
+		nw, err := netsim.New(netsim.DefaultConfig(numNodes, genesisPrivateKey))
+		require.NoError(t, err)
+		defer nw.Stop(5 * time.Second)
+
+		require.NoError(t, nw.Start(10*time.Second))
+		nw.SetDelay(0, 1, 3*ledger.TickDuration())
+		nw.SetDelay(1, 0, 3*ledger.TickDuration())
+		nw.DropMessages(2, 1)
+
+		from := ledger.TimeNow().Slot() + 3
+		txid, err := nw.WaitConvergence(from, 20*time.Second)
+		require.NoError(t, err)
+		t.Logf("converged on branch %s", txid.StringShort())
+	})
+	t.Run("virtual clock", func(t *testing.T) {
+		const (
+			numNodes = 3
+			numSlots = 100
+			// virtual clock runs 5 times faster than the real one
+			realTickDuration = 2 * time.Millisecond
+		)
+		clk := clock.NewVirtual(time.Now())
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		clk.AdvanceEvery(ctx, ledger.TickDuration(), realTickDuration)
+		ledger.SetClock(clk)
+		defer ledger.SetClock(clock.Real())
+
+		cfg := netsim.DefaultConfig(numNodes, genesisPrivateKey)
+		cfg.Clock = clk
+		nw, err := netsim.New(cfg)
+		require.NoError(t, err)
+		defer nw.Stop(5 * time.Second)
+
+		realStart := time.Now()
+		require.NoError(t, nw.Start(10*time.Second))
+		from := ledger.TimeNow().Slot() + numSlots
+		txid, err := nw.WaitConvergence(from, time.Minute)
+		require.NoError(t, err)
+		t.Logf("converged on branch %s after %d virtual slots in %v", txid.StringShort(), numSlots, time.Since(realStart))
+	})
+}
@@ -0,0 +1,159 @@
+// Package clock abstracts the source of time. Real clock follows the wall clock.
+// Virtual clock stays still until it is advanced explicitly, so time-dependent code can be tested
+// deterministically and much faster than in real time
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	Clock interface {
+		Now() time.Time
+		After(d time.Duration) <-chan time.Time
+		Sleep(d time.Duration)
+	}
+
+	realClock struct{}
+
+	Virtual struct {
+		mutex   sync.Mutex
+		now     time.Time
+		waiters []waiter
+	}
+
+	waiter struct {
+		deadline time.Time
+		ch       chan time.Time
+	}
+)
+
+var _real = realClock{}
+
+// Real returns clock which follows wall clock
+func Real() Clock {
+	return _real
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// NewVirtual creates virtual clock which starts at the given time
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+func (c *Virtual) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+// After returns channel which receives current virtual time when clock is advanced by d or more
+func (c *Virtual) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, waiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Sleep blocks until clock is advanced by d or more
+func (c *Virtual) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves clock forward by d and releases all waiters with deadlines up to the new time
+func (c *Virtual) Advance(d time.Duration) {
+	c.AdvanceTo(c.Now().Add(d))
+}
+
+// AdvanceTo moves clock forward to t. Waiters are released in the order of their deadlines.
+// Moving clock backwards is ignored
+func (c *Virtual) AdvanceTo(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !t.After(c.now) {
+		return
+	}
+	c.now = t
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+	})
+	i := 0
+	for ; i < len(c.waiters) && !c.waiters[i].deadline.After(t); i++ {
+		c.waiters[i].ch <- t
+	}
+	c.waiters = c.waiters[i:]
+}
+
+// AdvanceToNextDeadline moves clock to the earliest deadline of waiters. Returns false if there are no waiters
+func (c *Virtual) AdvanceToNextDeadline() bool {
+	deadline, ok := c.NextDeadline()
+	if ok {
+		c.AdvanceTo(deadline)
+	}
+	return ok
+}
+
+// NextDeadline returns the earliest deadline of waiters, if any
+func (c *Virtual) NextDeadline() (ret time.Time, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, w := range c.waiters {
+		if !ok || w.deadline.Before(ret) {
+			ret, ok = w.deadline, true
+		}
+	}
+	return
+}
+
+// NumWaiters number of goroutines (timers) waiting for the clock to advance
+func (c *Virtual) NumWaiters() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.waiters)
+}
+
+// AdvanceEvery runs background goroutine which advances clock by step every period of the real time
+// until the context is done. It makes the virtual clock run step/period times faster than the real one.
+// Missed ticks of the real clock are compensated by advancing clock by several steps at once
+func (c *Virtual) AdvanceEvery(ctx context.Context, step, period time.Duration) {
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		realStart := time.Now()
+		virtualStart := c.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				steps := time.Since(realStart) / period
+				c.AdvanceTo(virtualStart.Add(steps * step))
+			}
+		}
+	}()
+}
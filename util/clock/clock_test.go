@@ -0,0 +1,71 @@
+package clock
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtual(t *testing.T) {
+	start := time.Unix(1_000_000, 0)
+	t.Run("advance", func(t *testing.T) {
+		c := NewVirtual(start)
+		require.EqualValues(t, start, c.Now())
+		c.Advance(time.Second)
+		require.EqualValues(t, start.Add(time.Second), c.Now())
+		c.AdvanceTo(start)
+		require.EqualValues(t, start.Add(time.Second), c.Now())
+	})
+	t.Run("after", func(t *testing.T) {
+		c := NewVirtual(start)
+		ch := c.After(time.Second)
+		require.EqualValues(t, 1, c.NumWaiters())
+		c.Advance(999 * time.Millisecond)
+		select {
+		case <-ch:
+			t.Fatalf("must not be released")
+		default:
+		}
+		c.Advance(time.Millisecond)
+		require.EqualValues(t, start.Add(time.Second), <-ch)
+		require.EqualValues(t, 0, c.NumWaiters())
+
+		require.EqualValues(t, c.Now(), <-c.After(0))
+	})
+	t.Run("order", func(t *testing.T) {
+		c := NewVirtual(start)
+		var mutex sync.Mutex
+		var order []int
+		var wg sync.WaitGroup
+		for _, i := range []int{3, 1, 2} {
+			wg.Add(1)
+			ch := c.After(time.Duration(i) * time.Second)
+			go func(i int) {
+				<-ch
+				mutex.Lock()
+				order = append(order, i)
+				mutex.Unlock()
+				wg.Done()
+			}(i)
+		}
+		for c.AdvanceToNextDeadline() {
+			time.Sleep(10 * time.Millisecond)
+		}
+		wg.Wait()
+		require.EqualValues(t, []int{1, 2, 3}, order)
+		require.EqualValues(t, start.Add(3*time.Second), c.Now())
+	})
+	t.Run("sleep", func(t *testing.T) {
+		c := NewVirtual(start)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c.AdvanceEvery(ctx, time.Hour, time.Millisecond)
+		realStart := time.Now()
+		c.Sleep(100 * time.Hour)
+		require.True(t, time.Since(realStart) < 10*time.Second)
+		require.False(t, c.Now().Before(start.Add(100*time.Hour)))
+	})
+}
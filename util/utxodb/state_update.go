@@ -27,7 +27,11 @@ func updateValidateOptions(u *multistate.Updatable, txBytes []byte, traceOption
 	if err != nil {
 		return nil, err
 	}
-	ctx, err := transaction2.TxContextFromTransaction(tx, tx.InputLoaderByIndex(u.Readable().GetUTXO), traceOption)
+	lib, err := multistate.LibraryOfState(u.Readable())
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := transaction2.TxContextFromTransactionWithLibrary(tx, lib, tx.InputLoaderByIndex(u.Readable().GetUTXO), traceOption)
 	if err != nil {
 		return nil, err
 	}
@@ -48,6 +52,12 @@ func updateValidateOptions(u *multistate.Updatable, txBytes []byte, traceOption
 	if err != nil {
 		return nil, err
 	}
+	if len(muts.LibraryUpgrades()) > 0 {
+		// UTXODB is a single chain of states, so each committed state is final
+		if err = multistate.ApplyLibraryUpgradesFromState(u.Readable()); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := ConsistencyCheckAfterAddTransaction(tx, u.Readable()); err != nil {
 		return nil, err
@@ -61,7 +61,7 @@ func NewUTXODB(genesisPrivateKey ed25519.PrivateKey, trace ...bool) *UTXODB {
 	genesisStemOut := rdr.GetStemOutput()
 
 	distributionTxBytes := txbuilder.MustDistributeInitialSupply(stateStore, genesisPrivateKey, []ledger.LockBalance{
-		{faucetAddress, ledger.L().ID.InitialSupply / 2},
+		{Lock: faucetAddress, Balance: ledger.L().ID.InitialSupply / 2},
 	})
 
 	updatable := multistate.MustNewUpdatable(stateStore, genesisRoot)
@@ -442,7 +442,18 @@ func (u *UTXODB) DoTransfer(par *txbuilder.TransferData) error {
 }
 
 func (u *UTXODB) ValidationContextFromTransaction(txBytes []byte) (*transaction.TxContext, error) {
-	return transaction.TxContextFromTransferableBytes(txBytes, u.state.Readable().GetUTXO)
+	tx, err := transaction.FromBytes(txBytes, transaction.ScanSequencerData())
+	if err != nil {
+		return nil, err
+	}
+	return transaction.TxContextFromTransactionWithLibrary(tx, u.Library(), tx.InputLoaderByIndex(u.state.Readable().GetUTXO))
+}
+
+// Library returns the ledger library with upgrades committed into the current state
+func (u *UTXODB) Library() *ledger.Library {
+	lib, err := multistate.LibraryOfState(u.state.Readable())
+	util.AssertNoError(err)
+	return lib
 }
 
 func (u *UTXODB) TxToString(txbytes []byte) string {
@@ -0,0 +1,222 @@
+package visualizer
+
+// page renders the MemDAG received from the '/events' stream. Node attributes follow memdag/graph.go:
+// sequencer milestones are colored by sequencer ID, simple transactions are blue, branches are boxes,
+// undefined transactions are diamonds, bad ones are inverted triangles, virtual transactions are green.
+// Chain and stem edges are highlighted, endorsements are red. Deleted (pruned) vertices fade out
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Proxima UTXO tangle</title>
+<style>
+body { font-family: sans-serif; margin: 10px; }
+#controls { margin-bottom: 6px; }
+#canvas { border: 1px solid #ccc; overflow: auto; width: 100%; height: 85vh; }
+text { font-size: 9px; font-family: monospace; }
+.lane { font-size: 10px; fill: #666; }
+</style>
+</head>
+<body>
+<div id="controls">
+Proxima UTXO tangle.
+Slots: <input id="slots" type="number" value="3" min="1" max="50" style="width:4em">
+<label><input id="pause" type="checkbox"> pause</label>
+<span id="stats"></span>
+</div>
+<div id="canvas"><svg id="svg" xmlns="http://www.w3.org/2000/svg"></svg></div>
+<script>
+"use strict";
+const SVGNS = "http://www.w3.org/2000/svg";
+const TICK_WIDTH = 8, LANE_HEIGHT = 60, NODE_SIZE = 14, DELETED_TTL = 10000;
+// same palettes as Graphviz 'paired9' and 'blues3' in memdag/graph.go
+const PAIRED = ["#a6cee3","#1f78b4","#b2df8a","#33a02c","#fb9a99","#e31a1c","#fdbf6f","#ff7f00","#cab2d6"];
+const BLUES = ["#deebf7","#9ecae1","#3182bd"];
+const VIRTUAL = "#99d8c9", DELETED = "#dddddd";
+const TICKS_PER_SLOT = {{ticksPerSlot}};
+
+const vertices = new Map();
+const booked = new Set();
+const seqColors = new Map();
+let dirty = false;
+
+function seqColor(seqID) {
+  if (!seqColors.has(seqID)) {
+    seqColors.set(seqID, PAIRED[seqColors.size % PAIRED.length]);
+  }
+  return seqColors.get(seqID);
+}
+
+function onMessage(msg) {
+  switch (msg.type) {
+  case "vertex": {
+    const v = msg.vertex, prev = vertices.get(v.id);
+    // virtual vertex does not know its inputs: keep what is already known
+    if (prev && !v.inputs) { v.inputs = prev.inputs; }
+    if (prev && !v.endorsements) { v.endorsements = prev.endorsements; }
+    vertices.set(v.id, v);
+    break;
+  }
+  case "booked":
+    booked.add(msg.id);
+    break;
+  case "deleted": {
+    const v = vertices.get(msg.id);
+    if (v) { v.kind = "deleted"; v.deletedAt = Date.now(); }
+    booked.delete(msg.id);
+    break;
+  }
+  }
+  dirty = true;
+}
+
+function connect() {
+  const es = new EventSource("/events");
+  es.onmessage = (e) => onMessage(JSON.parse(e.data));
+  es.onerror = () => { es.close(); setTimeout(connect, 2000); };
+}
+
+function shape(v, x, y, fill) {
+  const h = NODE_SIZE / 2;
+  let el;
+  if (v.status === "BAD") {
+    el = document.createElementNS(SVGNS, "polygon");
+    el.setAttribute("points", (x-h)+","+(y-h)+" "+(x+h)+","+(y-h)+" "+x+","+(y+h));
+  } else if (v.status === "UNDEF" && v.kind === "vertex") {
+    el = document.createElementNS(SVGNS, "polygon");
+    el.setAttribute("points", x+","+(y-h)+" "+(x+h)+","+y+" "+x+","+(y+h)+" "+(x-h)+","+y);
+  } else if (v.branch) {
+    el = document.createElementNS(SVGNS, "rect");
+    el.setAttribute("x", x-h); el.setAttribute("y", y-h);
+    el.setAttribute("width", NODE_SIZE); el.setAttribute("height", NODE_SIZE);
+  } else {
+    el = document.createElementNS(SVGNS, "ellipse");
+    el.setAttribute("cx", x); el.setAttribute("cy", y);
+    el.setAttribute("rx", h); el.setAttribute("ry", h * 0.7);
+  }
+  el.setAttribute("fill", fill);
+  el.setAttribute("stroke", v.kind === "deleted" ? "#bbbbbb" : "#000000");
+  el.setAttribute("stroke-width", booked.has(v.id) ? 3 : 1);
+  return el;
+}
+
+function fillColor(v) {
+  switch (v.kind) {
+  case "deleted": return DELETED;
+  case "virtual": return VIRTUAL;
+  }
+  if (v.seq) { return seqColor(v.seq_id); }
+  return v.status === "GOOD" ? BLUES[2] : BLUES[1];
+}
+
+function render() {
+  const svg = document.getElementById("svg");
+  const now = Date.now();
+  for (const [id, v] of vertices) {
+    if (v.deletedAt && now - v.deletedAt > DELETED_TTL) { vertices.delete(id); }
+  }
+  let maxTime = 0;
+  for (const v of vertices.values()) { maxTime = Math.max(maxTime, v.time); }
+  const span = Number(document.getElementById("slots").value) * TICKS_PER_SLOT;
+  const minTime = Math.max(0, maxTime - span);
+
+  // one lane for each sequencer, simple transactions are packed below
+  const lanes = [...seqColors.keys()];
+  const visible = [...vertices.values()].filter(v => v.time >= minTime);
+  visible.forEach(v => { if (v.seq && !lanes.includes(v.seq_id)) { seqColor(v.seq_id); lanes.push(v.seq_id); } });
+  const pos = new Map();
+  const simpleRows = new Map();
+  for (const v of visible) {
+    const x = 80 + (v.time - minTime) * TICK_WIDTH;
+    let y;
+    if (v.seq) {
+      y = 30 + lanes.indexOf(v.seq_id) * LANE_HEIGHT;
+    } else {
+      const row = simpleRows.get(v.time) || 0;
+      simpleRows.set(v.time, row + 1);
+      y = 30 + lanes.length * LANE_HEIGHT + row * (NODE_SIZE + 4);
+    }
+    pos.set(v.id, [x, y]);
+  }
+  let maxRows = 0;
+  for (const r of simpleRows.values()) { maxRows = Math.max(maxRows, r); }
+
+  while (svg.firstChild) { svg.removeChild(svg.firstChild); }
+  svg.setAttribute("width", 160 + span * TICK_WIDTH);
+  svg.setAttribute("height", 60 + lanes.length * LANE_HEIGHT + maxRows * (NODE_SIZE + 4));
+
+  for (let slotStart = Math.ceil(minTime / TICKS_PER_SLOT) * TICKS_PER_SLOT; slotStart <= maxTime; slotStart += TICKS_PER_SLOT) {
+    const line = document.createElementNS(SVGNS, "line");
+    const x = 80 + (slotStart - minTime) * TICK_WIDTH;
+    line.setAttribute("x1", x); line.setAttribute("x2", x);
+    line.setAttribute("y1", 0); line.setAttribute("y2", "100%");
+    line.setAttribute("stroke", "#eeeeee");
+    svg.appendChild(line);
+    const t = document.createElementNS(SVGNS, "text");
+    t.setAttribute("x", x + 2); t.setAttribute("y", 10); t.setAttribute("class", "lane");
+    t.textContent = "slot " + (slotStart / TICKS_PER_SLOT);
+    svg.appendChild(t);
+  }
+  lanes.forEach((seqID, i) => {
+    const t = document.createElementNS(SVGNS, "text");
+    t.setAttribute("x", 2); t.setAttribute("y", 34 + i * LANE_HEIGHT); t.setAttribute("class", "lane");
+    t.textContent = seqID.substring(0, 8);
+    svg.appendChild(t);
+  });
+
+  const edge = (from, to, color, width, dashed, label) => {
+    const [x1, y1] = pos.get(from), [x2, y2] = pos.get(to);
+    const line = document.createElementNS(SVGNS, "line");
+    line.setAttribute("x1", x1); line.setAttribute("y1", y1);
+    line.setAttribute("x2", x2); line.setAttribute("y2", y2);
+    line.setAttribute("stroke", color); line.setAttribute("stroke-width", width);
+    if (dashed) { line.setAttribute("stroke-dasharray", "4,3"); }
+    if (label) {
+      const title = document.createElementNS(SVGNS, "title");
+      title.textContent = label;
+      line.appendChild(title);
+    }
+    svg.appendChild(line);
+  };
+  for (const v of visible) {
+    for (const inp of (v.inputs || [])) {
+      if (!pos.has(inp.to)) { continue; }
+      switch (inp.kind) {
+      case "chain": edge(v.id, inp.to, v.seq ? seqColor(v.seq_id) : "#000000", 3, false, inp.label); break;
+      case "stem": edge(v.id, inp.to, "#555555", 2, true, inp.label); break;
+      default: edge(v.id, inp.to, "#999999", 1, false, inp.label);
+      }
+    }
+    for (const e of (v.endorsements || [])) {
+      if (pos.has(e)) { edge(v.id, e, "#ff0000", 1, false, "endorsement"); }
+    }
+  }
+  for (const v of visible) {
+    const [x, y] = pos.get(v.id);
+    const el = shape(v, x, y, fillColor(v));
+    const title = document.createElementNS(SVGNS, "title");
+    title.textContent = v.label + " " + v.status + " " + v.kind + (v.seq ? " coverage: " + v.coverage : "");
+    el.appendChild(title);
+    svg.appendChild(el);
+    if (v.seq) {
+      const t = document.createElementNS(SVGNS, "text");
+      t.setAttribute("x", x - NODE_SIZE); t.setAttribute("y", y - NODE_SIZE / 2 - 2);
+      t.textContent = v.label;
+      svg.appendChild(t);
+    }
+  }
+  document.getElementById("stats").textContent =
+    " vertices: " + vertices.size + ", visible: " + visible.length + ", sequencers: " + lanes.length;
+}
+
+setInterval(() => {
+  if (!document.getElementById("pause").checked && (dirty || vertices.size > 0)) {
+    dirty = false;
+    render();
+  }
+}, 500);
+connect();
+</script>
+</body>
+</html>
+`
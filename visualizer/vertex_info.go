@@ -0,0 +1,124 @@
+package visualizer
+
+import (
+	"fmt"
+
+	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/util"
+)
+
+type (
+	// message is streamed to the web page as JSON
+	message struct {
+		// one of msgType constants
+		Type   string      `json:"type"`
+		Vertex *vertexInfo `json:"vertex,omitempty"`
+		// ID of the booked branch or deleted vertex
+		ID string `json:"id,omitempty"`
+	}
+
+	// vertexInfo is the vertex as it is rendered by the web page. It contains the same information
+	// as the Graphviz node attributes of the MemDAG in memdag/graph.go
+	vertexInfo struct {
+		ID    string `json:"id"`
+		Label string `json:"label"`
+		// Time is the ledger time in ticks since genesis
+		Time     uint64 `json:"time"`
+		Seq      bool   `json:"seq"`
+		Branch   bool   `json:"branch"`
+		SeqID    string `json:"seq_id,omitempty"`
+		Status   string `json:"status"`
+		Kind     string `json:"kind"`
+		Coverage uint64 `json:"coverage,omitempty"`
+		// Inputs and Endorsements are only known while the vertex is not virtual
+		Inputs       []edgeInfo `json:"inputs,omitempty"`
+		Endorsements []string   `json:"endorsements,omitempty"`
+	}
+
+	edgeInfo struct {
+		To    string `json:"to"`
+		Label string `json:"label"`
+		Kind  string `json:"kind"`
+	}
+)
+
+const (
+	msgTypeVertex  = "vertex"
+	msgTypeBooked  = "booked"
+	msgTypeDeleted = "deleted"
+
+	kindVertex  = "vertex"
+	kindVirtual = "virtual"
+	kindDeleted = "deleted"
+
+	edgeKindInput = "input"
+	edgeKindChain = "chain"
+	edgeKindStem  = "stem"
+)
+
+func makeVertexInfo(vid *vertex.WrappedTx) *vertexInfo {
+	ts := vid.Timestamp()
+	ret := &vertexInfo{
+		ID:       vid.ID.StringHex(),
+		Label:    vid.IDVeryShort(),
+		Time:     uint64(ts.Slot())*uint64(ledger.TicksPerSlot()) + uint64(ts.Tick()),
+		Seq:      vid.IsSequencerMilestone(),
+		Branch:   vid.IsBranchTransaction(),
+		Status:   vid.GetTxStatus().String(),
+		Coverage: vid.GetLedgerCoverage(),
+	}
+	if seqID, ok := vid.SequencerIDIfAvailable(); ok {
+		ret.SeqID = seqID.StringHex()
+	}
+	vid.RUnwrap(vertex.UnwrapOptions{
+		Vertex: func(v *vertex.Vertex) {
+			ret.Kind = kindVertex
+			ret.Inputs = inputEdges(v)
+			v.Tx.ForEachEndorsement(func(_ byte, txid *ledger.TransactionID) bool {
+				ret.Endorsements = append(ret.Endorsements, txid.StringHex())
+				return true
+			})
+		},
+		VirtualTx: func(_ *vertex.VirtualTransaction) {
+			ret.Kind = kindVirtual
+		},
+		Deleted: func() {
+			ret.Kind = kindDeleted
+		},
+	})
+	return ret
+}
+
+// inputEdges makes edge for each input. Edge to the chain predecessor and to the predecessor stem are marked
+func inputEdges(v *vertex.Vertex) []edgeInfo {
+	chainPredIdx := byte(0xff)
+	if v.Tx.IsSequencerMilestone() {
+		_, chainPredIdx = v.Tx.SequencerChainPredecessor()
+	}
+	var stemPred *ledger.OutputID
+	if v.Tx.IsBranchTransaction() {
+		stemPred = &v.Tx.StemOutputData().PredecessorOutputID
+	}
+	ret := make([]edgeInfo, 0, v.Tx.NumInputs())
+	v.Tx.ForEachInput(func(i byte, oid *ledger.OutputID) bool {
+		amountStr := "???"
+		if o := v.GetConsumedOutput(i); o != nil {
+			amountStr = util.GoTh(o.Amount())
+		}
+		edge := edgeInfo{
+			To:    util.Ref(oid.TransactionID()).StringHex(),
+			Label: fmt.Sprintf("%s(#%d)", amountStr, oid.Index()),
+			Kind:  edgeKindInput,
+		}
+		switch {
+		case i == chainPredIdx:
+			edge.Kind = edgeKindChain
+		case stemPred != nil && *oid == *stemPred:
+			edge.Kind = edgeKindStem
+		}
+		ret = append(ret, edge)
+		return true
+	})
+	return ret
+}
@@ -0,0 +1,162 @@
+// Package visualizer streams changes of the MemDAG to the web page, which renders the UTXO tangle dynamically.
+// It is a live counterpart of the static Graphviz rendering in memdag/graph.go
+package visualizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/ledger"
+)
+
+type (
+	// Environment is the source of MemDAG changes. Implemented by the workflow.Workflow
+	Environment interface {
+		Vertices(filterByID ...func(txid *ledger.TransactionID) bool) []*vertex.WrappedTx
+		ListenToNewVertices(fun func(vid *vertex.WrappedTx))
+		ListenToSequencers(fun func(vid *vertex.WrappedTx))
+		ListenToBadTransactions(fun func(vid *vertex.WrappedTx))
+		ListenToBookedBranches(fun func(txid ledger.TransactionID))
+		ListenToDeletedVertices(fun func(txid ledger.TransactionID))
+	}
+
+	// Visualizer serves the web page and the stream of server-sent events with MemDAG changes
+	Visualizer struct {
+		env         Environment
+		mutex       sync.Mutex
+		subscribers map[chan []byte]struct{}
+	}
+)
+
+// subscriberBufferSize is number of messages buffered for each web page. Subscriber which does not keep up
+// is dropped and the web page reconnects with the fresh snapshot
+const subscriberBufferSize = 1024
+
+func New(env Environment) *Visualizer {
+	ret := &Visualizer{
+		env:         env,
+		subscribers: make(map[chan []byte]struct{}),
+	}
+	env.ListenToNewVertices(ret.vertexChanged)
+	env.ListenToSequencers(ret.vertexChanged)
+	env.ListenToBadTransactions(ret.vertexChanged)
+	env.ListenToBookedBranches(func(txid ledger.TransactionID) {
+		ret.broadcast(&message{Type: msgTypeBooked, ID: txid.StringHex()})
+	})
+	env.ListenToDeletedVertices(func(txid ledger.TransactionID) {
+		ret.broadcast(&message{Type: msgTypeDeleted, ID: txid.StringHex()})
+	})
+	return ret
+}
+
+func (v *Visualizer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(strings.Replace(page, "{{ticksPerSlot}}", strconv.Itoa(int(ledger.TicksPerSlot())), 1)))
+	case "/events":
+		v.serveEvents(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (v *Visualizer) vertexChanged(vid *vertex.WrappedTx) {
+	if !v.hasSubscribers() {
+		return
+	}
+	v.broadcast(&message{Type: msgTypeVertex, Vertex: makeVertexInfo(vid)})
+}
+
+func (v *Visualizer) hasSubscribers() bool {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return len(v.subscribers) > 0
+}
+
+func (v *Visualizer) subscribe() chan []byte {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	ch := make(chan []byte, subscriberBufferSize)
+	v.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (v *Visualizer) unsubscribe(ch chan []byte) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if _, ok := v.subscribers[ch]; ok {
+		delete(v.subscribers, ch)
+		close(ch)
+	}
+}
+
+// broadcast never blocks the event queue
+func (v *Visualizer) broadcast(msg *message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for ch := range v.subscribers {
+		select {
+		case ch <- data:
+		default:
+			delete(v.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// serveEvents sends snapshot of the MemDAG and then streams changes until the client disconnects
+func (v *Visualizer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	// subscribe before snapshot, so that no changes are lost. Duplicates are merged by the web page
+	ch := v.subscribe()
+	defer v.unsubscribe(ch)
+
+	for _, vid := range v.env.Vertices() {
+		data, err := json.Marshal(&message{Type: msgTypeVertex, Vertex: makeVertexInfo(vid)})
+		if err != nil {
+			continue
+		}
+		if _, err = fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case data, ok := <-ch:
+			if !ok {
+				// subscriber was dropped
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package visualizer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lunfardo314/proxima/core/vertex"
+	"github.com/lunfardo314/proxima/ledger"
+	"github.com/lunfardo314/proxima/ledger/transaction"
+	"github.com/lunfardo314/proxima/ledger/txbuilder"
+	"github.com/lunfardo314/proxima/multistate"
+	"github.com/lunfardo314/proxima/util/testutil"
+	"github.com/lunfardo314/unitrie/common"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnvironment struct {
+	vertices  []*vertex.WrappedTx
+	onVertex  []func(vid *vertex.WrappedTx)
+	onBooked  func(txid ledger.TransactionID)
+	onDeleted func(txid ledger.TransactionID)
+}
+
+func (e *testEnvironment) Vertices(_ ...func(txid *ledger.TransactionID) bool) []*vertex.WrappedTx {
+	return e.vertices
+}
+
+func (e *testEnvironment) ListenToNewVertices(fun func(vid *vertex.WrappedTx)) {
+	e.onVertex = append(e.onVertex, fun)
+}
+
+func (e *testEnvironment) ListenToSequencers(fun func(vid *vertex.WrappedTx)) {
+	e.onVertex = append(e.onVertex, fun)
+}
+
+func (e *testEnvironment) ListenToBadTransactions(fun func(vid *vertex.WrappedTx)) {
+	e.onVertex = append(e.onVertex, fun)
+}
+
+func (e *testEnvironment) ListenToBookedBranches(fun func(txid ledger.TransactionID)) {
+	e.onBooked = fun
+}
+
+func (e *testEnvironment) ListenToDeletedVertices(fun func(txid ledger.TransactionID)) {
+	e.onDeleted = fun
+}
+
+func readMessage(t *testing.T, rdr *bufio.Reader) *message {
+	for {
+		line, err := rdr.ReadString('\n')
+		require.NoError(t, err)
+		if data, found := strings.CutPrefix(line, "data: "); found {
+			var ret message
+			require.NoError(t, json.Unmarshal([]byte(data), &ret))
+			return &ret
+		}
+	}
+}
+
+func TestVisualizer(t *testing.T) {
+	genesisPrivateKey := ledger.InitWithTestingLedgerIDData()
+	addr := ledger.AddressED25519FromPrivateKey(testutil.GetTestingPrivateKey(100))
+	stateStore := common.NewInMemoryKVStore()
+	multistate.InitStateStore(*ledger.L().ID, stateStore)
+	txBytes, distribTxID, err := txbuilder.DistributeInitialSupplyExt(stateStore, genesisPrivateKey, []ledger.LockBalance{
+		{Lock: addr, Balance: 1_000_000},
+	})
+	require.NoError(t, err)
+	tx, err := transaction.FromBytes(txBytes, transaction.MainTxValidationOptions...)
+	require.NoError(t, err)
+
+	genesisVID := vertex.WrapTxID(*ledger.GenesisTransactionID())
+	distribVID := vertex.New(tx).Wrap()
+	env := &testEnvironment{vertices: []*vertex.WrappedTx{genesisVID}}
+	srv := httptest.NewServer(New(env))
+	defer srv.Close()
+
+	t.Run("page", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.EqualValues(t, http.StatusOK, resp.StatusCode)
+		require.NotContains(t, string(body), "{{ticksPerSlot}}")
+	})
+	t.Run("events", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/events")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		rdr := bufio.NewReader(resp.Body)
+
+		// snapshot
+		msg := readMessage(t, rdr)
+		require.EqualValues(t, msgTypeVertex, msg.Type)
+		require.EqualValues(t, genesisVID.ID.StringHex(), msg.Vertex.ID)
+		require.EqualValues(t, kindVirtual, msg.Vertex.Kind)
+		require.True(t, msg.Vertex.Branch)
+
+		// changes
+		for _, fun := range env.onVertex {
+			fun(distribVID)
+		}
+		for range env.onVertex {
+			msg = readMessage(t, rdr)
+			require.EqualValues(t, msgTypeVertex, msg.Type)
+			require.EqualValues(t, distribVID.ID.StringHex(), msg.Vertex.ID)
+			require.EqualValues(t, kindVertex, msg.Vertex.Kind)
+			require.EqualValues(t, "UNDEF", msg.Vertex.Status)
+			require.True(t, msg.Vertex.Branch)
+			// distribution transaction consumes genesis output and genesis stem
+			require.EqualValues(t, 2, len(msg.Vertex.Inputs))
+			kinds := make(map[string]bool)
+			for _, inp := range msg.Vertex.Inputs {
+				require.EqualValues(t, genesisVID.ID.StringHex(), inp.To)
+				kinds[inp.Kind] = true
+			}
+			require.True(t, kinds[edgeKindStem])
+		}
+		env.onBooked(distribTxID)
+		msg = readMessage(t, rdr)
+		require.EqualValues(t, msgTypeBooked, msg.Type)
+		require.EqualValues(t, distribTxID.StringHex(), msg.ID)
+
+		env.onDeleted(distribTxID)
+		msg = readMessage(t, rdr)
+		require.EqualValues(t, msgTypeDeleted, msg.Type)
+		require.EqualValues(t, distribTxID.StringHex(), msg.ID)
+	})
+}